@@ -0,0 +1,93 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package capacityprofiles
+
+import (
+	"reflect"
+
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider"
+)
+
+// NewCloudProvider wraps a cloud provider so that minimum sizes of its node
+// groups are raised by profiles active in the manager. All code relying on
+// MinSize, including scale-down, keeps the raised minimum this way.
+func NewCloudProvider(delegate cloudprovider.CloudProvider, manager *Manager) cloudprovider.CloudProvider {
+	return &cloudProvider{CloudProvider: delegate, manager: manager}
+}
+
+type cloudProvider struct {
+	cloudprovider.CloudProvider
+	manager *Manager
+}
+
+func (p *cloudProvider) wrap(delegate cloudprovider.NodeGroup) cloudprovider.NodeGroup {
+	if delegate == nil || reflect.ValueOf(delegate).IsNil() {
+		return nil
+	}
+	return &nodeGroup{NodeGroup: delegate, manager: p.manager}
+}
+
+// NodeGroups returns all node groups configured for this cloud provider.
+func (p *cloudProvider) NodeGroups() []cloudprovider.NodeGroup {
+	delegates := p.CloudProvider.NodeGroups()
+	result := make([]cloudprovider.NodeGroup, 0, len(delegates))
+	for _, delegate := range delegates {
+		result = append(result, p.wrap(delegate))
+	}
+	return result
+}
+
+// NodeGroupForNode returns the node group for the given node.
+func (p *cloudProvider) NodeGroupForNode(node *apiv1.Node) (cloudprovider.NodeGroup, error) {
+	delegate, err := p.CloudProvider.NodeGroupForNode(node)
+	if err != nil {
+		return nil, err
+	}
+	return p.wrap(delegate), nil
+}
+
+// NewNodeGroup builds a theoretical node group based on the node definition provided.
+func (p *cloudProvider) NewNodeGroup(machineType string, labels map[string]string, systemLabels map[string]string,
+	taints []apiv1.Taint, extraResources map[string]resource.Quantity) (cloudprovider.NodeGroup, error) {
+	delegate, err := p.CloudProvider.NewNodeGroup(machineType, labels, systemLabels, taints, extraResources)
+	if err != nil {
+		return nil, err
+	}
+	return p.wrap(delegate), nil
+}
+
+type nodeGroup struct {
+	cloudprovider.NodeGroup
+	manager *Manager
+}
+
+// MinSize returns the minimum size of the node group, raised by the active
+// capacity profile.
+func (ng *nodeGroup) MinSize() int {
+	return ng.manager.MinSize(ng.Id(), ng.NodeGroup.MinSize(), ng.NodeGroup.MaxSize())
+}
+
+// Create creates the node group on the cloud provider side.
+func (ng *nodeGroup) Create() (cloudprovider.NodeGroup, error) {
+	created, err := ng.NodeGroup.Create()
+	if err != nil {
+		return nil, err
+	}
+	return &nodeGroup{NodeGroup: created, manager: ng.manager}, nil
+}
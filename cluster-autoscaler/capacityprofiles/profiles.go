@@ -0,0 +1,192 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package capacityprofiles implements scheduled capacity profiles. A profile
+// raises the minimum size of node groups during recurring time windows, for
+// example to have capacity ready before business hours. Node groups are scaled
+// up to the raised minimum when a window opens. When it closes, the minimum
+// drops back and the extra nodes are removed by regular scale-down once they
+// become unneeded.
+package capacityprofiles
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v2"
+
+	"k8s.io/autoscaler/cluster-autoscaler/clusterstate"
+	kube_util "k8s.io/autoscaler/cluster-autoscaler/utils/kubernetes"
+	"k8s.io/autoscaler/cluster-autoscaler/utils/schedule"
+	v1lister "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/klog"
+)
+
+const (
+	// CapacityProfilesConfigMapName is the name of the ConfigMap holding capacity profiles.
+	CapacityProfilesConfigMapName = "cluster-autoscaler-capacity-profiles"
+	// ConfigMapKey is the key in the ConfigMap under which profiles are stored.
+	ConfigMapKey = "profiles"
+	// MaxDuration is the maximum duration of a capacity profile window.
+	MaxDuration = 7 * 24 * time.Hour
+)
+
+// Profile raises minimum sizes of node groups for Duration, every time
+// Schedule fires. Schedules are evaluated in UTC.
+type Profile struct {
+	// Name identifies the profile in status and events.
+	Name string `yaml:"name"`
+	// Schedule is a five field cron expression, see schedule.Schedule.
+	Schedule string `yaml:"schedule"`
+	// Duration is how long the profile stays active after Schedule fires.
+	Duration time.Duration `yaml:"duration"`
+	// NodeGroups maps node group ids to their minimum sizes while the profile is active.
+	NodeGroups map[string]int `yaml:"nodeGroups"`
+}
+
+type parsedProfile struct {
+	Profile
+	schedule *schedule.Schedule
+}
+
+// parseProfiles parses and validates a YAML list of profiles.
+func parseProfiles(config string) ([]parsedProfile, error) {
+	var profiles []Profile
+	if err := yaml.Unmarshal([]byte(config), &profiles); err != nil {
+		return nil, fmt.Errorf("can't parse YAML: %v", err)
+	}
+	result := make([]parsedProfile, 0, len(profiles))
+	names := make(map[string]bool)
+	for _, profile := range profiles {
+		if profile.Name == "" {
+			return nil, fmt.Errorf("capacity profile without a name")
+		}
+		if names[profile.Name] {
+			return nil, fmt.Errorf("duplicate capacity profile %s", profile.Name)
+		}
+		names[profile.Name] = true
+		parsedSchedule, err := schedule.Parse(profile.Schedule)
+		if err != nil {
+			return nil, fmt.Errorf("invalid schedule of capacity profile %s: %v", profile.Name, err)
+		}
+		if profile.Duration <= 0 || profile.Duration > MaxDuration {
+			return nil, fmt.Errorf("duration of capacity profile %s has to be positive and not longer than %v, got %v",
+				profile.Name, MaxDuration, profile.Duration)
+		}
+		for nodeGroup, minSize := range profile.NodeGroups {
+			if minSize < 0 {
+				return nil, fmt.Errorf("negative min size %d of node group %s in capacity profile %s", minSize, nodeGroup, profile.Name)
+			}
+		}
+		result = append(result, parsedProfile{Profile: profile, schedule: parsedSchedule})
+	}
+	return result, nil
+}
+
+// Manager keeps track of capacity profiles configured in a ConfigMap and of
+// the ones active at the time of the last Refresh.
+type Manager struct {
+	sync.Mutex
+	configLoader *kube_util.ConfigMapLoader
+	profiles     []parsedProfile
+	active       map[string]clusterstate.CapacityProfile
+}
+
+// NewManager creates a Manager reading profiles from the capacity profiles
+// ConfigMap.
+func NewManager(configMapLister v1lister.ConfigMapNamespaceLister, logRecorder record.EventRecorder) *Manager {
+	return &Manager{
+		configLoader: kube_util.NewConfigMapLoader(configMapLister, logRecorder, CapacityProfilesConfigMapName, ConfigMapKey,
+			"capacity profiles", "CapacityProfilesConfigMapInvalid"),
+		active: make(map[string]clusterstate.CapacityProfile),
+	}
+}
+
+// Refresh reloads the ConfigMap and determines profiles active at now. If the
+// ConfigMap is invalid, previously loaded profiles are used.
+func (m *Manager) Refresh(now time.Time) {
+	var profiles []parsedProfile
+	err := m.configLoader.Load(func(config string) error {
+		var err error
+		profiles, err = parseProfiles(config)
+		return err
+	})
+	m.Lock()
+	defer m.Unlock()
+	if err == nil {
+		m.profiles = profiles
+	}
+
+	now = now.UTC()
+	active := make(map[string]clusterstate.CapacityProfile)
+	for _, profile := range m.profiles {
+		start, found := profile.schedule.LastStart(now, profile.Duration)
+		if !found {
+			continue
+		}
+		for nodeGroup, minSize := range profile.NodeGroups {
+			if current, found := active[nodeGroup]; found && current.MinSize >= minSize {
+				continue
+			}
+			active[nodeGroup] = clusterstate.CapacityProfile{
+				Name:    profile.Name,
+				MinSize: minSize,
+				Until:   start.Add(profile.Duration),
+			}
+		}
+	}
+	for nodeGroup, profile := range active {
+		if previous, found := m.active[nodeGroup]; !found || previous.Name != profile.Name {
+			klog.V(1).Infof("Capacity profile %s is active for node group %s until %v, min size %d",
+				profile.Name, nodeGroup, profile.Until, profile.MinSize)
+		}
+	}
+	for nodeGroup, profile := range m.active {
+		if _, found := active[nodeGroup]; !found {
+			klog.V(1).Infof("Capacity profile %s is no longer active for node group %s", profile.Name, nodeGroup)
+		}
+	}
+	m.active = active
+}
+
+// MinSize returns the minimum size of the node group raised by the active
+// profile, if there is one, but never above the maximum size.
+func (m *Manager) MinSize(nodeGroup string, minSize, maxSize int) int {
+	m.Lock()
+	defer m.Unlock()
+	profile, found := m.active[nodeGroup]
+	if !found || profile.MinSize <= minSize {
+		return minSize
+	}
+	if profile.MinSize > maxSize {
+		return maxSize
+	}
+	return profile.MinSize
+}
+
+// ActiveProfiles returns the profiles active at the time of the last Refresh,
+// by node group id.
+func (m *Manager) ActiveProfiles() map[string]clusterstate.CapacityProfile {
+	m.Lock()
+	defer m.Unlock()
+	result := make(map[string]clusterstate.CapacityProfile, len(m.active))
+	for nodeGroup, profile := range m.active {
+		result[nodeGroup] = profile
+	}
+	return result
+}
@@ -0,0 +1,157 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package capacityprofiles
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	testprovider "k8s.io/autoscaler/cluster-autoscaler/cloudprovider/test"
+	"k8s.io/autoscaler/cluster-autoscaler/clusterstate"
+	kube_util "k8s.io/autoscaler/cluster-autoscaler/utils/kubernetes"
+	"k8s.io/client-go/tools/record"
+)
+
+const (
+	testNamespace = "kube-system"
+	config        = `
+- name: business-hours
+  schedule: "0 8 * * 1-5"
+  duration: 10h
+  nodeGroups:
+    ng1: 5
+    ng2: 3
+- name: batch
+  schedule: "0 12 * * *"
+  duration: 1h
+  nodeGroups:
+    ng1: 8
+`
+)
+
+var (
+	// Monday.
+	businessHoursStart = time.Date(2019, time.June, 3, 8, 0, 0, 0, time.UTC)
+)
+
+func newTestManager(t *testing.T, data map[string]string) (*Manager, *record.FakeRecorder) {
+	var configMaps []*apiv1.ConfigMap
+	if data != nil {
+		configMaps = append(configMaps, &apiv1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: testNamespace,
+				Name:      CapacityProfilesConfigMapName,
+			},
+			Data: data,
+		})
+	}
+	lister, err := kube_util.NewTestConfigMapLister(configMaps)
+	assert.NoError(t, err)
+	recorder := record.NewFakeRecorder(10)
+	return NewManager(lister.ConfigMaps(testNamespace), recorder), recorder
+}
+
+func TestManagerActiveProfiles(t *testing.T) {
+	manager, _ := newTestManager(t, map[string]string{ConfigMapKey: config})
+
+	manager.Refresh(businessHoursStart.Add(-time.Minute))
+	assert.Empty(t, manager.ActiveProfiles())
+	assert.Equal(t, 1, manager.MinSize("ng1", 1, 10))
+
+	manager.Refresh(businessHoursStart.Add(time.Hour))
+	assert.Equal(t, map[string]clusterstate.CapacityProfile{
+		"ng1": {Name: "business-hours", MinSize: 5, Until: businessHoursStart.Add(10 * time.Hour)},
+		"ng2": {Name: "business-hours", MinSize: 3, Until: businessHoursStart.Add(10 * time.Hour)},
+	}, manager.ActiveProfiles())
+	assert.Equal(t, 5, manager.MinSize("ng1", 1, 10))
+	// Min size is never lowered, nor raised above max size.
+	assert.Equal(t, 6, manager.MinSize("ng1", 6, 10))
+	assert.Equal(t, 4, manager.MinSize("ng1", 1, 4))
+	assert.Equal(t, 1, manager.MinSize("ng3", 1, 10))
+
+	// The profile with the highest min size wins.
+	manager.Refresh(businessHoursStart.Add(4*time.Hour + 30*time.Minute))
+	assert.Equal(t, 8, manager.MinSize("ng1", 1, 10))
+	assert.Equal(t, 3, manager.MinSize("ng2", 1, 10))
+	assert.Equal(t, "batch", manager.ActiveProfiles()["ng1"].Name)
+
+	manager.Refresh(businessHoursStart.Add(10 * time.Hour))
+	assert.Empty(t, manager.ActiveProfiles())
+	assert.Equal(t, 1, manager.MinSize("ng1", 1, 10))
+}
+
+func TestManagerNoConfigMap(t *testing.T) {
+	manager, _ := newTestManager(t, nil)
+	manager.Refresh(businessHoursStart)
+	assert.Empty(t, manager.ActiveProfiles())
+}
+
+func TestManagerInvalidConfig(t *testing.T) {
+	for _, data := range []map[string]string{
+		{"other": config},
+		{ConfigMapKey: "not a list"},
+		{ConfigMapKey: "- schedule: \"* * * * *\"\n  duration: 1h"},
+		{ConfigMapKey: "- name: p\n  schedule: \"* * * *\"\n  duration: 1h"},
+		{ConfigMapKey: "- name: p\n  schedule: \"* * * * *\""},
+		{ConfigMapKey: "- name: p\n  schedule: \"* * * * *\"\n  duration: 200h"},
+		{ConfigMapKey: "- name: p\n  schedule: \"* * * * *\"\n  duration: 1h\n  nodeGroups:\n    ng1: -1"},
+		{ConfigMapKey: "- name: p\n  schedule: \"* * * * *\"\n  duration: 1h\n- name: p\n  schedule: \"* * * * *\"\n  duration: 1h"},
+	} {
+		manager, recorder := newTestManager(t, data)
+		// Profiles loaded before are kept.
+		manager.profiles, _ = parseProfiles(config)
+		manager.Refresh(businessHoursStart)
+		assert.Equal(t, 5, manager.MinSize("ng1", 1, 10), "config %v", data)
+		select {
+		case event := <-recorder.Events:
+			assert.Contains(t, event, "CapacityProfilesConfigMapInvalid")
+		default:
+			t.Errorf("no warning event for config %v", data)
+		}
+	}
+}
+
+func TestCloudProvider(t *testing.T) {
+	manager, _ := newTestManager(t, map[string]string{ConfigMapKey: config})
+	manager.Refresh(businessHoursStart)
+
+	delegate := testprovider.NewTestCloudProvider(nil, nil)
+	delegate.AddNodeGroup("ng1", 1, 10, 1)
+	delegate.AddNodeGroup("ng3", 1, 10, 1)
+	node := &apiv1.Node{ObjectMeta: metav1.ObjectMeta{Name: "n1"}}
+	delegate.AddNode("ng1", node)
+	provider := NewCloudProvider(delegate, manager)
+
+	minSizes := make(map[string]int)
+	for _, nodeGroup := range provider.NodeGroups() {
+		minSizes[nodeGroup.Id()] = nodeGroup.MinSize()
+	}
+	assert.Equal(t, map[string]int{"ng1": 5, "ng3": 1}, minSizes)
+
+	nodeGroup, err := provider.NodeGroupForNode(node)
+	assert.NoError(t, err)
+	assert.Equal(t, 5, nodeGroup.MinSize())
+	assert.Equal(t, 10, nodeGroup.MaxSize())
+
+	nodeGroup, err = provider.NodeGroupForNode(&apiv1.Node{ObjectMeta: metav1.ObjectMeta{Name: "n2"}})
+	assert.NoError(t, err)
+	assert.Nil(t, nodeGroup)
+}
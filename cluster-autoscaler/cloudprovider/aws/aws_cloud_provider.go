@@ -212,6 +212,12 @@ func (ng *AwsNodeGroup) Autoprovisioned() bool {
 	return false
 }
 
+// GetOptions returns NodeGroupAutoscalingOptions that should be used for this particular
+// NodeGroup. Defaults are overridden by the autoscaling-options tags set on the ASG.
+func (ng *AwsNodeGroup) GetOptions(defaults config.NodeGroupAutoscalingOptions) (*config.NodeGroupAutoscalingOptions, error) {
+	return extractAutoscalingOptionsFromAsg(ng.asg.Tags, defaults), nil
+}
+
 // Delete deletes the node group on the cloud provider side.
 // This will be executed only for autoprovisioned node groups, once their size drops to 0.
 func (ng *AwsNodeGroup) Delete() error {
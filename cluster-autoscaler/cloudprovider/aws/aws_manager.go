@@ -25,6 +25,7 @@ import (
 	"math/rand"
 	"os"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
@@ -39,6 +40,7 @@ import (
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider"
+	"k8s.io/autoscaler/cluster-autoscaler/config"
 	"k8s.io/autoscaler/cluster-autoscaler/utils/gpu"
 	"k8s.io/klog"
 	provider_aws "k8s.io/kubernetes/pkg/cloudprovider/providers/aws"
@@ -51,6 +53,8 @@ const (
 	maxRecordsReturnedByAPI = 100
 	maxAsgNamesPerDescribe  = 50
 	refreshInterval         = 1 * time.Minute
+
+	autoscalingOptionsTagPrefix = "k8s.io/cluster-autoscaler/node-template/autoscaling-options/"
 )
 
 // AwsManager is handles aws communication and data caching.
@@ -416,6 +420,75 @@ func extractAllocatableResourcesFromAsg(tags []*autoscaling.TagDescription) map[
 	return result
 }
 
+// extractAutoscalingOptionsFromAsg returns a copy of defaults with the values
+// overridden by the autoscaling-options tags. Tags with invalid values are ignored.
+func extractAutoscalingOptionsFromAsg(tags []*autoscaling.TagDescription, defaults config.NodeGroupAutoscalingOptions) *config.NodeGroupAutoscalingOptions {
+	options := defaults
+
+	for _, tag := range tags {
+		k := *tag.Key
+		v := *tag.Value
+		if !strings.HasPrefix(k, autoscalingOptionsTagPrefix) {
+			continue
+		}
+		var err error
+		switch option := strings.TrimPrefix(k, autoscalingOptionsTagPrefix); option {
+		case "scaledownutilizationthreshold":
+			options.ScaleDownUtilizationThreshold, err = parseUtilizationThreshold(v, defaults.ScaleDownUtilizationThreshold)
+		case "scaledowngpuutilizationthreshold":
+			options.ScaleDownGpuUtilizationThreshold, err = parseUtilizationThreshold(v, defaults.ScaleDownGpuUtilizationThreshold)
+		case "scaledownunneededtime":
+			options.ScaleDownUnneededTime, err = parseDuration(v, defaults.ScaleDownUnneededTime)
+		case "scaledownunreadytime":
+			options.ScaleDownUnreadyTime, err = parseDuration(v, defaults.ScaleDownUnreadyTime)
+		case "scaledownbudgetmaxnodes":
+			options.ScaleDownBudgetMaxNodes, err = parseBudgetMaxNodes(v, defaults.ScaleDownBudgetMaxNodes)
+		case "scaledownbudgetmaxratio":
+			options.ScaleDownBudgetMaxRatio, err = parseUtilizationThreshold(v, defaults.ScaleDownBudgetMaxRatio)
+		default:
+			klog.Warningf("Unknown autoscaling option tag %s", k)
+		}
+		if err != nil {
+			klog.Warningf("Failed to parse tag %s=%s, using default: %v", k, v, err)
+		}
+	}
+
+	return &options
+}
+
+func parseUtilizationThreshold(value string, defaultValue float64) (float64, error) {
+	threshold, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return defaultValue, err
+	}
+	if threshold < 0 || threshold > 1 {
+		return defaultValue, fmt.Errorf("threshold must be between 0 and 1, got %v", threshold)
+	}
+	return threshold, nil
+}
+
+func parseDuration(value string, defaultValue time.Duration) (time.Duration, error) {
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return defaultValue, err
+	}
+	if duration < 0 {
+		return defaultValue, fmt.Errorf("duration must not be negative, got %v", duration)
+	}
+	return duration, nil
+}
+
+func parseBudgetMaxNodes(value string, defaultValue int) (int, error) {
+	maxNodes, err := strconv.Atoi(value)
+	if err != nil {
+		return defaultValue, err
+	}
+	if maxNodes < 0 {
+		return defaultValue, fmt.Errorf("max nodes must not be negative, got %v", maxNodes)
+	}
+	return maxNodes, nil
+}
+
 func extractTaintsFromAsg(tags []*autoscaling.TagDescription) []apiv1.Taint {
 	taints := make([]apiv1.Taint, 0)
 
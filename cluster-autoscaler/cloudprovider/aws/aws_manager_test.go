@@ -26,6 +26,7 @@ import (
 	"sort"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/autoscaling"
@@ -35,6 +36,7 @@ import (
 	apiv1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider"
+	"k8s.io/autoscaler/cluster-autoscaler/config"
 	provider_aws "k8s.io/kubernetes/pkg/cloudprovider/providers/aws"
 	kubeletapis "k8s.io/kubernetes/pkg/kubelet/apis"
 )
@@ -127,6 +129,57 @@ func TestExtractLabelsFromAsg(t *testing.T) {
 	assert.Equal(t, "bar", labels["foo"])
 }
 
+func TestExtractAutoscalingOptionsFromAsg(t *testing.T) {
+	defaults := config.NodeGroupAutoscalingOptions{
+		ScaleDownUtilizationThreshold:    0.5,
+		ScaleDownGpuUtilizationThreshold: 0.5,
+		ScaleDownUnneededTime:            10 * time.Minute,
+		ScaleDownUnreadyTime:             20 * time.Minute,
+		ScaleDownBudgetMaxNodes:          3,
+	}
+	tags := []*autoscaling.TagDescription{
+		{
+			Key:   aws.String("k8s.io/cluster-autoscaler/node-template/autoscaling-options/scaledownutilizationthreshold"),
+			Value: aws.String("0.7"),
+		},
+		{
+			Key:   aws.String("k8s.io/cluster-autoscaler/node-template/autoscaling-options/scaledowngpuutilizationthreshold"),
+			Value: aws.String("1.5"),
+		},
+		{
+			Key:   aws.String("k8s.io/cluster-autoscaler/node-template/autoscaling-options/scaledownunneededtime"),
+			Value: aws.String("1h"),
+		},
+		{
+			Key:   aws.String("k8s.io/cluster-autoscaler/node-template/autoscaling-options/scaledownunreadytime"),
+			Value: aws.String("not-a-duration"),
+		},
+		{
+			Key:   aws.String("k8s.io/cluster-autoscaler/node-template/autoscaling-options/scaledownbudgetmaxnodes"),
+			Value: aws.String("-1"),
+		},
+		{
+			Key:   aws.String("k8s.io/cluster-autoscaler/node-template/autoscaling-options/scaledownbudgetmaxratio"),
+			Value: aws.String("0.1"),
+		},
+		{
+			Key:   aws.String("bar"),
+			Value: aws.String("baz"),
+		},
+	}
+
+	options := extractAutoscalingOptionsFromAsg(tags, defaults)
+
+	assert.Equal(t, &config.NodeGroupAutoscalingOptions{
+		ScaleDownUtilizationThreshold:    0.7,
+		ScaleDownGpuUtilizationThreshold: 0.5,
+		ScaleDownUnneededTime:            time.Hour,
+		ScaleDownUnreadyTime:             20 * time.Minute,
+		ScaleDownBudgetMaxNodes:          3,
+		ScaleDownBudgetMaxRatio:          0.1,
+	}, options)
+}
+
 func TestExtractTaintsFromAsg(t *testing.T) {
 	tags := []*autoscaling.TagDescription{
 		{
@@ -1,4 +1,4 @@
-// +build !gce,!aws,!azure,!kubemark,!alicloud,!magnum
+// +build !gce,!aws,!azure,!kubemark,!alicloud,!magnum,!externalgrpc
 
 /*
 Copyright 2018 The Kubernetes Authors.
@@ -24,6 +24,7 @@ import (
 	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider/aws"
 	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider/azure"
 	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider/baiducloud"
+	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider/externalgrpc"
 	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider/gce"
 	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider/magnum"
 	"k8s.io/autoscaler/cluster-autoscaler/config"
@@ -37,6 +38,7 @@ var AvailableCloudProviders = []string{
 	alicloud.ProviderName,
 	baiducloud.ProviderName,
 	magnum.ProviderName,
+	externalgrpc.ProviderName,
 }
 
 // DefaultCloudProvider is GCE.
@@ -56,6 +58,8 @@ func buildCloudProvider(opts config.AutoscalingOptions, do cloudprovider.NodeGro
 		return baiducloud.BuildBaiducloud(opts, do, rl)
 	case magnum.ProviderName:
 		return magnum.BuildMagnum(opts, do, rl)
+	case externalgrpc.ProviderName:
+		return externalgrpc.BuildExternalGrpc(opts, do, rl)
 	}
 	return nil
 }
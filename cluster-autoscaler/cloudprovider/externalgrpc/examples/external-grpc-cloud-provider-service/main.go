@@ -0,0 +1,178 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// external-grpc-cloud-provider-service is a reference implementation of the
+// externalgrpc cloud provider service. It serves an in-memory
+// TestCloudProvider, which makes it possible to run the cluster autoscaler
+// against the externalgrpc protocol on a single machine.
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider"
+	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider/externalgrpc/examples/external-grpc-cloud-provider-service/wrapper"
+	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider/externalgrpc/protos"
+	testprovider "k8s.io/autoscaler/cluster-autoscaler/cloudprovider/test"
+	"k8s.io/autoscaler/cluster-autoscaler/config/dynamic"
+	"k8s.io/autoscaler/cluster-autoscaler/utils/test"
+	"k8s.io/klog"
+	schedulernodeinfo "k8s.io/kubernetes/pkg/scheduler/nodeinfo"
+)
+
+// MultiStringFlag is a flag for passing multiple parameters using same flag
+type MultiStringFlag []string
+
+// String returns string representation of the flag values.
+func (flag *MultiStringFlag) String() string {
+	return "[" + strings.Join(*flag, " ") + "]"
+}
+
+// Set adds a new value to the flag.
+func (flag *MultiStringFlag) Set(value string) error {
+	*flag = append(*flag, value)
+	return nil
+}
+
+func multiStringFlag(name string, usage string) *MultiStringFlag {
+	value := new(MultiStringFlag)
+	flag.Var(value, name, usage)
+	return value
+}
+
+var (
+	address = flag.String("address", ":8086", "The address to expose the grpc service.")
+	keyCert = flag.String("key-cert", "", "The path to the certificate key file. Empty string for insecure communication.")
+	cert    = flag.String("cert", "", "The path to the certificate file. Empty string for insecure communication.")
+	cacert  = flag.String("ca-cert", "", "The path to the ca certificate file. Empty string for insecure communication.")
+
+	nodeGroupsFlag = multiStringFlag("nodes",
+		"Sets min,max size and other configuration data for a node group in a format accepted by the cloud provider. Can be used multiple times. Format: <min>:<max>:<node group name>")
+	nodesFlag = multiStringFlag("node",
+		"Registers an existing node as a member of a node group. Can be used multiple times. Format: <node group name>:<node name>")
+	templateCpu    = flag.Int64("template-cpu", 1000, "Allocatable cpu of template nodes, in millicores.")
+	templateMemory = flag.Int64("template-memory", 4*1024*1024*1024, "Allocatable memory of template nodes, in bytes.")
+)
+
+func main() {
+	klog.InitFlags(nil)
+	flag.Parse()
+
+	provider, err := buildTestCloudProvider(*nodeGroupsFlag, *nodesFlag)
+	if err != nil {
+		klog.Fatalf("Failed to build cloud provider: %v", err)
+	}
+
+	var serverOpts []grpc.ServerOption
+	if *keyCert == "" || *cert == "" || *cacert == "" {
+		klog.V(1).Info("Not all TLS flags set, using insecure communication")
+	} else {
+		creds, err := serverCredentials(*keyCert, *cert, *cacert)
+		if err != nil {
+			klog.Fatalf("Failed to load TLS credentials: %v", err)
+		}
+		serverOpts = append(serverOpts, grpc.Creds(creds))
+	}
+	server := grpc.NewServer(serverOpts...)
+	protos.RegisterCloudProviderServer(server, wrapper.NewCloudProviderGrpcWrapper(provider))
+
+	lis, err := net.Listen("tcp", *address)
+	if err != nil {
+		klog.Fatalf("Failed to listen: %v", err)
+	}
+	klog.V(1).Infof("Serving external cloud provider on %s", lis.Addr())
+	if err := server.Serve(lis); err != nil {
+		klog.Fatalf("Failed to serve: %v", err)
+	}
+}
+
+// buildTestCloudProvider creates a TestCloudProvider with the given node
+// groups and nodes. Scale up and scale down requests are only logged; the
+// target sizes are tracked by the TestCloudProvider itself.
+func buildTestCloudProvider(nodeGroupSpecs []string, nodes []string) (*testprovider.TestCloudProvider, error) {
+	onScaleUp := func(id string, delta int) error {
+		klog.Infof("Scale up of node group %s by %d", id, delta)
+		return nil
+	}
+	onScaleDown := func(id string, node string) error {
+		klog.Infof("Scale down of node group %s, deleting node %s", id, node)
+		return nil
+	}
+	specs := make([]*dynamic.NodeGroupSpec, 0, len(nodeGroupSpecs))
+	templates := make(map[string]*schedulernodeinfo.NodeInfo)
+	for _, value := range nodeGroupSpecs {
+		spec, err := dynamic.SpecFromString(value, true)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse node group spec: %v", err)
+		}
+		specs = append(specs, spec)
+		templates[spec.Name] = buildTemplateNodeInfo(spec.Name)
+	}
+	provider := testprovider.NewTestAutoprovisioningCloudProvider(onScaleUp, onScaleDown, nil, nil, nil, templates)
+	for _, spec := range specs {
+		provider.AddNodeGroup(spec.Name, spec.MinSize, spec.MaxSize, spec.MinSize)
+	}
+	for _, value := range nodes {
+		tokens := strings.SplitN(value, ":", 2)
+		if len(tokens) != 2 {
+			return nil, fmt.Errorf("wrong node configuration: %s", value)
+		}
+		if provider.GetNodeGroup(tokens[0]) == nil {
+			return nil, fmt.Errorf("node group %s of node %s not defined", tokens[0], tokens[1])
+		}
+		provider.AddNode(tokens[0], &apiv1.Node{ObjectMeta: metav1.ObjectMeta{Name: tokens[1]}})
+	}
+	return provider, nil
+}
+
+func buildTemplateNodeInfo(nodeGroup string) *schedulernodeinfo.NodeInfo {
+	node := test.BuildTestNode(fmt.Sprintf("%s-template", nodeGroup), *templateCpu, *templateMemory)
+	test.SetNodeReadyState(node, true, metav1.Now().Time)
+	nodeInfo := schedulernodeinfo.NewNodeInfo(cloudprovider.BuildKubeProxy(nodeGroup))
+	nodeInfo.SetNode(node)
+	return nodeInfo
+}
+
+func serverCredentials(keyCert, cert, cacert string) (credentials.TransportCredentials, error) {
+	certificate, err := tls.LoadX509KeyPair(cert, keyCert)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read certificate files: %v", err)
+	}
+	certPool := x509.NewCertPool()
+	ca, err := ioutil.ReadFile(cacert)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read client ca cert: %v", err)
+	}
+	if ok := certPool.AppendCertsFromPEM(ca); !ok {
+		return nil, fmt.Errorf("failed to append client certs")
+	}
+	return credentials.NewTLS(&tls.Config{
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		Certificates: []tls.Certificate{certificate},
+		ClientCAs:    certPool,
+	}), nil
+}
@@ -0,0 +1,393 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package wrapper
+
+import (
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/duration"
+	"github.com/golang/protobuf/ptypes/timestamp"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider"
+	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider/externalgrpc/protos"
+	"k8s.io/autoscaler/cluster-autoscaler/config"
+)
+
+// Wrapper implements protos.CloudProviderServer on top of any
+// cloudprovider.CloudProvider.
+type Wrapper struct {
+	provider cloudprovider.CloudProvider
+}
+
+// NewCloudProviderGrpcWrapper creates a grpc server that serves the given
+// cloud provider.
+func NewCloudProviderGrpcWrapper(provider cloudprovider.CloudProvider) *Wrapper {
+	return &Wrapper{
+		provider: provider,
+	}
+}
+
+// NodeGroups returns all node groups configured for this cloud provider.
+func (w *Wrapper) NodeGroups(_ context.Context, _ *protos.NodeGroupsRequest) (*protos.NodeGroupsResponse, error) {
+	pbNgs := make([]*protos.NodeGroup, 0)
+	for _, ng := range w.provider.NodeGroups() {
+		pbNgs = append(pbNgs, pbNodeGroup(ng))
+	}
+	return &protos.NodeGroupsResponse{
+		NodeGroups: pbNgs,
+	}, nil
+}
+
+// NodeGroupForNode returns the node group for the given node.
+func (w *Wrapper) NodeGroupForNode(_ context.Context, req *protos.NodeGroupForNodeRequest) (*protos.NodeGroupForNodeResponse, error) {
+	pbNode := req.GetNode()
+	if pbNode == nil {
+		return nil, status.Error(codes.InvalidArgument, "node cannot be nil")
+	}
+	node := apiNode(pbNode)
+	ng, err := w.provider.NodeGroupForNode(node)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	// Node group id "" means the node should not be processed by cluster autoscaler.
+	if ng == nil || reflect.ValueOf(ng).IsNil() {
+		return &protos.NodeGroupForNodeResponse{
+			NodeGroup: &protos.NodeGroup{Id: ""},
+		}, nil
+	}
+	return &protos.NodeGroupForNodeResponse{
+		NodeGroup: pbNodeGroup(ng),
+	}, nil
+}
+
+// PricingNodePrice returns a theoretical minimum price of running a node for
+// a given period of time on a perfectly matching machine.
+func (w *Wrapper) PricingNodePrice(_ context.Context, req *protos.PricingNodePriceRequest) (*protos.PricingNodePriceResponse, error) {
+	model, pricingErr := w.provider.Pricing()
+	if pricingErr != nil {
+		return nil, grpcError(pricingErr)
+	}
+	pbNode := req.GetNode()
+	if pbNode == nil {
+		return nil, status.Error(codes.InvalidArgument, "node cannot be nil")
+	}
+	startTime, endTime, err := apiTimes(req.GetStartTime(), req.GetEndTime())
+	if err != nil {
+		return nil, err
+	}
+	price, err := model.NodePrice(apiNode(pbNode), startTime, endTime)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return &protos.PricingNodePriceResponse{
+		Price: price,
+	}, nil
+}
+
+// PricingPodPrice returns a theoretical minimum price of running a pod for a given
+// period of time on a perfectly matching machine.
+func (w *Wrapper) PricingPodPrice(_ context.Context, req *protos.PricingPodPriceRequest) (*protos.PricingPodPriceResponse, error) {
+	model, pricingErr := w.provider.Pricing()
+	if pricingErr != nil {
+		return nil, grpcError(pricingErr)
+	}
+	pod := &apiv1.Pod{}
+	if err := pod.Unmarshal(req.GetPod()); err != nil {
+		return nil, status.Error(codes.InvalidArgument, fmt.Sprintf("failed to decode pod: %v", err))
+	}
+	startTime, endTime, err := apiTimes(req.GetStartTime(), req.GetEndTime())
+	if err != nil {
+		return nil, err
+	}
+	price, err := model.PodPrice(pod, startTime, endTime)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return &protos.PricingPodPriceResponse{
+		Price: price,
+	}, nil
+}
+
+// GPULabel returns the label added to nodes with GPU resource.
+func (w *Wrapper) GPULabel(_ context.Context, _ *protos.GPULabelRequest) (*protos.GPULabelResponse, error) {
+	return &protos.GPULabelResponse{
+		Label: w.provider.GPULabel(),
+	}, nil
+}
+
+// GetAvailableGPUTypes return all available GPU types cloud provider supports.
+func (w *Wrapper) GetAvailableGPUTypes(_ context.Context, _ *protos.GetAvailableGPUTypesRequest) (*protos.GetAvailableGPUTypesResponse, error) {
+	gpuTypes := make([]string, 0)
+	for gpuType := range w.provider.GetAvailableGPUTypes() {
+		gpuTypes = append(gpuTypes, gpuType)
+	}
+	return &protos.GetAvailableGPUTypesResponse{
+		GpuTypes: gpuTypes,
+	}, nil
+}
+
+// Cleanup cleans up open resources before the cloud provider is destroyed, i.e. go routines etc.
+func (w *Wrapper) Cleanup(_ context.Context, _ *protos.CleanupRequest) (*protos.CleanupResponse, error) {
+	if err := w.provider.Cleanup(); err != nil {
+		return nil, grpcError(err)
+	}
+	return &protos.CleanupResponse{}, nil
+}
+
+// Refresh is called before every main loop and can be used to dynamically update cloud provider state.
+func (w *Wrapper) Refresh(_ context.Context, _ *protos.RefreshRequest) (*protos.RefreshResponse, error) {
+	if err := w.provider.Refresh(); err != nil {
+		return nil, grpcError(err)
+	}
+	return &protos.RefreshResponse{}, nil
+}
+
+// NodeGroupTargetSize returns the current target size of the node group.
+func (w *Wrapper) NodeGroupTargetSize(_ context.Context, req *protos.NodeGroupTargetSizeRequest) (*protos.NodeGroupTargetSizeResponse, error) {
+	ng, err := w.getNodeGroup(req.GetId())
+	if err != nil {
+		return nil, err
+	}
+	size, err := ng.TargetSize()
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return &protos.NodeGroupTargetSizeResponse{
+		TargetSize: int32(size),
+	}, nil
+}
+
+// NodeGroupIncreaseSize increases the size of the node group.
+func (w *Wrapper) NodeGroupIncreaseSize(_ context.Context, req *protos.NodeGroupIncreaseSizeRequest) (*protos.NodeGroupIncreaseSizeResponse, error) {
+	ng, err := w.getNodeGroup(req.GetId())
+	if err != nil {
+		return nil, err
+	}
+	if err := ng.IncreaseSize(int(req.GetDelta())); err != nil {
+		return nil, grpcError(err)
+	}
+	return &protos.NodeGroupIncreaseSizeResponse{}, nil
+}
+
+// NodeGroupDeleteNodes deletes nodes from this node group.
+func (w *Wrapper) NodeGroupDeleteNodes(_ context.Context, req *protos.NodeGroupDeleteNodesRequest) (*protos.NodeGroupDeleteNodesResponse, error) {
+	ng, err := w.getNodeGroup(req.GetId())
+	if err != nil {
+		return nil, err
+	}
+	nodes := make([]*apiv1.Node, 0, len(req.GetNodes()))
+	for _, pbNode := range req.GetNodes() {
+		nodes = append(nodes, apiNode(pbNode))
+	}
+	if err := ng.DeleteNodes(nodes); err != nil {
+		return nil, grpcError(err)
+	}
+	return &protos.NodeGroupDeleteNodesResponse{}, nil
+}
+
+// NodeGroupDecreaseTargetSize decreases the target size of the node group.
+func (w *Wrapper) NodeGroupDecreaseTargetSize(_ context.Context, req *protos.NodeGroupDecreaseTargetSizeRequest) (*protos.NodeGroupDecreaseTargetSizeResponse, error) {
+	ng, err := w.getNodeGroup(req.GetId())
+	if err != nil {
+		return nil, err
+	}
+	if err := ng.DecreaseTargetSize(int(req.GetDelta())); err != nil {
+		return nil, grpcError(err)
+	}
+	return &protos.NodeGroupDecreaseTargetSizeResponse{}, nil
+}
+
+// NodeGroupNodes returns a list of all nodes that belong to this node group.
+func (w *Wrapper) NodeGroupNodes(_ context.Context, req *protos.NodeGroupNodesRequest) (*protos.NodeGroupNodesResponse, error) {
+	ng, err := w.getNodeGroup(req.GetId())
+	if err != nil {
+		return nil, err
+	}
+	instances, err := ng.Nodes()
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	pbInstances := make([]*protos.Instance, 0, len(instances))
+	for _, instance := range instances {
+		pbInstances = append(pbInstances, &protos.Instance{
+			Id:     instance.Id,
+			Status: pbInstanceStatus(instance.Status),
+		})
+	}
+	return &protos.NodeGroupNodesResponse{
+		Instances: pbInstances,
+	}, nil
+}
+
+// NodeGroupTemplateNodeInfo returns a structure of an empty (as if just started) node.
+func (w *Wrapper) NodeGroupTemplateNodeInfo(_ context.Context, req *protos.NodeGroupTemplateNodeInfoRequest) (*protos.NodeGroupTemplateNodeInfoResponse, error) {
+	ng, err := w.getNodeGroup(req.GetId())
+	if err != nil {
+		return nil, err
+	}
+	info, err := ng.TemplateNodeInfo()
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	if info.Node() == nil {
+		return nil, status.Error(codes.Internal, "cloud provider returned template without node")
+	}
+	node, err := info.Node().Marshal()
+	if err != nil {
+		return nil, status.Error(codes.Internal, fmt.Sprintf("failed to encode template node: %v", err))
+	}
+	return &protos.NodeGroupTemplateNodeInfoResponse{
+		NodeInfo: node,
+	}, nil
+}
+
+// NodeGroupGetOptions returns NodeGroupAutoscalingOptions that should be used for this
+// particular NodeGroup.
+func (w *Wrapper) NodeGroupGetOptions(_ context.Context, req *protos.NodeGroupAutoscalingOptionsRequest) (*protos.NodeGroupAutoscalingOptionsResponse, error) {
+	ng, err := w.getNodeGroup(req.GetId())
+	if err != nil {
+		return nil, err
+	}
+	pbDefaults := req.GetDefaults()
+	if pbDefaults == nil {
+		return nil, status.Error(codes.InvalidArgument, "request fields were nil")
+	}
+	defaults := config.NodeGroupAutoscalingOptions{
+		ScaleDownUtilizationThreshold:    pbDefaults.GetScaleDownUtilizationThreshold(),
+		ScaleDownGpuUtilizationThreshold: pbDefaults.GetScaleDownGpuUtilizationThreshold(),
+	}
+	if defaults.ScaleDownUnneededTime, err = apiDuration(pbDefaults.GetScaleDownUnneededTime()); err != nil {
+		return nil, err
+	}
+	if defaults.ScaleDownUnreadyTime, err = apiDuration(pbDefaults.GetScaleDownUnreadyTime()); err != nil {
+		return nil, err
+	}
+	opts, err := ng.GetOptions(defaults)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	if opts == nil {
+		return nil, status.Error(codes.Internal, "cloud provider returned nil options")
+	}
+	return &protos.NodeGroupAutoscalingOptionsResponse{
+		NodeGroupAutoscalingOptions: &protos.NodeGroupAutoscalingOptions{
+			ScaleDownUtilizationThreshold:    opts.ScaleDownUtilizationThreshold,
+			ScaleDownGpuUtilizationThreshold: opts.ScaleDownGpuUtilizationThreshold,
+			ScaleDownUnneededTime:            ptypes.DurationProto(opts.ScaleDownUnneededTime),
+			ScaleDownUnreadyTime:             ptypes.DurationProto(opts.ScaleDownUnreadyTime),
+		},
+	}, nil
+}
+
+// getNodeGroup returns the node group with the given id, or a NotFound error.
+func (w *Wrapper) getNodeGroup(id string) (cloudprovider.NodeGroup, error) {
+	for _, ng := range w.provider.NodeGroups() {
+		if ng.Id() == id {
+			return ng, nil
+		}
+	}
+	return nil, status.Error(codes.NotFound, fmt.Sprintf("node group %q not found", id))
+}
+
+func pbNodeGroup(ng cloudprovider.NodeGroup) *protos.NodeGroup {
+	return &protos.NodeGroup{
+		Id:      ng.Id(),
+		MinSize: int32(ng.MinSize()),
+		MaxSize: int32(ng.MaxSize()),
+		Debug:   ng.Debug(),
+	}
+}
+
+func apiNode(pbNode *protos.ExternalGrpcNode) *apiv1.Node {
+	return &apiv1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        pbNode.GetName(),
+			Labels:      pbNode.GetLabels(),
+			Annotations: pbNode.GetAnnotations(),
+		},
+		Spec: apiv1.NodeSpec{
+			ProviderID: pbNode.GetProviderID(),
+		},
+	}
+}
+
+func apiTimes(start, end *timestamp.Timestamp) (time.Time, time.Time, error) {
+	startTime, err := ptypes.Timestamp(start)
+	if err != nil {
+		return time.Time{}, time.Time{}, status.Error(codes.InvalidArgument, fmt.Sprintf("invalid start time: %v", err))
+	}
+	endTime, err := ptypes.Timestamp(end)
+	if err != nil {
+		return time.Time{}, time.Time{}, status.Error(codes.InvalidArgument, fmt.Sprintf("invalid end time: %v", err))
+	}
+	return startTime, endTime, nil
+}
+
+func apiDuration(d *duration.Duration) (time.Duration, error) {
+	if d == nil {
+		return 0, nil
+	}
+	result, err := ptypes.Duration(d)
+	if err != nil {
+		return 0, status.Error(codes.InvalidArgument, fmt.Sprintf("invalid duration: %v", err))
+	}
+	return result, nil
+}
+
+func pbInstanceStatus(instanceStatus *cloudprovider.InstanceStatus) *protos.InstanceStatus {
+	if instanceStatus == nil {
+		return &protos.InstanceStatus{
+			InstanceState: protos.InstanceStatus_unspecified,
+		}
+	}
+	pbStatus := &protos.InstanceStatus{}
+	switch instanceStatus.State {
+	case cloudprovider.InstanceRunning:
+		pbStatus.InstanceState = protos.InstanceStatus_instanceRunning
+	case cloudprovider.InstanceCreating:
+		pbStatus.InstanceState = protos.InstanceStatus_instanceCreating
+	case cloudprovider.InstanceDeleting:
+		pbStatus.InstanceState = protos.InstanceStatus_instanceDeleting
+	default:
+		pbStatus.InstanceState = protos.InstanceStatus_unspecified
+	}
+	if instanceStatus.ErrorInfo != nil {
+		pbStatus.ErrorInfo = &protos.InstanceErrorInfo{
+			ErrorCode:          instanceStatus.ErrorInfo.ErrorCode,
+			ErrorMessage:       instanceStatus.ErrorInfo.ErrorMessage,
+			InstanceErrorClass: int32(instanceStatus.ErrorInfo.ErrorClass),
+		}
+	}
+	return pbStatus
+}
+
+// grpcError maps cloudprovider.ErrNotImplemented to the UNIMPLEMENTED status
+// code; all other errors are reported as UNKNOWN.
+func grpcError(err error) error {
+	if err == cloudprovider.ErrNotImplemented {
+		return status.Error(codes.Unimplemented, err.Error())
+	}
+	return status.Error(codes.Unknown, err.Error())
+}
@@ -0,0 +1,392 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package externalgrpc
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/status"
+	yaml "gopkg.in/yaml.v2"
+
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider"
+	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider/externalgrpc/protos"
+	"k8s.io/autoscaler/cluster-autoscaler/config"
+	"k8s.io/autoscaler/cluster-autoscaler/utils/errors"
+	"k8s.io/klog"
+)
+
+const (
+	// ProviderName is the cloud provider name for externalgrpc.
+	ProviderName = "externalgrpc"
+
+	// defaultGrpcTimeout is the timeout of a single call to the external cloud provider.
+	defaultGrpcTimeout = 5 * time.Second
+)
+
+// cloudConfig is the configuration of the externalgrpc cloud provider, read
+// from the --cloud-config file.
+type cloudConfig struct {
+	// Address of the external cloud provider service, in host:port format.
+	Address string `yaml:"address"`
+	// Key is the path to the client private key used for mutual TLS.
+	Key string `yaml:"key"`
+	// Cert is the path to the client certificate used for mutual TLS.
+	Cert string `yaml:"cert"`
+	// Cacert is the path to the CA certificate used to verify the server.
+	Cacert string `yaml:"cacert"`
+	// GrpcTimeout is the timeout of a single call to the external cloud provider.
+	GrpcTimeout time.Duration `yaml:"grpc_timeout"`
+}
+
+// externalGrpcCloudProvider implements CloudProvider interface by calling
+// an external cloud provider service over gRPC.
+type externalGrpcCloudProvider struct {
+	resourceLimiter *cloudprovider.ResourceLimiter
+	client          protos.CloudProviderClient
+	grpcTimeout     time.Duration
+
+	// Responses that do not change between loops are cached until the next
+	// call to Refresh.
+	mutex                 sync.Mutex
+	nodeGroupsCache       []cloudprovider.NodeGroup
+	nodeGroupForNodeCache map[string]cloudprovider.NodeGroup
+	gpuLabelCache         *string
+	gpuTypesCache         map[string]struct{}
+	// Node groups by id, shared by NodeGroups and NodeGroupForNode results so
+	// that their own caches are filled once per loop.
+	nodeGroupCache map[string]*NodeGroup
+}
+
+// BuildExternalGrpc builds externalgrpc cloud provider.
+func BuildExternalGrpc(opts config.AutoscalingOptions, do cloudprovider.NodeGroupDiscoveryOptions, rl *cloudprovider.ResourceLimiter) cloudprovider.CloudProvider {
+	if opts.CloudConfig == "" {
+		klog.Fatal("No config file provided, please specify it via the --cloud-config flag")
+	}
+	configFile, err := os.Open(opts.CloudConfig)
+	if err != nil {
+		klog.Fatalf("Couldn't open cloud provider configuration %s: %v", opts.CloudConfig, err)
+	}
+	defer configFile.Close()
+
+	cfg, err := readCloudConfig(configFile)
+	if err != nil {
+		klog.Fatalf("Failed to read cloud provider configuration %s: %v", opts.CloudConfig, err)
+	}
+	conn, err := dialCloudProvider(cfg)
+	if err != nil {
+		klog.Fatalf("Failed to connect to the external cloud provider at %s: %v", cfg.Address, err)
+	}
+	return newExternalGrpcCloudProvider(protos.NewCloudProviderClient(conn), cfg.GrpcTimeout, rl)
+}
+
+func readCloudConfig(configReader io.Reader) (*cloudConfig, error) {
+	data, err := ioutil.ReadAll(configReader)
+	if err != nil {
+		return nil, err
+	}
+	cfg := &cloudConfig{}
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("can't parse YAML: %v", err)
+	}
+	if cfg.Address == "" {
+		return nil, fmt.Errorf("address of the external cloud provider must be specified")
+	}
+	if cfg.GrpcTimeout <= 0 {
+		cfg.GrpcTimeout = defaultGrpcTimeout
+	}
+	return cfg, nil
+}
+
+// dialCloudProvider creates a connection to the external cloud provider. TLS
+// is used if a CA certificate is configured; the client certificate and key
+// are optional and enable mutual TLS.
+func dialCloudProvider(cfg *cloudConfig) (*grpc.ClientConn, error) {
+	var dialOpt grpc.DialOption
+	if cfg.Cacert == "" {
+		klog.V(1).Info("No CA certificate provided, connecting to the external cloud provider without TLS")
+		dialOpt = grpc.WithInsecure()
+	} else {
+		tlsConfig, err := buildTLSConfig(cfg)
+		if err != nil {
+			return nil, err
+		}
+		dialOpt = grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig))
+	}
+	return grpc.Dial(cfg.Address, dialOpt)
+}
+
+func buildTLSConfig(cfg *cloudConfig) (*tls.Config, error) {
+	caCert, err := ioutil.ReadFile(cfg.Cacert)
+	if err != nil {
+		return nil, fmt.Errorf("could not read CA certificate %s: %v", cfg.Cacert, err)
+	}
+	certPool := x509.NewCertPool()
+	if !certPool.AppendCertsFromPEM(caCert) {
+		return nil, fmt.Errorf("failed to append CA certificate %s", cfg.Cacert)
+	}
+	tlsConfig := &tls.Config{RootCAs: certPool}
+	if host, _, err := net.SplitHostPort(cfg.Address); err == nil {
+		tlsConfig.ServerName = host
+	}
+	if cfg.Cert != "" || cfg.Key != "" {
+		certificate, err := tls.LoadX509KeyPair(cfg.Cert, cfg.Key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client key pair: %v", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{certificate}
+	}
+	return tlsConfig, nil
+}
+
+func newExternalGrpcCloudProvider(client protos.CloudProviderClient, grpcTimeout time.Duration, rl *cloudprovider.ResourceLimiter) *externalGrpcCloudProvider {
+	return &externalGrpcCloudProvider{
+		resourceLimiter:       rl,
+		client:                client,
+		grpcTimeout:           grpcTimeout,
+		nodeGroupForNodeCache: make(map[string]cloudprovider.NodeGroup),
+		nodeGroupCache:        make(map[string]*NodeGroup),
+	}
+}
+
+// Name returns name of the cloud provider.
+func (e *externalGrpcCloudProvider) Name() string {
+	return ProviderName
+}
+
+// NodeGroups returns all node groups configured for this cloud provider.
+func (e *externalGrpcCloudProvider) NodeGroups() []cloudprovider.NodeGroup {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if e.nodeGroupsCache != nil {
+		return e.nodeGroupsCache
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), e.grpcTimeout)
+	defer cancel()
+	klog.V(5).Info("Performing gRPC call NodeGroups")
+	res, err := e.client.NodeGroups(ctx, &protos.NodeGroupsRequest{})
+	if err != nil {
+		klog.V(1).Infof("Error on gRPC call NodeGroups: %v", err)
+		return []cloudprovider.NodeGroup{}
+	}
+	nodeGroups := make([]cloudprovider.NodeGroup, 0, len(res.GetNodeGroups()))
+	for _, pbNg := range res.GetNodeGroups() {
+		nodeGroups = append(nodeGroups, e.newNodeGroup(pbNg))
+	}
+	e.nodeGroupsCache = nodeGroups
+	return nodeGroups
+}
+
+// NodeGroupForNode returns the node group for the given node, nil if the node
+// should not be processed by cluster autoscaler, or non-nil error if such
+// occurred.
+func (e *externalGrpcCloudProvider) NodeGroupForNode(node *apiv1.Node) (cloudprovider.NodeGroup, error) {
+	if node == nil {
+		return nil, fmt.Errorf("node in NodeGroupForNode call cannot be nil")
+	}
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	key := node.Spec.ProviderID
+	if key == "" {
+		key = node.Name
+	}
+	if nodeGroup, found := e.nodeGroupForNodeCache[key]; found {
+		return nodeGroup, nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), e.grpcTimeout)
+	defer cancel()
+	klog.V(5).Infof("Performing gRPC call NodeGroupForNode for node %v", node.Name)
+	res, err := e.client.NodeGroupForNode(ctx, &protos.NodeGroupForNodeRequest{
+		Node: externalGrpcNode(node),
+	})
+	if err != nil {
+		klog.V(1).Infof("Error on gRPC call NodeGroupForNode: %v", err)
+		return nil, convertError(err)
+	}
+	pbNg := res.GetNodeGroup()
+	if pbNg.GetId() == "" {
+		// Node not managed by the cluster autoscaler.
+		e.nodeGroupForNodeCache[key] = nil
+		return nil, nil
+	}
+	nodeGroup := e.newNodeGroup(pbNg)
+	e.nodeGroupForNodeCache[key] = nodeGroup
+	return nodeGroup, nil
+}
+
+// Pricing returns pricing model for this cloud provider or error if not available.
+// Calls to the returned model fail with ErrNotImplemented if the external
+// cloud provider does not support pricing.
+func (e *externalGrpcCloudProvider) Pricing() (cloudprovider.PricingModel, errors.AutoscalerError) {
+	return &pricingModel{
+		client:      e.client,
+		grpcTimeout: e.grpcTimeout,
+	}, nil
+}
+
+// GetAvailableMachineTypes get all machine types that can be requested from the cloud provider.
+// Implementation optional.
+func (e *externalGrpcCloudProvider) GetAvailableMachineTypes() ([]string, error) {
+	return []string{}, cloudprovider.ErrNotImplemented
+}
+
+// NewNodeGroup builds a theoretical node group based on the node definition provided. The node group is not automatically
+// created on the cloud provider side. The node group is not returned by NodeGroups() until it is created.
+// Implementation optional.
+func (e *externalGrpcCloudProvider) NewNodeGroup(machineType string, labels map[string]string, systemLabels map[string]string,
+	taints []apiv1.Taint, extraResources map[string]resource.Quantity) (cloudprovider.NodeGroup, error) {
+	return nil, cloudprovider.ErrNotImplemented
+}
+
+// GetResourceLimiter returns struct containing limits (max, min) for resources (cores, memory etc.).
+func (e *externalGrpcCloudProvider) GetResourceLimiter() (*cloudprovider.ResourceLimiter, error) {
+	return e.resourceLimiter, nil
+}
+
+// GPULabel returns the label added to nodes with GPU resource.
+func (e *externalGrpcCloudProvider) GPULabel() string {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if e.gpuLabelCache != nil {
+		return *e.gpuLabelCache
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), e.grpcTimeout)
+	defer cancel()
+	klog.V(5).Info("Performing gRPC call GPULabel")
+	res, err := e.client.GPULabel(ctx, &protos.GPULabelRequest{})
+	if err != nil {
+		klog.V(1).Infof("Error on gRPC call GPULabel: %v", err)
+		return ""
+	}
+	gpuLabel := res.GetLabel()
+	e.gpuLabelCache = &gpuLabel
+	return gpuLabel
+}
+
+// GetAvailableGPUTypes return all available GPU types cloud provider supports.
+func (e *externalGrpcCloudProvider) GetAvailableGPUTypes() map[string]struct{} {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if e.gpuTypesCache != nil {
+		return e.gpuTypesCache
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), e.grpcTimeout)
+	defer cancel()
+	klog.V(5).Info("Performing gRPC call GetAvailableGPUTypes")
+	res, err := e.client.GetAvailableGPUTypes(ctx, &protos.GetAvailableGPUTypesRequest{})
+	if err != nil {
+		klog.V(1).Infof("Error on gRPC call GetAvailableGPUTypes: %v", err)
+		return nil
+	}
+	gpuTypes := make(map[string]struct{})
+	for _, gpuType := range res.GetGpuTypes() {
+		gpuTypes[gpuType] = struct{}{}
+	}
+	e.gpuTypesCache = gpuTypes
+	return gpuTypes
+}
+
+// Cleanup cleans up open resources before the cloud provider is destroyed, i.e. go routines etc.
+func (e *externalGrpcCloudProvider) Cleanup() error {
+	ctx, cancel := context.WithTimeout(context.Background(), e.grpcTimeout)
+	defer cancel()
+	klog.V(5).Info("Performing gRPC call Cleanup")
+	_, err := e.client.Cleanup(ctx, &protos.CleanupRequest{})
+	if err != nil {
+		klog.V(1).Infof("Error on gRPC call Cleanup: %v", err)
+		return convertError(err)
+	}
+	return nil
+}
+
+// Refresh is called before every main loop and can be used to dynamically update cloud provider state.
+// In particular the list of node groups returned by NodeGroups can change as a result of CloudProvider.Refresh().
+func (e *externalGrpcCloudProvider) Refresh() error {
+	e.mutex.Lock()
+	e.nodeGroupsCache = nil
+	e.nodeGroupForNodeCache = make(map[string]cloudprovider.NodeGroup)
+	e.nodeGroupCache = make(map[string]*NodeGroup)
+	e.gpuLabelCache = nil
+	e.gpuTypesCache = nil
+	e.mutex.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), e.grpcTimeout)
+	defer cancel()
+	klog.V(5).Info("Performing gRPC call Refresh")
+	_, err := e.client.Refresh(ctx, &protos.RefreshRequest{})
+	if err != nil {
+		klog.V(1).Infof("Error on gRPC call Refresh: %v", err)
+		return convertError(err)
+	}
+	return nil
+}
+
+// newNodeGroup returns the NodeGroup for the given response, reusing the one
+// with the same id created since the last Refresh. e.mutex must be held.
+func (e *externalGrpcCloudProvider) newNodeGroup(pbNg *protos.NodeGroup) *NodeGroup {
+	if nodeGroup, found := e.nodeGroupCache[pbNg.GetId()]; found {
+		return nodeGroup
+	}
+	nodeGroup := &NodeGroup{
+		id:          pbNg.GetId(),
+		minSize:     int(pbNg.GetMinSize()),
+		maxSize:     int(pbNg.GetMaxSize()),
+		debug:       pbNg.GetDebug(),
+		client:      e.client,
+		grpcTimeout: e.grpcTimeout,
+	}
+	e.nodeGroupCache[nodeGroup.id] = nodeGroup
+	return nodeGroup
+}
+
+// externalGrpcNode converts a node to the subset of its fields sent to the
+// external cloud provider.
+func externalGrpcNode(node *apiv1.Node) *protos.ExternalGrpcNode {
+	return &protos.ExternalGrpcNode{
+		ProviderID:  node.Spec.ProviderID,
+		Name:        node.Name,
+		Labels:      node.Labels,
+		Annotations: node.Annotations,
+	}
+}
+
+// convertError translates the UNIMPLEMENTED status code into
+// cloudprovider.ErrNotImplemented, so that optional methods behave the same
+// as in built-in cloud providers.
+func convertError(err error) error {
+	if status.Code(err) == codes.Unimplemented {
+		return cloudprovider.ErrNotImplemented
+	}
+	return err
+}
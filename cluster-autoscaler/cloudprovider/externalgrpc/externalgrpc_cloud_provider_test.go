@@ -0,0 +1,249 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package externalgrpc
+
+import (
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider"
+	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider/externalgrpc/examples/external-grpc-cloud-provider-service/wrapper"
+	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider/externalgrpc/protos"
+	testprovider "k8s.io/autoscaler/cluster-autoscaler/cloudprovider/test"
+	"k8s.io/autoscaler/cluster-autoscaler/config"
+	. "k8s.io/autoscaler/cluster-autoscaler/utils/test"
+	schedulernodeinfo "k8s.io/kubernetes/pkg/scheduler/nodeinfo"
+)
+
+// startTestServer serves the given cloud provider over gRPC on a local port
+// and returns an externalgrpc cloud provider connected to it.
+func startTestServer(t *testing.T, provider cloudprovider.CloudProvider, opts ...grpc.ServerOption) (*externalGrpcCloudProvider, func()) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	server := grpc.NewServer(opts...)
+	protos.RegisterCloudProviderServer(server, wrapper.NewCloudProviderGrpcWrapper(provider))
+	go server.Serve(lis)
+
+	conn, err := dialCloudProvider(&cloudConfig{Address: lis.Addr().String()})
+	assert.NoError(t, err)
+	client := newExternalGrpcCloudProvider(protos.NewCloudProviderClient(conn), defaultGrpcTimeout,
+		cloudprovider.NewResourceLimiter(nil, nil))
+	return client, func() {
+		conn.Close()
+		server.Stop()
+	}
+}
+
+func TestReadCloudConfig(t *testing.T) {
+	cfg, err := readCloudConfig(strings.NewReader(`
+address: "localhost:8086"
+cacert: /etc/ssl/ca.pem
+grpc_timeout: 10s
+`))
+	assert.NoError(t, err)
+	assert.Equal(t, "localhost:8086", cfg.Address)
+	assert.Equal(t, "/etc/ssl/ca.pem", cfg.Cacert)
+	assert.Equal(t, 10*time.Second, cfg.GrpcTimeout)
+
+	cfg, err = readCloudConfig(strings.NewReader(`address: "localhost:8086"`))
+	assert.NoError(t, err)
+	assert.Equal(t, defaultGrpcTimeout, cfg.GrpcTimeout)
+
+	_, err = readCloudConfig(strings.NewReader(`cacert: /etc/ssl/ca.pem`))
+	assert.Error(t, err)
+}
+
+func TestExternalGrpcCloudProvider(t *testing.T) {
+	scaleUps := make(map[string]int)
+	scaleDowns := make(map[string][]string)
+	n1 := BuildTestNode("n1", 1000, 1000)
+	n1.Spec.ProviderID = "test://n1"
+	n2 := BuildTestNode("n2", 1000, 1000)
+	n3 := BuildTestNode("n3", 1000, 1000)
+	template := BuildTestNode("ng1-template", 2000, 2000)
+	templateInfo := schedulernodeinfo.NewNodeInfo()
+	templateInfo.SetNode(template)
+
+	provider := testprovider.NewTestAutoprovisioningCloudProvider(
+		func(id string, delta int) error {
+			scaleUps[id] += delta
+			return nil
+		}, func(id string, node string) error {
+			scaleDowns[id] = append(scaleDowns[id], node)
+			return nil
+		}, nil, nil, nil, map[string]*schedulernodeinfo.NodeInfo{"ng1": templateInfo})
+	provider.AddNodeGroup("ng1", 1, 10, 2)
+	provider.AddNodeGroup("ng2", 0, 5, 1)
+	provider.AddNode("ng1", n1)
+	provider.AddNode("ng1", n2)
+	provider.AddNode("ng2", n3)
+	provider.GetNodeGroup("ng2").(*testprovider.TestNodeGroup).SetOptions(&config.NodeGroupAutoscalingOptions{
+		ScaleDownUtilizationThreshold: 0.2,
+		ScaleDownUnneededTime:         time.Minute,
+	})
+
+	client, stop := startTestServer(t, provider)
+	defer stop()
+
+	assert.Equal(t, ProviderName, client.Name())
+	assert.Equal(t, "TestGPULabel/accelerator", client.GPULabel())
+	assert.Equal(t, provider.GetAvailableGPUTypes(), client.GetAvailableGPUTypes())
+
+	nodeGroups := client.NodeGroups()
+	assert.Equal(t, 2, len(nodeGroups))
+	for _, ng := range nodeGroups {
+		expected := provider.GetNodeGroup(ng.Id())
+		assert.NotNil(t, expected)
+		assert.Equal(t, expected.MinSize(), ng.MinSize())
+		assert.Equal(t, expected.MaxSize(), ng.MaxSize())
+		assert.Equal(t, expected.Debug(), ng.Debug())
+	}
+
+	ng, err := client.NodeGroupForNode(n1)
+	assert.NoError(t, err)
+	assert.Equal(t, "ng1", ng.Id())
+	ng, err = client.NodeGroupForNode(BuildTestNode("unknown", 1000, 1000))
+	assert.NoError(t, err)
+	assert.Nil(t, ng)
+
+	ng1, err := client.NodeGroupForNode(n2)
+	assert.NoError(t, err)
+	size, err := ng1.TargetSize()
+	assert.NoError(t, err)
+	assert.Equal(t, 2, size)
+
+	assert.NoError(t, ng1.IncreaseSize(3))
+	assert.Equal(t, 3, scaleUps["ng1"])
+	size, err = ng1.TargetSize()
+	assert.NoError(t, err)
+	assert.Equal(t, 5, size)
+
+	assert.NoError(t, ng1.DeleteNodes([]*apiv1.Node{n1}))
+	assert.Equal(t, []string{"n1"}, scaleDowns["ng1"])
+
+	instances, err := ng1.Nodes()
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(instances))
+
+	nodeInfo, err := ng1.TemplateNodeInfo()
+	assert.NoError(t, err)
+	assert.Equal(t, template.Name, nodeInfo.Node().Name)
+	assert.Equal(t, template.Status.Allocatable.Cpu().MilliValue(), nodeInfo.Node().Status.Allocatable.Cpu().MilliValue())
+	assert.Equal(t, template.Status.Allocatable.Memory().Value(), nodeInfo.Node().Status.Allocatable.Memory().Value())
+
+	defaults := config.NodeGroupAutoscalingOptions{
+		ScaleDownUtilizationThreshold:    0.5,
+		ScaleDownGpuUtilizationThreshold: 0.5,
+		ScaleDownUnneededTime:            10 * time.Minute,
+		ScaleDownUnreadyTime:             20 * time.Minute,
+	}
+	_, err = ng1.GetOptions(defaults)
+	assert.Equal(t, cloudprovider.ErrNotImplemented, err)
+
+	ng2, err := client.NodeGroupForNode(n3)
+	assert.NoError(t, err)
+	opts, err := ng2.GetOptions(defaults)
+	assert.NoError(t, err)
+	assert.Equal(t, 0.2, opts.ScaleDownUtilizationThreshold)
+	assert.Equal(t, time.Minute, opts.ScaleDownUnneededTime)
+	// Thresholds left unset by the service have the default values.
+	assert.Equal(t, 0.5, opts.ScaleDownGpuUtilizationThreshold)
+	_, err = ng2.TemplateNodeInfo()
+	assert.Error(t, err)
+
+	pricing, pricingErr := client.Pricing()
+	assert.NoError(t, pricingErr)
+	_, err = pricing.NodePrice(n1, time.Now(), time.Now().Add(time.Hour))
+	assert.Equal(t, cloudprovider.ErrNotImplemented, err)
+
+	// Node groups are cached until the next refresh.
+	provider.AddNodeGroup("ng3", 0, 3, 0)
+	assert.Equal(t, 2, len(client.NodeGroups()))
+	assert.NoError(t, client.Refresh())
+	assert.Equal(t, 3, len(client.NodeGroups()))
+
+	assert.NoError(t, client.Cleanup())
+}
+
+func TestNodeGroupGetOptionsCached(t *testing.T) {
+	provider := testprovider.NewTestCloudProvider(nil, nil)
+	provider.AddNodeGroup("ng1", 1, 10, 2)
+	provider.AddNodeGroup("ng2", 1, 10, 2)
+	for _, name := range []string{"n1", "n2", "n3"} {
+		provider.AddNode("ng1", BuildTestNode(name, 1000, 1000))
+	}
+	provider.GetNodeGroup("ng1").(*testprovider.TestNodeGroup).SetOptions(&config.NodeGroupAutoscalingOptions{
+		ScaleDownUtilizationThreshold: 0.2,
+	})
+
+	var mutex sync.Mutex
+	calls := 0
+	countCalls := func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if strings.HasSuffix(info.FullMethod, "/NodeGroupGetOptions") {
+			mutex.Lock()
+			calls++
+			mutex.Unlock()
+		}
+		return handler(ctx, req)
+	}
+	client, stop := startTestServer(t, provider, grpc.UnaryInterceptor(countCalls))
+	defer stop()
+
+	defaults := config.NodeGroupAutoscalingOptions{ScaleDownUtilizationThreshold: 0.5}
+	getOptions := func(nodeGroup cloudprovider.NodeGroup, err error) (*config.NodeGroupAutoscalingOptions, error) {
+		assert.NoError(t, err)
+		return nodeGroup.GetOptions(defaults)
+	}
+
+	// One call per node group, whether it comes from NodeGroups or NodeGroupForNode.
+	for _, name := range []string{"n1", "n2", "n3"} {
+		opts, err := getOptions(client.NodeGroupForNode(BuildTestNode(name, 1000, 1000)))
+		assert.NoError(t, err)
+		assert.Equal(t, 0.2, opts.ScaleDownUtilizationThreshold)
+	}
+	for _, nodeGroup := range client.NodeGroups() {
+		_, err := getOptions(nodeGroup, nil)
+		if nodeGroup.Id() == "ng2" {
+			assert.Equal(t, cloudprovider.ErrNotImplemented, err)
+		}
+	}
+	assert.Equal(t, 2, calls)
+
+	// Errors are cached too.
+	for _, nodeGroup := range client.NodeGroups() {
+		getOptions(nodeGroup, nil)
+	}
+	assert.Equal(t, 2, calls)
+
+	// Different defaults need another call.
+	defaults.ScaleDownUtilizationThreshold = 0.6
+	getOptions(client.NodeGroupForNode(BuildTestNode("n1", 1000, 1000)))
+	assert.Equal(t, 3, calls)
+
+	// Options are fetched again after a refresh.
+	assert.NoError(t, client.Refresh())
+	getOptions(client.NodeGroupForNode(BuildTestNode("n1", 1000, 1000)))
+	assert.Equal(t, 4, calls)
+}
@@ -0,0 +1,97 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package externalgrpc
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/timestamp"
+	"golang.org/x/net/context"
+
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider/externalgrpc/protos"
+	"k8s.io/klog"
+)
+
+// pricingModel implements cloudprovider.PricingModel by calling the external
+// cloud provider.
+type pricingModel struct {
+	client      protos.CloudProviderClient
+	grpcTimeout time.Duration
+}
+
+// NodePrice returns a price of running the given node for a given period of time.
+// All prices returned by the structure should be in the same currency.
+func (m *pricingModel) NodePrice(node *apiv1.Node, startTime time.Time, endTime time.Time) (float64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), m.grpcTimeout)
+	defer cancel()
+	klog.V(5).Infof("Performing gRPC call PricingNodePrice for node %v", node.Name)
+	start, end, err := timestampProtos(startTime, endTime)
+	if err != nil {
+		return 0, err
+	}
+	res, err := m.client.PricingNodePrice(ctx, &protos.PricingNodePriceRequest{
+		Node:      externalGrpcNode(node),
+		StartTime: start,
+		EndTime:   end,
+	})
+	if err != nil {
+		klog.V(1).Infof("Error on gRPC call PricingNodePrice: %v", err)
+		return 0, convertError(err)
+	}
+	return res.GetPrice(), nil
+}
+
+// PodPrice returns a theoretical minimum price of running a pod for a given
+// period of time on a perfectly matching machine.
+func (m *pricingModel) PodPrice(pod *apiv1.Pod, startTime time.Time, endTime time.Time) (float64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), m.grpcTimeout)
+	defer cancel()
+	klog.V(5).Infof("Performing gRPC call PricingPodPrice for pod %v", pod.Name)
+	start, end, err := timestampProtos(startTime, endTime)
+	if err != nil {
+		return 0, err
+	}
+	pbPod, err := pod.Marshal()
+	if err != nil {
+		return 0, fmt.Errorf("failed to encode pod %s: %v", pod.Name, err)
+	}
+	res, err := m.client.PricingPodPrice(ctx, &protos.PricingPodPriceRequest{
+		Pod:       pbPod,
+		StartTime: start,
+		EndTime:   end,
+	})
+	if err != nil {
+		klog.V(1).Infof("Error on gRPC call PricingPodPrice: %v", err)
+		return 0, convertError(err)
+	}
+	return res.GetPrice(), nil
+}
+
+func timestampProtos(startTime time.Time, endTime time.Time) (*timestamp.Timestamp, *timestamp.Timestamp, error) {
+	start, err := ptypes.TimestampProto(startTime)
+	if err != nil {
+		return nil, nil, err
+	}
+	end, err := ptypes.TimestampProto(endTime)
+	if err != nil {
+		return nil, nil, err
+	}
+	return start, end, nil
+}
@@ -18,6 +18,7 @@ package mocks
 
 import cache "k8s.io/kubernetes/pkg/scheduler/nodeinfo"
 import cloudprovider "k8s.io/autoscaler/cluster-autoscaler/cloudprovider"
+import config "k8s.io/autoscaler/cluster-autoscaler/config"
 import mock "github.com/stretchr/testify/mock"
 import v1 "k8s.io/api/core/v1"
 
@@ -133,6 +134,29 @@ func (_m *NodeGroup) Exist() bool {
 	return r0
 }
 
+// GetOptions provides a mock function with given fields: defaults
+func (_m *NodeGroup) GetOptions(defaults config.NodeGroupAutoscalingOptions) (*config.NodeGroupAutoscalingOptions, error) {
+	ret := _m.Called(defaults)
+
+	var r0 *config.NodeGroupAutoscalingOptions
+	if rf, ok := ret.Get(0).(func(config.NodeGroupAutoscalingOptions) *config.NodeGroupAutoscalingOptions); ok {
+		r0 = rf(defaults)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*config.NodeGroupAutoscalingOptions)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(config.NodeGroupAutoscalingOptions) error); ok {
+		r1 = rf(defaults)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // Id provides a mock function with given fields:
 func (_m *NodeGroup) Id() string {
 	ret := _m.Called()
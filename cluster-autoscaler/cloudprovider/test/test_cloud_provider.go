@@ -23,6 +23,7 @@ import (
 	apiv1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider"
+	"k8s.io/autoscaler/cluster-autoscaler/config"
 	"k8s.io/autoscaler/cluster-autoscaler/utils/errors"
 	schedulernodeinfo "k8s.io/kubernetes/pkg/scheduler/nodeinfo"
 )
@@ -261,6 +262,7 @@ type TestNodeGroup struct {
 	machineType     string
 	labels          map[string]string
 	taints          []apiv1.Taint
+	opts            *config.NodeGroupAutoscalingOptions
 }
 
 // NewTestNodeGroup creates a TestNodeGroup without setting up the realted TestCloudProvider.
@@ -415,6 +417,25 @@ func (tng *TestNodeGroup) Autoprovisioned() bool {
 	return tng.autoprovisioned
 }
 
+// GetOptions returns NodeGroupAutoscalingOptions set with SetOptions, or
+// ErrNotImplemented if none were set.
+func (tng *TestNodeGroup) GetOptions(defaults config.NodeGroupAutoscalingOptions) (*config.NodeGroupAutoscalingOptions, error) {
+	tng.Lock()
+	defer tng.Unlock()
+
+	if tng.opts == nil {
+		return nil, cloudprovider.ErrNotImplemented
+	}
+	return tng.opts, nil
+}
+
+// SetOptions sets NodeGroupAutoscalingOptions returned by GetOptions. Function is used only in tests.
+func (tng *TestNodeGroup) SetOptions(opts *config.NodeGroupAutoscalingOptions) {
+	tng.Lock()
+	defer tng.Unlock()
+	tng.opts = opts
+}
+
 // TemplateNodeInfo returns a node template for this node group.
 func (tng *TestNodeGroup) TemplateNodeInfo() (*schedulernodeinfo.NodeInfo, error) {
 	if tng.cloudProvider.machineTemplates == nil {
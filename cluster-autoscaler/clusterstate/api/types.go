@@ -35,6 +35,9 @@ const (
 	// ClusterAutoscalerScaleUp is a condition that explains what is the current status
 	// of a node group with regard to scale up activities.
 	ClusterAutoscalerScaleUp ClusterAutoscalerConditionType = "ScaleUp"
+	// ClusterAutoscalerCapacityProfile is a condition that explains whether a scheduled
+	// capacity profile is currently raising the minimum size of a node group.
+	ClusterAutoscalerCapacityProfile ClusterAutoscalerConditionType = "CapacityProfile"
 )
 
 // ClusterAutoscalerConditionStatus is a status of ClusterAutoscalerCondition.
@@ -67,6 +70,13 @@ const (
 	ClusterAutoscalerNoActivity ClusterAutoscalerConditionStatus = "NoActivity"
 	// ClusterAutoscalerBackoff status means that due to a recently failed scale-up no further scale-ups attempts will be made for some time.
 	ClusterAutoscalerBackoff ClusterAutoscalerConditionStatus = "Backoff"
+
+	// Statuses for CapacityProfile condition type.
+
+	// ClusterAutoscalerProfileActive status means that a capacity profile is active for the node group.
+	ClusterAutoscalerProfileActive ClusterAutoscalerConditionStatus = "Active"
+	// ClusterAutoscalerProfileInactive status means that no capacity profile is active for the node group.
+	ClusterAutoscalerProfileInactive ClusterAutoscalerConditionStatus = "Inactive"
 )
 
 // ClusterAutoscalerCondition describes some aspect of ClusterAutoscaler work.
@@ -92,6 +102,8 @@ type ClusterAutoscalerStatus struct {
 	NodeGroupStatuses []NodeGroupStatus `json:"nodeGroupStatuses,omitempty"`
 	// ClusterwideConditions contains conditions that apply to the whole autoscaler.
 	ClusterwideConditions []ClusterAutoscalerCondition `json:"clusterwideConditions,omitempty"`
+	// Readiness contains the numbers of nodes in the cluster in different states.
+	Readiness *Readiness `json:"readiness,omitempty"`
 }
 
 // NodeGroupStatus contains status of a group of nodes controlled by ClusterAutoscaler.
@@ -101,4 +113,36 @@ type NodeGroupStatus struct {
 	ProviderID string `json:"providerID,omitempty"`
 	// Conditions is a list of conditions that describe the state of the node group.
 	Conditions []ClusterAutoscalerCondition `json:"conditions,omitempty"`
+	// Readiness contains the numbers of nodes in the node group in different states.
+	Readiness *Readiness `json:"readiness,omitempty"`
+	// Backoff is set while scale-ups of the node group are backed off after failures.
+	Backoff *Backoff `json:"backoff,omitempty"`
+}
+
+// Readiness contains the numbers of nodes in different states.
+type Readiness struct {
+	// Ready is the number of ready nodes.
+	Ready int `json:"ready"`
+	// Unready is the number of unready nodes that broke down after they started.
+	Unready int `json:"unready"`
+	// NotStarted is the number of nodes that are not yet fully started.
+	NotStarted int `json:"notStarted"`
+	// LongNotStarted is the number of nodes that failed to start within a reasonable limit.
+	LongNotStarted int `json:"longNotStarted"`
+	// Registered is the number of all registered nodes.
+	Registered int `json:"registered"`
+	// LongUnregistered is the number of nodes that failed to register within a reasonable limit.
+	LongUnregistered int `json:"longUnregistered"`
+	// Unregistered is the number of nodes that haven't yet registered.
+	Unregistered int `json:"unregistered"`
+	// Deleted is the number of nodes that are being deleted.
+	Deleted int `json:"deleted"`
+}
+
+// Backoff describes a scale-up backoff of a node group.
+type Backoff struct {
+	// Until is the time when the node group stops being backed off.
+	Until metav1.Time `json:"until"`
+	// LastFailure is the time of the last failed scale-up that caused the backoff.
+	LastFailure metav1.Time `json:"lastFailure"`
 }
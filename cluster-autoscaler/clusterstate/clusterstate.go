@@ -81,6 +81,25 @@ type ScaleDownRequest struct {
 	ExpectedDeleteTime time.Time
 }
 
+// ScaleUpRequestState is the content of a ScaleUpRequest, kept across restarts.
+type ScaleUpRequestState struct {
+	// Time is the time when the request was submitted.
+	Time time.Time `json:"time"`
+	// ExpectedAddTime is the time at which the request should be fulfilled.
+	ExpectedAddTime time.Time `json:"expectedAddTime"`
+	// How much the node group is increased.
+	Increase int `json:"increase"`
+}
+
+// SoftState is the part of the ClusterStateRegistry state that can't be rebuilt from
+// the cluster and is kept across restarts.
+type SoftState struct {
+	// ScaleUpRequests are scale-up requests by node group id.
+	ScaleUpRequests map[string]ScaleUpRequestState `json:"scaleUpRequests,omitempty"`
+	// Backoff is the backoff data of node groups.
+	Backoff map[string]backoff.Entry `json:"backoff,omitempty"`
+}
+
 // ClusterStateRegistryConfig contains configuration information for ClusterStateRegistry.
 type ClusterStateRegistryConfig struct {
 	// Maximum percentage of unready nodes in total, if the number of unready nodes is higher than OkTotalUnreadyCount.
@@ -113,6 +132,16 @@ type UnregisteredNode struct {
 	UnregisteredSince time.Time
 }
 
+// CapacityProfile is a scheduled capacity profile raising the minimum size of a node group.
+type CapacityProfile struct {
+	// Name of the profile.
+	Name string
+	// MinSize is the minimum size of the node group while the profile is active.
+	MinSize int
+	// Until is the time when the profile stops being active.
+	Until time.Time
+}
+
 // ClusterStateRegistry is a structure to keep track the current state of the cluster.
 type ClusterStateRegistry struct {
 	sync.Mutex
@@ -134,6 +163,7 @@ type ClusterStateRegistry struct {
 	logRecorder                        *utils.LogEventRecorder
 	cloudProviderNodeInstances         map[string][]cloudprovider.Instance
 	previousCloudProviderNodeInstances map[string][]cloudprovider.Instance
+	capacityProfiles                   map[string]CapacityProfile
 }
 
 // NewClusterStateRegistry creates new ClusterStateRegistry.
@@ -211,6 +241,51 @@ func (csr *ClusterStateRegistry) RegisterScaleDown(request *ScaleDownRequest) {
 	csr.scaleDownRequests = append(csr.scaleDownRequests, request)
 }
 
+// GetSoftState returns the scale-up requests and backoff data of the registry.
+func (csr *ClusterStateRegistry) GetSoftState() SoftState {
+	csr.Lock()
+	defer csr.Unlock()
+	state := SoftState{
+		ScaleUpRequests: make(map[string]ScaleUpRequestState, len(csr.scaleUpRequests)),
+		Backoff:         csr.backoff.GetEntries(),
+	}
+	for nodeGroupName, scaleUpRequest := range csr.scaleUpRequests {
+		state.ScaleUpRequests[nodeGroupName] = ScaleUpRequestState{
+			Time:            scaleUpRequest.Time,
+			ExpectedAddTime: scaleUpRequest.ExpectedAddTime,
+			Increase:        scaleUpRequest.Increase,
+		}
+	}
+	return state
+}
+
+// RestoreSoftState adds scale-up requests and backoff data previously returned by
+// GetSoftState. Scale-up requests of node groups that no longer exist or that should
+// have been fulfilled already are skipped. Should be called after refreshing the
+// cloud provider and before the first UpdateNodes.
+func (csr *ClusterStateRegistry) RestoreSoftState(state SoftState, currentTime time.Time) {
+	csr.Lock()
+	defer csr.Unlock()
+	nodeGroups := make(map[string]cloudprovider.NodeGroup)
+	for _, nodeGroup := range csr.cloudProvider.NodeGroups() {
+		nodeGroups[nodeGroup.Id()] = nodeGroup
+	}
+	for nodeGroupName, requestState := range state.ScaleUpRequests {
+		nodeGroup, found := nodeGroups[nodeGroupName]
+		if !found || requestState.ExpectedAddTime.Before(currentTime) || requestState.Increase <= 0 {
+			klog.V(4).Infof("Skipping stale scale-up request of node group %s", nodeGroupName)
+			continue
+		}
+		csr.scaleUpRequests[nodeGroupName] = &ScaleUpRequest{
+			NodeGroup:       nodeGroup,
+			Time:            requestState.Time,
+			ExpectedAddTime: requestState.ExpectedAddTime,
+			Increase:        requestState.Increase,
+		}
+	}
+	csr.backoff.RestoreEntries(state.Backoff, currentTime)
+}
+
 // To be executed under a lock.
 func (csr *ClusterStateRegistry) updateScaleRequests(currentTime time.Time) {
 	// clean up stale backoff info
@@ -656,6 +731,12 @@ func (csr *ClusterStateRegistry) UpdateScaleDownCandidates(nodes []*apiv1.Node,
 	csr.lastScaleDownUpdateTime = now
 }
 
+// SetCapacityProfiles sets capacity profiles active for node groups, by node group id.
+// Once set, the status of each node group includes a CapacityProfile condition.
+func (csr *ClusterStateRegistry) SetCapacityProfiles(profiles map[string]CapacityProfile) {
+	csr.capacityProfiles = profiles
+}
+
 // GetStatus returns ClusterAutoscalerStatus with the current cluster autoscaler status.
 func (csr *ClusterStateRegistry) GetStatus(now time.Time) *api.ClusterAutoscalerStatus {
 	result := &api.ClusterAutoscalerStatus{
@@ -669,6 +750,8 @@ func (csr *ClusterStateRegistry) GetStatus(now time.Time) *api.ClusterAutoscaler
 		}
 		readiness := csr.perNodeGroupReadiness[nodeGroup.Id()]
 		acceptable := csr.acceptableRanges[nodeGroup.Id()]
+		nodeGroupStatus.Readiness = buildReadinessStatus(readiness)
+		nodeGroupStatus.Backoff = csr.GetBackoffStatus(nodeGroup, now)
 
 		// Health.
 		nodeGroupStatus.Conditions = append(nodeGroupStatus.Conditions, buildHealthStatusNodeGroup(
@@ -685,8 +768,16 @@ func (csr *ClusterStateRegistry) GetStatus(now time.Time) *api.ClusterAutoscaler
 		nodeGroupStatus.Conditions = append(nodeGroupStatus.Conditions, buildScaleDownStatusNodeGroup(
 			csr.candidatesForScaleDown[nodeGroup.Id()], csr.lastScaleDownUpdateTime))
 
+		// Capacity profile.
+		if csr.capacityProfiles != nil {
+			profile, found := csr.capacityProfiles[nodeGroup.Id()]
+			nodeGroupStatus.Conditions = append(nodeGroupStatus.Conditions, buildCapacityProfileStatusNodeGroup(
+				found, profile, now))
+		}
+
 		result.NodeGroupStatuses = append(result.NodeGroupStatuses, nodeGroupStatus)
 	}
+	result.Readiness = buildReadinessStatus(csr.totalReadiness)
 	result.ClusterwideConditions = append(result.ClusterwideConditions,
 		buildHealthStatusClusterwide(csr.IsClusterHealthy(), csr.totalReadiness))
 	result.ClusterwideConditions = append(result.ClusterwideConditions,
@@ -704,6 +795,31 @@ func (csr *ClusterStateRegistry) GetClusterReadiness() Readiness {
 	return csr.totalReadiness
 }
 
+func buildReadinessStatus(readiness Readiness) *api.Readiness {
+	return &api.Readiness{
+		Ready:            readiness.Ready,
+		Unready:          readiness.Unready,
+		NotStarted:       readiness.NotStarted,
+		LongNotStarted:   readiness.LongNotStarted,
+		Registered:       readiness.Registered,
+		LongUnregistered: readiness.LongUnregistered,
+		Unregistered:     readiness.Unregistered,
+		Deleted:          readiness.Deleted,
+	}
+}
+
+// GetBackoffStatus returns the scale-up backoff of the node group, or nil if the node group isn't backed off.
+func (csr *ClusterStateRegistry) GetBackoffStatus(nodeGroup cloudprovider.NodeGroup, now time.Time) *api.Backoff {
+	entry, found := csr.backoff.GetEntry(nodeGroup, csr.nodeInfosForGroups[nodeGroup.Id()])
+	if !found || !entry.BackoffUntil.After(now) {
+		return nil
+	}
+	return &api.Backoff{
+		Until:       metav1.NewTime(entry.BackoffUntil),
+		LastFailure: metav1.NewTime(entry.LastFailedExecution),
+	}
+}
+
 func buildHealthStatusNodeGroup(isReady bool, readiness Readiness, acceptable AcceptableRange, minSize, maxSize int) api.ClusterAutoscalerCondition {
 	condition := api.ClusterAutoscalerCondition{
 		Type: api.ClusterAutoscalerHealth,
@@ -759,6 +875,23 @@ func buildScaleDownStatusNodeGroup(candidates []string, lastProbed time.Time) ap
 	return condition
 }
 
+func buildCapacityProfileStatusNodeGroup(isActive bool, profile CapacityProfile, now time.Time) api.ClusterAutoscalerCondition {
+	condition := api.ClusterAutoscalerCondition{
+		Type:          api.ClusterAutoscalerCapacityProfile,
+		LastProbeTime: metav1.Time{Time: now},
+	}
+	if isActive {
+		condition.Status = api.ClusterAutoscalerProfileActive
+		condition.Message = fmt.Sprintf("profile=%s minSize=%d until=%s",
+			profile.Name,
+			profile.MinSize,
+			profile.Until.Format(time.RFC3339))
+	} else {
+		condition.Status = api.ClusterAutoscalerProfileInactive
+	}
+	return condition
+}
+
 func buildHealthStatusClusterwide(isReady bool, readiness Readiness) api.ClusterAutoscalerCondition {
 	condition := api.ClusterAutoscalerCondition{
 		Type: api.ClusterAutoscalerHealth,
@@ -857,13 +990,8 @@ func updateLastTransition(oldStatus, newStatus *api.ClusterAutoscalerStatus) {
 				break
 			}
 		}
-		newConds := updateLastTransitionSingleList(oldConds, ngStatus.Conditions)
-		updatedNgStatuses = append(
-			updatedNgStatuses,
-			api.NodeGroupStatus{
-				ProviderID: ngStatus.ProviderID,
-				Conditions: newConds,
-			})
+		ngStatus.Conditions = updateLastTransitionSingleList(oldConds, ngStatus.Conditions)
+		updatedNgStatuses = append(updatedNgStatuses, ngStatus)
 	}
 	newStatus.NodeGroupStatuses = updatedNgStatuses
 }
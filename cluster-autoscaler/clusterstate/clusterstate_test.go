@@ -25,6 +25,7 @@ import (
 	testprovider "k8s.io/autoscaler/cluster-autoscaler/cloudprovider/test"
 	"k8s.io/autoscaler/cluster-autoscaler/clusterstate/api"
 	"k8s.io/autoscaler/cluster-autoscaler/clusterstate/utils"
+	"k8s.io/autoscaler/cluster-autoscaler/metrics"
 	. "k8s.io/autoscaler/cluster-autoscaler/utils/test"
 	"k8s.io/client-go/kubernetes/fake"
 	kube_record "k8s.io/client-go/tools/record"
@@ -702,6 +703,95 @@ func TestGetClusterSize(t *testing.T) {
 	assert.Equal(t, 30, targetSize)
 }
 
+func TestCapacityProfileStatus(t *testing.T) {
+	now := time.Now()
+
+	ng1_1 := BuildTestNode("ng1-1", 1000, 1000)
+	SetNodeReadyState(ng1_1, true, now.Add(-time.Minute))
+	provider := testprovider.NewTestCloudProvider(nil, nil)
+	provider.AddNodeGroup("ng1", 1, 10, 1)
+	provider.AddNodeGroup("ng2", 1, 10, 1)
+	provider.AddNode("ng1", ng1_1)
+
+	fakeClient := &fake.Clientset{}
+	fakeLogRecorder, _ := utils.NewStatusMapRecorder(fakeClient, "kube-system", kube_record.NewFakeRecorder(5), false)
+	clusterstate := NewClusterStateRegistry(provider, ClusterStateRegistryConfig{
+		MaxTotalUnreadyPercentage: 10,
+		OkTotalUnreadyCount:       1,
+	}, fakeLogRecorder, newBackoff())
+	err := clusterstate.UpdateNodes([]*apiv1.Node{ng1_1}, nil, now)
+	assert.NoError(t, err)
+
+	// No condition unless capacity profiles are set.
+	status := clusterstate.GetStatus(now)
+	for _, nodeGroupStatus := range status.NodeGroupStatuses {
+		assert.Nil(t, api.GetConditionByType(api.ClusterAutoscalerCapacityProfile, nodeGroupStatus.Conditions))
+	}
+
+	until := time.Date(2019, time.June, 3, 18, 0, 0, 0, time.UTC)
+	clusterstate.SetCapacityProfiles(map[string]CapacityProfile{
+		"ng1": {Name: "business-hours", MinSize: 5, Until: until},
+	})
+	status = clusterstate.GetStatus(now)
+	conditions := make(map[string]*api.ClusterAutoscalerCondition)
+	for _, nodeGroupStatus := range status.NodeGroupStatuses {
+		conditions[nodeGroupStatus.ProviderID] = api.GetConditionByType(api.ClusterAutoscalerCapacityProfile, nodeGroupStatus.Conditions)
+	}
+	assert.NotNil(t, conditions["ng1"])
+	assert.Equal(t, api.ClusterAutoscalerProfileActive, conditions["ng1"].Status)
+	assert.Equal(t, "profile=business-hours minSize=5 until=2019-06-03T18:00:00Z", conditions["ng1"].Message)
+	assert.NotNil(t, conditions["ng2"])
+	assert.Equal(t, api.ClusterAutoscalerProfileInactive, conditions["ng2"].Status)
+}
+
+func TestReadinessAndBackoffStatus(t *testing.T) {
+	now := time.Now()
+
+	ng1_1 := BuildTestNode("ng1-1", 1000, 1000)
+	SetNodeReadyState(ng1_1, true, now.Add(-time.Minute))
+	ng1_2 := BuildTestNode("ng1-2", 1000, 1000)
+	SetNodeReadyState(ng1_2, false, now.Add(-time.Minute))
+	ng2_1 := BuildTestNode("ng2-1", 1000, 1000)
+	SetNodeReadyState(ng2_1, true, now.Add(-time.Minute))
+	provider := testprovider.NewTestCloudProvider(nil, nil)
+	provider.AddNodeGroup("ng1", 1, 10, 2)
+	provider.AddNodeGroup("ng2", 1, 10, 1)
+	provider.AddNode("ng1", ng1_1)
+	provider.AddNode("ng1", ng1_2)
+	provider.AddNode("ng2", ng2_1)
+
+	fakeClient := &fake.Clientset{}
+	fakeLogRecorder, _ := utils.NewStatusMapRecorder(fakeClient, "kube-system", kube_record.NewFakeRecorder(5), false)
+	clusterstate := NewClusterStateRegistry(provider, ClusterStateRegistryConfig{
+		MaxTotalUnreadyPercentage: 10,
+		OkTotalUnreadyCount:       1,
+		MaxNodeProvisionTime:      time.Minute,
+	}, fakeLogRecorder, newBackoff())
+	err := clusterstate.UpdateNodes([]*apiv1.Node{ng1_1, ng1_2, ng2_1}, nil, now)
+	assert.NoError(t, err)
+	clusterstate.RegisterFailedScaleUp(provider.GetNodeGroup("ng2"), metrics.Timeout, now)
+
+	status := clusterstate.GetStatus(now)
+	assert.Equal(t, &api.Readiness{Ready: 2, Unready: 1, Registered: 3}, status.Readiness)
+	nodeGroupStatuses := make(map[string]api.NodeGroupStatus)
+	for _, nodeGroupStatus := range status.NodeGroupStatuses {
+		nodeGroupStatuses[nodeGroupStatus.ProviderID] = nodeGroupStatus
+	}
+	assert.Equal(t, &api.Readiness{Ready: 1, Unready: 1, Registered: 2}, nodeGroupStatuses["ng1"].Readiness)
+	assert.Nil(t, nodeGroupStatuses["ng1"].Backoff)
+	assert.Equal(t, &api.Readiness{Ready: 1, Registered: 1}, nodeGroupStatuses["ng2"].Readiness)
+	assert.NotNil(t, nodeGroupStatuses["ng2"].Backoff)
+	assert.True(t, nodeGroupStatuses["ng2"].Backoff.Until.After(now))
+	assert.True(t, nodeGroupStatuses["ng2"].Backoff.LastFailure.Time.Equal(now))
+
+	// The backoff is no longer reported once it expires.
+	later := now.Add(time.Hour)
+	status = clusterstate.GetStatus(later)
+	for _, nodeGroupStatus := range status.NodeGroupStatuses {
+		assert.Nil(t, nodeGroupStatus.Backoff)
+	}
+}
+
 func TestUpdateScaleUp(t *testing.T) {
 	now := time.Now()
 	later := now.Add(time.Minute)
@@ -746,6 +836,41 @@ func TestUpdateScaleUp(t *testing.T) {
 	assert.Nil(t, clusterstate.scaleUpRequests["ng1"])
 }
 
+func TestRestoreSoftState(t *testing.T) {
+	now := time.Now()
+
+	provider := testprovider.NewTestCloudProvider(nil, nil)
+	provider.AddNodeGroup("ng1", 1, 10, 5)
+	provider.AddNodeGroup("ng2", 1, 10, 1)
+	fakeClient := &fake.Clientset{}
+	fakeLogRecorder, _ := utils.NewStatusMapRecorder(fakeClient, "kube-system", kube_record.NewFakeRecorder(5), false)
+	config := ClusterStateRegistryConfig{
+		MaxTotalUnreadyPercentage: 10,
+		OkTotalUnreadyCount:       1,
+		MaxNodeProvisionTime:      10 * time.Minute,
+	}
+	clusterstate := NewClusterStateRegistry(provider, config, fakeLogRecorder, newBackoff())
+	clusterstate.RegisterOrUpdateScaleUp(provider.GetNodeGroup("ng1"), 4, now)
+	clusterstate.RegisterFailedScaleUp(provider.GetNodeGroup("ng2"), metrics.Timeout, now)
+
+	state := clusterstate.GetSoftState()
+	assert.Equal(t, ScaleUpRequestState{Time: now, ExpectedAddTime: now.Add(10 * time.Minute), Increase: 4}, state.ScaleUpRequests["ng1"])
+	assert.Contains(t, state.Backoff, "ng2")
+	state.ScaleUpRequests["missing"] = ScaleUpRequestState{Time: now, ExpectedAddTime: now.Add(10 * time.Minute), Increase: 1}
+
+	restored := NewClusterStateRegistry(provider, config, fakeLogRecorder, newBackoff())
+	restored.RestoreSoftState(state, now.Add(time.Minute))
+	assert.Equal(t, 1, len(restored.scaleUpRequests))
+	assert.Equal(t, 4, restored.scaleUpRequests["ng1"].Increase)
+	assert.Equal(t, "ng1", restored.scaleUpRequests["ng1"].NodeGroup.Id())
+	assert.False(t, restored.IsNodeGroupSafeToScaleUp(provider.GetNodeGroup("ng2"), now.Add(time.Minute)))
+
+	// Scale-up requests that should have been fulfilled already are skipped.
+	expired := NewClusterStateRegistry(provider, config, fakeLogRecorder, newBackoff())
+	expired.RestoreSoftState(state, now.Add(11*time.Minute))
+	assert.Equal(t, 0, len(expired.scaleUpRequests))
+}
+
 func TestIsNodeStillStarting(t *testing.T) {
 	testCases := []struct {
 		desc           string
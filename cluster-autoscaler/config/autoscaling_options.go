@@ -30,10 +30,78 @@ type GpuLimits struct {
 	Max int64
 }
 
+// Headroom defines spare capacity CA keeps free in the cluster or in a node group.
+// It is either given as absolute amounts of resources or as a percentage of
+// allocatable resources of ready nodes.
+type Headroom struct {
+	// NodeGroup in which the headroom is kept, empty for cluster-wide headroom.
+	NodeGroup string
+	// MilliCpu is the amount of CPU to keep free, in millicores.
+	MilliCpu int64
+	// Memory is the amount of memory to keep free, in bytes.
+	Memory int64
+	// Gpu is the number of GPUs to keep free.
+	Gpu int64
+	// Percentage of allocatable CPU, memory and GPU to keep free. Overrides the
+	// absolute amounts if positive.
+	Percentage int64
+	// Pods is the number of equal virtual pods the headroom is split into. Each
+	// of them has to fit on a single node.
+	Pods int
+}
+
+// PreDeletionHookFailurePolicy defines what happens to a node deletion when its pre-deletion
+// hook can't be called or returns an invalid response.
+type PreDeletionHookFailurePolicy string
+
+const (
+	// PreDeletionHookFail - the deletion is vetoed.
+	PreDeletionHookFail PreDeletionHookFailurePolicy = "Fail"
+	// PreDeletionHookIgnore - the deletion goes on as if the hook approved it.
+	PreDeletionHookIgnore PreDeletionHookFailurePolicy = "Ignore"
+)
+
+// PreDeletionHook is an HTTP webhook called before a node is drained or deleted, which can
+// approve, delay or veto the deletion.
+type PreDeletionHook struct {
+	// URL the hook requests are posted to.
+	URL string
+	// Timeout of a single hook request.
+	Timeout time.Duration
+	// FailurePolicy applies when the hook can't be called or returns an invalid response.
+	FailurePolicy PreDeletionHookFailurePolicy
+	// MaxDelay is the maximum time the hook can delay a deletion, after which it goes on.
+	MaxDelay time.Duration
+}
+
+// NodeGroupAutoscalingOptions contain various options to customize how autoscaling of
+// a given NodeGroup works. Different options can be used for each NodeGroup.
+type NodeGroupAutoscalingOptions struct {
+	// ScaleDownUtilizationThreshold sets threshold for nodes to be considered for scale down if cpu or memory utilization is over threshold.
+	// Well-utilized nodes are not touched.
+	ScaleDownUtilizationThreshold float64
+	// ScaleDownGpuUtilizationThreshold sets threshold for gpu nodes to be considered for scale down if gpu utilization is over threshold.
+	// Well-utilized nodes are not touched.
+	ScaleDownGpuUtilizationThreshold float64
+	// ScaleDownUnneededTime sets the duration CA expects a node to be unneeded/eligible for removal
+	// before scaling down the node.
+	ScaleDownUnneededTime time.Duration
+	// ScaleDownUnreadyTime represents how long an unready node should be unneeded before it is eligible for scale down
+	ScaleDownUnreadyTime time.Duration
+	// ScaleDownBudgetMaxNodes is the maximum number of nodes removed from the node group within the
+	// scale-down budget window. 0 means no limit.
+	ScaleDownBudgetMaxNodes int
+	// ScaleDownBudgetMaxRatio is the maximum fraction of the node group removed within the scale-down
+	// budget window. 0 means no limit.
+	ScaleDownBudgetMaxRatio float64
+}
+
 // AutoscalingOptions contain various options to customize how autoscaling works
 type AutoscalingOptions struct {
 	// MaxEmptyBulkDelete is a number of empty nodes that can be removed at the same time.
 	MaxEmptyBulkDelete int
+	// MaxDrainParallelism is the maximum number of non empty nodes drained at the same time.
+	MaxDrainParallelism int
 	// ScaleDownUtilizationThreshold sets threshold for nodes to be considered for scale down if cpu or memory utilization is over threshold.
 	// Well-utilized nodes are not touched.
 	ScaleDownUtilizationThreshold float64
@@ -45,6 +113,12 @@ type AutoscalingOptions struct {
 	ScaleDownUnneededTime time.Duration
 	// ScaleDownUnreadyTime represents how long an unready node should be unneeded before it is eligible for scale down
 	ScaleDownUnreadyTime time.Duration
+	// ScaleDownBudgetMaxNodes is the default maximum number of nodes removed from a node group within ScaleDownBudgetWindow.
+	ScaleDownBudgetMaxNodes int
+	// ScaleDownBudgetMaxRatio is the default maximum fraction of a node group removed within ScaleDownBudgetWindow.
+	ScaleDownBudgetMaxRatio float64
+	// ScaleDownBudgetWindow is the sliding time window in which node deletions count against scale-down budgets.
+	ScaleDownBudgetWindow time.Duration
 	// MaxNodesTotal sets the maximum number of nodes in the whole cluster
 	MaxNodesTotal int
 	// MaxCoresTotal sets the maximum number of cores in the whole cluster
@@ -63,6 +137,10 @@ type AutoscalingOptions struct {
 	EstimatorName string
 	// ExpanderName sets the type of node group expander to be used in scale up
 	ExpanderName string
+	// ScaleDownOrderName sets the order in which nodes are considered for scale down
+	ScaleDownOrderName string
+	// ExternalExpanderConfig is the path to the configuration of the external expander.
+	ExternalExpanderConfig string
 	// IgnoreDaemonSetsUtilization is whether CA will ignore DaemonSet pods when calculating resource utilization for scaling down
 	IgnoreDaemonSetsUtilization bool
 	// IgnoreMirrorPodsUtilization is whether CA will ignore Mirror pods when calculating resource utilization for scaling down
@@ -107,8 +185,25 @@ type AutoscalingOptions struct {
 	NodeDeletionDelayTimeout time.Duration
 	// WriteStatusConfigMap tells if the status information should be written to a ConfigMap
 	WriteStatusConfigMap bool
+	// WriteStatusCRD tells if the status information should be written to a ClusterAutoscalerStatus object
+	WriteStatusCRD bool
+	// RecordUnremovableNodeReasons tells if the reasons why nodes can't be scaled down should be recorded as node events and annotations
+	RecordUnremovableNodeReasons bool
 	// BalanceSimilarNodeGroups enables logic that identifies node groups with similar machines and tries to balance node count between them.
 	BalanceSimilarNodeGroups bool
+	// RebalanceSimilarNodeGroups makes scale-down keep similar node groups balanced and shrink node groups that
+	// drifted larger than similar ones, even if their nodes are above the utilization threshold.
+	RebalanceSimilarNodeGroups bool
+	// BalancingIgnoredLabels are labels whose values may differ between similar node groups, in addition to the hostname, zone and region labels.
+	BalancingIgnoredLabels []string
+	// BalancingResourceTolerances are the ratios by which allocatable and free resources may differ between similar node groups, by resource name.
+	BalancingResourceTolerances map[string]float64
+	// BalancingCompareTaints makes only node groups with the same taints similar.
+	BalancingCompareTaints bool
+	// BalancingProviderLabels makes the cloud provider's node group comparator ignore labels the provider sets to values specific to each node group.
+	BalancingProviderLabels bool
+	// MaxParallelScaleUps is the maximum number of expansion options with disjoint pods executed in a single scale-up.
+	MaxParallelScaleUps int
 	// ConfigNamespace is the namespace cluster-autoscaler is running in and all related configmaps live in
 	ConfigNamespace string
 	// ClusterName if available
@@ -137,4 +232,46 @@ type AutoscalingOptions struct {
 	FilterOutSchedulablePodsUsesPacking bool
 	// IgnoredTaints is a list of taints to ignore when considering a node template for scheduling.
 	IgnoredTaints []string
+	// DryRun makes CA only report the actions it would take instead of performing them.
+	DryRun bool
+	// CapacityProfilesEnabled makes CA raise minimum sizes of node groups according to scheduled
+	// capacity profiles configured in a ConfigMap.
+	CapacityProfilesEnabled bool
+	// Headroom is a list of spare capacity specifications CA keeps free.
+	Headroom []Headroom
+	// ConsolidationEnabled makes CA replace underutilized nodes with fewer nodes from
+	// a cheaper node group.
+	ConsolidationEnabled bool
+	// MaxConsolidatedNodes is the maximum number of nodes replaced in a single consolidation.
+	MaxConsolidatedNodes int
+	// ConsolidationMinSavings is the minimum fraction of the price of replaced nodes
+	// a consolidation has to save.
+	ConsolidationMinSavings float64
+	// SoftStateStoreName is the name of the store keeping soft state, like unneeded node timers and
+	// node group backoffs, across restarts. Soft state isn't kept if empty.
+	SoftStateStoreName string
+	// SoftStateFile is the file used by the file soft state store.
+	SoftStateFile string
+	// SoftStateCheckpointInterval is how often soft state is saved.
+	SoftStateCheckpointInterval time.Duration
+	// SoftStateMaxAge is the maximum age of saved soft state that is restored on startup.
+	SoftStateMaxAge time.Duration
+	// MaintenanceWindowsEnabled makes CA disable scale-down, or limit it to empty nodes, during
+	// maintenance windows configured in a ConfigMap.
+	MaintenanceWindowsEnabled bool
+	// PreDeletionHooks are called, in order, before nodes are drained and before they are deleted.
+	PreDeletionHooks []PreDeletionHook
+}
+
+// NodeGroupDefaults returns the NodeGroupAutoscalingOptions that apply to node groups
+// which don't override them.
+func (o AutoscalingOptions) NodeGroupDefaults() NodeGroupAutoscalingOptions {
+	return NodeGroupAutoscalingOptions{
+		ScaleDownUtilizationThreshold:    o.ScaleDownUtilizationThreshold,
+		ScaleDownGpuUtilizationThreshold: o.ScaleDownGpuUtilizationThreshold,
+		ScaleDownUnneededTime:            o.ScaleDownUnneededTime,
+		ScaleDownUnreadyTime:             o.ScaleDownUnreadyTime,
+		ScaleDownBudgetMaxNodes:          o.ScaleDownBudgetMaxNodes,
+		ScaleDownBudgetMaxRatio:          o.ScaleDownBudgetMaxRatio,
+	}
 }
@@ -23,6 +23,7 @@ import (
 	"k8s.io/autoscaler/cluster-autoscaler/estimator"
 	"k8s.io/autoscaler/cluster-autoscaler/expander"
 	processor_callbacks "k8s.io/autoscaler/cluster-autoscaler/processors/callbacks"
+	"k8s.io/autoscaler/cluster-autoscaler/scaledownorder"
 	"k8s.io/autoscaler/cluster-autoscaler/simulator"
 	kube_util "k8s.io/autoscaler/cluster-autoscaler/utils/kubernetes"
 	kube_client "k8s.io/client-go/kubernetes"
@@ -46,6 +47,8 @@ type AutoscalingContext struct {
 	ExpanderStrategy expander.Strategy
 	// EstimatorBuilder is the builder function for node count estimator to be used.
 	EstimatorBuilder estimator.EstimatorBuilder
+	// ScaleDownOrder is the strategy used to order nodes considered for scale down
+	ScaleDownOrder scaledownorder.Strategy
 	// ProcessorCallbacks is interface defining extra callback methods which can be called by processors used in extension points.
 	ProcessorCallbacks processor_callbacks.ProcessorCallbacks
 }
@@ -86,7 +89,7 @@ func NewResourceLimiterFromAutoscalingOptions(options config.AutoscalingOptions)
 func NewAutoscalingContext(options config.AutoscalingOptions, predicateChecker *simulator.PredicateChecker,
 	autoscalingKubeClients *AutoscalingKubeClients, cloudProvider cloudprovider.CloudProvider,
 	expanderStrategy expander.Strategy, estimatorBuilder estimator.EstimatorBuilder,
-	processorCallbacks processor_callbacks.ProcessorCallbacks) *AutoscalingContext {
+	scaleDownOrder scaledownorder.Strategy, processorCallbacks processor_callbacks.ProcessorCallbacks) *AutoscalingContext {
 	return &AutoscalingContext{
 		AutoscalingOptions:     options,
 		CloudProvider:          cloudProvider,
@@ -94,6 +97,7 @@ func NewAutoscalingContext(options config.AutoscalingOptions, predicateChecker *
 		PredicateChecker:       predicateChecker,
 		ExpanderStrategy:       expanderStrategy,
 		EstimatorBuilder:       estimatorBuilder,
+		ScaleDownOrder:         scaleDownOrder,
 		ProcessorCallbacks:     processorCallbacks,
 	}
 }
@@ -19,18 +19,27 @@ package core
 import (
 	"time"
 
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/autoscaler/cluster-autoscaler/capacityprofiles"
 	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider"
 	cloudBuilder "k8s.io/autoscaler/cluster-autoscaler/cloudprovider/builder"
 	"k8s.io/autoscaler/cluster-autoscaler/clusterstate"
 	"k8s.io/autoscaler/cluster-autoscaler/config"
 	"k8s.io/autoscaler/cluster-autoscaler/context"
+	"k8s.io/autoscaler/cluster-autoscaler/debuginfo"
 	"k8s.io/autoscaler/cluster-autoscaler/estimator"
 	"k8s.io/autoscaler/cluster-autoscaler/expander"
 	"k8s.io/autoscaler/cluster-autoscaler/expander/factory"
+	"k8s.io/autoscaler/cluster-autoscaler/maintenancewindows"
 	ca_processors "k8s.io/autoscaler/cluster-autoscaler/processors"
+	"k8s.io/autoscaler/cluster-autoscaler/processors/nodegroupset"
+	"k8s.io/autoscaler/cluster-autoscaler/processors/pods"
+	"k8s.io/autoscaler/cluster-autoscaler/scaledownorder"
 	"k8s.io/autoscaler/cluster-autoscaler/simulator"
+	"k8s.io/autoscaler/cluster-autoscaler/softstate"
 	"k8s.io/autoscaler/cluster-autoscaler/utils/backoff"
 	"k8s.io/autoscaler/cluster-autoscaler/utils/errors"
+	kube_util "k8s.io/autoscaler/cluster-autoscaler/utils/kubernetes"
 	kube_client "k8s.io/client-go/kubernetes"
 )
 
@@ -44,8 +53,13 @@ type AutoscalerOptions struct {
 	PredicateChecker       *simulator.PredicateChecker
 	ExpanderStrategy       expander.Strategy
 	EstimatorBuilder       estimator.EstimatorBuilder
+	ScaleDownOrder         scaledownorder.Strategy
 	Processors             *ca_processors.AutoscalingProcessors
 	Backoff                backoff.Backoff
+	CapacityProfiles       *capacityprofiles.Manager
+	MaintenanceWindows     *maintenancewindows.Manager
+	SoftStateStore         softstate.Store
+	DebugInfo              *debuginfo.Recorder
 }
 
 // Autoscaler is the main component of CA which scales up/down node groups according to its configuration
@@ -71,7 +85,38 @@ func NewAutoscaler(opts AutoscalerOptions) (Autoscaler, errors.AutoscalerError)
 		opts.CloudProvider,
 		opts.ExpanderStrategy,
 		opts.EstimatorBuilder,
-		opts.Backoff), nil
+		opts.ScaleDownOrder,
+		opts.Backoff,
+		opts.CapacityProfiles,
+		opts.MaintenanceWindows,
+		opts.SoftStateStore,
+		opts.DebugInfo), nil
+}
+
+// NewProcessors returns the processors making autoscaling decisions the way
+// they are made with the given options. Processors only reporting decisions,
+// like status writers, are left at their defaults.
+func NewProcessors(options config.AutoscalingOptions) *ca_processors.AutoscalingProcessors {
+	processors := ca_processors.DefaultProcessors()
+	processors.PodListProcessor = NewFilterOutSchedulablePodListProcessor()
+	comparator := nodegroupset.CreateGenericNodeInfoComparator(nodeInfoComparatorOptions(options))
+	if options.BalancingProviderLabels {
+		comparator = nodegroupset.CreateNodeInfoComparator(options.CloudProviderName, nodeInfoComparatorOptions(options))
+	}
+	processors.NodeGroupSetProcessor = &nodegroupset.BalancingNodeGroupSetProcessor{Comparator: comparator}
+	return processors
+}
+
+func nodeInfoComparatorOptions(options config.AutoscalingOptions) nodegroupset.NodeInfoComparatorOptions {
+	tolerances := make(map[apiv1.ResourceName]float64, len(options.BalancingResourceTolerances))
+	for res, tolerance := range options.BalancingResourceTolerances {
+		tolerances[apiv1.ResourceName(res)] = tolerance
+	}
+	return nodegroupset.NodeInfoComparatorOptions{
+		IgnoredLabels:      options.BalancingIgnoredLabels,
+		ResourceTolerances: tolerances,
+		CompareTaints:      options.BalancingCompareTaints,
+	}
 }
 
 // Initialize default options if not provided.
@@ -79,6 +124,14 @@ func initializeDefaultOptions(opts *AutoscalerOptions) error {
 	if opts.Processors == nil {
 		opts.Processors = ca_processors.DefaultProcessors()
 	}
+	if len(opts.Headroom) > 0 {
+		// Headroom pods are added after filtering out schedulable pods, so they
+		// never disable scale-down.
+		opts.Processors.PodListProcessor = pods.NewCombinedPodListProcessor([]pods.PodListProcessor{
+			opts.Processors.PodListProcessor,
+			pods.NewHeadroomPodListProcessor(opts.Headroom),
+		})
+	}
 	if opts.AutoscalingKubeClients == nil {
 		opts.AutoscalingKubeClients = context.NewAutoscalingKubeClients(opts.AutoscalingOptions, opts.KubeClient, opts.EventsKubeClient)
 	}
@@ -95,7 +148,7 @@ func initializeDefaultOptions(opts *AutoscalerOptions) error {
 	}
 	if opts.ExpanderStrategy == nil {
 		expanderStrategy, err := factory.ExpanderStrategyFromString(opts.ExpanderName,
-			opts.CloudProvider, opts.AutoscalingKubeClients, opts.KubeClient, opts.ConfigNamespace)
+			opts.CloudProvider, opts.AutoscalingKubeClients, opts.KubeClient, opts.ConfigNamespace, opts.ExternalExpanderConfig)
 		if err != nil {
 			return err
 		}
@@ -108,10 +161,35 @@ func initializeDefaultOptions(opts *AutoscalerOptions) error {
 		}
 		opts.EstimatorBuilder = estimatorBuilder
 	}
+	if opts.ScaleDownOrder == nil {
+		scaleDownOrder, err := scaledownorder.NewStrategy(opts.ScaleDownOrderName, opts.CloudProvider)
+		if err != nil {
+			return err
+		}
+		opts.ScaleDownOrder = scaleDownOrder
+	}
 	if opts.Backoff == nil {
 		opts.Backoff =
 			backoff.NewIdBasedExponentialBackoff(clusterstate.InitialNodeGroupBackoffDuration, clusterstate.MaxNodeGroupBackoffDuration, clusterstate.NodeGroupBackoffResetTimeout)
 	}
+	if opts.CapacityProfilesEnabled && opts.CapacityProfiles == nil {
+		// Like the priority expander's lister, this one is never stopped.
+		stopChannel := make(chan struct{})
+		lister := kube_util.NewConfigMapListerForNamespace(opts.KubeClient, stopChannel, opts.ConfigNamespace)
+		opts.CapacityProfiles = capacityprofiles.NewManager(lister.ConfigMaps(opts.ConfigNamespace), opts.AutoscalingKubeClients.Recorder)
+	}
+	if opts.MaintenanceWindowsEnabled && opts.MaintenanceWindows == nil {
+		stopChannel := make(chan struct{})
+		lister := kube_util.NewConfigMapListerForNamespace(opts.KubeClient, stopChannel, opts.ConfigNamespace)
+		opts.MaintenanceWindows = maintenancewindows.NewManager(lister.ConfigMaps(opts.ConfigNamespace), opts.AutoscalingKubeClients.Recorder)
+	}
+	if opts.SoftStateStoreName != "" && opts.SoftStateStore == nil {
+		softStateStore, err := softstate.NewStore(opts.SoftStateStoreName, opts.KubeClient, opts.ConfigNamespace, opts.SoftStateFile)
+		if err != nil {
+			return err
+		}
+		opts.SoftStateStore = softStateStore
+	}
 
 	return nil
 }
@@ -0,0 +1,50 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import (
+	"testing"
+
+	"k8s.io/autoscaler/cluster-autoscaler/config"
+	"k8s.io/autoscaler/cluster-autoscaler/processors/nodegroupset"
+	. "k8s.io/autoscaler/cluster-autoscaler/utils/test"
+
+	schedulernodeinfo "k8s.io/kubernetes/pkg/scheduler/nodeinfo"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewProcessorsProviderLabels(t *testing.T) {
+	ni1 := schedulernodeinfo.NewNodeInfo()
+	n1 := BuildTestNode("node1", 1000, 2000)
+	n1.ObjectMeta.Labels["eks.amazonaws.com/nodegroup"] = "ng1"
+	ni1.SetNode(n1)
+	ni2 := schedulernodeinfo.NewNodeInfo()
+	n2 := BuildTestNode("node2", 1000, 2000)
+	n2.ObjectMeta.Labels["eks.amazonaws.com/nodegroup"] = "ng2"
+	ni2.SetNode(n2)
+
+	comparator := func(options config.AutoscalingOptions) nodegroupset.NodeInfoComparator {
+		processor, ok := NewProcessors(options).NodeGroupSetProcessor.(*nodegroupset.BalancingNodeGroupSetProcessor)
+		assert.True(t, ok)
+		return processor.Comparator
+	}
+	// Labels set by the cloud provider are compared unless enabled by the flag.
+	assert.False(t, comparator(config.AutoscalingOptions{CloudProviderName: "aws"})(ni1, ni2))
+	assert.True(t, comparator(config.AutoscalingOptions{CloudProviderName: "aws", BalancingProviderLabels: true})(ni1, ni2))
+	assert.False(t, comparator(config.AutoscalingOptions{CloudProviderName: "gce", BalancingProviderLabels: true})(ni1, ni2))
+}
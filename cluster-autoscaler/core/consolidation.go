@@ -0,0 +1,465 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider"
+	"k8s.io/autoscaler/cluster-autoscaler/clusterstate"
+	"k8s.io/autoscaler/cluster-autoscaler/context"
+	"k8s.io/autoscaler/cluster-autoscaler/estimator"
+	"k8s.io/autoscaler/cluster-autoscaler/metrics"
+	"k8s.io/autoscaler/cluster-autoscaler/processors/nodegroupset"
+	"k8s.io/autoscaler/cluster-autoscaler/processors/status"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator"
+	"k8s.io/autoscaler/cluster-autoscaler/utils/errors"
+	"k8s.io/autoscaler/cluster-autoscaler/utils/gpu"
+	kube_util "k8s.io/autoscaler/cluster-autoscaler/utils/kubernetes"
+	scheduler_util "k8s.io/autoscaler/cluster-autoscaler/utils/scheduler"
+
+	apiv1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1beta1"
+	"k8s.io/klog"
+	schedulernodeinfo "k8s.io/kubernetes/pkg/scheduler/nodeinfo"
+)
+
+// Consolidation replaces several underutilized nodes, whose pods can't be moved
+// to other existing nodes, with fewer nodes from a cheaper node group. It scales
+// the cheaper node group up first, waits until all of the new nodes are ready and
+// only then drains the replaced nodes, one at a time.
+type Consolidation struct {
+	context              *context.AutoscalingContext
+	clusterStateRegistry *clusterstate.ClusterStateRegistry
+	scaleDown            *ScaleDown
+	// underutilizedSince keeps the time since which nodes are below the
+	// utilization threshold, by node name.
+	underutilizedSince map[string]time.Time
+	// plan is the consolidation being carried out, nil if there is none.
+	plan *consolidationPlan
+}
+
+// consolidationPlan describes replacing nodesToRemove with newNodes nodes from nodeGroup.
+type consolidationPlan struct {
+	nodeGroup cloudprovider.NodeGroup
+	newNodes  int
+	// readyNodes is the number of ready nodes in nodeGroup before the scale-up.
+	readyNodes    int
+	nodesToRemove []*apiv1.Node
+	// savings is the difference in price per hour.
+	savings   float64
+	startTime time.Time
+}
+
+type consolidationCandidate struct {
+	node      *apiv1.Node
+	nodeGroup cloudprovider.NodeGroup
+	pods      []*apiv1.Pod
+	price     float64
+}
+
+// NewConsolidation builds new Consolidation object.
+func NewConsolidation(context *context.AutoscalingContext, clusterStateRegistry *clusterstate.ClusterStateRegistry, scaleDown *ScaleDown) *Consolidation {
+	return &Consolidation{
+		context:              context,
+		clusterStateRegistry: clusterStateRegistry,
+		scaleDown:            scaleDown,
+		underutilizedSince:   make(map[string]time.Time),
+	}
+}
+
+// InProgress returns true if a consolidation was started and not all of the
+// replaced nodes have been drained yet.
+func (c *Consolidation) InProgress() bool {
+	return c.plan != nil
+}
+
+// TryToConsolidate looks for underutilized nodes that can be replaced with fewer,
+// cheaper nodes and, if it finds any, scales the cheaper node group up. The replaced
+// nodes are drained by Continue in the following loops. Returns true if a
+// consolidation was started.
+func (c *Consolidation) TryToConsolidate(allNodes []*apiv1.Node, pods []*apiv1.Pod, pdbs []*policyv1.PodDisruptionBudget,
+	nodeInfos map[string]*schedulernodeinfo.NodeInfo, currentTime time.Time) (bool, errors.AutoscalerError) {
+	pricing, err := c.context.CloudProvider.Pricing()
+	if err != nil {
+		if err != cloudprovider.ErrNotImplemented {
+			klog.Warningf("Consolidation: failed to get pricing: %v", err)
+		}
+		return false, nil
+	}
+
+	candidates := c.findCandidates(allNodes, pods, pdbs, pricing, currentTime)
+	if len(candidates) == 0 {
+		klog.V(4).Infof("Consolidation: no candidates")
+		return false, nil
+	}
+
+	plan, typedErr := c.findBestPlan(allNodes, candidates, nodeInfos, pricing, currentTime)
+	if typedErr != nil {
+		return false, typedErr
+	}
+	if plan == nil {
+		klog.V(4).Infof("Consolidation: no replacement lowers the cost")
+		return false, nil
+	}
+
+	nodeNames := make([]string, 0, len(plan.nodesToRemove))
+	for _, node := range plan.nodesToRemove {
+		nodeNames = append(nodeNames, node.Name)
+	}
+	if c.context.DryRun {
+		klog.V(0).Infof("Dry run consolidation: would replace nodes %s with %d nodes from %s, saving %.3f per hour",
+			strings.Join(nodeNames, ","), plan.newNodes, plan.nodeGroup.Id(), plan.savings)
+		return false, nil
+	}
+	klog.V(0).Infof("Consolidation: replacing nodes %s with %d nodes from %s, saving %.3f per hour",
+		strings.Join(nodeNames, ","), plan.newNodes, plan.nodeGroup.Id(), plan.savings)
+	c.context.LogRecorder.Eventf(apiv1.EventTypeNormal, "Consolidation", "Consolidation: replacing nodes %s with %d nodes from %s",
+		strings.Join(nodeNames, ","), plan.newNodes, plan.nodeGroup.Id())
+
+	targetSize, sizeErr := plan.nodeGroup.TargetSize()
+	if sizeErr != nil {
+		return false, errors.ToAutoscalerError(errors.CloudProviderError, sizeErr)
+	}
+	info := nodegroupset.ScaleUpInfo{
+		Group:       plan.nodeGroup,
+		CurrentSize: targetSize,
+		NewSize:     targetSize + plan.newNodes,
+		MaxSize:     plan.nodeGroup.MaxSize(),
+	}
+	gpuType := gpu.GetGpuTypeForMetrics(c.context.CloudProvider.GPULabel(), c.context.CloudProvider.GetAvailableGPUTypes(), nodeInfos[plan.nodeGroup.Id()].Node(), nil)
+	if typedErr := executeScaleUp(c.context, c.clusterStateRegistry, info, gpuType, currentTime); typedErr != nil {
+		return false, typedErr.AddPrefix("failed to start consolidation: ")
+	}
+	c.clusterStateRegistry.Recalculate()
+
+	plan.startTime = currentTime
+	c.plan = plan
+	return true, nil
+}
+
+// Continue carries out the consolidation in progress. It waits until all of the
+// new nodes are ready and then starts draining the next replaced node, provided
+// its pods still fit elsewhere in the cluster.
+func (c *Consolidation) Continue(allNodes []*apiv1.Node, pods []*apiv1.Pod, pdbs []*policyv1.PodDisruptionBudget,
+	currentTime time.Time) (*status.ScaleDownStatus, errors.AutoscalerError) {
+	scaleDownStatus := &status.ScaleDownStatus{Result: status.ScaleDownInProgress}
+	plan := c.plan
+	if plan == nil {
+		scaleDownStatus.Result = status.ScaleDownNoUnneeded
+		return scaleDownStatus, nil
+	}
+
+	readyNodes := countReadyNodes(c.context.CloudProvider, allNodes, plan.nodeGroup)
+	if readyNodes < plan.readyNodes+plan.newNodes {
+		if plan.startTime.Add(c.context.MaxNodeProvisionTime).Before(currentTime) {
+			klog.Warningf("Consolidation: new nodes in %s not ready after %v, giving up", plan.nodeGroup.Id(), c.context.MaxNodeProvisionTime)
+			c.context.LogRecorder.Eventf(apiv1.EventTypeWarning, "ConsolidationFailed", "Consolidation: new nodes in %s not ready, giving up",
+				plan.nodeGroup.Id())
+			c.plan = nil
+			scaleDownStatus.Result = status.ScaleDownNoNodeDeleted
+			return scaleDownStatus, nil
+		}
+		klog.V(1).Infof("Consolidation: waiting for new nodes in %s, %d of %d ready", plan.nodeGroup.Id(),
+			readyNodes-plan.readyNodes, plan.newNodes)
+		return scaleDownStatus, nil
+	}
+
+	// Nodes removed in the meantime are skipped, the rest is taken from the current node list.
+	nodesByName := make(map[string]*apiv1.Node, len(allNodes))
+	for _, node := range allNodes {
+		nodesByName[node.Name] = node
+	}
+	remaining := make([]*apiv1.Node, 0, len(plan.nodesToRemove))
+	for _, node := range plan.nodesToRemove {
+		if current, found := nodesByName[node.Name]; found {
+			remaining = append(remaining, current)
+		}
+	}
+	if len(remaining) == 0 {
+		c.plan = nil
+		scaleDownStatus.Result = status.ScaleDownNoUnneeded
+		return scaleDownStatus, nil
+	}
+	toRemove := remaining[0]
+	nodeGroup, err := c.context.CloudProvider.NodeGroupForNode(toRemove)
+	if err != nil {
+		plan.nodesToRemove = remaining
+		scaleDownStatus.Result = status.ScaleDownError
+		return scaleDownStatus, errors.ToAutoscalerError(errors.CloudProviderError, err)
+	}
+	// The replaced nodes count against scale-down budgets like any other removed node.
+	if nodeGroup != nil && !reflect.ValueOf(nodeGroup).IsNil() {
+		size, err := nodeGroup.TargetSize()
+		if err != nil {
+			plan.nodesToRemove = remaining
+			scaleDownStatus.Result = status.ScaleDownError
+			return scaleDownStatus, errors.ToAutoscalerError(errors.CloudProviderError, err)
+		}
+		if c.scaleDown.nodeGroupScaleDownBudgetLeft(nodeGroup, size, currentTime) <= 0 {
+			klog.V(1).Infof("Consolidation: waiting to remove %s, node group %s scale-down budget exceeded", toRemove.Name, nodeGroup.Id())
+			plan.nodesToRemove = remaining
+			scaleDownStatus.Result = status.ScaleDownNoNodeDeleted
+			return scaleDownStatus, nil
+		}
+	}
+	plan.nodesToRemove = remaining[1:]
+	if len(plan.nodesToRemove) == 0 {
+		c.plan = nil
+	}
+
+	// Pods of the node can't be moved to the other replaced nodes.
+	otherReplaced := make(map[string]bool, len(plan.nodesToRemove))
+	for _, node := range plan.nodesToRemove {
+		otherReplaced[node.Name] = true
+	}
+	destinations := make([]*apiv1.Node, 0, len(allNodes))
+	for _, node := range allNodes {
+		if !otherReplaced[node.Name] {
+			destinations = append(destinations, node)
+		}
+	}
+	nonExpendablePods := filterOutExpendablePods(pods, c.context.ExpendablePodsPriorityCutoff)
+	nodesToRemove, _, _, typedErr := simulator.FindNodesToRemove([]*apiv1.Node{toRemove}, destinations, nonExpendablePods,
+		c.context.ListerRegistry, c.context.PredicateChecker, 1, false, map[string]string{}, simulator.NewUsageTracker(), currentTime, pdbs)
+	if typedErr != nil {
+		scaleDownStatus.Result = status.ScaleDownError
+		return scaleDownStatus, typedErr.AddPrefix("Find node to remove failed: ")
+	}
+	if len(nodesToRemove) == 0 {
+		klog.Warningf("Consolidation: node %s can no longer be removed", toRemove.Name)
+		scaleDownStatus.Result = status.ScaleDownNoNodeDeleted
+		return scaleDownStatus, nil
+	}
+
+	podsToReschedule := nodesToRemove[0].PodsToReschedule
+	klog.V(0).Infof("Consolidation: removing node %s, pods to reschedule: %d", toRemove.Name, len(podsToReschedule))
+	c.context.LogRecorder.Eventf(apiv1.EventTypeNormal, "ScaleDown", "Consolidation: removing node %s", toRemove.Name)
+	c.scaleDown.scheduleDeleteNode(toRemove, podsToReschedule, nodeGroup, metrics.Consolidated)
+
+	nodeGroups := map[string]cloudprovider.NodeGroup{toRemove.Name: nodeGroup}
+	scaleDownStatus.ScaledDownNodes = c.scaleDown.mapNodesToStatusScaleDownNodes([]*apiv1.Node{toRemove}, nodeGroups,
+		map[string][]*apiv1.Pod{toRemove.Name: podsToReschedule})
+	scaleDownStatus.Result = status.ScaleDownNodeDeleteStarted
+	return scaleDownStatus, nil
+}
+
+// findCandidates returns nodes that have been underutilized for long enough, aren't
+// removed by the regular scale-down and can be drained, least utilized first.
+func (c *Consolidation) findCandidates(allNodes []*apiv1.Node, pods []*apiv1.Pod, pdbs []*policyv1.PodDisruptionBudget,
+	pricing cloudprovider.PricingModel, currentTime time.Time) []consolidationCandidate {
+	sd := c.scaleDown
+	nonExpendablePods := filterOutExpendablePods(pods, c.context.ExpendablePodsPriorityCutoff)
+	nodeNameToNodeInfo := scheduler_util.CreateNodeNameToInfoMap(nonExpendablePods, allNodes)
+
+	underutilizedSince := make(map[string]time.Time)
+	candidates := make([]consolidationCandidate, 0)
+	for _, node := range allNodes {
+		utilInfo, found := sd.nodeUtilizationMap[node.Name]
+		if !found || !kube_util.IsNodeReadyAndSchedulable(node) {
+			continue
+		}
+		nodeGroup, err := c.context.CloudProvider.NodeGroupForNode(node)
+		if err != nil || nodeGroup == nil || reflect.ValueOf(nodeGroup).IsNil() {
+			continue
+		}
+		if !sd.isNodeBelowUtilizationThreshold(node, sd.getNodeGroupOptions(nodeGroup), utilInfo) {
+			continue
+		}
+		since, found := c.underutilizedSince[node.Name]
+		if !found {
+			since = currentTime
+		}
+		underutilizedSince[node.Name] = since
+		if since.Add(sd.getNodeGroupOptions(nodeGroup).ScaleDownUnneededTime).After(currentTime) {
+			continue
+		}
+		if _, unneeded := sd.unneededNodes[node.Name]; unneeded {
+			continue
+		}
+		nodeInfo, found := nodeNameToNodeInfo[node.Name]
+		if !found {
+			continue
+		}
+		podsToMove, err := simulator.GetPodsToMove(nodeInfo, c.context.ListerRegistry, pdbs)
+		if err != nil {
+			klog.V(4).Infof("Consolidation: node %s can't be drained: %v", node.Name, err)
+			continue
+		}
+		price, err := pricing.NodePrice(node, currentTime, currentTime.Add(time.Hour))
+		if err != nil {
+			klog.V(4).Infof("Consolidation: failed to get price of node %s: %v", node.Name, err)
+			continue
+		}
+		// The pods are only used to plan the replacement nodes, so they must not
+		// be bound to the node they are running on.
+		unboundPods := make([]*apiv1.Pod, 0, len(podsToMove))
+		for _, pod := range podsToMove {
+			podCopy := pod.DeepCopy()
+			podCopy.Spec.NodeName = ""
+			unboundPods = append(unboundPods, podCopy)
+		}
+		candidates = append(candidates, consolidationCandidate{node: node, nodeGroup: nodeGroup, pods: unboundPods, price: price})
+	}
+	c.underutilizedSince = underutilizedSince
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return sd.nodeUtilizationMap[candidates[i].node.Name].Utilization < sd.nodeUtilizationMap[candidates[j].node.Name].Utilization
+	})
+
+	// Node groups can't be scaled down below their min size.
+	available := make(map[string]int)
+	result := make([]consolidationCandidate, 0, c.context.MaxConsolidatedNodes)
+	for _, candidate := range candidates {
+		if len(result) >= c.context.MaxConsolidatedNodes {
+			break
+		}
+		id := candidate.nodeGroup.Id()
+		if _, found := available[id]; !found {
+			size, err := candidate.nodeGroup.TargetSize()
+			if err != nil {
+				klog.Errorf("Failed to get size for %s: %v", id, err)
+				continue
+			}
+			available[id] = size - candidate.nodeGroup.MinSize() - sd.nodeDeletionTracker.GetDeletionsInProgress(id)
+		}
+		if available[id] <= 0 {
+			continue
+		}
+		available[id]--
+		result = append(result, candidate)
+	}
+	return result
+}
+
+// findBestPlan finds the replacement of the first k candidates with nodes from a
+// single node group that saves the most.
+func (c *Consolidation) findBestPlan(allNodes []*apiv1.Node, candidates []consolidationCandidate,
+	nodeInfos map[string]*schedulernodeinfo.NodeInfo, pricing cloudprovider.PricingModel, currentTime time.Time) (*consolidationPlan, errors.AutoscalerError) {
+	resourceLimiter, err := c.context.CloudProvider.GetResourceLimiter()
+	if err != nil {
+		return nil, errors.ToAutoscalerError(errors.CloudProviderError, err)
+	}
+	nodeGroups := c.context.CloudProvider.NodeGroups()
+	nodesFromNotAutoscaledGroups, typedErr := filterOutNodesFromNotAutoscaledGroups(allNodes, c.context.CloudProvider)
+	if typedErr != nil {
+		return nil, typedErr.AddPrefix("failed to filter out nodes which are from not autoscaled groups: ")
+	}
+	resourcesLeft, typedErr := computeScaleUpResourcesLeftLimits(c.context.CloudProvider, nodeGroups, nodeInfos, nodesFromNotAutoscaledGroups, resourceLimiter)
+	if typedErr != nil {
+		return nil, typedErr.AddPrefix("Could not compute total resources: ")
+	}
+	binpacking := estimator.NewBinpackingNodeEstimator(c.context.PredicateChecker)
+
+	var best *consolidationPlan
+	pods := make([]*apiv1.Pod, 0)
+	removedPrice := 0.0
+	for k, candidate := range candidates {
+		pods = append(pods, candidate.pods...)
+		removedPrice += candidate.price
+		// Empty nodes are removed by the regular scale-down.
+		if len(pods) == 0 {
+			continue
+		}
+		for _, nodeGroup := range nodeGroups {
+			nodeInfo, found := nodeInfos[nodeGroup.Id()]
+			if !found || !c.clusterStateRegistry.IsNodeGroupSafeToScaleUp(nodeGroup, currentTime) {
+				continue
+			}
+			if !c.podsFitNodeInfo(pods, nodeInfo) {
+				continue
+			}
+			newNodes := binpacking.Estimate(pods, nodeInfo, nil)
+			targetSize, err := nodeGroup.TargetSize()
+			if err != nil || targetSize+newNodes > nodeGroup.MaxSize() {
+				continue
+			}
+			// New nodes are added before the replaced ones are removed.
+			if c.context.MaxNodesTotal > 0 && len(allNodes)+newNodes > c.context.MaxNodesTotal {
+				continue
+			}
+			delta, typedErr := computeScaleUpResourcesDelta(c.context.CloudProvider, nodeInfo, nodeGroup, resourceLimiter)
+			if typedErr != nil {
+				continue
+			}
+			for resource := range delta {
+				delta[resource] *= int64(newNodes)
+			}
+			if resourcesLeft.checkScaleUpDeltaWithinLimits(delta).exceeded {
+				continue
+			}
+			price, err := pricing.NodePrice(nodeInfo.Node(), currentTime, currentTime.Add(time.Hour))
+			if err != nil {
+				continue
+			}
+			savings := removedPrice - price*float64(newNodes)
+			if savings <= 0 || savings < removedPrice*c.context.ConsolidationMinSavings {
+				continue
+			}
+			klog.V(4).Infof("Consolidation: %d nodes can be replaced with %d nodes from %s, saving %.3f per hour",
+				k+1, newNodes, nodeGroup.Id(), savings)
+			if best == nil || savings > best.savings {
+				best = &consolidationPlan{
+					nodeGroup:     nodeGroup,
+					newNodes:      newNodes,
+					readyNodes:    countReadyNodes(c.context.CloudProvider, allNodes, nodeGroup),
+					nodesToRemove: candidateNodes(candidates[:k+1]),
+					savings:       savings,
+				}
+			}
+		}
+	}
+	return best, nil
+}
+
+func (c *Consolidation) podsFitNodeInfo(pods []*apiv1.Pod, nodeInfo *schedulernodeinfo.NodeInfo) bool {
+	for _, pod := range pods {
+		if err := c.context.PredicateChecker.CheckPredicates(pod, nil, nodeInfo); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+func candidateNodes(candidates []consolidationCandidate) []*apiv1.Node {
+	nodes := make([]*apiv1.Node, 0, len(candidates))
+	for _, candidate := range candidates {
+		nodes = append(nodes, candidate.node)
+	}
+	return nodes
+}
+
+// countReadyNodes returns the number of ready nodes belonging to the node group.
+func countReadyNodes(cloudProvider cloudprovider.CloudProvider, nodes []*apiv1.Node, nodeGroup cloudprovider.NodeGroup) int {
+	count := 0
+	for _, node := range nodes {
+		if !kube_util.IsNodeReadyAndSchedulable(node) {
+			continue
+		}
+		group, err := cloudProvider.NodeGroupForNode(node)
+		if err != nil || group == nil || reflect.ValueOf(group).IsNil() {
+			continue
+		}
+		if group.Id() == nodeGroup.Id() {
+			count++
+		}
+	}
+	return count
+}
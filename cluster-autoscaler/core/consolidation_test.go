@@ -0,0 +1,361 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	appsv1 "k8s.io/api/apps/v1"
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	testprovider "k8s.io/autoscaler/cluster-autoscaler/cloudprovider/test"
+	"k8s.io/autoscaler/cluster-autoscaler/clusterstate"
+	"k8s.io/autoscaler/cluster-autoscaler/config"
+	"k8s.io/autoscaler/cluster-autoscaler/processors/status"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator"
+	kube_util "k8s.io/autoscaler/cluster-autoscaler/utils/kubernetes"
+	. "k8s.io/autoscaler/cluster-autoscaler/utils/test"
+	"k8s.io/client-go/kubernetes/fake"
+	core "k8s.io/client-go/testing"
+	kube_record "k8s.io/client-go/tools/record"
+	schedulernodeinfo "k8s.io/kubernetes/pkg/scheduler/nodeinfo"
+)
+
+type consolidationTest struct {
+	consolidation *Consolidation
+	provider      *testprovider.TestCloudProvider
+	nodeInfos     map[string]*schedulernodeinfo.NodeInfo
+	scaledUp      chan string
+	deletedNodes  chan string
+	deleteErr     error
+	n1, n2        *apiv1.Node
+	p1, p2        *apiv1.Pod
+}
+
+// newConsolidationTest builds two large nodes in ng1, each running a single
+// replicated pod, and an empty ng2 with smaller, cheaper nodes.
+func newConsolidationTest(t *testing.T, nodePrices map[string]float64) *consolidationTest {
+	test := &consolidationTest{
+		scaledUp:     make(chan string, 10),
+		deletedNodes: make(chan string, 10),
+	}
+	test.n1 = BuildTestNode("n1", 4000, 4000)
+	SetNodeReadyState(test.n1, true, time.Time{})
+	test.n2 = BuildTestNode("n2", 4000, 4000)
+	SetNodeReadyState(test.n2, true, time.Time{})
+	ng1Template := BuildTestNode("ng1-template", 4000, 4000)
+	SetNodeReadyState(ng1Template, true, time.Time{})
+	ng2Template := BuildTestNode("ng2-template", 2000, 2000)
+	SetNodeReadyState(ng2Template, true, time.Time{})
+
+	rs := appsv1.ReplicaSet{ObjectMeta: metav1.ObjectMeta{Name: "rs", Namespace: "default", SelfLink: "api/v1/namespaces/default/replicasets/rs"}}
+	ownerRef := GenerateOwnerReferences(rs.Name, "ReplicaSet", "extensions/v1beta1", "")
+	test.p1 = BuildTestPod("p1", 900, 900)
+	test.p1.OwnerReferences = ownerRef
+	test.p1.Spec.NodeName = "n1"
+	test.p2 = BuildTestPod("p2", 900, 900)
+	test.p2.OwnerReferences = ownerRef
+	test.p2.Spec.NodeName = "n2"
+
+	fakeClient := &fake.Clientset{}
+	fakeClient.Fake.AddReactor("get", "pods", func(action core.Action) (bool, runtime.Object, error) {
+		return true, nil, errors.NewNotFound(apiv1.Resource("pod"), "whatever")
+	})
+	fakeClient.Fake.AddReactor("get", "nodes", func(action core.Action) (bool, runtime.Object, error) {
+		getAction := action.(core.GetAction)
+		switch getAction.GetName() {
+		case test.n1.Name:
+			return true, test.n1, nil
+		case test.n2.Name:
+			return true, test.n2, nil
+		}
+		return true, nil, fmt.Errorf("wrong node: %v", getAction.GetName())
+	})
+	fakeClient.Fake.AddReactor("update", "nodes", func(action core.Action) (bool, runtime.Object, error) {
+		update := action.(core.UpdateAction)
+		return true, update.GetObject(), nil
+	})
+
+	test.provider = testprovider.NewTestCloudProvider(func(nodeGroup string, delta int) error {
+		test.scaledUp <- fmt.Sprintf("%s-%d", nodeGroup, delta)
+		return nil
+	}, func(nodeGroup string, node string) error {
+		test.deletedNodes <- node
+		return test.deleteErr
+	})
+	test.provider.AddNodeGroup("ng1", 0, 10, 2)
+	test.provider.AddNode("ng1", test.n1)
+	test.provider.AddNode("ng1", test.n2)
+	test.provider.AddNodeGroup("ng2", 0, 10, 0)
+	test.provider.SetPricingModel(&testPricingModel{nodePrices: nodePrices})
+
+	test.nodeInfos = map[string]*schedulernodeinfo.NodeInfo{
+		"ng1": schedulernodeinfo.NewNodeInfo(),
+		"ng2": schedulernodeinfo.NewNodeInfo(),
+	}
+	test.nodeInfos["ng1"].SetNode(ng1Template)
+	test.nodeInfos["ng2"].SetNode(ng2Template)
+
+	rsLister, err := kube_util.NewTestReplicaSetLister([]*appsv1.ReplicaSet{&rs})
+	assert.NoError(t, err)
+	registry := kube_util.NewListerRegistry(nil, nil, nil, nil, nil, nil, nil, nil, rsLister, nil)
+	options := config.AutoscalingOptions{
+		ScaleDownUtilizationThreshold: 0.5,
+		ScaleDownUnneededTime:         time.Minute,
+		MaxGracefulTerminationSec:     60,
+		MaxNodeProvisionTime:          15 * time.Minute,
+		MaxConsolidatedNodes:          3,
+		ConsolidationMinSavings:       0.2,
+	}
+	context := NewScaleTestAutoscalingContext(options, fakeClient, registry, test.provider, nil)
+	context.Recorder = kube_record.NewFakeRecorder(20)
+
+	clusterStateRegistry := clusterstate.NewClusterStateRegistry(test.provider, clusterstate.ClusterStateRegistryConfig{}, context.LogRecorder, newBackoff())
+	clusterStateRegistry.UpdateNodes([]*apiv1.Node{test.n1, test.n2}, test.nodeInfos, time.Now())
+	scaleDown := NewScaleDown(&context, clusterStateRegistry)
+	scaleDown.nodeUtilizationMap = map[string]simulator.UtilizationInfo{
+		"n1": {Utilization: 0.225},
+		"n2": {Utilization: 0.225},
+	}
+	test.consolidation = NewConsolidation(&context, clusterStateRegistry, scaleDown)
+	return test
+}
+
+func TestConsolidation(t *testing.T) {
+	test := newConsolidationTest(t, map[string]float64{
+		"n1":           1.0,
+		"n2":           1.0,
+		"ng1-template": 1.0,
+		"ng2-template": 0.5,
+	})
+	c := test.consolidation
+	nodes := []*apiv1.Node{test.n1, test.n2}
+	pods := []*apiv1.Pod{test.p1, test.p2}
+	now := time.Now()
+
+	// The nodes haven't been underutilized for long enough yet.
+	started, err := c.TryToConsolidate(nodes, pods, nil, test.nodeInfos, now)
+	assert.NoError(t, err)
+	assert.False(t, started)
+
+	// Both nodes are replaced with a single ng2 node.
+	now = now.Add(2 * time.Minute)
+	started, err = c.TryToConsolidate(nodes, pods, nil, test.nodeInfos, now)
+	assert.NoError(t, err)
+	assert.True(t, started)
+	assert.True(t, c.InProgress())
+	assert.Equal(t, "ng2-1", getStringFromChan(test.scaledUp))
+
+	// Nothing is drained until the new node is ready.
+	scaleDownStatus, err := c.Continue(nodes, pods, nil, now)
+	assert.NoError(t, err)
+	assert.Equal(t, status.ScaleDownInProgress, scaleDownStatus.Result)
+	assert.Equal(t, nothingReturned, getStringFromChan(test.deletedNodes))
+
+	n3 := BuildTestNode("n3", 2000, 2000)
+	SetNodeReadyState(n3, true, time.Time{})
+	test.provider.AddNode("ng2", n3)
+	nodes = append(nodes, n3)
+	scaleDownStatus, err = c.Continue(nodes, pods, nil, now)
+	assert.NoError(t, err)
+	assert.Equal(t, status.ScaleDownNodeDeleteStarted, scaleDownStatus.Result)
+	assert.Equal(t, "n1", scaleDownStatus.ScaledDownNodes[0].Node.Name)
+	waitForDeleteToFinish(t, c.scaleDown)
+	assert.Equal(t, "n1", getStringFromChan(test.deletedNodes))
+	assert.True(t, c.InProgress())
+
+	// The pod from n1 was moved to n3, the one from n2 still fits there.
+	movedPod := test.p1.DeepCopy()
+	movedPod.Spec.NodeName = "n3"
+	scaleDownStatus, err = c.Continue([]*apiv1.Node{test.n2, n3}, []*apiv1.Pod{movedPod, test.p2}, nil, now)
+	assert.NoError(t, err)
+	assert.Equal(t, status.ScaleDownNodeDeleteStarted, scaleDownStatus.Result)
+	waitForDeleteToFinish(t, c.scaleDown)
+	assert.Equal(t, "n2", getStringFromChan(test.deletedNodes))
+	assert.False(t, c.InProgress())
+}
+
+func TestConsolidationNotCheaper(t *testing.T) {
+	test := newConsolidationTest(t, map[string]float64{
+		"n1":           1.0,
+		"n2":           1.0,
+		"ng1-template": 2.5,
+		"ng2-template": 1.9,
+	})
+	now := time.Now()
+	nodes := []*apiv1.Node{test.n1, test.n2}
+	pods := []*apiv1.Pod{test.p1, test.p2}
+	for _, ts := range []time.Time{now, now.Add(2 * time.Minute)} {
+		started, err := test.consolidation.TryToConsolidate(nodes, pods, nil, test.nodeInfos, ts)
+		assert.NoError(t, err)
+		assert.False(t, started)
+	}
+	assert.Equal(t, nothingReturned, getStringFromChan(test.scaledUp))
+}
+
+func TestConsolidationNewNodesNotReady(t *testing.T) {
+	test := newConsolidationTest(t, map[string]float64{
+		"n1":           1.0,
+		"n2":           1.0,
+		"ng1-template": 1.0,
+		"ng2-template": 0.5,
+	})
+	c := test.consolidation
+	now := time.Now()
+	nodes := []*apiv1.Node{test.n1, test.n2}
+	pods := []*apiv1.Pod{test.p1, test.p2}
+	c.TryToConsolidate(nodes, pods, nil, test.nodeInfos, now)
+	now = now.Add(2 * time.Minute)
+	started, err := c.TryToConsolidate(nodes, pods, nil, test.nodeInfos, now)
+	assert.NoError(t, err)
+	assert.True(t, started)
+
+	scaleDownStatus, err := c.Continue(nodes, pods, nil, now.Add(20*time.Minute))
+	assert.NoError(t, err)
+	assert.Equal(t, status.ScaleDownNoNodeDeleted, scaleDownStatus.Result)
+	assert.False(t, c.InProgress())
+	assert.Equal(t, nothingReturned, getStringFromChan(test.deletedNodes))
+}
+
+func TestConsolidationKeepsHeadroom(t *testing.T) {
+	test := newConsolidationTest(t, map[string]float64{
+		"n1":           1.0,
+		"n2":           1.0,
+		"ng1-template": 1.5,
+		"ng2-template": 0.5,
+	})
+	c := test.consolidation
+	now := time.Now()
+	headroomPod := BuildTestPod("headroom", 1500, 1500)
+	headroomPod.Annotations = map[string]string{simulator.HeadroomPodAnnotationKey: "true"}
+	headroomPod.Spec.NodeName = "n1"
+	nodes := []*apiv1.Node{test.n1, test.n2}
+	pods := []*apiv1.Pod{test.p1, test.p2, headroomPod}
+	c.TryToConsolidate(nodes, pods, nil, test.nodeInfos, now)
+	now = now.Add(2 * time.Minute)
+
+	// Without the headroom a single ng2 node would be enough.
+	started, err := c.TryToConsolidate(nodes, pods, nil, test.nodeInfos, now)
+	assert.NoError(t, err)
+	assert.True(t, started)
+	assert.Equal(t, "ng2-2", getStringFromChan(test.scaledUp))
+
+	for _, name := range []string{"n3", "n4"} {
+		node := BuildTestNode(name, 2000, 2000)
+		SetNodeReadyState(node, true, time.Time{})
+		test.provider.AddNode("ng2", node)
+		nodes = append(nodes, node)
+	}
+	// The headroom pod moves to the new nodes, but isn't evicted.
+	scaleDownStatus, err := c.Continue(nodes, pods, nil, now)
+	assert.NoError(t, err)
+	assert.Equal(t, status.ScaleDownNodeDeleteStarted, scaleDownStatus.Result)
+	assert.Equal(t, "n1", scaleDownStatus.ScaledDownNodes[0].Node.Name)
+	assert.Equal(t, []*apiv1.Pod{test.p1}, scaleDownStatus.ScaledDownNodes[0].EvictedPods)
+	waitForDeleteToFinish(t, c.scaleDown)
+	assert.Equal(t, "n1", getStringFromChan(test.deletedNodes))
+}
+
+// startConsolidation starts replacing n1 and n2 with a new ready ng2 node n3.
+func startConsolidation(t *testing.T, test *consolidationTest, now time.Time) *apiv1.Node {
+	nodes := []*apiv1.Node{test.n1, test.n2}
+	pods := []*apiv1.Pod{test.p1, test.p2}
+	test.consolidation.TryToConsolidate(nodes, pods, nil, test.nodeInfos, now.Add(-2*time.Minute))
+	started, err := test.consolidation.TryToConsolidate(nodes, pods, nil, test.nodeInfos, now)
+	assert.NoError(t, err)
+	assert.True(t, started)
+	n3 := BuildTestNode("n3", 2000, 2000)
+	SetNodeReadyState(n3, true, time.Time{})
+	test.provider.AddNode("ng2", n3)
+	return n3
+}
+
+func TestConsolidationScaleDownBudget(t *testing.T) {
+	test := newConsolidationTest(t, map[string]float64{
+		"n1":           1.0,
+		"n2":           1.0,
+		"ng1-template": 1.0,
+		"ng2-template": 0.5,
+	})
+	c := test.consolidation
+	c.context.ScaleDownBudgetMaxNodes = 1
+	c.scaleDown.scaleDownBudgetTracker = NewScaleDownBudgetTracker(time.Hour)
+	now := time.Now()
+	n3 := startConsolidation(t, test, now)
+
+	scaleDownStatus, err := c.Continue([]*apiv1.Node{test.n1, test.n2, n3}, []*apiv1.Pod{test.p1, test.p2}, nil, now)
+	assert.NoError(t, err)
+	assert.Equal(t, status.ScaleDownNodeDeleteStarted, scaleDownStatus.Result)
+	waitForDeleteToFinish(t, c.scaleDown)
+	assert.Equal(t, "n1", getStringFromChan(test.deletedNodes))
+
+	// The budget of ng1 is used up, so n2 waits.
+	movedPod := test.p1.DeepCopy()
+	movedPod.Spec.NodeName = "n3"
+	scaleDownStatus, err = c.Continue([]*apiv1.Node{test.n2, n3}, []*apiv1.Pod{movedPod, test.p2}, nil, now)
+	assert.NoError(t, err)
+	assert.Equal(t, status.ScaleDownNoNodeDeleted, scaleDownStatus.Result)
+	assert.Equal(t, nothingReturned, getStringFromChan(test.deletedNodes))
+	assert.True(t, c.InProgress())
+
+	// n2 is removed once the budget allows it.
+	c.context.ScaleDownBudgetMaxNodes = 2
+	scaleDownStatus, err = c.Continue([]*apiv1.Node{test.n2, n3}, []*apiv1.Pod{movedPod, test.p2}, nil, now)
+	assert.NoError(t, err)
+	assert.Equal(t, status.ScaleDownNodeDeleteStarted, scaleDownStatus.Result)
+	waitForDeleteToFinish(t, c.scaleDown)
+	assert.Equal(t, "n2", getStringFromChan(test.deletedNodes))
+	assert.False(t, c.InProgress())
+}
+
+func TestConsolidationFailedDeletionReturnsBudget(t *testing.T) {
+	test := newConsolidationTest(t, map[string]float64{
+		"n1":           1.0,
+		"n2":           1.0,
+		"ng1-template": 1.0,
+		"ng2-template": 0.5,
+	})
+	c := test.consolidation
+	c.context.ScaleDownBudgetMaxNodes = 1
+	c.scaleDown.scaleDownBudgetTracker = NewScaleDownBudgetTracker(time.Hour)
+	now := time.Now()
+	n3 := startConsolidation(t, test, now)
+	nodes := []*apiv1.Node{test.n1, test.n2, n3}
+	pods := []*apiv1.Pod{test.p1, test.p2}
+
+	test.deleteErr = fmt.Errorf("failed to delete n1")
+	scaleDownStatus, err := c.Continue(nodes, pods, nil, now)
+	assert.NoError(t, err)
+	assert.Equal(t, status.ScaleDownNodeDeleteStarted, scaleDownStatus.Result)
+	waitForDeleteToFinish(t, c.scaleDown)
+	assert.Equal(t, "n1", getStringFromChan(test.deletedNodes))
+	assert.Equal(t, 0, c.scaleDown.scaleDownBudgetTracker.DeletionsInWindow("ng1", now))
+
+	test.deleteErr = nil
+	scaleDownStatus, err = c.Continue(nodes, pods, nil, now)
+	assert.NoError(t, err)
+	assert.Equal(t, status.ScaleDownNodeDeleteStarted, scaleDownStatus.Result)
+	waitForDeleteToFinish(t, c.scaleDown)
+	assert.Equal(t, "n2", getStringFromChan(test.deletedNodes))
+	assert.Equal(t, 1, c.scaleDown.scaleDownBudgetTracker.DeletionsInWindow("ng1", now))
+}
@@ -92,7 +92,7 @@ func filterOutSchedulableByPacking(unschedulableCandidates []*apiv1.Pod, nodes [
 	predicateChecker *simulator.PredicateChecker, expendablePodsPriorityCutoff int) []*apiv1.Pod {
 	var unschedulablePods []*apiv1.Pod
 	nonExpendableScheduled := filterOutExpendablePods(allScheduled, expendablePodsPriorityCutoff)
-	nodeNameToNodeInfo := schedulerutil.CreateNodeNameToInfoMap(nonExpendableScheduled, nodes)
+	snapshot := simulator.NewClusterSnapshotFromPods(nonExpendableScheduled, nodes)
 	loggingQuota := glogx.PodsLoggingQuota()
 
 	sort.Slice(unschedulableCandidates, func(i, j int) bool {
@@ -100,12 +100,14 @@ func filterOutSchedulableByPacking(unschedulableCandidates []*apiv1.Pod, nodes [
 	})
 
 	for _, pod := range unschedulableCandidates {
-		nodeName, err := predicateChecker.FitsAny(pod, nodeNameToNodeInfo)
+		nodeName, err := predicateChecker.FitsAny(pod, snapshot.NodeInfos())
+		if err == nil {
+			err = snapshot.AddPod(pod, nodeName)
+		}
 		if err != nil {
 			unschedulablePods = append(unschedulablePods, pod)
 		} else {
 			glogx.V(4).UpTo(loggingQuota).Infof("Pod %s marked as unschedulable can be scheduled on %s. Ignoring in scale up.", pod.Name, nodeName)
-			nodeNameToNodeInfo[nodeName] = schedulerutil.NodeWithPod(nodeNameToNodeInfo[nodeName], pod)
 		}
 	}
 
@@ -20,14 +20,17 @@ import (
 	"fmt"
 	"math"
 	"reflect"
+	"sort"
 	"strings"
 	"sync"
 	"time"
 
 	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider"
 	"k8s.io/autoscaler/cluster-autoscaler/clusterstate"
+	"k8s.io/autoscaler/cluster-autoscaler/config"
 	"k8s.io/autoscaler/cluster-autoscaler/context"
 	"k8s.io/autoscaler/cluster-autoscaler/metrics"
+	"k8s.io/autoscaler/cluster-autoscaler/predeletionhooks"
 	"k8s.io/autoscaler/cluster-autoscaler/simulator"
 	"k8s.io/autoscaler/cluster-autoscaler/utils/deletetaint"
 	"k8s.io/autoscaler/cluster-autoscaler/utils/errors"
@@ -74,7 +77,8 @@ const (
 // NodeDeletionTracker keeps track of node deletions.
 type NodeDeletionTracker struct {
 	sync.Mutex
-	nonEmptyNodeDeleteInProgress bool
+	// Number of non empty nodes being drained and deleted.
+	nonEmptyNodeDeletesInProgress int
 	// A map of node delete results by node name. It's being constantly emptied into ScaleDownStatus
 	// objects in order to notify the ScaleDownStatusProcessor that the node drain has ended or that
 	// an error occurred during the deletion process.
@@ -99,14 +103,34 @@ func NewNodeDeletionTracker() *NodeDeletionTracker {
 func (n *NodeDeletionTracker) IsNonEmptyNodeDeleteInProgress() bool {
 	n.Lock()
 	defer n.Unlock()
-	return n.nonEmptyNodeDeleteInProgress
+	return n.nonEmptyNodeDeletesInProgress > 0
 }
 
-// SetNonEmptyNodeDeleteInProgress sets non empty node deletion in progress status.
-func (n *NodeDeletionTracker) SetNonEmptyNodeDeleteInProgress(status bool) {
+// NonEmptyNodeDeletesInProgress returns the number of non empty nodes being deleted.
+func (n *NodeDeletionTracker) NonEmptyNodeDeletesInProgress() int {
 	n.Lock()
 	defer n.Unlock()
-	n.nonEmptyNodeDeleteInProgress = status
+	return n.nonEmptyNodeDeletesInProgress
+}
+
+// StartNonEmptyNodeDelete increments the number of non empty node deletions in progress.
+func (n *NodeDeletionTracker) StartNonEmptyNodeDelete() {
+	n.Lock()
+	defer n.Unlock()
+	n.nonEmptyNodeDeletesInProgress++
+	metrics.UpdateNonEmptyNodeDeletionsInProgress(n.nonEmptyNodeDeletesInProgress)
+}
+
+// EndNonEmptyNodeDelete decrements the number of non empty node deletions in progress.
+func (n *NodeDeletionTracker) EndNonEmptyNodeDelete() {
+	n.Lock()
+	defer n.Unlock()
+	n.nonEmptyNodeDeletesInProgress--
+	if n.nonEmptyNodeDeletesInProgress < 0 {
+		n.nonEmptyNodeDeletesInProgress = 0
+		klog.Errorf("This should never happen, counter of non empty node deletions in NodeDeletionTracker is below 0")
+	}
+	metrics.UpdateNonEmptyNodeDeletionsInProgress(n.nonEmptyNodeDeletesInProgress)
 }
 
 // StartDeletion increments node deletion in progress counter for the given nodegroup.
@@ -349,24 +373,45 @@ type ScaleDown struct {
 	unneededNodes        map[string]time.Time
 	unneededNodesList    []*apiv1.Node
 	unremovableNodes     map[string]time.Time
-	podLocationHints     map[string]string
-	nodeUtilizationMap   map[string]simulator.UtilizationInfo
-	usageTracker         *simulator.UsageTracker
-	nodeDeletionTracker  *NodeDeletionTracker
+	// Why the nodes checked in the last loop can't be removed, by node name.
+	unremovableNodeReasons map[string]*simulator.UnremovableNode
+	podLocationHints       map[string]string
+	nodeUtilizationMap     map[string]simulator.UtilizationInfo
+	usageTracker           *simulator.UsageTracker
+	nodeDeletionTracker    *NodeDeletionTracker
+	scaleDownBudgetTracker *ScaleDownBudgetTracker
+	// Name of the open maintenance window limiting scale-down to empty nodes, empty if there is none.
+	emptyNodesOnlyWindow string
+	// Hooks called before nodes are drained and deleted, nil if there are none.
+	preDeletionHooks *predeletionhooks.Runner
+	// Ids of node groups similar to each node group, by node group id, empty if
+	// scale-down isn't balanced between similar node groups.
+	similarNodeGroups map[string][]string
+	// Unneeded nodes above the utilization threshold, which are removed only to
+	// rebalance their node groups with similar ones.
+	rebalanceNodes map[string]bool
 }
 
 // NewScaleDown builds new ScaleDown object.
 func NewScaleDown(context *context.AutoscalingContext, clusterStateRegistry *clusterstate.ClusterStateRegistry) *ScaleDown {
+	var preDeletionHooks *predeletionhooks.Runner
+	if len(context.PreDeletionHooks) > 0 {
+		preDeletionHooks = predeletionhooks.NewRunner(context.PreDeletionHooks)
+	}
 	return &ScaleDown{
-		context:              context,
-		clusterStateRegistry: clusterStateRegistry,
-		unneededNodes:        make(map[string]time.Time),
-		unremovableNodes:     make(map[string]time.Time),
-		podLocationHints:     make(map[string]string),
-		nodeUtilizationMap:   make(map[string]simulator.UtilizationInfo),
-		usageTracker:         simulator.NewUsageTracker(),
-		unneededNodesList:    make([]*apiv1.Node, 0),
-		nodeDeletionTracker:  NewNodeDeletionTracker(),
+		context:                context,
+		clusterStateRegistry:   clusterStateRegistry,
+		unneededNodes:          make(map[string]time.Time),
+		unremovableNodes:       make(map[string]time.Time),
+		unremovableNodeReasons: make(map[string]*simulator.UnremovableNode),
+		podLocationHints:       make(map[string]string),
+		nodeUtilizationMap:     make(map[string]simulator.UtilizationInfo),
+		usageTracker:           simulator.NewUsageTracker(),
+		unneededNodesList:      make([]*apiv1.Node, 0),
+		nodeDeletionTracker:    NewNodeDeletionTracker(),
+		scaleDownBudgetTracker: NewScaleDownBudgetTracker(context.ScaleDownBudgetWindow),
+		preDeletionHooks:       preDeletionHooks,
+		rebalanceNodes:         make(map[string]bool),
 	}
 }
 
@@ -375,11 +420,66 @@ func (sd *ScaleDown) CleanUp(timestamp time.Time) {
 	sd.usageTracker.CleanUp(timestamp.Add(-sd.context.ScaleDownUnneededTime))
 }
 
+// SetEmptyNodesOnlyWindow limits scale-down to empty nodes while the named maintenance window
+// is open. An empty name lifts the limit.
+func (sd *ScaleDown) SetEmptyNodesOnlyWindow(name string) {
+	sd.emptyNodesOnlyWindow = name
+}
+
+// SetSimilarNodeGroups sets the ids of node groups similar to each node group, by node group id,
+// to balance scale-down between them. Nil disables balancing.
+func (sd *ScaleDown) SetSimilarNodeGroups(similarNodeGroups map[string][]string) {
+	sd.similarNodeGroups = similarNodeGroups
+}
+
+// newSimilarNodeGroupsBalancer returns a balancer for the given node group sizes, nil if
+// scale-down isn't balanced between similar node groups.
+func (sd *ScaleDown) newSimilarNodeGroupsBalancer(nodeGroupSize map[string]int) *similarNodeGroupsBalancer {
+	if len(sd.similarNodeGroups) == 0 {
+		return nil
+	}
+	sizes := make(map[string]int, len(nodeGroupSize))
+	for id, size := range nodeGroupSize {
+		sizes[id] = size - sd.nodeDeletionTracker.GetDeletionsInProgress(id)
+	}
+	return newSimilarNodeGroupsBalancer(sd.similarNodeGroups, sizes, sd.context.RebalanceSimilarNodeGroups)
+}
+
 // GetCandidatesForScaleDown gets candidates for scale down.
 func (sd *ScaleDown) GetCandidatesForScaleDown() []*apiv1.Node {
 	return sd.unneededNodesList
 }
 
+// GetUnneededNodes returns the unneeded nodes and the time since when each of them is unneeded.
+func (sd *ScaleDown) GetUnneededNodes() map[string]time.Time {
+	return sd.unneededNodes
+}
+
+// GetUnremovableNodes returns the nodes found unremovable in the last loop with the reasons
+// why they can't be removed, sorted by name.
+func (sd *ScaleDown) GetUnremovableNodes() []*simulator.UnremovableNode {
+	result := make([]*simulator.UnremovableNode, 0, len(sd.unremovableNodeReasons))
+	for _, unremovable := range sd.unremovableNodeReasons {
+		result = append(result, unremovable)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Node.Name < result[j].Node.Name })
+	return result
+}
+
+// addUnremovableNode records why the node can't be removed in this loop.
+func (sd *ScaleDown) addUnremovableNode(node *apiv1.Node, reason simulator.UnremovableReason, message string) {
+	sd.unremovableNodeReasons[node.Name] = &simulator.UnremovableNode{Node: node, Reason: reason, Message: message}
+}
+
+// updateUnremovableNodesMetric records the number of unremovable nodes by reason.
+func (sd *ScaleDown) updateUnremovableNodesMetric() {
+	countByReason := make(map[string]int)
+	for _, unremovable := range sd.unremovableNodeReasons {
+		countByReason[unremovable.Reason.String()]++
+	}
+	metrics.UpdateUnremovableNodesCount(countByReason)
+}
+
 // CleanUpUnneededNodes clears the list of unneeded nodes.
 func (sd *ScaleDown) CleanUpUnneededNodes() {
 	sd.unneededNodesList = make([]*apiv1.Node, 0)
@@ -402,13 +502,30 @@ func (sd *ScaleDown) UpdateUnneededNodes(
 	nonExpendablePods := filterOutExpendablePods(pods, sd.context.ExpendablePodsPriorityCutoff)
 	nodeNameToNodeInfo := scheduler_util.CreateNodeNameToInfoMap(nonExpendablePods, nodes)
 	utilizationMap := make(map[string]simulator.UtilizationInfo)
+	// The rebalancer keeps track of the nodes considered only to rebalance node groups.
+	var balancer, rebalancer *similarNodeGroupsBalancer
+	if len(sd.similarNodeGroups) > 0 {
+		nodeGroupSize := getNodeGroupSizeMap(sd.context.CloudProvider)
+		balancer = sd.newSimilarNodeGroupsBalancer(nodeGroupSize)
+		rebalancer = sd.newSimilarNodeGroupsBalancer(nodeGroupSize)
+	}
+	nodeGroups := make(map[string]cloudprovider.NodeGroup)
+	rebalanceNodes := make(map[string]bool)
 
 	sd.updateUnremovableNodes(nodes)
+	previousUnremovableNodeReasons := sd.unremovableNodeReasons
+	sd.unremovableNodeReasons = make(map[string]*simulator.UnremovableNode)
 	// Filter out nodes that were recently checked
 	filteredNodesToCheck := make([]*apiv1.Node, 0)
 	for _, node := range nodesToCheck {
 		if unremovableTimestamp, found := sd.unremovableNodes[node.Name]; found {
 			if unremovableTimestamp.After(timestamp) {
+				// Keep the reason found when the node was checked, if there is one.
+				if previous, found := previousUnremovableNodeReasons[node.Name]; found {
+					sd.unremovableNodeReasons[node.Name] = &simulator.UnremovableNode{Node: node, Reason: previous.Reason, Message: previous.Message}
+				} else {
+					sd.addUnremovableNode(node, simulator.RecentlyUnremovable, "")
+				}
 				continue
 			}
 			delete(sd.unremovableNodes, node.Name)
@@ -429,18 +546,21 @@ func (sd *ScaleDown) UpdateUnneededNodes(
 		// and they have not been deleted.
 		if isNodeBeingDeleted(node, timestamp) {
 			klog.V(1).Infof("Skipping %s from delete considerations - the node is currently being deleted", node.Name)
+			sd.addUnremovableNode(node, simulator.CurrentlyBeingDeleted, "")
 			continue
 		}
 
 		// Skip nodes marked with no scale down annotation
 		if hasNoScaleDownAnnotation(node) {
 			klog.V(1).Infof("Skipping %s from delete consideration - the node is marked as no scale down", node.Name)
+			sd.addUnremovableNode(node, simulator.ScaleDownDisabledAnnotation, "")
 			continue
 		}
 
 		nodeInfo, found := nodeNameToNodeInfo[node.Name]
 		if !found {
 			klog.Errorf("Node info for %s not found", node.Name)
+			sd.addUnremovableNode(node, simulator.UnexpectedError, "node info not found")
 			continue
 		}
 
@@ -451,12 +571,34 @@ func (sd *ScaleDown) UpdateUnneededNodes(
 		klog.V(4).Infof("Node %s - %s utilization %f", node.Name, utilInfo.ResourceName, utilInfo.Utilization)
 		utilizationMap[node.Name] = utilInfo
 
-		if !sd.isNodeBelowUtilzationThreshold(node, utilInfo) {
+		nodeGroup, err := sd.context.CloudProvider.NodeGroupForNode(node)
+		if err != nil {
+			klog.Warningf("Error while checking node group for %s, using default options: %v", node.Name, err)
+			nodeGroup = nil
+		}
+		if nodeGroup != nil && !reflect.ValueOf(nodeGroup).IsNil() {
+			nodeGroups[node.Name] = nodeGroup
+		}
+
+		if !sd.isNodeBelowUtilizationThreshold(node, sd.getNodeGroupOptions(nodeGroup), utilInfo) {
+			// Nodes of node groups larger than similar ones are considered regardless of
+			// their utilization, as many as would make the node groups balanced.
+			if nodeGroup, found := nodeGroups[node.Name]; found && rebalancer.canRemove(nodeGroup.Id(), true) {
+				klog.V(4).Infof("Node %s - %s utilization %f too big, but node group %s is larger than similar node groups", node.Name,
+					utilInfo.ResourceName, utilInfo.Utilization, nodeGroup.Id())
+				rebalancer.remove(nodeGroup.Id())
+				rebalanceNodes[node.Name] = true
+				currentlyUnneededNodes = append(currentlyUnneededNodes, node)
+				continue
+			}
 			klog.V(4).Infof("Node %s is not suitable for removal - %s utilization too big (%f)", node.Name, utilInfo.ResourceName, utilInfo.Utilization)
+			sd.addUnremovableNode(node, simulator.NotUnderutilized, fmt.Sprintf("%s utilization %f", utilInfo.ResourceName, utilInfo.Utilization))
 			continue
 		}
 		currentlyUnneededNodes = append(currentlyUnneededNodes, node)
 	}
+	currentlyUnneededNodes = sd.orderNodes(currentlyUnneededNodes, utilizationMap, timestamp)
+	currentlyUnneededNodes = balancer.order(currentlyUnneededNodes, nodeGroups)
 
 	emptyNodes := make(map[string]bool)
 
@@ -475,10 +617,38 @@ func (sd *ScaleDown) UpdateUnneededNodes(
 	// Phase2 - check which nodes can be probably removed using fast drain.
 	currentCandidates, currentNonCandidates := sd.chooseCandidates(currentlyUnneededNonEmptyNodes)
 
+	// Limit the additional candidates pool size for better performance.
+	additionalCandidatesPoolSize := int(math.Ceil(float64(len(nodes)) * sd.context.ScaleDownCandidatesPoolRatio))
+	if additionalCandidatesPoolSize < sd.context.ScaleDownCandidatesPoolMinCount {
+		additionalCandidatesPoolSize = sd.context.ScaleDownCandidatesPoolMinCount
+	}
+	if additionalCandidatesPoolSize > len(currentNonCandidates) {
+		additionalCandidatesPoolSize = len(currentNonCandidates)
+	}
+
+	simulationNodes := nodes
+	maxCount := len(currentCandidates)
+	if len(simulator.HeadroomPods(nonExpendablePods)) > 0 {
+		// All unneeded nodes have to be removable together without eating into
+		// the headroom. Empty nodes are removed anyway, so headroom pods can't
+		// move there, and all candidates are checked in a single pass.
+		simulationNodes = make([]*apiv1.Node, 0, len(nodes))
+		for _, node := range nodes {
+			if !emptyNodes[node.Name] {
+				simulationNodes = append(simulationNodes, node)
+			}
+		}
+		currentCandidates = append(currentCandidates, currentNonCandidates[:additionalCandidatesPoolSize]...)
+		currentNonCandidates = nil
+		if maxCount < sd.context.ScaleDownNonEmptyCandidatesCount {
+			maxCount = sd.context.ScaleDownNonEmptyCandidatesCount
+		}
+	}
+
 	// Look for nodes to remove in the current candidates
 	nodesToRemove, unremovable, newHints, simulatorErr := simulator.FindNodesToRemove(
-		currentCandidates, nodes, nonExpendablePods, nil, sd.context.PredicateChecker,
-		len(currentCandidates), true, sd.podLocationHints, sd.usageTracker, timestamp, pdbs)
+		currentCandidates, simulationNodes, nonExpendablePods, nil, sd.context.PredicateChecker,
+		maxCount, true, sd.podLocationHints, sd.usageTracker, timestamp, pdbs)
 	if simulatorErr != nil {
 		return sd.markSimulationError(simulatorErr, timestamp)
 	}
@@ -487,11 +657,6 @@ func (sd *ScaleDown) UpdateUnneededNodes(
 	if additionalCandidatesCount > len(currentNonCandidates) {
 		additionalCandidatesCount = len(currentNonCandidates)
 	}
-	// Limit the additional candidates pool size for better performance.
-	additionalCandidatesPoolSize := int(math.Ceil(float64(len(nodes)) * sd.context.ScaleDownCandidatesPoolRatio))
-	if additionalCandidatesPoolSize < sd.context.ScaleDownCandidatesPoolMinCount {
-		additionalCandidatesPoolSize = sd.context.ScaleDownCandidatesPoolMinCount
-	}
 	if additionalCandidatesPoolSize > len(currentNonCandidates) {
 		additionalCandidatesPoolSize = len(currentNonCandidates)
 	}
@@ -518,9 +683,13 @@ func (sd *ScaleDown) UpdateUnneededNodes(
 	// Update the timestamp map.
 	result := make(map[string]time.Time)
 	unneededNodesList := make([]*apiv1.Node, 0, len(nodesToRemove))
+	sd.rebalanceNodes = make(map[string]bool)
 	for _, node := range nodesToRemove {
 		name := node.Node.Name
 		unneededNodesList = append(unneededNodesList, node.Node)
+		if rebalanceNodes[name] {
+			sd.rebalanceNodes[name] = true
+		}
 		if val, found := sd.unneededNodes[name]; !found {
 			result[name] = timestamp
 		} else {
@@ -531,8 +700,9 @@ func (sd *ScaleDown) UpdateUnneededNodes(
 	// Add nodes to unremovable map
 	if len(unremovable) > 0 {
 		unremovableTimeout := timestamp.Add(sd.context.AutoscalingOptions.UnremovableNodeRecheckTimeout)
-		for _, node := range unremovable {
-			sd.unremovableNodes[node.Name] = unremovableTimeout
+		for _, unremovableNode := range unremovable {
+			sd.unremovableNodes[unremovableNode.Node.Name] = unremovableTimeout
+			sd.unremovableNodeReasons[unremovableNode.Node.Name] = unremovableNode
 		}
 		klog.V(1).Infof("%v nodes found to be unremovable in simulation, will re-check them at %v", len(unremovable), unremovableTimeout)
 	}
@@ -544,23 +714,44 @@ func (sd *ScaleDown) UpdateUnneededNodes(
 	sd.nodeUtilizationMap = utilizationMap
 	sd.clusterStateRegistry.UpdateScaleDownCandidates(sd.unneededNodesList, timestamp)
 	metrics.UpdateUnneededNodesCount(len(sd.unneededNodesList))
+	sd.updateUnremovableNodesMetric()
 	return nil
 }
 
-// isNodeBelowUtilzationThreshold determintes if a given node utilization is blow threshold.
-func (sd *ScaleDown) isNodeBelowUtilzationThreshold(node *apiv1.Node, utilInfo simulator.UtilizationInfo) bool {
+// isNodeBelowUtilizationThreshold determines if a given node utilization is below threshold.
+func (sd *ScaleDown) isNodeBelowUtilizationThreshold(node *apiv1.Node, options config.NodeGroupAutoscalingOptions, utilInfo simulator.UtilizationInfo) bool {
 	if gpu.NodeHasGpu(sd.context.CloudProvider.GPULabel(), node) {
-		if utilInfo.Utilization >= sd.context.ScaleDownGpuUtilizationThreshold {
+		if utilInfo.Utilization >= options.ScaleDownGpuUtilizationThreshold {
 			return false
 		}
 	} else {
-		if utilInfo.Utilization >= sd.context.ScaleDownUtilizationThreshold {
+		if utilInfo.Utilization >= options.ScaleDownUtilizationThreshold {
 			return false
 		}
 	}
 	return true
 }
 
+// getNodeGroupOptions returns autoscaling options that should be used for the given node group.
+// Global defaults are used if the node group is nil or doesn't provide its own options.
+func (sd *ScaleDown) getNodeGroupOptions(nodeGroup cloudprovider.NodeGroup) config.NodeGroupAutoscalingOptions {
+	defaults := sd.context.NodeGroupDefaults()
+	if nodeGroup == nil || reflect.ValueOf(nodeGroup).IsNil() {
+		return defaults
+	}
+	options, err := nodeGroup.GetOptions(defaults)
+	if err != nil {
+		if err != cloudprovider.ErrNotImplemented {
+			klog.Errorf("Failed to get autoscaling options for node group %s, using defaults: %v", nodeGroup.Id(), err)
+		}
+		return defaults
+	}
+	if options == nil {
+		return defaults
+	}
+	return *options
+}
+
 // updateUnremovableNodes updates unremovableNodes map according to current
 // state of the cluster. Removes from the map nodes that are no longer in the
 // nodes list.
@@ -617,6 +808,14 @@ func (sd *ScaleDown) chooseCandidates(nodes []*apiv1.Node) ([]*apiv1.Node, []*ap
 	return currentCandidates, currentNonCandidates
 }
 
+// orderNodes orders nodes so that the ones preferred for removal go first.
+func (sd *ScaleDown) orderNodes(nodes []*apiv1.Node, utilization map[string]simulator.UtilizationInfo, now time.Time) []*apiv1.Node {
+	if sd.context.ScaleDownOrder == nil {
+		return nodes
+	}
+	return sd.context.ScaleDownOrder.Order(nodes, utilization, now)
+}
+
 func (sd *ScaleDown) mapNodesToStatusScaleDownNodes(nodes []*apiv1.Node, nodeGroups map[string]cloudprovider.NodeGroup, evictedPodLists map[string][]*apiv1.Pod) []*status.ScaleDownNode {
 	var result []*status.ScaleDownNode
 	for _, node := range nodes {
@@ -674,19 +873,33 @@ func (sd *ScaleDown) SoftTaintUnneededNodes(allNodes []*apiv1.Node) (errors []er
 	return
 }
 
+// FreeDrainSlots returns how many more non empty nodes can be drained along with the ones being drained.
+func (sd *ScaleDown) FreeDrainSlots() int {
+	maxDrains := sd.context.MaxDrainParallelism
+	if maxDrains < 1 {
+		maxDrains = 1
+	}
+	return maxDrains - sd.nodeDeletionTracker.NonEmptyNodeDeletesInProgress()
+}
+
 // TryToScaleDown tries to scale down the cluster. It returns a result inside a ScaleDownStatus indicating if any node was
-// removed and error if such occurred.
-func (sd *ScaleDown) TryToScaleDown(allNodes []*apiv1.Node, pods []*apiv1.Pod, pdbs []*policyv1.PodDisruptionBudget, currentTime time.Time) (*status.ScaleDownStatus, errors.AutoscalerError) {
+// removed and error if such occurred. At most maxDrains non empty nodes are drained, see FreeDrainSlots.
+func (sd *ScaleDown) TryToScaleDown(allNodes []*apiv1.Node, pods []*apiv1.Pod, pdbs []*policyv1.PodDisruptionBudget,
+	maxDrains int, currentTime time.Time) (*status.ScaleDownStatus, errors.AutoscalerError) {
 	scaleDownStatus := &status.ScaleDownStatus{NodeDeleteResults: sd.nodeDeletionTracker.GetAndClearNodeDeleteResults()}
 	nodeDeletionDuration := time.Duration(0)
 	findNodesToRemoveDuration := time.Duration(0)
 	defer updateScaleDownMetrics(time.Now(), &findNodesToRemoveDuration, &nodeDeletionDuration)
+	defer func() {
+		scaleDownStatus.UnremovableNodes = sd.GetUnremovableNodes()
+		sd.updateUnremovableNodesMetric()
+	}()
 	nodesWithoutMaster := filterOutMasters(allNodes, pods)
 	candidates := make([]*apiv1.Node, 0)
 	readinessMap := make(map[string]bool)
 	candidateNodeGroups := make(map[string]cloudprovider.NodeGroup)
-	gpuLabel := sd.context.CloudProvider.GPULabel()
-	availableGPUTypes := sd.context.CloudProvider.GetAvailableGPUTypes()
+	// How many more nodes can be removed from each node group without exceeding its scale-down budget.
+	scaleDownBudgetsLeft := make(map[string]int)
 
 	resourceLimiter, errCP := sd.context.CloudProvider.GetResourceLimiter()
 	if errCP != nil {
@@ -706,52 +919,71 @@ func (sd *ScaleDown) TryToScaleDown(allNodes []*apiv1.Node, pods []*apiv1.Pod, p
 			// Check if node is marked with no scale down annotation.
 			if hasNoScaleDownAnnotation(node) {
 				klog.V(4).Infof("Skipping %s - scale down disabled annotation found", node.Name)
+				sd.addUnremovableNode(node, simulator.ScaleDownDisabledAnnotation, "")
 				continue
 			}
 
 			ready, _, _ := kube_util.GetReadinessState(node)
 			readinessMap[node.Name] = ready
 
-			// Check how long the node was underutilized.
-			if ready && !val.Add(sd.context.ScaleDownUnneededTime).Before(currentTime) {
-				continue
-			}
-
-			// Unready nodes may be deleted after a different time than underutilized nodes.
-			if !ready && !val.Add(sd.context.ScaleDownUnreadyTime).Before(currentTime) {
-				continue
-			}
-
 			nodeGroup, err := sd.context.CloudProvider.NodeGroupForNode(node)
 			if err != nil {
 				klog.Errorf("Error while checking node group for %s: %v", node.Name, err)
+				sd.addUnremovableNode(node, simulator.UnexpectedError, err.Error())
 				continue
 			}
 			if nodeGroup == nil || reflect.ValueOf(nodeGroup).IsNil() {
 				klog.V(4).Infof("Skipping %s - no node group config", node.Name)
+				sd.addUnremovableNode(node, simulator.NotAutoscaled, "")
+				continue
+			}
+			nodeGroupOptions := sd.getNodeGroupOptions(nodeGroup)
+
+			// Check how long the node was underutilized.
+			if ready && !val.Add(nodeGroupOptions.ScaleDownUnneededTime).Before(currentTime) {
+				sd.addUnremovableNode(node, simulator.NotUnneededLongEnough, "")
+				continue
+			}
+
+			// Unready nodes may be deleted after a different time than underutilized nodes.
+			if !ready && !val.Add(nodeGroupOptions.ScaleDownUnreadyTime).Before(currentTime) {
+				sd.addUnremovableNode(node, simulator.NotUnreadyLongEnough, "")
 				continue
 			}
 
 			size, found := nodeGroupSize[nodeGroup.Id()]
 			if !found {
 				klog.Errorf("Error while checking node group size %s: group size not found in cache", nodeGroup.Id())
+				sd.addUnremovableNode(node, simulator.UnexpectedError, "node group size not found")
 				continue
 			}
 
 			if size-sd.nodeDeletionTracker.GetDeletionsInProgress(nodeGroup.Id()) <= nodeGroup.MinSize() {
 				klog.V(1).Infof("Skipping %s - node group min size reached", node.Name)
+				sd.addUnremovableNode(node, simulator.NodeGroupMinSizeReached, "")
+				continue
+			}
+
+			if _, found := scaleDownBudgetsLeft[nodeGroup.Id()]; !found {
+				scaleDownBudgetsLeft[nodeGroup.Id()] = sd.nodeGroupScaleDownBudgetLeft(nodeGroup, size, currentTime)
+			}
+			if scaleDownBudgetsLeft[nodeGroup.Id()] <= 0 {
+				klog.V(1).Infof("Skipping %s - node group scale-down budget exceeded", node.Name)
+				sd.addUnremovableNode(node, simulator.ScaleDownBudgetExceeded, "")
 				continue
 			}
 
 			scaleDownResourcesDelta, err := computeScaleDownResourcesDelta(sd.context.CloudProvider, node, nodeGroup, resourcesWithLimits)
 			if err != nil {
 				klog.Errorf("Error getting node resources: %v", err)
+				sd.addUnremovableNode(node, simulator.UnexpectedError, err.Error())
 				continue
 			}
 
 			checkResult := scaleDownResourcesLeft.checkScaleDownDeltaWithinLimits(scaleDownResourcesDelta)
 			if checkResult.exceeded {
 				klog.V(4).Infof("Skipping %s - minimal limit exceeded for %v", node.Name, checkResult.exceededResources)
+				sd.addUnremovableNode(node, simulator.MinimalResourceLimitExceeded, fmt.Sprintf("%v", checkResult.exceededResources))
 				continue
 			}
 
@@ -764,11 +996,23 @@ func (sd *ScaleDown) TryToScaleDown(allNodes []*apiv1.Node, pods []*apiv1.Pod, p
 		scaleDownStatus.Result = status.ScaleDownNoUnneeded
 		return scaleDownStatus, nil
 	}
+	candidates = sd.orderNodes(candidates, sd.nodeUtilizationMap, currentTime)
+	balancer := sd.newSimilarNodeGroupsBalancer(nodeGroupSize)
+	candidates = balancer.order(candidates, candidateNodeGroups)
 
 	// Trying to delete empty nodes in bulk. If there are no empty nodes then CA will
 	// try to delete not-so-empty nodes, possibly killing some pods and allowing them
 	// to recreate on other nodes.
-	emptyNodes := sd.getEmptyNodes(candidates, pods, sd.context.MaxEmptyBulkDelete, scaleDownResourcesLeft)
+	emptyNodes := sd.getEmptyNodes(candidates, pods, sd.context.MaxEmptyBulkDelete, scaleDownResourcesLeft, scaleDownBudgetsLeft, balancer)
+	if len(emptyNodes) > 0 && sd.context.DryRun {
+		// Empty nodes are only reported in dry run mode, they stay candidates for the next loops.
+		for _, node := range emptyNodes {
+			klog.V(0).Infof("Dry run scale-down: would remove empty node %s", node.Name)
+		}
+		scaleDownStatus.ScaledDownNodes = sd.mapNodesToStatusScaleDownNodes(emptyNodes, candidateNodeGroups, make(map[string][]*apiv1.Pod))
+		scaleDownStatus.Result = status.ScaleDownNodeDeleteStarted
+		return scaleDownStatus, nil
+	}
 	if len(emptyNodes) > 0 {
 		nodeDeletionStart := time.Now()
 		deletedNodes, err := sd.scheduleDeleteEmptyNodes(emptyNodes, sd.context.ClientSet, sd.context.Recorder, readinessMap, candidateNodeGroups)
@@ -786,13 +1030,24 @@ func (sd *ScaleDown) TryToScaleDown(allNodes []*apiv1.Node, pods []*apiv1.Pod, p
 		}
 		return scaleDownStatus, nil
 	}
+	if sd.emptyNodesOnlyWindow != "" {
+		klog.V(1).Infof("No empty node to remove, non-empty nodes are not removed during maintenance window %s", sd.emptyNodesOnlyWindow)
+		scaleDownStatus.Result = status.ScaleDownInMaintenanceWindow
+		scaleDownStatus.MaintenanceWindow = sd.emptyNodesOnlyWindow
+		return scaleDownStatus, nil
+	}
 
 	findNodesToRemoveStart := time.Now()
 	// Only scheduled non expendable pods are taken into account and have to be moved.
 	nonExpendablePods := filterOutExpendablePods(pods, sd.context.ExpendablePodsPriorityCutoff)
-	// We look for only 1 node so new hints may be incomplete.
-	nodesToRemove, _, _, err := simulator.FindNodesToRemove(candidates, nodesWithoutMaster, nonExpendablePods, sd.context.ListerRegistry,
-		sd.context.PredicateChecker, 1, false,
+	findNodesToRemove := simulator.FindNodesToRemoveTogether
+	if maxDrains <= 1 {
+		maxDrains = 1
+		findNodesToRemove = simulator.FindNodesToRemove
+	}
+	// We look for only a few nodes so new hints may be incomplete.
+	nodesToRemove, unremovable, _, err := findNodesToRemove(candidates, nodesWithoutMaster, nonExpendablePods, sd.context.ListerRegistry,
+		sd.context.PredicateChecker, maxDrains, false,
 		sd.podLocationHints, sd.usageTracker, time.Now(), pdbs)
 	findNodesToRemoveDuration = time.Now().Sub(findNodesToRemoveStart)
 
@@ -800,56 +1055,170 @@ func (sd *ScaleDown) TryToScaleDown(allNodes []*apiv1.Node, pods []*apiv1.Pod, p
 		scaleDownStatus.Result = status.ScaleDownError
 		return scaleDownStatus, err.AddPrefix("Find node to remove failed: ")
 	}
+	for _, unremovableNode := range unremovable {
+		sd.unremovableNodeReasons[unremovableNode.Node.Name] = unremovableNode
+	}
+	if len(nodesToRemove) > 1 || balancer != nil {
+		nodesToRemove = sd.limitNodesToRemove(nodesToRemove, candidateNodeGroups, scaleDownResourcesLeft, scaleDownBudgetsLeft, balancer)
+	}
 	if len(nodesToRemove) == 0 {
 		klog.V(1).Infof("No node to remove")
 		scaleDownStatus.Result = status.ScaleDownNoNodeDeleted
 		return scaleDownStatus, nil
 	}
-	toRemove := nodesToRemove[0]
-	utilization := sd.nodeUtilizationMap[toRemove.Node.Name]
-	podNames := make([]string, 0, len(toRemove.PodsToReschedule))
-	for _, pod := range toRemove.PodsToReschedule {
-		podNames = append(podNames, pod.Namespace+"/"+pod.Name)
-	}
-	klog.V(0).Infof("Scale-down: removing node %s, utilization: %v, pods to reschedule: %s", toRemove.Node.Name, utilization,
-		strings.Join(podNames, ","))
-	sd.context.LogRecorder.Eventf(apiv1.EventTypeNormal, "ScaleDown", "Scale-down: removing node %s, utilization: %v, pods to reschedule: %s",
-		toRemove.Node.Name, utilization, strings.Join(podNames, ","))
 
-	// Nothing super-bad should happen if the node is removed from tracker prematurely.
-	simulator.RemoveNodeFromTracker(sd.usageTracker, toRemove.Node.Name, sd.unneededNodes)
 	nodeDeletionStart := time.Now()
+	removedNodes := make([]*apiv1.Node, 0, len(nodesToRemove))
+	evictedPods := make(map[string][]*apiv1.Pod, len(nodesToRemove))
+	for _, toRemove := range nodesToRemove {
+		removedNodes = append(removedNodes, toRemove.Node)
+		evictedPods[toRemove.Node.Name] = toRemove.PodsToReschedule
+		utilization := sd.nodeUtilizationMap[toRemove.Node.Name]
+		podNames := make([]string, 0, len(toRemove.PodsToReschedule))
+		for _, pod := range toRemove.PodsToReschedule {
+			podNames = append(podNames, pod.Namespace+"/"+pod.Name)
+		}
 
-	// Starting deletion.
+		if sd.context.DryRun {
+			// Nothing is drained in dry run mode, the node stays a candidate for the next loops.
+			klog.V(0).Infof("Dry run scale-down: would remove node %s, utilization: %v, pods to evict: %s", toRemove.Node.Name, utilization,
+				strings.Join(podNames, ","))
+			continue
+		}
+		klog.V(0).Infof("Scale-down: removing node %s, utilization: %v, pods to reschedule: %s", toRemove.Node.Name, utilization,
+			strings.Join(podNames, ","))
+		sd.context.LogRecorder.Eventf(apiv1.EventTypeNormal, "ScaleDown", "Scale-down: removing node %s, utilization: %v, pods to reschedule: %s",
+			toRemove.Node.Name, utilization, strings.Join(podNames, ","))
+
+		// Nothing super-bad should happen if the node is removed from tracker prematurely.
+		simulator.RemoveNodeFromTracker(sd.usageTracker, toRemove.Node.Name, sd.unneededNodes)
+
+		// Starting deletion.
+		reason := metrics.Underutilized
+		if !readinessMap[toRemove.Node.Name] {
+			reason = metrics.Unready
+		}
+		sd.scheduleDeleteNode(toRemove.Node, toRemove.PodsToReschedule, candidateNodeGroups[toRemove.Node.Name], reason)
+	}
 	nodeDeletionDuration = time.Now().Sub(nodeDeletionStart)
-	sd.nodeDeletionTracker.SetNonEmptyNodeDeleteInProgress(true)
+
+	scaleDownStatus.ScaledDownNodes = sd.mapNodesToStatusScaleDownNodes(removedNodes, candidateNodeGroups, evictedPods)
+	scaleDownStatus.Result = status.ScaleDownNodeDeleteStarted
+	return scaleDownStatus, nil
+}
+
+// limitNodesToRemove returns the nodes that can be drained at the same time
+// without going below the minimal sizes of node groups and the cluster-wide
+// resource limits, without exceeding the scale-down budgets left for the
+// node groups and without unbalancing similar node groups, if there's a
+// balancer. Node groups missing from scaleDownBudgetsLeft have no budget.
+func (sd *ScaleDown) limitNodesToRemove(nodesToRemove []simulator.NodeToBeRemoved, nodeGroups map[string]cloudprovider.NodeGroup,
+	resourcesLimits scaleDownResourcesLimits, scaleDownBudgetsLeft map[string]int, balancer *similarNodeGroupsBalancer) []simulator.NodeToBeRemoved {
+
+	availabilityMap := make(map[string]int)
+	budgetsLeft := copyScaleDownBudgets(scaleDownBudgetsLeft)
+	result := make([]simulator.NodeToBeRemoved, 0, len(nodesToRemove))
+	resourcesLimitsCopy := copyScaleDownResourcesLimits(resourcesLimits) // we do not want to modify input parameter
+	resourcesNames := sets.StringKeySet(resourcesLimits).List()
+	for _, toRemove := range nodesToRemove {
+		nodeGroup, found := nodeGroups[toRemove.Node.Name]
+		if !found {
+			continue
+		}
+		available, found := availabilityMap[nodeGroup.Id()]
+		if !found {
+			// Will be cached.
+			size, err := nodeGroup.TargetSize()
+			if err != nil {
+				klog.Errorf("Failed to get size for %s: %v ", nodeGroup.Id(), err)
+				continue
+			}
+			available = size - nodeGroup.MinSize() - sd.nodeDeletionTracker.GetDeletionsInProgress(nodeGroup.Id())
+		}
+		if available <= 0 {
+			klog.V(1).Infof("Skipping %s - node group min size reached", toRemove.Node.Name)
+			sd.addUnremovableNode(toRemove.Node, simulator.NodeGroupMinSizeReached, "")
+			continue
+		}
+		if budgetLeft, found := budgetsLeft[nodeGroup.Id()]; found && budgetLeft <= 0 {
+			klog.V(1).Infof("Skipping %s - node group scale-down budget exceeded", toRemove.Node.Name)
+			sd.addUnremovableNode(toRemove.Node, simulator.ScaleDownBudgetExceeded, "")
+			continue
+		}
+		if balancer != nil && !balancer.canRemove(nodeGroup.Id(), sd.rebalanceNodes[toRemove.Node.Name]) {
+			klog.V(1).Infof("Skipping %s - node group would be smaller than similar node groups", toRemove.Node.Name)
+			sd.addUnremovableNode(toRemove.Node, simulator.SimilarNodeGroupsUnbalanced, "")
+			continue
+		}
+		resourcesDelta, err := computeScaleDownResourcesDelta(sd.context.CloudProvider, toRemove.Node, nodeGroup, resourcesNames)
+		if err != nil {
+			klog.Errorf("Error getting node resources: %v", err)
+			sd.addUnremovableNode(toRemove.Node, simulator.UnexpectedError, err.Error())
+			continue
+		}
+		if checkResult := resourcesLimitsCopy.tryDecrementLimitsByDelta(resourcesDelta); checkResult.exceeded {
+			klog.V(4).Infof("Skipping %s - minimal limit exceeded for %v", toRemove.Node.Name, checkResult.exceededResources)
+			sd.addUnremovableNode(toRemove.Node, simulator.MinimalResourceLimitExceeded, fmt.Sprintf("%v", checkResult.exceededResources))
+			continue
+		}
+		availabilityMap[nodeGroup.Id()] = available - 1
+		if _, found := budgetsLeft[nodeGroup.Id()]; found {
+			budgetsLeft[nodeGroup.Id()]--
+		}
+		balancer.remove(nodeGroup.Id())
+		result = append(result, toRemove)
+	}
+	return result
+}
+
+func copyScaleDownBudgets(budgets map[string]int) map[string]int {
+	result := make(map[string]int, len(budgets))
+	for id, left := range budgets {
+		result[id] = left
+	}
+	return result
+}
+
+// nodeGroupScaleDownBudgetLeft returns how many more nodes can be removed from the node group
+// of the given target size, or math.MaxInt32 if the node group has no scale-down budget.
+func (sd *ScaleDown) nodeGroupScaleDownBudgetLeft(nodeGroup cloudprovider.NodeGroup, size int, currentTime time.Time) int {
+	budgetLeft, hasBudget := scaleDownBudgetLeft(sd.getNodeGroupOptions(nodeGroup), size-sd.nodeDeletionTracker.GetDeletionsInProgress(nodeGroup.Id()),
+		sd.scaleDownBudgetTracker.DeletionsInWindow(nodeGroup.Id(), currentTime))
+	if !hasBudget {
+		return math.MaxInt32
+	}
+	return budgetLeft
+}
+
+// scheduleDeleteNode drains and deletes a non-empty node in the background. A failed
+// deletion doesn't count against the scale-down budget of the node group.
+func (sd *ScaleDown) scheduleDeleteNode(node *apiv1.Node, pods []*apiv1.Pod, nodeGroup cloudprovider.NodeGroup, reason metrics.NodeScaleDownReason) {
+	gpuLabel := sd.context.CloudProvider.GPULabel()
+	availableGPUTypes := sd.context.CloudProvider.GetAvailableGPUTypes()
+	sd.nodeDeletionTracker.StartNonEmptyNodeDelete()
+	deletionTime := now()
+	if nodeGroup != nil && !reflect.ValueOf(nodeGroup).IsNil() {
+		sd.scaleDownBudgetTracker.RegisterDeletion(nodeGroup.Id(), deletionTime)
+	}
 
 	go func() {
 		// Finishing the delete process once this goroutine is over.
 		var result status.NodeDeleteResult
-		defer func() { sd.nodeDeletionTracker.AddNodeDeleteResult(toRemove.Node.Name, result) }()
-		defer sd.nodeDeletionTracker.SetNonEmptyNodeDeleteInProgress(false)
-		nodeGroup, found := candidateNodeGroups[toRemove.Node.Name]
-		if !found {
+		defer func() { sd.nodeDeletionTracker.AddNodeDeleteResult(node.Name, result) }()
+		defer sd.nodeDeletionTracker.EndNonEmptyNodeDelete()
+		if nodeGroup == nil || reflect.ValueOf(nodeGroup).IsNil() {
 			result = status.NodeDeleteResult{ResultType: status.NodeDeleteErrorFailedToDelete, Err: errors.NewAutoscalerError(
-				errors.CloudProviderError, "failed to find node group for %s", toRemove.Node.Name)}
+				errors.CloudProviderError, "failed to find node group for %s", node.Name)}
 			return
 		}
-		result = sd.deleteNode(toRemove.Node, toRemove.PodsToReschedule, nodeGroup)
+		result = sd.deleteNode(node, pods, nodeGroup)
 		if result.ResultType != status.NodeDeleteOk {
-			klog.Errorf("Failed to delete %s: %v", toRemove.Node.Name, result.Err)
+			klog.Errorf("Failed to delete %s: %v", node.Name, result.Err)
+			sd.scaleDownBudgetTracker.UnregisterDeletion(nodeGroup.Id(), deletionTime)
 			return
 		}
-		if readinessMap[toRemove.Node.Name] {
-			metrics.RegisterScaleDown(1, gpu.GetGpuTypeForMetrics(gpuLabel, availableGPUTypes, toRemove.Node, nodeGroup), metrics.Underutilized)
-		} else {
-			metrics.RegisterScaleDown(1, gpu.GetGpuTypeForMetrics(gpuLabel, availableGPUTypes, toRemove.Node, nodeGroup), metrics.Unready)
-		}
+		metrics.RegisterScaleDown(1, gpu.GetGpuTypeForMetrics(gpuLabel, availableGPUTypes, node, nodeGroup), reason)
 	}()
-
-	scaleDownStatus.ScaledDownNodes = sd.mapNodesToStatusScaleDownNodes([]*apiv1.Node{toRemove.Node}, candidateNodeGroups, map[string][]*apiv1.Pod{toRemove.Node.Name: toRemove.PodsToReschedule})
-	scaleDownStatus.Result = status.ScaleDownNodeDeleteStarted
-	return scaleDownStatus, nil
 }
 
 // updateScaleDownMetrics registers duration of different parts of scale down.
@@ -863,13 +1232,14 @@ func updateScaleDownMetrics(scaleDownStart time.Time, findNodesToRemoveDuration
 }
 
 func (sd *ScaleDown) getEmptyNodesNoResourceLimits(candidates []*apiv1.Node, pods []*apiv1.Pod, maxEmptyBulkDelete int) []*apiv1.Node {
-	return sd.getEmptyNodes(candidates, pods, maxEmptyBulkDelete, noScaleDownLimitsOnResources())
+	return sd.getEmptyNodes(candidates, pods, maxEmptyBulkDelete, noScaleDownLimitsOnResources(), nil, nil)
 }
 
 // This functions finds empty nodes among passed candidates and returns a list of empty nodes
-// that can be deleted at the same time.
+// that can be deleted at the same time. Node groups missing from scaleDownBudgetsLeft have
+// no scale-down budget. Similar node groups are kept balanced if there's a balancer.
 func (sd *ScaleDown) getEmptyNodes(candidates []*apiv1.Node, pods []*apiv1.Pod, maxEmptyBulkDelete int,
-	resourcesLimits scaleDownResourcesLimits) []*apiv1.Node {
+	resourcesLimits scaleDownResourcesLimits, scaleDownBudgetsLeft map[string]int, balancer *similarNodeGroupsBalancer) []*apiv1.Node {
 
 	emptyNodes := simulator.FindEmptyNodesToRemove(candidates, pods)
 	availabilityMap := make(map[string]int)
@@ -877,6 +1247,9 @@ func (sd *ScaleDown) getEmptyNodes(candidates []*apiv1.Node, pods []*apiv1.Pod,
 	resourcesLimitsCopy := copyScaleDownResourcesLimits(resourcesLimits) // we do not want to modify input parameter
 	resourcesNames := sets.StringKeySet(resourcesLimits).List()
 	for _, node := range emptyNodes {
+		if len(result) >= maxEmptyBulkDelete {
+			break
+		}
 		nodeGroup, err := sd.context.CloudProvider.NodeGroupForNode(node)
 		if err != nil {
 			klog.Errorf("Failed to get group for %s", node.Name)
@@ -895,12 +1268,18 @@ func (sd *ScaleDown) getEmptyNodes(candidates []*apiv1.Node, pods []*apiv1.Pod,
 				continue
 			}
 			available = size - nodeGroup.MinSize() - sd.nodeDeletionTracker.GetDeletionsInProgress(nodeGroup.Id())
+			if budgetLeft, found := scaleDownBudgetsLeft[nodeGroup.Id()]; found && budgetLeft < available {
+				available = budgetLeft
+			}
 			if available < 0 {
 				available = 0
 			}
 			availabilityMap[nodeGroup.Id()] = available
 		}
 		if available > 0 {
+			if balancer != nil && !balancer.canRemove(nodeGroup.Id(), sd.rebalanceNodes[node.Name]) {
+				continue
+			}
 			resourcesDelta, err := computeScaleDownResourcesDelta(sd.context.CloudProvider, node, nodeGroup, resourcesNames)
 			if err != nil {
 				klog.Errorf("Error: %v", err)
@@ -912,14 +1291,11 @@ func (sd *ScaleDown) getEmptyNodes(candidates []*apiv1.Node, pods []*apiv1.Pod,
 			}
 			available--
 			availabilityMap[nodeGroup.Id()] = available
+			balancer.remove(nodeGroup.Id())
 			result = append(result, node)
 		}
 	}
-	limit := maxEmptyBulkDelete
-	if len(result) < limit {
-		limit = len(result)
-	}
-	return result[:limit]
+	return result
 }
 
 func (sd *ScaleDown) scheduleDeleteEmptyNodes(emptyNodes []*apiv1.Node, client kube_client.Interface,
@@ -941,6 +1317,8 @@ func (sd *ScaleDown) scheduleDeleteEmptyNodes(emptyNodes []*apiv1.Node, client k
 			return deletedNodes, errors.ToAutoscalerError(errors.ApiCallError, taintErr)
 		}
 		deletedNodes = append(deletedNodes, node)
+		deletionTime := now()
+		sd.scaleDownBudgetTracker.RegisterDeletion(nodeGroup.Id(), deletionTime)
 		go func(nodeToDelete *apiv1.Node, nodeGroupForDeletedNode cloudprovider.NodeGroup) {
 			sd.nodeDeletionTracker.StartDeletion(nodeGroupForDeletedNode.Id())
 			defer sd.nodeDeletionTracker.EndDeletion(nodeGroupForDeletedNode.Id())
@@ -951,6 +1329,7 @@ func (sd *ScaleDown) scheduleDeleteEmptyNodes(emptyNodes []*apiv1.Node, client k
 			// If we fail to delete the node we want to remove delete taint
 			defer func() {
 				if deleteErr != nil {
+					sd.scaleDownBudgetTracker.UnregisterDeletion(nodeGroupForDeletedNode.Id(), deletionTime)
 					deletetaint.CleanToBeDeleted(nodeToDelete, client)
 					recorder.Eventf(nodeToDelete, apiv1.EventTypeWarning, "ScaleDownFailed", "failed to delete empty node: %v", deleteErr)
 				} else {
@@ -964,6 +1343,12 @@ func (sd *ScaleDown) scheduleDeleteEmptyNodes(emptyNodes []*apiv1.Node, client k
 				result = status.NodeDeleteResult{ResultType: status.NodeDeleteErrorFailedToDelete, Err: deleteErr}
 				return
 			}
+			deleteErr = sd.runPreDeletionHooks(nodeToDelete, nodeGroupForDeletedNode, predeletionhooks.DeleteStage)
+			if deleteErr != nil {
+				klog.Errorf("Problem with empty node deletion: %v", deleteErr)
+				result = status.NodeDeleteResult{ResultType: status.NodeDeleteErrorVetoedByHook, Err: deleteErr}
+				return
+			}
 			deleteErr = deleteNodeFromCloudProvider(nodeToDelete, sd.context.CloudProvider,
 				sd.context.Recorder, sd.clusterStateRegistry)
 			if deleteErr != nil {
@@ -986,6 +1371,7 @@ func (sd *ScaleDown) deleteNode(node *apiv1.Node, pods []*apiv1.Pod,
 	nodeGroup cloudprovider.NodeGroup) status.NodeDeleteResult {
 	deleteSuccessful := false
 	drainSuccessful := false
+	var vetoErr errors.AutoscalerError
 
 	if err := deletetaint.MarkToBeDeleted(node, sd.context.ClientSet); err != nil {
 		sd.context.Recorder.Eventf(node, apiv1.EventTypeWarning, "ScaleDownFailed", "failed to mark the node as toBeDeleted/unschedulable: %v", err)
@@ -999,7 +1385,9 @@ func (sd *ScaleDown) deleteNode(node *apiv1.Node, pods []*apiv1.Pod,
 	defer func() {
 		if !deleteSuccessful {
 			deletetaint.CleanToBeDeleted(node, sd.context.ClientSet)
-			if !drainSuccessful {
+			if vetoErr != nil {
+				sd.context.Recorder.Eventf(node, apiv1.EventTypeWarning, "ScaleDownFailed", "node deletion %v", vetoErr)
+			} else if !drainSuccessful {
 				sd.context.Recorder.Eventf(node, apiv1.EventTypeWarning, "ScaleDownFailed", "failed to drain the node, aborting ScaleDown")
 			} else {
 				sd.context.Recorder.Eventf(node, apiv1.EventTypeWarning, "ScaleDownFailed", "failed to delete the node")
@@ -1009,6 +1397,10 @@ func (sd *ScaleDown) deleteNode(node *apiv1.Node, pods []*apiv1.Pod,
 
 	sd.context.Recorder.Eventf(node, apiv1.EventTypeNormal, "ScaleDown", "marked the node as toBeDeleted/unschedulable")
 
+	if vetoErr = sd.runPreDeletionHooks(node, nodeGroup, predeletionhooks.DrainStage); vetoErr != nil {
+		return status.NodeDeleteResult{ResultType: status.NodeDeleteErrorVetoedByHook, Err: vetoErr}
+	}
+
 	// attempt drain
 	evictionResults, err := drainNode(node, pods, sd.context.ClientSet, sd.context.Recorder, sd.context.MaxGracefulTerminationSec, MaxPodEvictionTime, EvictionRetryTime, PodEvictionHeadroom)
 	if err != nil {
@@ -1020,6 +1412,10 @@ func (sd *ScaleDown) deleteNode(node *apiv1.Node, pods []*apiv1.Pod,
 		return status.NodeDeleteResult{ResultType: status.NodeDeleteErrorFailedToDelete, Err: typedErr}
 	}
 
+	if vetoErr = sd.runPreDeletionHooks(node, nodeGroup, predeletionhooks.DeleteStage); vetoErr != nil {
+		return status.NodeDeleteResult{ResultType: status.NodeDeleteErrorVetoedByHook, Err: vetoErr}
+	}
+
 	// attempt delete from cloud provider
 
 	if typedErr := deleteNodeFromCloudProvider(node, sd.context.CloudProvider, sd.context.Recorder, sd.clusterStateRegistry); typedErr != nil {
@@ -1143,6 +1539,18 @@ func drainNode(node *apiv1.Node, pods []*apiv1.Pod, client kube_client.Interface
 	return evictionResults, errors.NewAutoscalerError(errors.TransientError, "Failed to drain node %s/%s: pods remaining after timeout", node.Namespace, node.Name)
 }
 
+// runPreDeletionHooks calls the pre-deletion hooks, if there are any, before the given stage
+// of the node deletion. It returns an error if the deletion was vetoed.
+func (sd *ScaleDown) runPreDeletionHooks(node *apiv1.Node, nodeGroup cloudprovider.NodeGroup, stage predeletionhooks.Stage) errors.AutoscalerError {
+	if sd.preDeletionHooks == nil {
+		return nil
+	}
+	if err := sd.preDeletionHooks.Run(node, nodeGroup.Id(), stage); err != nil {
+		return errors.NewAutoscalerError(errors.TransientError, "%v", err)
+	}
+	return nil
+}
+
 // Removes the given node from cloud provider. No extra pre-deletion actions are executed on
 // the Kubernetes side.
 func deleteNodeFromCloudProvider(node *apiv1.Node, cloudProvider cloudprovider.CloudProvider,
@@ -0,0 +1,212 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import (
+	"sort"
+
+	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider"
+	"k8s.io/autoscaler/cluster-autoscaler/context"
+	"k8s.io/autoscaler/cluster-autoscaler/processors/nodegroupset"
+
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/klog"
+	schedulernodeinfo "k8s.io/kubernetes/pkg/scheduler/nodeinfo"
+)
+
+// findSimilarNodeGroups returns the ids of node groups similar to each node group, by node
+// group id. Node groups without similar ones are left out.
+func findSimilarNodeGroups(context *context.AutoscalingContext, processor nodegroupset.NodeGroupSetProcessor,
+	nodeInfosForGroups map[string]*schedulernodeinfo.NodeInfo) map[string][]string {
+
+	result := make(map[string][]string)
+	for _, nodeGroup := range context.CloudProvider.NodeGroups() {
+		similarNodeGroups, err := processor.FindSimilarNodeGroups(context, nodeGroup, nodeInfosForGroups)
+		if err != nil {
+			klog.V(4).Infof("Failed to find node groups similar to %s: %v", nodeGroup.Id(), err)
+			continue
+		}
+		for _, similar := range similarNodeGroups {
+			result[nodeGroup.Id()] = append(result[nodeGroup.Id()], similar.Id())
+		}
+	}
+	return result
+}
+
+// similarNodeGroupsBalancer balances a scale-down between sets of similar node groups,
+// keeping track of the node group sizes as nodes are chosen for removal. Similarity is
+// extended transitively, so each node group belongs to at most one set.
+//
+// Nodes are preferably removed from the largest node group of each set. In strict mode
+// nodes can't be removed from a node group smaller than another one in its set, and nodes
+// above the utilization threshold can be removed from node groups larger than the smallest
+// one in their set by more than one node, to rebalance sets that drifted apart.
+//
+// A nil balancer doesn't balance anything.
+type similarNodeGroupsBalancer struct {
+	// Index of the set each node group belongs to, by node group id, and node group
+	// ids in each set.
+	setIndex map[string]int
+	sets     [][]string
+	// Target sizes of node groups without the nodes being deleted or chosen for removal.
+	sizes  map[string]int
+	strict bool
+}
+
+// newSimilarNodeGroupsBalancer creates a balancer for the given similar node groups, by
+// node group id, and the sizes of the node groups.
+func newSimilarNodeGroupsBalancer(similarNodeGroups map[string][]string, sizes map[string]int, strict bool) *similarNodeGroupsBalancer {
+	b := &similarNodeGroupsBalancer{
+		setIndex: make(map[string]int),
+		sizes:    sizes,
+		strict:   strict,
+	}
+	ids := make([]string, 0, len(similarNodeGroups))
+	for id := range similarNodeGroups {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		if _, found := b.setIndex[id]; found {
+			continue
+		}
+		index := len(b.sets)
+		set := []string{id}
+		b.setIndex[id] = index
+		for i := 0; i < len(set); i++ {
+			for _, similar := range similarNodeGroups[set[i]] {
+				if _, found := b.setIndex[similar]; !found {
+					b.setIndex[similar] = index
+					set = append(set, similar)
+				}
+			}
+		}
+		sort.Strings(set)
+		b.sets = append(b.sets, set)
+	}
+	return b
+}
+
+// sizeRange returns the smallest and the largest size of the other node groups in the set of
+// the given node group, false if there are none.
+func (b *similarNodeGroupsBalancer) sizeRange(nodeGroupId string) (int, int, bool) {
+	index, found := b.setIndex[nodeGroupId]
+	if !found {
+		return 0, 0, false
+	}
+	minSize, maxSize, others := 0, 0, false
+	for _, id := range b.sets[index] {
+		size, found := b.sizes[id]
+		if id == nodeGroupId || !found {
+			continue
+		}
+		if !others || size < minSize {
+			minSize = size
+		}
+		if !others || size > maxSize {
+			maxSize = size
+		}
+		others = true
+	}
+	return minSize, maxSize, others
+}
+
+// drifted tells if the node group is larger than the smallest one in its set by more than one node.
+func (b *similarNodeGroupsBalancer) drifted(nodeGroupId string) bool {
+	if b == nil || !b.strict {
+		return false
+	}
+	size, found := b.sizes[nodeGroupId]
+	minSize, _, others := b.sizeRange(nodeGroupId)
+	return found && others && size-minSize > 1
+}
+
+// canRemove tells if a node can be removed from the node group without unbalancing its set.
+// Nodes above the utilization threshold, rebalanceOnly, can only be removed from drifted
+// node groups.
+func (b *similarNodeGroupsBalancer) canRemove(nodeGroupId string, rebalanceOnly bool) bool {
+	if rebalanceOnly {
+		return b.drifted(nodeGroupId)
+	}
+	if b == nil || !b.strict {
+		return true
+	}
+	size, found := b.sizes[nodeGroupId]
+	_, maxSize, others := b.sizeRange(nodeGroupId)
+	return !found || !others || size >= maxSize
+}
+
+// remove records that a node was chosen for removal from the node group.
+func (b *similarNodeGroupsBalancer) remove(nodeGroupId string) {
+	if b == nil {
+		return
+	}
+	if _, found := b.sizes[nodeGroupId]; found {
+		b.sizes[nodeGroupId]--
+	}
+}
+
+// order reorders nodes, so that nodes from the largest node group of each set go first,
+// assuming that the nodes before them are removed. The positions of nodes from each set
+// and the order of nodes within each node group are kept. nodeGroups are node groups
+// by node name. The sizes tracked by the balancer aren't changed.
+func (b *similarNodeGroupsBalancer) order(nodes []*apiv1.Node, nodeGroups map[string]cloudprovider.NodeGroup) []*apiv1.Node {
+	if b == nil {
+		return nodes
+	}
+	// Nodes in the order they are taken from each node group, by node group id.
+	queues := make(map[string][]int)
+	for i, node := range nodes {
+		if nodeGroup, found := nodeGroups[node.Name]; found {
+			if _, found := b.setIndex[nodeGroup.Id()]; found {
+				queues[nodeGroup.Id()] = append(queues[nodeGroup.Id()], i)
+			}
+		}
+	}
+	sizes := make(map[string]int, len(b.sizes))
+	for id, size := range b.sizes {
+		sizes[id] = size
+	}
+	result := make([]*apiv1.Node, 0, len(nodes))
+	for _, node := range nodes {
+		nodeGroup, found := nodeGroups[node.Name]
+		if !found {
+			result = append(result, node)
+			continue
+		}
+		index, found := b.setIndex[nodeGroup.Id()]
+		if !found {
+			result = append(result, node)
+			continue
+		}
+		// Take the next node from the largest node group of the set, the one whose next
+		// node goes first if there's a tie.
+		best := ""
+		for _, id := range b.sets[index] {
+			if len(queues[id]) == 0 {
+				continue
+			}
+			if best == "" || sizes[id] > sizes[best] || (sizes[id] == sizes[best] && queues[id][0] < queues[best][0]) {
+				best = id
+			}
+		}
+		result = append(result, nodes[queues[best][0]])
+		queues[best] = queues[best][1:]
+		sizes[best]--
+	}
+	return result
+}
@@ -0,0 +1,181 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import (
+	"testing"
+	"time"
+
+	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider"
+	testprovider "k8s.io/autoscaler/cluster-autoscaler/cloudprovider/test"
+	"k8s.io/autoscaler/cluster-autoscaler/clusterstate"
+	"k8s.io/autoscaler/cluster-autoscaler/config"
+	"k8s.io/autoscaler/cluster-autoscaler/processors/nodegroupset"
+	. "k8s.io/autoscaler/cluster-autoscaler/utils/test"
+
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	schedulernodeinfo "k8s.io/kubernetes/pkg/scheduler/nodeinfo"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFindSimilarNodeGroups(t *testing.T) {
+	provider := testprovider.NewTestCloudProvider(nil, nil)
+	nodeInfos := make(map[string]*schedulernodeinfo.NodeInfo)
+	for id, cpu := range map[string]int64{"ng1": 1000, "ng2": 1000, "ng3": 2000} {
+		provider.AddNodeGroup(id, 1, 10, 1)
+		nodeInfos[id] = schedulernodeinfo.NewNodeInfo()
+		nodeInfos[id].SetNode(BuildTestNode(id+"-node", cpu, 1000))
+	}
+	// ng4 has no node info.
+	provider.AddNodeGroup("ng4", 1, 10, 1)
+	context := NewScaleTestAutoscalingContext(config.AutoscalingOptions{}, &fake.Clientset{}, nil, provider, nil)
+
+	similar := findSimilarNodeGroups(&context, nodegroupset.NewDefaultNodeGroupSetProcessor(), nodeInfos)
+	assert.Equal(t, map[string][]string{"ng1": {"ng2"}, "ng2": {"ng1"}}, similar)
+}
+
+func buildBalancerTestNodes(nodeGroupIds ...string) ([]*apiv1.Node, map[string]cloudprovider.NodeGroup) {
+	provider := testprovider.NewTestCloudProvider(nil, nil)
+	nodes := make([]*apiv1.Node, 0, len(nodeGroupIds))
+	nodeGroups := make(map[string]cloudprovider.NodeGroup)
+	for i, id := range nodeGroupIds {
+		node := BuildTestNode(id+"-"+string('a'+rune(i)), 1000, 1000)
+		nodes = append(nodes, node)
+		nodeGroups[node.Name] = provider.BuildNodeGroup(id, 0, 10, 0, false, "")
+	}
+	return nodes, nodeGroups
+}
+
+func nodeNames(nodes []*apiv1.Node) []string {
+	names := make([]string, 0, len(nodes))
+	for _, node := range nodes {
+		names = append(names, node.Name)
+	}
+	return names
+}
+
+func TestSimilarNodeGroupsBalancerOrder(t *testing.T) {
+	// ng1 and ng3 are similar through ng2, ng4 isn't similar to any.
+	similar := map[string][]string{"ng1": {"ng2"}, "ng2": {"ng1", "ng3"}, "ng3": {"ng2"}}
+	sizes := map[string]int{"ng1": 2, "ng2": 3, "ng3": 5, "ng4": 1}
+	b := newSimilarNodeGroupsBalancer(similar, sizes, false)
+
+	nodes, nodeGroups := buildBalancerTestNodes("ng1", "ng4", "ng1", "ng2", "ng3", "ng3", "ng2", "ng3")
+	ordered := b.order(nodes, nodeGroups)
+	// ng3 goes down to 3, then ties go to the node group whose next node is first.
+	assert.Equal(t, []string{"ng3-e", "ng4-b", "ng3-f", "ng2-d", "ng3-h", "ng1-a", "ng2-g", "ng1-c"}, nodeNames(ordered))
+	// Ordering doesn't change tracked sizes.
+	assert.Equal(t, 5, b.sizes["ng3"])
+
+	var nilBalancer *similarNodeGroupsBalancer
+	assert.Equal(t, nodes, nilBalancer.order(nodes, nodeGroups))
+}
+
+func TestSimilarNodeGroupsBalancerCanRemove(t *testing.T) {
+	similar := map[string][]string{"ng1": {"ng2"}, "ng2": {"ng1"}}
+
+	b := newSimilarNodeGroupsBalancer(similar, map[string]int{"ng1": 2, "ng2": 4, "ng3": 1}, false)
+	assert.True(t, b.canRemove("ng1", false))
+	assert.True(t, b.canRemove("ng2", false))
+	// Only strict balancers rebalance.
+	assert.False(t, b.canRemove("ng2", true))
+
+	b = newSimilarNodeGroupsBalancer(similar, map[string]int{"ng1": 2, "ng2": 4, "ng3": 1}, true)
+	assert.False(t, b.canRemove("ng1", false))
+	assert.True(t, b.canRemove("ng2", false))
+	assert.True(t, b.canRemove("ng3", false))
+	assert.True(t, b.canRemove("ng2", true))
+	assert.False(t, b.canRemove("ng1", true))
+	assert.False(t, b.canRemove("ng3", true))
+
+	b.remove("ng2")
+	// 2 and 3 nodes, ng2 isn't drifted anymore.
+	assert.False(t, b.canRemove("ng2", true))
+	assert.True(t, b.canRemove("ng2", false))
+	b.remove("ng2")
+	assert.True(t, b.canRemove("ng1", false))
+
+	var nilBalancer *similarNodeGroupsBalancer
+	assert.True(t, nilBalancer.canRemove("ng1", false))
+	assert.False(t, nilBalancer.canRemove("ng1", true))
+	nilBalancer.remove("ng1")
+}
+
+func TestScaleDownEmptyBalancedSimilarNodeGroups(t *testing.T) {
+	options := defaultScaleDownOptions
+	options.MaxEmptyBulkDelete = 2
+	config := &scaleTestConfig{
+		nodes: []nodeConfig{
+			{"n2_1", 1000, 1000, 0, true, "ng2"},
+			{"n2_2", 1000, 1000, 0, true, "ng2"},
+			{"n1_1", 1000, 1000, 0, true, "ng1"},
+			{"n1_2", 1000, 1000, 0, true, "ng1"},
+			{"n1_3", 1000, 1000, 0, true, "ng1"},
+			{"n1_4", 1000, 1000, 0, true, "ng1"},
+		},
+		options:            options,
+		similarNodeGroups:  map[string][]string{"ng1": {"ng2"}, "ng2": {"ng1"}},
+		expectedScaleDowns: []string{"n1_1", "n1_2"},
+	}
+	simpleScaleDownEmpty(t, config)
+}
+
+func TestFindUnneededNodesRebalanceSimilarNodeGroups(t *testing.T) {
+	provider := testprovider.NewTestCloudProvider(nil, nil)
+	provider.AddNodeGroup("ng1", 1, 10, 4)
+	provider.AddNodeGroup("ng2", 1, 10, 1)
+	ownerRef := GenerateOwnerReferences("rs", "ReplicaSet", "extensions/v1beta1", "")
+	nodes := make([]*apiv1.Node, 0)
+	pods := make([]*apiv1.Pod, 0)
+	for _, name := range []string{"n1_1", "n1_2", "n1_3", "n1_4", "n2_1"} {
+		node := BuildTestNode(name, 1000, 10)
+		SetNodeReadyState(node, true, time.Time{})
+		nodes = append(nodes, node)
+		provider.AddNode("ng"+name[1:2], node)
+		// Utilization of all nodes is over the threshold.
+		pod := BuildTestPod("p"+name[1:], 500, 0)
+		pod.Spec.NodeName = name
+		pod.OwnerReferences = ownerRef
+		pods = append(pods, pod)
+	}
+
+	options := config.AutoscalingOptions{
+		ScaleDownUtilizationThreshold:    0.35,
+		UnremovableNodeRecheckTimeout:    5 * time.Minute,
+		ScaleDownNonEmptyCandidatesCount: 10,
+		ScaleDownCandidatesPoolRatio:     1,
+		RebalanceSimilarNodeGroups:       true,
+	}
+	context := NewScaleTestAutoscalingContext(options, &fake.Clientset{}, nil, provider, nil)
+	clusterStateRegistry := clusterstate.NewClusterStateRegistry(provider, clusterstate.ClusterStateRegistryConfig{}, context.LogRecorder, newBackoff())
+	sd := NewScaleDown(&context, clusterStateRegistry)
+
+	// Without similar node groups no node is unneeded.
+	sd.UpdateUnneededNodes(nodes, nodes, pods, time.Now(), nil)
+	assert.Empty(t, sd.unneededNodes)
+
+	// ng1 is larger than ng2 by 3 nodes, removing 2 of them is enough to balance them.
+	sd.SetSimilarNodeGroups(map[string][]string{"ng1": {"ng2"}, "ng2": {"ng1"}})
+	sd.UpdateUnneededNodes(nodes, nodes, pods, time.Now(), nil)
+	assert.Equal(t, 2, len(sd.unneededNodes))
+	for _, name := range []string{"n1_1", "n1_2"} {
+		assert.Contains(t, sd.unneededNodes, name)
+		assert.True(t, sd.rebalanceNodes[name])
+	}
+}
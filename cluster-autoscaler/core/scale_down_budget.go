@@ -0,0 +1,133 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"k8s.io/autoscaler/cluster-autoscaler/config"
+)
+
+// ScaleDownBudgetTracker keeps a sliding-window record of node deletions started
+// in each node group, used to enforce scale-down budgets.
+type ScaleDownBudgetTracker struct {
+	sync.Mutex
+	window time.Duration
+	// Start times of node deletions in the window, oldest first, by node group id.
+	deletions map[string][]time.Time
+}
+
+// NewScaleDownBudgetTracker creates a ScaleDownBudgetTracker remembering deletions for the given window.
+func NewScaleDownBudgetTracker(window time.Duration) *ScaleDownBudgetTracker {
+	return &ScaleDownBudgetTracker{
+		window:    window,
+		deletions: make(map[string][]time.Time),
+	}
+}
+
+// RegisterDeletion records that the deletion of a node from the node group was started.
+func (t *ScaleDownBudgetTracker) RegisterDeletion(nodeGroupId string, now time.Time) {
+	t.Lock()
+	defer t.Unlock()
+	t.deletions[nodeGroupId] = append(t.deletions[nodeGroupId], now)
+}
+
+// UnregisterDeletion forgets a deletion registered at the given time, so a failed deletion
+// doesn't count against the budget.
+func (t *ScaleDownBudgetTracker) UnregisterDeletion(nodeGroupId string, started time.Time) {
+	t.Lock()
+	defer t.Unlock()
+	times := t.deletions[nodeGroupId]
+	for i, deletion := range times {
+		if deletion.Equal(started) {
+			t.deletions[nodeGroupId] = append(times[:i:i], times[i+1:]...)
+			break
+		}
+	}
+	if len(t.deletions[nodeGroupId]) == 0 {
+		delete(t.deletions, nodeGroupId)
+	}
+}
+
+// DeletionsInWindow returns the number of node deletions started in the node group within the window.
+func (t *ScaleDownBudgetTracker) DeletionsInWindow(nodeGroupId string, now time.Time) int {
+	t.Lock()
+	defer t.Unlock()
+	t.cleanUp(now)
+	return len(t.deletions[nodeGroupId])
+}
+
+// cleanUp forgets deletions that are out of the window. Must be called with the lock held.
+func (t *ScaleDownBudgetTracker) cleanUp(now time.Time) {
+	windowStart := now.Add(-t.window)
+	for id, times := range t.deletions {
+		first := 0
+		for first < len(times) && !times[first].After(windowStart) {
+			first++
+		}
+		if first == len(times) {
+			delete(t.deletions, id)
+		} else {
+			t.deletions[id] = times[first:]
+		}
+	}
+}
+
+// GetState returns a copy of the deletions in the window.
+func (t *ScaleDownBudgetTracker) GetState(now time.Time) map[string][]time.Time {
+	t.Lock()
+	defer t.Unlock()
+	t.cleanUp(now)
+	state := make(map[string][]time.Time, len(t.deletions))
+	for id, times := range t.deletions {
+		state[id] = append([]time.Time{}, times...)
+	}
+	return state
+}
+
+// Restore adds the saved deletions to the record.
+func (t *ScaleDownBudgetTracker) Restore(state map[string][]time.Time, now time.Time) {
+	t.Lock()
+	defer t.Unlock()
+	for id, times := range state {
+		t.deletions[id] = append(append([]time.Time{}, times...), t.deletions[id]...)
+	}
+	t.cleanUp(now)
+}
+
+// scaleDownBudgetLeft returns how many more nodes can be removed from a node group of the
+// given target size without exceeding its scale-down budget, or false if the node group has
+// no budget. The ratio budget is relative to the size of the node group before the deletions
+// in the window and is rounded up, so it always allows removing at least one node per window.
+func scaleDownBudgetLeft(options config.NodeGroupAutoscalingOptions, size int, deletionsInWindow int) (int, bool) {
+	maxNodes := options.ScaleDownBudgetMaxNodes
+	if options.ScaleDownBudgetMaxRatio > 0 {
+		byRatio := int(math.Ceil(options.ScaleDownBudgetMaxRatio * float64(size+deletionsInWindow)))
+		if maxNodes <= 0 || byRatio < maxNodes {
+			maxNodes = byRatio
+		}
+	}
+	if maxNodes <= 0 {
+		return 0, false
+	}
+	if deletionsInWindow >= maxNodes {
+		return 0, true
+	}
+	return maxNodes - deletionsInWindow, true
+}
@@ -0,0 +1,80 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import (
+	"testing"
+	"time"
+
+	"k8s.io/autoscaler/cluster-autoscaler/config"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScaleDownBudgetTracker(t *testing.T) {
+	now := time.Now()
+	tracker := NewScaleDownBudgetTracker(time.Hour)
+	tracker.RegisterDeletion("ng1", now.Add(-90*time.Minute))
+	tracker.RegisterDeletion("ng1", now.Add(-30*time.Minute))
+	tracker.RegisterDeletion("ng1", now)
+	tracker.RegisterDeletion("ng2", now.Add(-61*time.Minute))
+
+	assert.Equal(t, 2, tracker.DeletionsInWindow("ng1", now))
+	assert.Equal(t, 0, tracker.DeletionsInWindow("ng2", now))
+	assert.Equal(t, 1, tracker.DeletionsInWindow("ng1", now.Add(45*time.Minute)))
+	assert.Equal(t, map[string][]time.Time{"ng1": {now}}, tracker.GetState(now.Add(45*time.Minute)))
+
+	// Failed deletions are forgotten.
+	tracker.RegisterDeletion("ng1", now.Add(time.Minute))
+	tracker.UnregisterDeletion("ng1", now)
+	assert.Equal(t, map[string][]time.Time{"ng1": {now.Add(time.Minute)}}, tracker.GetState(now.Add(time.Minute)))
+	tracker.UnregisterDeletion("ng1", now.Add(time.Minute))
+	assert.Equal(t, map[string][]time.Time{}, tracker.GetState(now.Add(time.Minute)))
+
+	restored := NewScaleDownBudgetTracker(time.Hour)
+	restored.RegisterDeletion("ng1", now.Add(time.Minute))
+	restored.Restore(map[string][]time.Time{"ng1": {now.Add(-2 * time.Hour), now}}, now)
+	assert.Equal(t, 2, restored.DeletionsInWindow("ng1", now.Add(time.Minute)))
+}
+
+func TestScaleDownBudgetLeft(t *testing.T) {
+	testCases := []struct {
+		name              string
+		maxNodes          int
+		maxRatio          float64
+		size              int
+		deletionsInWindow int
+		expectedLeft      int
+		expectedBudget    bool
+	}{
+		{name: "no budget", size: 10, deletionsInWindow: 5},
+		{name: "max nodes", maxNodes: 3, size: 10, deletionsInWindow: 1, expectedLeft: 2, expectedBudget: true},
+		{name: "max nodes used up", maxNodes: 3, size: 10, deletionsInWindow: 4, expectedLeft: 0, expectedBudget: true},
+		{name: "ratio of size before deletions", maxRatio: 0.2, size: 8, deletionsInWindow: 1, expectedLeft: 1, expectedBudget: true},
+		{name: "ratio rounded up", maxRatio: 0.1, size: 3, expectedLeft: 1, expectedBudget: true},
+		{name: "lower of both", maxNodes: 5, maxRatio: 0.1, size: 20, expectedLeft: 2, expectedBudget: true},
+		{name: "lower of both, max nodes", maxNodes: 1, maxRatio: 0.5, size: 20, expectedLeft: 1, expectedBudget: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			options := config.NodeGroupAutoscalingOptions{ScaleDownBudgetMaxNodes: tc.maxNodes, ScaleDownBudgetMaxRatio: tc.maxRatio}
+			left, hasBudget := scaleDownBudgetLeft(options, tc.size, tc.deletionsInWindow)
+			assert.Equal(t, tc.expectedBudget, hasBudget)
+			assert.Equal(t, tc.expectedLeft, left)
+		})
+	}
+}
@@ -17,7 +17,10 @@ limitations under the License.
 package core
 
 import (
+	"encoding/json"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"sort"
 	"testing"
 	"time"
@@ -33,12 +36,16 @@ import (
 	"k8s.io/autoscaler/cluster-autoscaler/clusterstate"
 	"k8s.io/autoscaler/cluster-autoscaler/config"
 	"k8s.io/autoscaler/cluster-autoscaler/context"
+	"k8s.io/autoscaler/cluster-autoscaler/predeletionhooks"
+	"k8s.io/autoscaler/cluster-autoscaler/scaledownorder"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator"
 	kube_util "k8s.io/autoscaler/cluster-autoscaler/utils/kubernetes"
 	. "k8s.io/autoscaler/cluster-autoscaler/utils/test"
 	"k8s.io/autoscaler/cluster-autoscaler/utils/units"
 	kube_client "k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/kubernetes/fake"
 	core "k8s.io/client-go/testing"
+	kube_record "k8s.io/client-go/tools/record"
 
 	"strconv"
 
@@ -144,6 +151,14 @@ func TestFindUnneededNodes(t *testing.T) {
 	assert.True(t, found)
 	assert.Contains(t, sd.podLocationHints, p2.Namespace+"/"+p2.Name)
 	assert.Equal(t, 6, len(sd.nodeUtilizationMap))
+	assert.Equal(t, map[string]simulator.UnremovableReason{
+		"n1": simulator.BlockedByPod,
+		"n3": simulator.NotUnderutilized,
+		"n4": simulator.NoPlaceToMovePods,
+		"n5": simulator.ScaleDownDisabledAnnotation,
+		"n6": simulator.UnexpectedError,
+		"n9": simulator.CurrentlyBeingDeleted,
+	}, unremovableReasons(sd.GetUnremovableNodes()))
 
 	sd.unremovableNodes = make(map[string]time.Time)
 	sd.unneededNodes["n1"] = time.Now()
@@ -163,6 +178,8 @@ func TestFindUnneededNodes(t *testing.T) {
 	// Node n1 is unneeded, but should be skipped because it has just recently been found to be unremovable
 	sd.UpdateUnneededNodes([]*apiv1.Node{n1}, []*apiv1.Node{n1}, []*apiv1.Pod{}, time.Now(), nil)
 	assert.Equal(t, 0, len(sd.unneededNodes))
+	// The reason found in the previous check is kept.
+	assert.Equal(t, map[string]simulator.UnremovableReason{"n1": simulator.BlockedByPod}, unremovableReasons(sd.GetUnremovableNodes()))
 	// Verify that no other nodes are in unremovable map.
 	assert.Equal(t, 1, len(sd.unremovableNodes))
 
@@ -173,6 +190,55 @@ func TestFindUnneededNodes(t *testing.T) {
 	assert.Equal(t, 0, len(sd.unremovableNodes))
 }
 
+func unremovableReasons(unremovable []*simulator.UnremovableNode) map[string]simulator.UnremovableReason {
+	result := make(map[string]simulator.UnremovableReason, len(unremovable))
+	for _, node := range unremovable {
+		result[node.Node.Name] = node.Reason
+	}
+	return result
+}
+
+func TestFindUnneededNodesWithNodeGroupOptions(t *testing.T) {
+	// shared owner reference
+	ownerRef := GenerateOwnerReferences("rs", "ReplicaSet", "extensions/v1beta1", "")
+
+	p1 := BuildTestPod("p1", 300, 0)
+	p1.Spec.NodeName = "n1"
+	p1.OwnerReferences = ownerRef
+
+	p2 := BuildTestPod("p2", 300, 0)
+	p2.Spec.NodeName = "n2"
+	p2.OwnerReferences = ownerRef
+
+	n1 := BuildTestNode("n1", 1000, 10)
+	n2 := BuildTestNode("n2", 1000, 10)
+	SetNodeReadyState(n1, true, time.Time{})
+	SetNodeReadyState(n2, true, time.Time{})
+
+	provider := testprovider.NewTestCloudProvider(nil, nil)
+	provider.AddNodeGroup("ng1", 1, 10, 1)
+	ng2 := provider.BuildNodeGroup("ng2", 1, 10, 1, false, "")
+	// Utilization of n2 is over the threshold configured for its node group.
+	ng2.SetOptions(&config.NodeGroupAutoscalingOptions{ScaleDownUtilizationThreshold: 0.2})
+	provider.InsertNodeGroup(ng2)
+	provider.AddNode("ng1", n1)
+	provider.AddNode("ng2", n2)
+
+	options := config.AutoscalingOptions{
+		ScaleDownUtilizationThreshold: 0.35,
+		UnremovableNodeRecheckTimeout: 5 * time.Minute,
+	}
+	context := NewScaleTestAutoscalingContext(options, &fake.Clientset{}, nil, provider, nil)
+
+	clusterStateRegistry := clusterstate.NewClusterStateRegistry(provider, clusterstate.ClusterStateRegistryConfig{}, context.LogRecorder, newBackoff())
+	sd := NewScaleDown(&context, clusterStateRegistry)
+	sd.UpdateUnneededNodes([]*apiv1.Node{n1, n2}, []*apiv1.Node{n1, n2}, []*apiv1.Pod{p1, p2}, time.Now(), nil)
+
+	assert.Equal(t, 1, len(sd.unneededNodes))
+	_, found := sd.unneededNodes["n1"]
+	assert.True(t, found)
+}
+
 func TestFindUnneededGPUNodes(t *testing.T) {
 	// shared owner reference
 	ownerRef := GenerateOwnerReferences("rs", "ReplicaSet", "extensions/v1beta1", "")
@@ -487,6 +553,9 @@ func TestDeleteNode(t *testing.T) {
 		nodeDeleteSuccess  bool
 		expectedDeletion   bool
 		expectedResultType status.NodeDeleteResultType
+		// Decisions of a pre-deletion hook by stage, no hook is configured if nil.
+		hookDecisions  map[predeletionhooks.Stage]predeletionhooks.Decision
+		expectedStages []predeletionhooks.Stage
 	}{
 		{
 			name:               "successful attempt to delete node with pods",
@@ -531,6 +600,44 @@ func TestDeleteNode(t *testing.T) {
 			expectedDeletion:   false,
 			expectedResultType: status.NodeDeleteErrorFailedToDelete,
 		},
+		{
+			name:               "deletion approved by pre-deletion hook",
+			pods:               []string{"p1", "p2"},
+			drainSuccess:       true,
+			nodeDeleteSuccess:  true,
+			expectedDeletion:   true,
+			expectedResultType: status.NodeDeleteOk,
+			hookDecisions: map[predeletionhooks.Stage]predeletionhooks.Decision{
+				predeletionhooks.DrainStage:  predeletionhooks.Approve,
+				predeletionhooks.DeleteStage: predeletionhooks.Approve,
+			},
+			expectedStages: []predeletionhooks.Stage{predeletionhooks.DrainStage, predeletionhooks.DeleteStage},
+		},
+		{
+			name:               "drain vetoed by pre-deletion hook",
+			pods:               []string{"p1", "p2"},
+			drainSuccess:       true,
+			nodeDeleteSuccess:  true,
+			expectedDeletion:   false,
+			expectedResultType: status.NodeDeleteErrorVetoedByHook,
+			hookDecisions: map[predeletionhooks.Stage]predeletionhooks.Decision{
+				predeletionhooks.DrainStage: predeletionhooks.Veto,
+			},
+			expectedStages: []predeletionhooks.Stage{predeletionhooks.DrainStage},
+		},
+		{
+			name:               "deletion vetoed by pre-deletion hook",
+			pods:               []string{"p1", "p2"},
+			drainSuccess:       true,
+			nodeDeleteSuccess:  true,
+			expectedDeletion:   false,
+			expectedResultType: status.NodeDeleteErrorVetoedByHook,
+			hookDecisions: map[predeletionhooks.Stage]predeletionhooks.Decision{
+				predeletionhooks.DrainStage:  predeletionhooks.Approve,
+				predeletionhooks.DeleteStage: predeletionhooks.Veto,
+			},
+			expectedStages: []predeletionhooks.Stage{predeletionhooks.DrainStage, predeletionhooks.DeleteStage},
+		},
 	}
 
 	for _, scenario := range testScenarios {
@@ -599,9 +706,23 @@ func TestDeleteNode(t *testing.T) {
 				})
 			fakeClient.Fake.AddReactor("get", "pods", podNotFoundFunc)
 
+			// set up stand-in pre-deletion hook
+			options := config.AutoscalingOptions{}
+			var hookStages []predeletionhooks.Stage
+			if scenario.hookDecisions != nil {
+				hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					var request predeletionhooks.Request
+					assert.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+					hookStages = append(hookStages, request.Stage)
+					json.NewEncoder(w).Encode(predeletionhooks.Response{Decision: scenario.hookDecisions[request.Stage]})
+				}))
+				defer hook.Close()
+				options.PreDeletionHooks = []config.PreDeletionHook{{URL: hook.URL, Timeout: 5 * time.Second, FailurePolicy: config.PreDeletionHookFail}}
+			}
+
 			// build context
 			registry := kube_util.NewListerRegistry(nil, nil, nil, nil, nil, nil, nil, nil, nil, nil)
-			context := NewScaleTestAutoscalingContext(config.AutoscalingOptions{}, fakeClient, registry, provider, nil)
+			context := NewScaleTestAutoscalingContext(options, fakeClient, registry, provider, nil)
 
 			clusterStateRegistry := clusterstate.NewClusterStateRegistry(provider, clusterstate.ClusterStateRegistryConfig{}, context.LogRecorder, newBackoff())
 			sd := NewScaleDown(&context, clusterStateRegistry)
@@ -626,6 +747,11 @@ func TestDeleteNode(t *testing.T) {
 				assert.Equal(t, untaintedUpdate, getStringFromChanImmediately(updatedNodes))
 			}
 			assert.Equal(t, nothingReturned, getStringFromChanImmediately(updatedNodes))
+			assert.Equal(t, scenario.expectedStages, hookStages)
+			if len(hookStages) == 1 {
+				// Nothing is evicted if the drain is vetoed.
+				assert.Equal(t, nothingReturned, getStringFromChanImmediately(deletedPods))
+			}
 		})
 	}
 }
@@ -942,7 +1068,7 @@ func TestScaleDown(t *testing.T) {
 	scaleDown := NewScaleDown(&context, clusterStateRegistry)
 	scaleDown.UpdateUnneededNodes([]*apiv1.Node{n1, n2},
 		[]*apiv1.Node{n1, n2}, []*apiv1.Pod{p1, p2, p3}, time.Now().Add(-5*time.Minute), nil)
-	scaleDownStatus, err := scaleDown.TryToScaleDown([]*apiv1.Node{n1, n2}, []*apiv1.Pod{p1, p2, p3}, nil, time.Now())
+	scaleDownStatus, err := scaleDown.TryToScaleDown([]*apiv1.Node{n1, n2}, []*apiv1.Pod{p1, p2, p3}, nil, 1, time.Now())
 	waitForDeleteToFinish(t, scaleDown)
 	assert.NoError(t, err)
 	assert.Equal(t, status.ScaleDownNodeDeleteStarted, scaleDownStatus.Result)
@@ -950,6 +1076,221 @@ func TestScaleDown(t *testing.T) {
 	assert.Equal(t, n1.Name, getStringFromChan(updatedNodes))
 }
 
+func TestScaleDownEmptyNodesOnlyWindow(t *testing.T) {
+	deletedNodes := make(chan string, 10)
+	job := batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "job",
+			Namespace: "default",
+			SelfLink:  "/apivs/batch/v1/namespaces/default/jobs/job",
+		},
+	}
+	n1 := BuildTestNode("n1", 1000, 1000)
+	SetNodeReadyState(n1, true, time.Time{})
+	n2 := BuildTestNode("n2", 1000, 1000)
+	SetNodeReadyState(n2, true, time.Time{})
+	p1 := BuildTestPod("p1", 100, 0)
+	p1.OwnerReferences = GenerateOwnerReferences(job.Name, "Job", "batch/v1", "")
+	p1.Spec.NodeName = "n1"
+	p2 := BuildTestPod("p2", 800, 0)
+	p2.Spec.NodeName = "n2"
+
+	provider := testprovider.NewTestCloudProvider(nil, func(nodeGroup string, node string) error {
+		deletedNodes <- node
+		return nil
+	})
+	provider.AddNodeGroup("ng1", 1, 10, 2)
+	provider.AddNode("ng1", n1)
+	provider.AddNode("ng1", n2)
+
+	options := config.AutoscalingOptions{
+		ScaleDownUtilizationThreshold: 0.5,
+		ScaleDownUnneededTime:         time.Minute,
+		MaxGracefulTerminationSec:     60,
+	}
+	jobLister, err := kube_util.NewTestJobLister([]*batchv1.Job{&job})
+	assert.NoError(t, err)
+	registry := kube_util.NewListerRegistry(nil, nil, nil, nil, nil, nil, nil, jobLister, nil, nil)
+	context := NewScaleTestAutoscalingContext(options, &fake.Clientset{}, registry, provider, nil)
+
+	clusterStateRegistry := clusterstate.NewClusterStateRegistry(provider, clusterstate.ClusterStateRegistryConfig{}, context.LogRecorder, newBackoff())
+	scaleDown := NewScaleDown(&context, clusterStateRegistry)
+	nodes := []*apiv1.Node{n1, n2}
+	pods := []*apiv1.Pod{p1, p2}
+	scaleDown.UpdateUnneededNodes(nodes, nodes, pods, time.Now().Add(-5*time.Minute), nil)
+
+	// Non-empty nodes aren't removed while scale-down is limited to empty nodes.
+	scaleDown.SetEmptyNodesOnlyWindow("nightly-backup")
+	scaleDownStatus, err := scaleDown.TryToScaleDown(nodes, pods, nil, 1, time.Now())
+	assert.NoError(t, err)
+	assert.Equal(t, status.ScaleDownInMaintenanceWindow, scaleDownStatus.Result)
+	assert.Equal(t, "nightly-backup", scaleDownStatus.MaintenanceWindow)
+	assert.Equal(t, nothingReturned, getStringFromChanImmediately(deletedNodes))
+}
+func TestScaleDownParallelDrains(t *testing.T) {
+	testCases := []struct {
+		name            string
+		maxDrains       int
+		drainsRunning   int
+		minSize         int
+		expectedDeleted int
+	}{
+		{name: "single drain", maxDrains: 1, minSize: 1, expectedDeleted: 1},
+		{name: "limited by free capacity", maxDrains: 3, minSize: 1, expectedDeleted: 2},
+		{name: "limited by node group min size", maxDrains: 3, minSize: 3, expectedDeleted: 1},
+		{name: "limited by drains in progress", maxDrains: 3, drainsRunning: 2, minSize: 1, expectedDeleted: 1},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			deletedNodes := make(chan string, 10)
+			fakeClient := &fake.Clientset{}
+
+			job := batchv1.Job{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "job",
+					Namespace: "default",
+					SelfLink:  "/apivs/batch/v1/namespaces/default/jobs/job",
+				},
+			}
+			nodes := make([]*apiv1.Node, 0)
+			pods := make([]*apiv1.Pod, 0)
+			for i := 1; i <= 4; i++ {
+				node := BuildTestNode(fmt.Sprintf("n%d", i), 1000, 1000)
+				SetNodeReadyState(node, true, time.Time{})
+				nodes = append(nodes, node)
+				pod := BuildTestPod(fmt.Sprintf("p%d", i), 300, 0)
+				pod.OwnerReferences = GenerateOwnerReferences(job.Name, "Job", "batch/v1", "")
+				if i == 4 {
+					// n4 can't be drained and has room for only one more pod.
+					pod = BuildTestPod("p4", 500, 0)
+				}
+				pod.Spec.NodeName = node.Name
+				pods = append(pods, pod)
+			}
+
+			fakeClient.Fake.AddReactor("get", "pods", func(action core.Action) (bool, runtime.Object, error) {
+				return true, nil, errors.NewNotFound(apiv1.Resource("pod"), "whatever")
+			})
+			fakeClient.Fake.AddReactor("get", "nodes", func(action core.Action) (bool, runtime.Object, error) {
+				getAction := action.(core.GetAction)
+				for _, node := range nodes {
+					if node.Name == getAction.GetName() {
+						return true, node, nil
+					}
+				}
+				return true, nil, fmt.Errorf("wrong node: %v", getAction.GetName())
+			})
+			fakeClient.Fake.AddReactor("update", "nodes", func(action core.Action) (bool, runtime.Object, error) {
+				update := action.(core.UpdateAction)
+				return true, update.GetObject(), nil
+			})
+
+			provider := testprovider.NewTestCloudProvider(nil, func(nodeGroup string, node string) error {
+				deletedNodes <- node
+				return nil
+			})
+			provider.AddNodeGroup("ng1", tc.minSize, 10, len(nodes))
+			for _, node := range nodes {
+				provider.AddNode("ng1", node)
+			}
+
+			options := defaultScaleDownOptions
+			options.MaxDrainParallelism = tc.maxDrains
+			jobLister, err := kube_util.NewTestJobLister([]*batchv1.Job{&job})
+			assert.NoError(t, err)
+			registry := kube_util.NewListerRegistry(nil, nil, nil, nil, nil, nil, nil, jobLister, nil, nil)
+			context := NewScaleTestAutoscalingContext(options, fakeClient, registry, provider, nil)
+			// Several drains record events at the same time.
+			context.Recorder = kube_record.NewFakeRecorder(20)
+
+			clusterStateRegistry := clusterstate.NewClusterStateRegistry(provider, clusterstate.ClusterStateRegistryConfig{}, context.LogRecorder, newBackoff())
+			scaleDown := NewScaleDown(&context, clusterStateRegistry)
+			scaleDown.UpdateUnneededNodes(nodes, nodes, pods, time.Now().Add(-5*time.Minute), nil)
+			for i := 0; i < tc.drainsRunning; i++ {
+				scaleDown.nodeDeletionTracker.StartNonEmptyNodeDelete()
+			}
+			assert.Equal(t, tc.maxDrains-tc.drainsRunning, scaleDown.FreeDrainSlots())
+			scaleDownStatus, err := scaleDown.TryToScaleDown(nodes, pods, nil, scaleDown.FreeDrainSlots(), time.Now())
+			assert.NoError(t, err)
+			assert.Equal(t, status.ScaleDownNodeDeleteStarted, scaleDownStatus.Result)
+			assert.Equal(t, tc.expectedDeleted, len(scaleDownStatus.ScaledDownNodes))
+			for i := 0; i < tc.drainsRunning; i++ {
+				scaleDown.nodeDeletionTracker.EndNonEmptyNodeDelete()
+			}
+			waitForDeleteToFinish(t, scaleDown)
+			assert.Equal(t, tc.expectedDeleted, len(deletedNodes))
+
+			// Every drain reports its own result.
+			results := scaleDown.nodeDeletionTracker.GetAndClearNodeDeleteResults()
+			assert.Equal(t, tc.expectedDeleted, len(results))
+			for _, node := range scaleDownStatus.ScaledDownNodes {
+				assert.Equal(t, status.NodeDeleteOk, results[node.Node.Name].ResultType)
+			}
+		})
+	}
+}
+
+func TestScaleDownDryRun(t *testing.T) {
+	updatedNodes := make(chan string, 10)
+	deletedNodes := make(chan string, 10)
+	fakeClient := &fake.Clientset{}
+
+	job := batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "job",
+			Namespace: "default",
+			SelfLink:  "/apivs/batch/v1/namespaces/default/jobs/job",
+		},
+	}
+	n1 := BuildTestNode("n1", 1000, 1000)
+	SetNodeReadyState(n1, true, time.Time{})
+	n2 := BuildTestNode("n2", 1000, 1000)
+	SetNodeReadyState(n2, true, time.Time{})
+	p1 := BuildTestPod("p1", 100, 0)
+	p1.OwnerReferences = GenerateOwnerReferences(job.Name, "Job", "batch/v1", "")
+	p2 := BuildTestPod("p2", 800, 0)
+	p1.Spec.NodeName = "n1"
+	p2.Spec.NodeName = "n2"
+
+	fakeClient.Fake.AddReactor("update", "nodes", func(action core.Action) (bool, runtime.Object, error) {
+		obj := action.(core.UpdateAction).GetObject().(*apiv1.Node)
+		updatedNodes <- obj.Name
+		return true, obj, nil
+	})
+
+	provider := testprovider.NewTestCloudProvider(nil, func(nodeGroup string, node string) error {
+		deletedNodes <- node
+		return nil
+	})
+	provider.AddNodeGroup("ng1", 1, 10, 2)
+	provider.AddNode("ng1", n1)
+	provider.AddNode("ng1", n2)
+
+	options := defaultScaleDownOptions
+	options.DryRun = true
+	jobLister, err := kube_util.NewTestJobLister([]*batchv1.Job{&job})
+	assert.NoError(t, err)
+	registry := kube_util.NewListerRegistry(nil, nil, nil, nil, nil, nil, nil, jobLister, nil, nil)
+	context := NewScaleTestAutoscalingContext(options, fakeClient, registry, provider, nil)
+
+	clusterStateRegistry := clusterstate.NewClusterStateRegistry(provider, clusterstate.ClusterStateRegistryConfig{}, context.LogRecorder, newBackoff())
+	scaleDown := NewScaleDown(&context, clusterStateRegistry)
+	scaleDown.UpdateUnneededNodes([]*apiv1.Node{n1, n2},
+		[]*apiv1.Node{n1, n2}, []*apiv1.Pod{p1, p2}, time.Now().Add(-5*time.Minute), nil)
+	scaleDownStatus, err := scaleDown.TryToScaleDown([]*apiv1.Node{n1, n2}, []*apiv1.Pod{p1, p2}, nil, 1, time.Now())
+	assert.NoError(t, err)
+	assert.Equal(t, status.ScaleDownNodeDeleteStarted, scaleDownStatus.Result)
+	assert.Equal(t, 1, len(scaleDownStatus.ScaledDownNodes))
+	assert.Equal(t, n1.Name, scaleDownStatus.ScaledDownNodes[0].Node.Name)
+	assert.Equal(t, []*apiv1.Pod{p1}, scaleDownStatus.ScaledDownNodes[0].EvictedPods)
+
+	// Nothing is drained or deleted and the node stays unneeded.
+	assert.False(t, scaleDown.nodeDeletionTracker.IsNonEmptyNodeDeleteInProgress())
+	assert.Contains(t, scaleDown.unneededNodes, n1.Name)
+	assert.Equal(t, nothingReturned, getStringFromChanImmediately(updatedNodes))
+	assert.Equal(t, nothingReturned, getStringFromChanImmediately(deletedNodes))
+}
+
 func waitForDeleteToFinish(t *testing.T, sd *ScaleDown) {
 	for start := time.Now(); time.Since(start) < 20*time.Second; time.Sleep(100 * time.Millisecond) {
 		if !sd.nodeDeletionTracker.IsNonEmptyNodeDeleteInProgress() {
@@ -1019,6 +1360,23 @@ func TestScaleDownEmptySingleNodeGroup(t *testing.T) {
 	simpleScaleDownEmpty(t, config)
 }
 
+func TestScaleDownEmptyMostExpensiveFirst(t *testing.T) {
+	options := defaultScaleDownOptions
+	options.MaxEmptyBulkDelete = 1
+	options.ScaleDownOrderName = scaledownorder.PriceOrderName
+	config := &scaleTestConfig{
+		nodes: []nodeConfig{
+			{"n1", 1000, 1000, 0, true, "ng1"},
+			{"n2", 1000, 1000, 0, true, "ng1"},
+			{"n3", 1000, 1000, 0, true, "ng1"},
+		},
+		options:            options,
+		expectedScaleDowns: []string{"n2"},
+		nodePrices:         map[string]float64{"n1": 0.3, "n2": 1.0, "n3": 0.3},
+	}
+	simpleScaleDownEmpty(t, config)
+}
+
 func TestScaleDownEmptyMinCoresLimitHit(t *testing.T) {
 	options := defaultScaleDownOptions
 	options.MinCoresTotal = 2
@@ -1108,6 +1466,80 @@ func TestScaleDownEmptyMinGroupSizeLimitHitWhenOneNodeIsBeingDeleted(t *testing.
 	simpleScaleDownEmpty(t, config)
 }
 
+func TestScaleDownEmptyBudgetMaxNodes(t *testing.T) {
+	options := defaultScaleDownOptions
+	options.ScaleDownBudgetMaxNodes = 2
+	options.ScaleDownBudgetWindow = time.Hour
+	config := &scaleTestConfig{
+		nodes: []nodeConfig{
+			{"n1", 1000, 1000, 0, true, "ng1"},
+			{"n2", 1000, 1000, 0, true, "ng1"},
+			{"n3", 1000, 1000, 0, true, "ng1"},
+			{"n4", 1000, 1000, 0, true, "ng1"},
+		},
+		options:            options,
+		expectedScaleDowns: []string{"n1", "n2"},
+	}
+	simpleScaleDownEmpty(t, config)
+}
+
+func TestScaleDownEmptyBudgetMaxRatio(t *testing.T) {
+	options := defaultScaleDownOptions
+	options.ScaleDownBudgetMaxRatio = 0.25
+	options.ScaleDownBudgetWindow = time.Hour
+	config := &scaleTestConfig{
+		nodes: []nodeConfig{
+			{"n1", 1000, 1000, 0, true, "ng1"},
+			{"n2", 1000, 1000, 0, true, "ng1"},
+			{"n3", 1000, 1000, 0, true, "ng1"},
+			{"n4", 1000, 1000, 0, true, "ng1"},
+			{"n5", 1000, 1000, 0, true, "ng2"},
+			{"n6", 1000, 1000, 0, true, "ng2"},
+			{"n7", 1000, 1000, 0, true, "ng2"},
+		},
+		options: options,
+		// A quarter of ng1 is one node, for ng2 it's rounded up to one node.
+		expectedScaleDowns: []string{"n1", "n5"},
+	}
+	simpleScaleDownEmpty(t, config)
+}
+
+func TestScaleDownEmptyBudgetUsedUp(t *testing.T) {
+	options := defaultScaleDownOptions
+	options.ScaleDownBudgetMaxNodes = 2
+	options.ScaleDownBudgetWindow = time.Hour
+	tracker := NewScaleDownBudgetTracker(options.ScaleDownBudgetWindow)
+	tracker.RegisterDeletion("ng1", time.Now().Add(-30*time.Minute))
+	tracker.RegisterDeletion("ng1", time.Now().Add(-20*time.Minute))
+	tracker.RegisterDeletion("ng2", time.Now().Add(-2*time.Hour))
+	config := &scaleTestConfig{
+		nodes: []nodeConfig{
+			{"n1", 1000, 1000, 0, true, "ng1"},
+			{"n2", 1000, 1000, 0, true, "ng1"},
+			{"n3", 1000, 1000, 0, true, "ng2"},
+			{"n4", 1000, 1000, 0, true, "ng2"},
+		},
+		options:                options,
+		scaleDownBudgetTracker: tracker,
+		// Deletions out of the window don't count.
+		expectedScaleDowns: []string{"n3"},
+	}
+	simpleScaleDownEmpty(t, config)
+}
+
+func TestScaleDownEmptyInEmptyNodesOnlyWindow(t *testing.T) {
+	config := &scaleTestConfig{
+		nodes: []nodeConfig{
+			{"n1", 1000, 1000, 0, true, "ng1"},
+			{"n2", 1000, 1000, 0, true, "ng1"},
+		},
+		options:              defaultScaleDownOptions,
+		emptyNodesOnlyWindow: "nightly-backup",
+		expectedScaleDowns:   []string{"n1"},
+	}
+	simpleScaleDownEmpty(t, config)
+}
+
 func simpleScaleDownEmpty(t *testing.T, config *scaleTestConfig) {
 	updatedNodes := make(chan string, 10)
 	deletedNodes := make(chan string, 10)
@@ -1164,6 +1596,9 @@ func simpleScaleDownEmpty(t *testing.T, config *scaleTestConfig) {
 
 	resourceLimiter := context.NewResourceLimiterFromAutoscalingOptions(config.options)
 	provider.SetResourceLimiter(resourceLimiter)
+	if config.nodePrices != nil {
+		provider.SetPricingModel(&testPricingModel{nodePrices: config.nodePrices})
+	}
 
 	assert.NotNil(t, provider)
 
@@ -1175,9 +1610,14 @@ func simpleScaleDownEmpty(t *testing.T, config *scaleTestConfig) {
 	if config.nodeDeletionTracker != nil {
 		scaleDown.nodeDeletionTracker = config.nodeDeletionTracker
 	}
+	if config.scaleDownBudgetTracker != nil {
+		scaleDown.scaleDownBudgetTracker = config.scaleDownBudgetTracker
+	}
+	scaleDown.SetEmptyNodesOnlyWindow(config.emptyNodesOnlyWindow)
+	scaleDown.SetSimilarNodeGroups(config.similarNodeGroups)
 	scaleDown.UpdateUnneededNodes(nodes,
 		nodes, []*apiv1.Pod{}, time.Now().Add(-5*time.Minute), nil)
-	scaleDownStatus, err := scaleDown.TryToScaleDown(nodes, []*apiv1.Pod{}, nil, time.Now())
+	scaleDownStatus, err := scaleDown.TryToScaleDown(nodes, []*apiv1.Pod{}, nil, 1, time.Now())
 	assert.False(t, scaleDown.nodeDeletionTracker.IsNonEmptyNodeDeleteInProgress())
 
 	assert.NoError(t, err)
@@ -1251,11 +1691,15 @@ func TestNoScaleDownUnready(t *testing.T) {
 	scaleDown := NewScaleDown(&context, clusterStateRegistry)
 	scaleDown.UpdateUnneededNodes([]*apiv1.Node{n1, n2},
 		[]*apiv1.Node{n1, n2}, []*apiv1.Pod{p2}, time.Now().Add(-5*time.Minute), nil)
-	scaleDownStatus, err := scaleDown.TryToScaleDown([]*apiv1.Node{n1, n2}, []*apiv1.Pod{p2}, nil, time.Now())
+	scaleDownStatus, err := scaleDown.TryToScaleDown([]*apiv1.Node{n1, n2}, []*apiv1.Pod{p2}, nil, 1, time.Now())
 	waitForDeleteToFinish(t, scaleDown)
 
 	assert.NoError(t, err)
 	assert.Equal(t, status.ScaleDownNoUnneeded, scaleDownStatus.Result)
+	assert.Equal(t, map[string]simulator.UnremovableReason{
+		"n1": simulator.NotUnreadyLongEnough,
+		"n2": simulator.NotUnderutilized,
+	}, unremovableReasons(scaleDownStatus.UnremovableNodes))
 
 	deletedNodes := make(chan string, 10)
 
@@ -1273,7 +1717,70 @@ func TestNoScaleDownUnready(t *testing.T) {
 	scaleDown = NewScaleDown(&context, clusterStateRegistry)
 	scaleDown.UpdateUnneededNodes([]*apiv1.Node{n1, n2}, []*apiv1.Node{n1, n2},
 		[]*apiv1.Pod{p2}, time.Now().Add(-2*time.Hour), nil)
-	scaleDownStatus, err = scaleDown.TryToScaleDown([]*apiv1.Node{n1, n2}, []*apiv1.Pod{p2}, nil, time.Now())
+	scaleDownStatus, err = scaleDown.TryToScaleDown([]*apiv1.Node{n1, n2}, []*apiv1.Pod{p2}, nil, 1, time.Now())
+	waitForDeleteToFinish(t, scaleDown)
+
+	assert.NoError(t, err)
+	assert.Equal(t, status.ScaleDownNodeDeleteStarted, scaleDownStatus.Result)
+	assert.Equal(t, n1.Name, getStringFromChan(deletedNodes))
+}
+
+func TestScaleDownUnreadyTimeFromNodeGroupOptions(t *testing.T) {
+	fakeClient := &fake.Clientset{}
+	n1 := BuildTestNode("n1", 1000, 1000)
+	SetNodeReadyState(n1, false, time.Now().Add(-10*time.Minute))
+	n2 := BuildTestNode("n2", 1000, 1000)
+	SetNodeReadyState(n2, true, time.Time{})
+	p2 := BuildTestPod("p2", 800, 0)
+	p2.Spec.NodeName = "n2"
+
+	fakeClient.Fake.AddReactor("list", "pods", func(action core.Action) (bool, runtime.Object, error) {
+		return true, &apiv1.PodList{Items: []apiv1.Pod{*p2}}, nil
+	})
+	fakeClient.Fake.AddReactor("get", "pods", func(action core.Action) (bool, runtime.Object, error) {
+		return true, nil, errors.NewNotFound(apiv1.Resource("pod"), "whatever")
+	})
+	fakeClient.Fake.AddReactor("get", "nodes", func(action core.Action) (bool, runtime.Object, error) {
+		getAction := action.(core.GetAction)
+		switch getAction.GetName() {
+		case n1.Name:
+			return true, n1, nil
+		case n2.Name:
+			return true, n2, nil
+		}
+		return true, nil, fmt.Errorf("wrong node: %v", getAction.GetName())
+	})
+
+	deletedNodes := make(chan string, 10)
+	provider := testprovider.NewTestCloudProvider(nil, func(nodeGroup string, node string) error {
+		deletedNodes <- node
+		return nil
+	})
+	ng1 := provider.BuildNodeGroup("ng1", 1, 10, 2, false, "")
+	ng1.SetOptions(&config.NodeGroupAutoscalingOptions{
+		ScaleDownUtilizationThreshold: 0.5,
+		ScaleDownUnneededTime:         time.Minute,
+		ScaleDownUnreadyTime:          time.Minute,
+	})
+	provider.InsertNodeGroup(ng1)
+	provider.AddNode("ng1", n1)
+	provider.AddNode("ng1", n2)
+
+	options := config.AutoscalingOptions{
+		ScaleDownUtilizationThreshold: 0.5,
+		ScaleDownUnneededTime:         time.Minute,
+		ScaleDownUnreadyTime:          time.Hour,
+		MaxGracefulTerminationSec:     60,
+	}
+	registry := kube_util.NewListerRegistry(nil, nil, nil, nil, nil, nil, nil, nil, nil, nil)
+	context := NewScaleTestAutoscalingContext(options, fakeClient, registry, provider, nil)
+
+	// N1 is unready for less than the global unready time, but longer than the one set for its node group.
+	clusterStateRegistry := clusterstate.NewClusterStateRegistry(provider, clusterstate.ClusterStateRegistryConfig{}, context.LogRecorder, newBackoff())
+	scaleDown := NewScaleDown(&context, clusterStateRegistry)
+	scaleDown.UpdateUnneededNodes([]*apiv1.Node{n1, n2},
+		[]*apiv1.Node{n1, n2}, []*apiv1.Pod{p2}, time.Now().Add(-5*time.Minute), nil)
+	scaleDownStatus, err := scaleDown.TryToScaleDown([]*apiv1.Node{n1, n2}, []*apiv1.Pod{p2}, nil, 1, time.Now())
 	waitForDeleteToFinish(t, scaleDown)
 
 	assert.NoError(t, err)
@@ -1354,7 +1861,7 @@ func TestScaleDownNoMove(t *testing.T) {
 	scaleDown := NewScaleDown(&context, clusterStateRegistry)
 	scaleDown.UpdateUnneededNodes([]*apiv1.Node{n1, n2}, []*apiv1.Node{n1, n2},
 		[]*apiv1.Pod{p1, p2}, time.Now().Add(5*time.Minute), nil)
-	scaleDownStatus, err := scaleDown.TryToScaleDown([]*apiv1.Node{n1, n2}, []*apiv1.Pod{p1, p2}, nil, time.Now())
+	scaleDownStatus, err := scaleDown.TryToScaleDown([]*apiv1.Node{n1, n2}, []*apiv1.Pod{p1, p2}, nil, 1, time.Now())
 	waitForDeleteToFinish(t, scaleDown)
 
 	assert.NoError(t, err)
@@ -17,7 +17,9 @@ limitations under the License.
 package core
 
 import (
+	"fmt"
 	"testing"
+	"time"
 
 	"k8s.io/apimachinery/pkg/api/resource"
 	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider"
@@ -29,6 +31,7 @@ import (
 	"k8s.io/autoscaler/cluster-autoscaler/metrics"
 	processor_callbacks "k8s.io/autoscaler/cluster-autoscaler/processors/callbacks"
 	"k8s.io/autoscaler/cluster-autoscaler/processors/nodegroups"
+	"k8s.io/autoscaler/cluster-autoscaler/scaledownorder"
 	"k8s.io/autoscaler/cluster-autoscaler/simulator"
 	"k8s.io/autoscaler/cluster-autoscaler/utils/errors"
 	kube_util "k8s.io/autoscaler/cluster-autoscaler/utils/kubernetes"
@@ -76,6 +79,10 @@ type scaleTestConfig struct {
 	expectedScaleDowns     []string
 	options                config.AutoscalingOptions
 	nodeDeletionTracker    *NodeDeletionTracker
+	scaleDownBudgetTracker *ScaleDownBudgetTracker
+	emptyNodesOnlyWindow   string
+	similarNodeGroups      map[string][]string
+	nodePrices             map[string]float64
 }
 
 // NewScaleTestAutoscalingContext creates a new test autoscaling context for scaling tests.
@@ -88,6 +95,7 @@ func NewScaleTestAutoscalingContext(
 	// Ignoring error here is safe - if a test doesn't specify valid estimatorName,
 	// it either doesn't need one, or should fail when it turns out to be nil.
 	estimatorBuilder, _ := estimator.NewEstimatorBuilder(options.EstimatorName)
+	scaleDownOrder, _ := scaledownorder.NewStrategy(options.ScaleDownOrderName, provider)
 	return context.AutoscalingContext{
 		AutoscalingOptions: options,
 		AutoscalingKubeClients: context.AutoscalingKubeClients{
@@ -100,6 +108,7 @@ func NewScaleTestAutoscalingContext(
 		PredicateChecker:   simulator.NewTestPredicateChecker(),
 		ExpanderStrategy:   random.NewStrategy(),
 		EstimatorBuilder:   estimatorBuilder,
+		ScaleDownOrder:     scaleDownOrder,
 		ProcessorCallbacks: processorCallbacks,
 	}
 }
@@ -174,3 +183,18 @@ func (p *mockAutoprovisioningNodeGroupListProcessor) CleanUp() {
 func newBackoff() backoff.Backoff {
 	return backoff.NewIdBasedExponentialBackoff(clusterstate.InitialNodeGroupBackoffDuration, clusterstate.MaxNodeGroupBackoffDuration, clusterstate.NodeGroupBackoffResetTimeout)
 }
+
+type testPricingModel struct {
+	nodePrices map[string]float64
+}
+
+func (tpm *testPricingModel) NodePrice(node *apiv1.Node, startTime time.Time, endTime time.Time) (float64, error) {
+	if price, found := tpm.nodePrices[node.Name]; found {
+		return price, nil
+	}
+	return 0.0, fmt.Errorf("price for node %v not found", node.Name)
+}
+
+func (tpm *testPricingModel) PodPrice(pod *apiv1.Pod, startTime time.Time, endTime time.Time) (float64, error) {
+	return 0.0, nil
+}
@@ -224,6 +224,18 @@ func (limits *scaleUpResourcesLimits) checkScaleUpDeltaWithinLimits(delta scaleU
 	return scaleUpLimitsNotExceeded()
 }
 
+// subtractScaleUpDelta lowers the limits by the resources of nodeCount nodes
+// described by delta.
+func (limits *scaleUpResourcesLimits) subtractScaleUpDelta(delta scaleUpResourcesDelta, nodeCount int) {
+	for resource, resourceDelta := range delta {
+		resourceLeft, found := (*limits)[resource]
+		if !found || resourceLeft == scaleUpLimitUnknown {
+			continue
+		}
+		(*limits)[resource] = computeBelowMax(resourceDelta*int64(nodeCount), resourceLeft)
+	}
+}
+
 func getNodeInfoCoresAndMemory(nodeInfo *schedulernodeinfo.NodeInfo) (int64, int64) {
 	return getNodeCoresAndMemory(nodeInfo.Node())
 }
@@ -420,9 +432,30 @@ func ScaleUp(context *context.AutoscalingContext, processors *ca_processors.Auto
 		return &status.ScaleUpStatus{Result: status.ScaleUpNoOptionsAvailable, PodsRemainUnschedulable: getRemainingPods(podsRemainUnschedulable, skippedNodeGroups)}, nil
 	}
 
-	// Pick some expansion option.
-	bestOption := context.ExpanderStrategy.BestOption(expansionOptions, nodeInfos)
-	if bestOption != nil && bestOption.NodeCount > 0 {
+	maxParallelScaleUps := context.MaxParallelScaleUps
+	if maxParallelScaleUps < 1 {
+		maxParallelScaleUps = 1
+	}
+
+	var scaleUpInfos []nodegroupset.ScaleUpInfo
+	var scaleUpOptions []status.ScaleUpOption
+	podsTriggeredScaleUp := make([]*apiv1.Pod, 0)
+	scaledUpGroups := make(map[string]bool)
+	newNodesTotal := 0
+	// Nodes requested for the executed options, with the pods that triggered them.
+	requestedNodes := make([]*schedulernodeinfo.NodeInfo, 0)
+
+	// Options executed before an error are still reported.
+	scaleUpError := func(err errors.AutoscalerError) (*status.ScaleUpStatus, errors.AutoscalerError) {
+		return &status.ScaleUpStatus{Result: status.ScaleUpError, ScaleUpInfos: scaleUpInfos, ScaleUpOptions: scaleUpOptions}, err
+	}
+
+	for len(expansionOptions) > 0 && len(scaleUpOptions) < maxParallelScaleUps {
+		// Pick some expansion option.
+		bestOption := context.ExpanderStrategy.BestOption(expansionOptions, nodeInfos)
+		if bestOption == nil || bestOption.NodeCount <= 0 {
+			break
+		}
 		klog.V(1).Infof("Best option to resize: %s", bestOption.NodeGroup.Id())
 		if len(bestOption.Debug) > 0 {
 			klog.V(1).Info(bestOption.Debug)
@@ -431,13 +464,16 @@ func ScaleUp(context *context.AutoscalingContext, processors *ca_processors.Auto
 
 		newNodes := bestOption.NodeCount
 
-		if context.MaxNodesTotal > 0 && len(nodes)+newNodes+len(upcomingNodes) > context.MaxNodesTotal {
+		if context.MaxNodesTotal > 0 && len(nodes)+newNodesTotal+newNodes+len(upcomingNodes) > context.MaxNodesTotal {
 			klog.V(1).Infof("Capping size to max cluster total size (%d)", context.MaxNodesTotal)
-			newNodes = context.MaxNodesTotal - len(nodes) - len(upcomingNodes)
+			newNodes = context.MaxNodesTotal - len(nodes) - newNodesTotal - len(upcomingNodes)
 			if newNodes < 1 {
-				return &status.ScaleUpStatus{Result: status.ScaleUpError}, errors.NewAutoscalerError(
+				if len(scaleUpOptions) > 0 {
+					break
+				}
+				return scaleUpError(errors.NewAutoscalerError(
 					errors.TransientError,
-					"max node total count already reached")
+					"max node total count already reached"))
 			}
 		}
 
@@ -445,9 +481,10 @@ func ScaleUp(context *context.AutoscalingContext, processors *ca_processors.Auto
 			oldId := bestOption.NodeGroup.Id()
 			createNodeGroupResult, err := processors.NodeGroupManager.CreateNodeGroup(context, bestOption.NodeGroup)
 			if err != nil {
-				return &status.ScaleUpStatus{Result: status.ScaleUpError}, err
+				return scaleUpError(err)
 			}
 			bestOption.NodeGroup = createNodeGroupResult.MainCreatedNodeGroup
+			scaledUpGroups[oldId] = true
 
 			// If possible replace candidate node-info with node info based on crated node group. The latter
 			// one should be more in line with nodes which will be created by node group.
@@ -485,26 +522,28 @@ func ScaleUp(context *context.AutoscalingContext, processors *ca_processors.Auto
 			// This should never happen, as we already should have retrieved
 			// nodeInfo for any considered nodegroup.
 			klog.Errorf("No node info for: %s", bestOption.NodeGroup.Id())
-			return &status.ScaleUpStatus{Result: status.ScaleUpError}, errors.NewAutoscalerError(
+			return scaleUpError(errors.NewAutoscalerError(
 				errors.CloudProviderError,
-				"No node info for best expansion option!")
+				"No node info for best expansion option!"))
 		}
 
 		// apply upper limits for CPU and memory
 		newNodes, err = applyScaleUpResourcesLimits(context.CloudProvider, newNodes, scaleUpResourcesLeft, nodeInfo, bestOption.NodeGroup, resourceLimiter)
 		if err != nil {
-			return &status.ScaleUpStatus{Result: status.ScaleUpError}, err
+			return scaleUpError(err)
 		}
 
 		targetNodeGroups := []cloudprovider.NodeGroup{bestOption.NodeGroup}
 		if context.BalanceSimilarNodeGroups {
 			similarNodeGroups, typedErr := processors.NodeGroupSetProcessor.FindSimilarNodeGroups(context, bestOption.NodeGroup, nodeInfos)
 			if typedErr != nil {
-				return &status.ScaleUpStatus{Result: status.ScaleUpError}, typedErr.AddPrefix("Failed to find matching node groups: ")
+				return scaleUpError(typedErr.AddPrefix("Failed to find matching node groups: "))
 			}
 			similarNodeGroups = filterNodeGroupsByPods(similarNodeGroups, bestOption.Pods, getPodsPassingPredicates)
 			for _, ng := range similarNodeGroups {
-				if clusterStateRegistry.IsNodeGroupSafeToScaleUp(ng, now) {
+				if scaledUpGroups[ng.Id()] {
+					klog.V(2).Infof("Ignoring node group %s when balancing: group was already scaled up", ng.Id())
+				} else if clusterStateRegistry.IsNodeGroupSafeToScaleUp(ng, now) {
 					targetNodeGroups = append(targetNodeGroups, ng)
 				} else {
 					// This should never happen, as we will filter out the node group earlier on
@@ -524,30 +563,133 @@ func ScaleUp(context *context.AutoscalingContext, processors *ca_processors.Auto
 				klog.V(1).Infof("Splitting scale-up between %v similar node groups: {%v}", len(targetNodeGroups), buffer.String())
 			}
 		}
-		scaleUpInfos, typedErr := processors.NodeGroupSetProcessor.BalanceScaleUpBetweenGroups(
+		optionScaleUpInfos, typedErr := processors.NodeGroupSetProcessor.BalanceScaleUpBetweenGroups(
 			context, targetNodeGroups, newNodes)
 		if typedErr != nil {
-			return &status.ScaleUpStatus{Result: status.ScaleUpError}, typedErr
+			return scaleUpError(typedErr)
 		}
-		klog.V(1).Infof("Final scale-up plan: %v", scaleUpInfos)
-		for _, info := range scaleUpInfos {
+		klog.V(1).Infof("Final scale-up plan: %v", optionScaleUpInfos)
+		for _, info := range optionScaleUpInfos {
 			typedErr := executeScaleUp(context, clusterStateRegistry, info, gpu.GetGpuTypeForMetrics(gpuLabel, availableGPUTypes, nodeInfo.Node(), nil), now)
 			if typedErr != nil {
-				return &status.ScaleUpStatus{Result: status.ScaleUpError}, typedErr
+				return scaleUpError(typedErr)
+			}
+			scaleUpInfos = append(scaleUpInfos, info)
+			scaledUpGroups[info.Group.Id()] = true
+		}
+		scaledUpGroups[bestOption.NodeGroup.Id()] = true
+		scaleUpOptions = append(scaleUpOptions, status.ScaleUpOption{ScaleUpInfos: optionScaleUpInfos, Pods: bestOption.Pods, Debug: bestOption.Debug})
+		podsTriggeredScaleUp = append(podsTriggeredScaleUp, bestOption.Pods...)
+		newNodesTotal += newNodes
+
+		if len(scaleUpOptions) < maxParallelScaleUps {
+			delta, err := computeScaleUpResourcesDelta(context.CloudProvider, nodeInfo, bestOption.NodeGroup, resourceLimiter)
+			if err != nil {
+				return scaleUpError(err)
+			}
+			scaleUpResourcesLeft.subtractScaleUpDelta(delta, newNodes)
+			// Pods left are estimated taking into account the room left on the requested nodes.
+			requestedNodes = append(requestedNodes, requestedNodeInfos(context, optionScaleUpInfos, nodeInfos, bestOption.Pods)...)
+			expansionOptions = remainingExpansionOptions(context, expansionOptions, podsTriggeredScaleUp, scaledUpGroups,
+				nodeInfos, append(append([]*schedulernodeinfo.NodeInfo{}, upcomingNodes...), requestedNodes...),
+				scaleUpResourcesLeft, resourceLimiter)
+		}
+	}
+
+	if len(scaleUpOptions) == 0 {
+		return &status.ScaleUpStatus{Result: status.ScaleUpNoOptionsAvailable, PodsRemainUnschedulable: getRemainingPods(podsRemainUnschedulable, skippedNodeGroups)}, nil
+	}
+
+	clusterStateRegistry.Recalculate()
+	return &status.ScaleUpStatus{
+			Result:                  status.ScaleUpSuccessful,
+			ScaleUpInfos:            scaleUpInfos,
+			ScaleUpOptions:          scaleUpOptions,
+			PodsRemainUnschedulable: getRemainingPods(podsRemainUnschedulable, skippedNodeGroups),
+			PodsTriggeredScaleUp:    podsTriggeredScaleUp,
+			PodsAwaitEvaluation:     getPodsAwaitingEvaluation(unschedulablePods, podsRemainUnschedulable, podsTriggeredScaleUp)},
+		nil
+}
+
+// requestedNodeInfos returns node infos of the nodes requested by the scale-up
+// infos, with the given pods placed on them where they fit.
+func requestedNodeInfos(context *context.AutoscalingContext, scaleUpInfos []nodegroupset.ScaleUpInfo,
+	nodeInfos map[string]*schedulernodeinfo.NodeInfo, pods []*apiv1.Pod) []*schedulernodeinfo.NodeInfo {
+	result := make([]*schedulernodeinfo.NodeInfo, 0)
+	for _, info := range scaleUpInfos {
+		template, found := nodeInfos[info.Group.Id()]
+		if !found {
+			continue
+		}
+		for i := info.CurrentSize; i < info.NewSize; i++ {
+			result = append(result, template.Clone())
+		}
+	}
+	for _, pod := range pods {
+		for _, nodeInfo := range result {
+			if err := context.PredicateChecker.CheckPredicates(pod, nil, nodeInfo); err == nil {
+				nodeInfo.AddPod(pod)
+				break
 			}
 		}
+	}
+	return result
+}
 
-		clusterStateRegistry.Recalculate()
-		return &status.ScaleUpStatus{
-				Result:                  status.ScaleUpSuccessful,
-				ScaleUpInfos:            scaleUpInfos,
-				PodsRemainUnschedulable: getRemainingPods(podsRemainUnschedulable, skippedNodeGroups),
-				PodsTriggeredScaleUp:    bestOption.Pods,
-				PodsAwaitEvaluation:     getPodsAwaitingEvaluation(unschedulablePods, podsRemainUnschedulable, bestOption.Pods)},
-			nil
+// remainingExpansionOptions returns the expansion options that can still be
+// executed in the same scale-up. Pods that already triggered a scale-up are
+// removed from the options, node groups that were already resized are dropped
+// and the node counts are estimated again for the pods left, with the nodes
+// requested so far among the upcoming nodes.
+func remainingExpansionOptions(context *context.AutoscalingContext, options []expander.Option, podsTriggeredScaleUp []*apiv1.Pod,
+	scaledUpGroups map[string]bool, nodeInfos map[string]*schedulernodeinfo.NodeInfo, upcomingNodes []*schedulernodeinfo.NodeInfo,
+	scaleUpResourcesLeft scaleUpResourcesLimits, resourceLimiter *cloudprovider.ResourceLimiter) []expander.Option {
+
+	triggered := make(map[*apiv1.Pod]bool, len(podsTriggeredScaleUp))
+	for _, pod := range podsTriggeredScaleUp {
+		triggered[pod] = true
 	}
 
-	return &status.ScaleUpStatus{Result: status.ScaleUpNoOptionsAvailable, PodsRemainUnschedulable: getRemainingPods(podsRemainUnschedulable, skippedNodeGroups)}, nil
+	result := make([]expander.Option, 0, len(options))
+	for _, option := range options {
+		if scaledUpGroups[option.NodeGroup.Id()] {
+			continue
+		}
+		pods := make([]*apiv1.Pod, 0, len(option.Pods))
+		for _, pod := range option.Pods {
+			if !triggered[pod] {
+				pods = append(pods, pod)
+			}
+		}
+		if len(pods) == 0 {
+			continue
+		}
+		nodeInfo, found := nodeInfos[option.NodeGroup.Id()]
+		if !found {
+			continue
+		}
+		delta, err := computeScaleUpResourcesDelta(context.CloudProvider, nodeInfo, option.NodeGroup, resourceLimiter)
+		if err != nil {
+			klog.Errorf("Skipping node group %s; error getting node group resources: %v", option.NodeGroup.Id(), err)
+			continue
+		}
+		if checkResult := scaleUpResourcesLeft.checkScaleUpDeltaWithinLimits(delta); checkResult.exceeded {
+			klog.V(4).Infof("Skipping node group %s; maximal limit exceeded for %v", option.NodeGroup.Id(), checkResult.exceededResources)
+			continue
+		}
+		estimator := context.EstimatorBuilder(context.PredicateChecker)
+		nodeCount := estimator.Estimate(pods, nodeInfo, upcomingNodes)
+		if nodeCount == 0 {
+			continue
+		}
+		result = append(result, expander.Option{
+			NodeGroup: option.NodeGroup,
+			NodeCount: nodeCount,
+			Pods:      pods,
+			Debug:     option.Debug,
+		})
+	}
+	return result
 }
 
 type podsPredicatePassingCheckFunctions struct {
@@ -696,11 +838,15 @@ func executeScaleUp(context *context.AutoscalingContext, clusterStateRegistry *c
 		return errors.NewAutoscalerError(errors.CloudProviderError,
 			"failed to increase node group size: %v", err)
 	}
-	clusterStateRegistry.RegisterOrUpdateScaleUp(
-		info.Group,
-		increase,
-		time.Now())
-	metrics.RegisterScaleUp(increase, gpuType)
+	// Target sizes don't change in dry run mode, so the scale-up is neither
+	// tracked nor counted in metrics.
+	if !context.DryRun {
+		clusterStateRegistry.RegisterOrUpdateScaleUp(
+			info.Group,
+			increase,
+			time.Now())
+		metrics.RegisterScaleUp(increase, gpuType)
+	}
 	context.LogRecorder.Eventf(apiv1.EventTypeNormal, "ScaledUpGroup",
 		"Scale-up: group %s size set to %d", info.Group.Id(), info.NewSize)
 	return nil
@@ -28,6 +28,8 @@ import (
 	"k8s.io/autoscaler/cluster-autoscaler/clusterstate"
 	"k8s.io/autoscaler/cluster-autoscaler/config"
 	"k8s.io/autoscaler/cluster-autoscaler/estimator"
+	"k8s.io/autoscaler/cluster-autoscaler/processors/nodegroupset"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator"
 	kube_util "k8s.io/autoscaler/cluster-autoscaler/utils/kubernetes"
 	. "k8s.io/autoscaler/cluster-autoscaler/utils/test"
 	"k8s.io/autoscaler/cluster-autoscaler/utils/units"
@@ -35,6 +37,7 @@ import (
 
 	appsv1 "k8s.io/api/apps/v1"
 	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes/fake"
 	schedulernodeinfo "k8s.io/kubernetes/pkg/scheduler/nodeinfo"
 
@@ -341,6 +344,14 @@ func (s assertingStrategy) BestOption(options []expander.Option, nodeInfo map[st
 	return nil
 }
 
+func (s assertingStrategy) BestOptions(options []expander.Option, nodeInfo map[string]*schedulernodeinfo.NodeInfo) []expander.Option {
+	best := s.BestOption(options, nodeInfo)
+	if best == nil {
+		return nil
+	}
+	return []expander.Option{*best}
+}
+
 func expanderOptionsToGroupSizeChanges(options []expander.Option) []groupSizeChange {
 	groupSizeChanges := make([]groupSizeChange, 0, len(options))
 	for _, option := range options {
@@ -729,6 +740,86 @@ func TestScaleUpBalanceGroups(t *testing.T) {
 	assert.Equal(t, 2, ng3size)
 }
 
+func TestScaleUpVolumeTopology(t *testing.T) {
+	provider := testprovider.NewTestCloudProvider(func(string, int) error {
+		return nil
+	}, nil)
+
+	nodes := make([]*apiv1.Node, 0)
+	for gid, zone := range map[string]string{"ng1": "us-east1-a", "ng2": "us-east1-b"} {
+		provider.AddNodeGroup(gid, 1, 5, 1)
+		node := BuildTestNode(fmt.Sprintf("%v-node", gid), 100, 1000)
+		node.Labels[apiv1.LabelZoneFailureDomain] = zone
+		SetNodeReadyState(node, true, time.Now())
+		nodes = append(nodes, node)
+		provider.AddNode(gid, node)
+	}
+
+	// Pods of a stateful set, with claims bound to volumes in us-east1-b.
+	pods := make([]*apiv1.Pod, 0)
+	pvcs := make([]*apiv1.PersistentVolumeClaim, 0)
+	pvs := make([]*apiv1.PersistentVolume, 0)
+	for i := 0; i < 2; i++ {
+		pv := &apiv1.PersistentVolume{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   fmt.Sprintf("pv-%v", i),
+				Labels: map[string]string{apiv1.LabelZoneFailureDomain: "us-east1-b"},
+			},
+		}
+		pvc := &apiv1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("data-ss-%v", i), Namespace: "default"},
+			Spec:       apiv1.PersistentVolumeClaimSpec{VolumeName: pv.Name},
+		}
+		pod := BuildTestPod(fmt.Sprintf("ss-%v", i), 80, 0)
+		pod.OwnerReferences = GenerateOwnerReferences("ss", "StatefulSet", "apps/v1", "ss-uid")
+		pod.Spec.Volumes = []apiv1.Volume{{
+			Name: "data",
+			VolumeSource: apiv1.VolumeSource{
+				PersistentVolumeClaim: &apiv1.PersistentVolumeClaimVolumeSource{ClaimName: pvc.Name},
+			},
+		}}
+		pvs = append(pvs, pv)
+		pvcs = append(pvcs, pvc)
+		pods = append(pods, pod)
+	}
+	pvcLister, err := kube_util.NewTestPersistentVolumeClaimLister(pvcs)
+	assert.NoError(t, err)
+	pvLister, err := kube_util.NewTestPersistentVolumeLister(pvs)
+	assert.NoError(t, err)
+	classLister, err := kube_util.NewTestStorageClassLister(nil)
+	assert.NoError(t, err)
+
+	podLister := kube_util.NewTestPodLister([]*apiv1.Pod{})
+	listers := kube_util.NewListerRegistry(nil, nil, podLister, nil, nil, nil, nil, nil, nil, nil)
+	options := config.AutoscalingOptions{
+		EstimatorName:            estimator.BinpackingEstimatorName,
+		BalanceSimilarNodeGroups: true,
+		MaxCoresTotal:            config.DefaultMaxClusterCores,
+		MaxMemoryTotal:           config.DefaultMaxClusterMemory,
+	}
+	context := NewScaleTestAutoscalingContext(options, &fake.Clientset{}, listers, provider, nil)
+	context.PredicateChecker.SetVolumeTopologyChecker(simulator.NewVolumeTopologyChecker(pvcLister, pvLister, classLister))
+
+	nodeInfos, _ := getNodeInfosForGroups(nodes, nil, provider, listers, []*appsv1.DaemonSet{}, context.PredicateChecker, nil)
+	clusterState := clusterstate.NewClusterStateRegistry(provider, clusterstate.ClusterStateRegistryConfig{}, context.LogRecorder, newBackoff())
+	clusterState.UpdateNodes(nodes, nodeInfos, time.Now())
+
+	processors := NewTestProcessors()
+	scaleUpStatus, typedErr := ScaleUp(&context, processors, clusterState, pods, nodes, []*appsv1.DaemonSet{}, nodeInfos, nil)
+	assert.NoError(t, typedErr)
+	assert.True(t, scaleUpStatus.WasSuccessful())
+
+	// Both pods go to the node group in the zone of their volumes, ng1 isn't
+	// balanced with it.
+	groupSizes := make(map[string]int)
+	for _, group := range provider.NodeGroups() {
+		size, err := group.TargetSize()
+		assert.NoError(t, err)
+		groupSizes[group.Id()] = size
+	}
+	assert.Equal(t, map[string]int{"ng1": 1, "ng2": 3}, groupSizes)
+}
+
 func TestScaleUpAutoprovisionedNodeGroup(t *testing.T) {
 	createdGroups := make(chan string, 10)
 	expandedGroups := make(chan string, 10)
@@ -776,6 +867,137 @@ func TestScaleUpAutoprovisionedNodeGroup(t *testing.T) {
 	assert.Equal(t, "autoprovisioned-T1-1", getStringFromChan(expandedGroups))
 }
 
+func TestScaleUpParallel(t *testing.T) {
+	testCases := []struct {
+		name                string
+		maxParallelScaleUps int
+		maxNodesTotal       int
+		maxCoresTotal       int64
+		expectedGroups      int
+	}{
+		{name: "both groups", maxParallelScaleUps: 2, expectedGroups: 2},
+		{name: "parallel scale-up disabled", maxParallelScaleUps: 1, expectedGroups: 1},
+		{name: "max nodes total", maxParallelScaleUps: 2, maxNodesTotal: 3, expectedGroups: 1},
+		{name: "max cores total", maxParallelScaleUps: 2, maxCoresTotal: 10, expectedGroups: 1},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			expandedGroups := make(chan groupSizeChange, 10)
+			provider := testprovider.NewTestCloudProvider(func(nodeGroup string, increase int) error {
+				expandedGroups <- groupSizeChange{groupName: nodeGroup, sizeChange: increase}
+				return nil
+			}, nil)
+			gpuNode := BuildTestNode("gpu-node-1", 2000, 1000*MiB)
+			AddGpusToNode(gpuNode, 1)
+			SetNodeReadyState(gpuNode, true, time.Now())
+			stdNode := BuildTestNode("std-node-1", 4000, 1000*MiB)
+			SetNodeReadyState(stdNode, true, time.Now())
+			provider.AddNodeGroup("gpu-pool", 1, 10, 1)
+			provider.AddNode("gpu-pool", gpuNode)
+			provider.AddNodeGroup("std-pool", 1, 10, 1)
+			provider.AddNode("std-pool", stdNode)
+			nodes := []*apiv1.Node{gpuNode, stdNode}
+
+			options := defaultOptions
+			options.MaxParallelScaleUps = tc.maxParallelScaleUps
+			options.MaxNodesTotal = tc.maxNodesTotal
+			if tc.maxCoresTotal > 0 {
+				options.MaxCoresTotal = tc.maxCoresTotal
+			}
+			provider.SetResourceLimiter(cloudprovider.NewResourceLimiter(
+				map[string]int64{cloudprovider.ResourceNameCores: 0, cloudprovider.ResourceNameMemory: 0},
+				map[string]int64{cloudprovider.ResourceNameCores: options.MaxCoresTotal, cloudprovider.ResourceNameMemory: options.MaxMemoryTotal}))
+
+			listers := kube_util.NewListerRegistry(nil, nil, kube_util.NewTestPodLister(nil), nil, nil, nil, nil, nil, nil, nil)
+			context := NewScaleTestAutoscalingContext(options, &fake.Clientset{}, listers, provider, nil)
+			nodeInfos, _ := getNodeInfosForGroups(nodes, nil, provider, listers, []*appsv1.DaemonSet{}, context.PredicateChecker, nil)
+			clusterState := clusterstate.NewClusterStateRegistry(provider, clusterstate.ClusterStateRegistryConfig{}, context.LogRecorder, newBackoff())
+			clusterState.UpdateNodes(nodes, nodeInfos, time.Now())
+
+			// Each pod fits only one of the node groups.
+			gpuPod := buildTestPod(podConfig{"gpu-pod", 100, 100 * MiB, 1, ""})
+			bigPod := buildTestPod(podConfig{"big-pod", 3000, 100 * MiB, 0, ""})
+
+			processors := NewTestProcessors()
+			scaleUpStatus, err := ScaleUp(&context, processors, clusterState, []*apiv1.Pod{gpuPod, bigPod}, nodes, []*appsv1.DaemonSet{}, nodeInfos, nil)
+			assert.NoError(t, err)
+			assert.True(t, scaleUpStatus.WasSuccessful())
+			assert.Equal(t, tc.expectedGroups, len(scaleUpStatus.ScaleUpOptions))
+			assert.Equal(t, tc.expectedGroups, len(scaleUpStatus.ScaleUpInfos))
+			assert.Equal(t, tc.expectedGroups, len(scaleUpStatus.PodsTriggeredScaleUp))
+
+			expanded := make(map[string]int)
+			for i := 0; i < tc.expectedGroups; i++ {
+				change := getGroupSizeChangeFromChan(expandedGroups)
+				if assert.NotNil(t, change) {
+					expanded[change.groupName] = change.sizeChange
+				}
+			}
+			assert.Equal(t, tc.expectedGroups, len(expanded))
+			for _, option := range scaleUpStatus.ScaleUpOptions {
+				assert.Equal(t, 1, len(option.Pods))
+				assert.Equal(t, 1, len(option.ScaleUpInfos))
+				assert.Equal(t, 1, expanded[option.ScaleUpInfos[0].Group.Id()])
+			}
+			select {
+			case change := <-expandedGroups:
+				assert.Fail(t, "unexpected scale-up", "%v", change)
+			default:
+			}
+		})
+	}
+}
+
+func TestRemainingExpansionOptions(t *testing.T) {
+	provider := testprovider.NewTestCloudProvider(nil, nil)
+	gpuNode := BuildTestNode("gpu-node-1", 2000, 1000*MiB)
+	AddGpusToNode(gpuNode, 1)
+	SetNodeReadyState(gpuNode, true, time.Now())
+	stdNode := BuildTestNode("std-node-1", 4000, 1000*MiB)
+	SetNodeReadyState(stdNode, true, time.Now())
+	provider.AddNodeGroup("gpu-pool", 1, 10, 1)
+	provider.AddNode("gpu-pool", gpuNode)
+	provider.AddNodeGroup("std-pool", 1, 10, 1)
+	provider.AddNode("std-pool", stdNode)
+	nodes := []*apiv1.Node{gpuNode, stdNode}
+	resourceLimiter := cloudprovider.NewResourceLimiter(map[string]int64{}, map[string]int64{})
+
+	listers := kube_util.NewListerRegistry(nil, nil, kube_util.NewTestPodLister(nil), nil, nil, nil, nil, nil, nil, nil)
+	context := NewScaleTestAutoscalingContext(defaultOptions, &fake.Clientset{}, listers, provider, nil)
+	nodeInfos, _ := getNodeInfosForGroups(nodes, nil, provider, listers, []*appsv1.DaemonSet{}, context.PredicateChecker, nil)
+
+	gpuPod := buildTestPod(podConfig{"gpu-pod", 100, 100 * MiB, 1, ""})
+	mediumPod := buildTestPod(podConfig{"medium-pod", 1500, 100 * MiB, 0, ""})
+	bigPod := buildTestPod(podConfig{"big-pod", 3000, 100 * MiB, 0, ""})
+
+	// gpu-pool was scaled up by one node for gpuPod, which leaves room for mediumPod.
+	gpuGroup := provider.GetNodeGroup("gpu-pool")
+	requested := requestedNodeInfos(&context, []nodegroupset.ScaleUpInfo{{Group: gpuGroup, CurrentSize: 1, NewSize: 2, MaxSize: 10}},
+		nodeInfos, []*apiv1.Pod{gpuPod})
+	assert.Equal(t, 1, len(requested))
+	assert.Equal(t, 1, len(requested[0].Pods()))
+
+	stdOption := expander.Option{
+		NodeGroup: provider.GetNodeGroup("std-pool"),
+		NodeCount: 2,
+		Pods:      []*apiv1.Pod{mediumPod, bigPod},
+		Debug:     "std-pool debug",
+	}
+	scaledUpGroups := map[string]bool{"gpu-pool": true}
+
+	options := remainingExpansionOptions(&context, []expander.Option{stdOption}, []*apiv1.Pod{gpuPod}, scaledUpGroups,
+		nodeInfos, []*schedulernodeinfo.NodeInfo{}, scaleUpResourcesLimits{}, resourceLimiter)
+	assert.Equal(t, 1, len(options))
+	assert.Equal(t, 2, options[0].NodeCount)
+
+	options = remainingExpansionOptions(&context, []expander.Option{stdOption}, []*apiv1.Pod{gpuPod}, scaledUpGroups,
+		nodeInfos, requested, scaleUpResourcesLimits{}, resourceLimiter)
+	assert.Equal(t, 1, len(options))
+	assert.Equal(t, 1, options[0].NodeCount)
+	assert.Equal(t, []*apiv1.Pod{mediumPod, bigPod}, options[0].Pods)
+	assert.Equal(t, "std-pool debug", options[0].Debug)
+}
+
 func TestCheckScaleUpDeltaWithinLimits(t *testing.T) {
 	type testcase struct {
 		limits            scaleUpResourcesLimits
@@ -0,0 +1,90 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import (
+	"time"
+
+	"k8s.io/autoscaler/cluster-autoscaler/softstate"
+
+	"k8s.io/klog"
+)
+
+// getSoftState returns the unneeded node timers, the usage tracker content and the
+// deletions counted against scale-down budgets.
+func (sd *ScaleDown) getSoftState(state *softstate.State) {
+	state.UnneededNodes = make(map[string]time.Time, len(sd.unneededNodes))
+	for name, since := range sd.unneededNodes {
+		state.UnneededNodes[name] = since
+	}
+	state.NodeUsage = sd.usageTracker.GetState()
+	state.ScaleDownDeletions = sd.scaleDownBudgetTracker.GetState(state.Timestamp)
+}
+
+// restoreSoftState sets the unneeded node timers, the usage tracker content and the
+// deletions counted against scale-down budgets. Nodes that are no longer unneeded are
+// dropped by the next UpdateUnneededNodes.
+func (sd *ScaleDown) restoreSoftState(state *softstate.State, timestamp time.Time) {
+	for name, since := range state.UnneededNodes {
+		sd.unneededNodes[name] = since
+	}
+	sd.usageTracker.Restore(state.NodeUsage, timestamp.Add(-sd.context.ScaleDownUnneededTime))
+	sd.scaleDownBudgetTracker.Restore(state.ScaleDownDeletions, timestamp)
+}
+
+// restoreSoftState loads the soft state saved by a previous run. State older than
+// SoftStateMaxAge is ignored. If the state can't be loaded, the restore is retried
+// in the next loops until any saved state would be too old anyway, and no state is
+// saved until then, so the previous state isn't overwritten.
+func (a *StaticAutoscaler) restoreSoftState(currentTime time.Time) {
+	state, err := a.softStateStore.Load()
+	if err != nil {
+		if a.startTime.Add(a.SoftStateMaxAge).Before(currentTime) {
+			klog.Errorf("Failed to load soft state, giving up: %v", err)
+			a.softStateRestored = true
+		} else {
+			klog.Errorf("Failed to load soft state: %v", err)
+		}
+		return
+	}
+	a.softStateRestored = true
+	if state == nil {
+		klog.V(1).Info("No soft state saved, starting from scratch")
+		return
+	}
+	if state.Timestamp.Add(a.SoftStateMaxAge).Before(currentTime) {
+		klog.V(1).Infof("Ignoring soft state saved at %v, it is too old", state.Timestamp)
+		return
+	}
+	a.scaleDown.restoreSoftState(state, currentTime)
+	a.clusterStateRegistry.RestoreSoftState(state.ClusterState, currentTime)
+	klog.V(1).Infof("Restored soft state saved at %v", state.Timestamp)
+}
+
+// checkpointSoftState saves the current soft state.
+func (a *StaticAutoscaler) checkpointSoftState(currentTime time.Time) {
+	state := &softstate.State{
+		Timestamp:    currentTime,
+		ClusterState: a.clusterStateRegistry.GetSoftState(),
+	}
+	a.scaleDown.getSoftState(state)
+	if err := a.softStateStore.Save(state); err != nil {
+		klog.Errorf("Failed to save soft state: %v", err)
+		return
+	}
+	a.lastSoftStateCheckpoint = currentTime
+}
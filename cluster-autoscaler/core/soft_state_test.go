@@ -0,0 +1,130 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	testprovider "k8s.io/autoscaler/cluster-autoscaler/cloudprovider/test"
+	"k8s.io/autoscaler/cluster-autoscaler/clusterstate"
+	"k8s.io/autoscaler/cluster-autoscaler/config"
+	"k8s.io/autoscaler/cluster-autoscaler/metrics"
+	"k8s.io/autoscaler/cluster-autoscaler/softstate"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+type memorySoftStateStore struct {
+	state   *softstate.State
+	loadErr error
+}
+
+func (s *memorySoftStateStore) Save(state *softstate.State) error {
+	s.state = state
+	return nil
+}
+
+func (s *memorySoftStateStore) Load() (*softstate.State, error) {
+	return s.state, s.loadErr
+}
+
+func newSoftStateTestProvider() *testprovider.TestCloudProvider {
+	provider := testprovider.NewTestCloudProvider(nil, nil)
+	provider.AddNodeGroup("ng1", 1, 10, 3)
+	provider.AddNodeGroup("ng2", 1, 10, 1)
+	return provider
+}
+
+func newSoftStateTestAutoscaler(provider *testprovider.TestCloudProvider, store softstate.Store, startTime time.Time) *StaticAutoscaler {
+	options := config.AutoscalingOptions{
+		ScaleDownUnneededTime:       10 * time.Minute,
+		MaxNodeProvisionTime:        15 * time.Minute,
+		SoftStateCheckpointInterval: time.Minute,
+		SoftStateMaxAge:             10 * time.Minute,
+		ScaleDownBudgetWindow:       time.Hour,
+	}
+	context := NewScaleTestAutoscalingContext(options, &fake.Clientset{}, nil, provider, nil)
+	clusterState := clusterstate.NewClusterStateRegistry(provider, clusterstate.ClusterStateRegistryConfig{
+		MaxNodeProvisionTime: options.MaxNodeProvisionTime,
+	}, context.LogRecorder, newBackoff())
+	return &StaticAutoscaler{
+		AutoscalingContext:   &context,
+		clusterStateRegistry: clusterState,
+		scaleDown:            NewScaleDown(&context, clusterState),
+		startTime:            startTime,
+		softStateStore:       store,
+	}
+}
+
+func TestSoftStateCheckpointAndRestore(t *testing.T) {
+	now := time.Now()
+	provider := newSoftStateTestProvider()
+	store := &memorySoftStateStore{}
+	autoscaler := newSoftStateTestAutoscaler(provider, store, now)
+	autoscaler.restoreSoftState(now)
+	assert.True(t, autoscaler.softStateRestored)
+
+	autoscaler.scaleDown.unneededNodes["n1"] = now.Add(-8 * time.Minute)
+	autoscaler.scaleDown.usageTracker.RegisterUsage("n1", "n2", now.Add(-time.Minute))
+	autoscaler.scaleDown.scaleDownBudgetTracker.RegisterDeletion("ng1", now.Add(-time.Minute))
+	autoscaler.clusterStateRegistry.RegisterOrUpdateScaleUp(provider.GetNodeGroup("ng1"), 2, now)
+	autoscaler.clusterStateRegistry.RegisterFailedScaleUp(provider.GetNodeGroup("ng2"), metrics.Timeout, now)
+	autoscaler.checkpointSoftState(now)
+	assert.Equal(t, now, autoscaler.lastSoftStateCheckpoint)
+	assert.Equal(t, now, store.state.Timestamp)
+
+	// A new leader picks up the state.
+	restarted := newSoftStateTestAutoscaler(provider, store, now.Add(2*time.Minute))
+	restarted.restoreSoftState(now.Add(2 * time.Minute))
+	assert.True(t, restarted.softStateRestored)
+	assert.Equal(t, now.Add(-8*time.Minute), restarted.scaleDown.unneededNodes["n1"])
+	_, found := restarted.scaleDown.usageTracker.Get("n2")
+	assert.True(t, found)
+	assert.Equal(t, 1, restarted.scaleDown.scaleDownBudgetTracker.DeletionsInWindow("ng1", now.Add(2*time.Minute)))
+	assert.Equal(t, 2, restarted.clusterStateRegistry.GetSoftState().ScaleUpRequests["ng1"].Increase)
+	assert.False(t, restarted.clusterStateRegistry.IsNodeGroupSafeToScaleUp(provider.GetNodeGroup("ng2"), now.Add(2*time.Minute)))
+}
+
+func TestSoftStateTooOld(t *testing.T) {
+	now := time.Now()
+	provider := newSoftStateTestProvider()
+	store := &memorySoftStateStore{}
+	autoscaler := newSoftStateTestAutoscaler(provider, store, now)
+	autoscaler.scaleDown.unneededNodes["n1"] = now
+	autoscaler.checkpointSoftState(now)
+
+	restarted := newSoftStateTestAutoscaler(provider, store, now.Add(time.Hour))
+	restarted.restoreSoftState(now.Add(time.Hour))
+	assert.True(t, restarted.softStateRestored)
+	assert.Equal(t, 0, len(restarted.scaleDown.unneededNodes))
+}
+
+func TestSoftStateLoadError(t *testing.T) {
+	now := time.Now()
+	provider := newSoftStateTestProvider()
+	store := &memorySoftStateStore{loadErr: fmt.Errorf("API error")}
+	autoscaler := newSoftStateTestAutoscaler(provider, store, now)
+
+	// The restore is retried until saved state would be too old anyway.
+	autoscaler.restoreSoftState(now)
+	assert.False(t, autoscaler.softStateRestored)
+	autoscaler.restoreSoftState(now.Add(11 * time.Minute))
+	assert.True(t, autoscaler.softStateRestored)
+}
@@ -22,17 +22,22 @@ import (
 
 	apiv1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/autoscaler/cluster-autoscaler/capacityprofiles"
 	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider"
 	"k8s.io/autoscaler/cluster-autoscaler/clusterstate"
 	"k8s.io/autoscaler/cluster-autoscaler/clusterstate/utils"
 	"k8s.io/autoscaler/cluster-autoscaler/config"
 	"k8s.io/autoscaler/cluster-autoscaler/context"
+	"k8s.io/autoscaler/cluster-autoscaler/debuginfo"
 	"k8s.io/autoscaler/cluster-autoscaler/estimator"
 	"k8s.io/autoscaler/cluster-autoscaler/expander"
+	"k8s.io/autoscaler/cluster-autoscaler/maintenancewindows"
 	"k8s.io/autoscaler/cluster-autoscaler/metrics"
 	ca_processors "k8s.io/autoscaler/cluster-autoscaler/processors"
 	"k8s.io/autoscaler/cluster-autoscaler/processors/status"
+	"k8s.io/autoscaler/cluster-autoscaler/scaledownorder"
 	"k8s.io/autoscaler/cluster-autoscaler/simulator"
+	"k8s.io/autoscaler/cluster-autoscaler/softstate"
 	"k8s.io/autoscaler/cluster-autoscaler/utils/backoff"
 	"k8s.io/autoscaler/cluster-autoscaler/utils/deletetaint"
 	"k8s.io/autoscaler/cluster-autoscaler/utils/errors"
@@ -71,6 +76,18 @@ type StaticAutoscaler struct {
 	// Caches nodeInfo computed for previously seen nodes
 	nodeInfoCache map[string]*schedulernodeinfo.NodeInfo
 	ignoredTaints taintKeySet
+	// Scheduled capacity profiles, nil if disabled.
+	capacityProfiles *capacityprofiles.Manager
+	// Scale-down maintenance windows, nil if disabled.
+	maintenanceWindows *maintenancewindows.Manager
+	// Node consolidation, nil if disabled.
+	consolidation *Consolidation
+	// Store keeping soft state across restarts, nil if disabled.
+	softStateStore          softstate.Store
+	softStateRestored       bool
+	lastSoftStateCheckpoint time.Time
+	// Recorder of the state served by the debug endpoints, nil if disabled.
+	debugInfo *debuginfo.Recorder
 }
 
 type staticAutoscalerProcessorCallbacks struct {
@@ -100,10 +117,19 @@ func NewStaticAutoscaler(
 	cloudProvider cloudprovider.CloudProvider,
 	expanderStrategy expander.Strategy,
 	estimatorBuilder estimator.EstimatorBuilder,
-	backoff backoff.Backoff) *StaticAutoscaler {
+	scaleDownOrder scaledownorder.Strategy,
+	backoff backoff.Backoff,
+	capacityProfiles *capacityprofiles.Manager,
+	maintenanceWindows *maintenancewindows.Manager,
+	softStateStore softstate.Store,
+	debugInfo *debuginfo.Recorder) *StaticAutoscaler {
+
+	if capacityProfiles != nil {
+		cloudProvider = capacityprofiles.NewCloudProvider(cloudProvider, capacityProfiles)
+	}
 
 	processorCallbacks := newStaticAutoscalerProcessorCallbacks()
-	autoscalingContext := context.NewAutoscalingContext(opts, predicateChecker, autoscalingKubeClients, cloudProvider, expanderStrategy, estimatorBuilder, processorCallbacks)
+	autoscalingContext := context.NewAutoscalingContext(opts, predicateChecker, autoscalingKubeClients, cloudProvider, expanderStrategy, estimatorBuilder, scaleDownOrder, processorCallbacks)
 
 	clusterStateConfig := clusterstate.ClusterStateRegistryConfig{
 		MaxTotalUnreadyPercentage: opts.MaxTotalUnreadyPercentage,
@@ -120,6 +146,10 @@ func NewStaticAutoscaler(
 	clusterStateRegistry := clusterstate.NewClusterStateRegistry(autoscalingContext.CloudProvider, clusterStateConfig, autoscalingContext.LogRecorder, backoff)
 
 	scaleDown := NewScaleDown(autoscalingContext, clusterStateRegistry)
+	var consolidation *Consolidation
+	if opts.ConsolidationEnabled {
+		consolidation = NewConsolidation(autoscalingContext, clusterStateRegistry, scaleDown)
+	}
 
 	return &StaticAutoscaler{
 		AutoscalingContext:      autoscalingContext,
@@ -133,6 +163,11 @@ func NewStaticAutoscaler(
 		clusterStateRegistry:    clusterStateRegistry,
 		nodeInfoCache:           make(map[string]*schedulernodeinfo.NodeInfo),
 		ignoredTaints:           ignoredTaints,
+		capacityProfiles:        capacityProfiles,
+		maintenanceWindows:      maintenanceWindows,
+		consolidation:           consolidation,
+		softStateStore:          softStateStore,
+		debugInfo:               debugInfo,
 	}
 }
 
@@ -191,6 +226,31 @@ func (a *StaticAutoscaler) RunOnce(currentTime time.Time) errors.AutoscalerError
 		return errors.ToAutoscalerError(errors.CloudProviderError, err)
 	}
 
+	if a.softStateStore != nil && !a.softStateRestored {
+		a.restoreSoftState(currentTime)
+	}
+
+	if a.capacityProfiles != nil {
+		a.capacityProfiles.Refresh(currentTime)
+		a.clusterStateRegistry.SetCapacityProfiles(a.capacityProfiles.ActiveProfiles())
+	}
+
+	var maintenanceWindow *maintenancewindows.ActiveWindow
+	emptyNodesOnlyWindow := ""
+	if a.maintenanceWindows != nil {
+		a.maintenanceWindows.Refresh(currentTime)
+		maintenanceWindow = a.maintenanceWindows.ActiveWindow()
+	}
+	if maintenanceWindow != nil {
+		switch maintenanceWindow.Mode {
+		case maintenancewindows.ScaleDownDisabled:
+			a.processorCallbacks.DisableScaleDownForLoop()
+		case maintenancewindows.EmptyNodesOnly:
+			emptyNodesOnlyWindow = maintenanceWindow.Name
+		}
+	}
+	scaleDown.SetEmptyNodesOnlyWindow(emptyNodesOnlyWindow)
+
 	nodeInfosForGroups, autoscalerError := getNodeInfosForGroups(
 		readyNodes, a.nodeInfoCache, autoscalingContext.CloudProvider, autoscalingContext.ListerRegistry, daemonsets, autoscalingContext.PredicateChecker, a.ignoredTaints)
 	if autoscalerError != nil {
@@ -229,6 +289,10 @@ func (a *StaticAutoscaler) RunOnce(currentTime time.Time) errors.AutoscalerError
 		if err != nil {
 			klog.Errorf("AutoscalingStatusProcessor error: %v.", err)
 		}
+
+		if a.softStateStore != nil && a.softStateRestored && a.lastSoftStateCheckpoint.Add(a.SoftStateCheckpointInterval).Before(currentTime) {
+			a.checkpointSoftState(currentTime)
+		}
 	}()
 
 	// Check if there are any nodes that failed to register in Kubernetes
@@ -276,6 +340,23 @@ func (a *StaticAutoscaler) RunOnce(currentTime time.Time) errors.AutoscalerError
 		return nil
 	}
 
+	if a.capacityProfiles != nil {
+		scaledUp, err := scaleUpToMinSize(autoscalingContext, a.clusterStateRegistry, currentTime)
+		if err != nil {
+			// Failed node groups are backed off, the loop goes on for the other ones.
+			klog.Errorf("Failed to scale up some node groups to their min size: %v", err)
+		}
+		if scaledUp && a.DryRun {
+			// Target sizes don't change in dry run mode, so the rest of the loop is
+			// evaluated as it would be once the node groups reach their min size.
+			klog.V(0).Infof("Dry run: some node groups would be scaled up to their min size")
+		} else if scaledUp {
+			a.lastScaleUpTime = currentTime
+			klog.V(0).Infof("Some node groups were scaled up to their min size, skipping the iteration")
+			return nil
+		}
+	}
+
 	metrics.UpdateLastTime(metrics.Autoscaling, time.Now())
 
 	unschedulablePods, err := unschedulablePodLister.List()
@@ -342,7 +423,9 @@ func (a *StaticAutoscaler) RunOnce(currentTime time.Time) errors.AutoscalerError
 			klog.Errorf("Failed to scale up: %v", typedErr)
 			return typedErr
 		}
-		if scaleUpStatus.Result == status.ScaleUpSuccessful {
+		// Target sizes don't change in dry run mode, so scale-down is evaluated
+		// after a scale-up too instead of waiting for nodes that never come.
+		if scaleUpStatus.Result == status.ScaleUpSuccessful && !a.DryRun {
 			a.lastScaleUpTime = currentTime
 			// No scale down in this iteration.
 			scaleDownStatus.Result = status.ScaleDownInCooldown
@@ -363,6 +446,9 @@ func (a *StaticAutoscaler) RunOnce(currentTime time.Time) errors.AutoscalerError
 		klog.V(4).Infof("Calculating unneeded nodes")
 
 		scaleDown.CleanUp(currentTime)
+		if a.BalanceSimilarNodeGroups {
+			scaleDown.SetSimilarNodeGroups(findSimilarNodeGroups(autoscalingContext, a.processors.NodeGroupSetProcessor, nodeInfosForGroups))
+		}
 		potentiallyUnneeded := getPotentiallyUnneededNodes(autoscalingContext, allNodes)
 
 		// We use scheduledPods (not originalScheduledPods) here, so artificial scheduled pods introduced by processors
@@ -375,6 +461,11 @@ func (a *StaticAutoscaler) RunOnce(currentTime time.Time) errors.AutoscalerError
 		}
 
 		metrics.UpdateDurationFromStart(metrics.FindUnneeded, unneededStart)
+		scaleDownStatus.UnremovableNodes = scaleDown.GetUnremovableNodes()
+
+		if a.debugInfo != nil {
+			a.debugInfo.UpdateScaleDown(scaleDown.GetUnneededNodes(), currentTime)
+		}
 
 		if klog.V(4) {
 			for key, val := range scaleDown.unneededNodes {
@@ -386,18 +477,45 @@ func (a *StaticAutoscaler) RunOnce(currentTime time.Time) errors.AutoscalerError
 			a.lastScaleUpTime.Add(a.ScaleDownDelayAfterAdd).After(currentTime) ||
 			a.lastScaleDownFailTime.Add(a.ScaleDownDelayAfterFailure).After(currentTime) ||
 			a.lastScaleDownDeleteTime.Add(a.ScaleDownDelayAfterDelete).After(currentTime)
+		// Scale-down goes on while fewer than MaxDrainParallelism non empty nodes are drained.
+		freeDrainSlots := scaleDown.FreeDrainSlots()
 		// In dry run only utilization is updated
-		calculateUnneededOnly := scaleDownInCooldown || scaleDown.nodeDeletionTracker.IsNonEmptyNodeDeleteInProgress()
+		calculateUnneededOnly := scaleDownInCooldown || freeDrainSlots <= 0
 
 		klog.V(4).Infof("Scale down status: unneededOnly=%v lastScaleUpTime=%s "+
-			"lastScaleDownDeleteTime=%v lastScaleDownFailTime=%s scaleDownForbidden=%v isDeleteInProgress=%v",
+			"lastScaleDownDeleteTime=%v lastScaleDownFailTime=%s scaleDownForbidden=%v freeDrainSlots=%v",
 			calculateUnneededOnly, a.lastScaleUpTime, a.lastScaleDownDeleteTime, a.lastScaleDownFailTime,
-			a.processorCallbacks.disableScaleDownForLoop, scaleDown.nodeDeletionTracker.IsNonEmptyNodeDeleteInProgress())
+			a.processorCallbacks.disableScaleDownForLoop, freeDrainSlots)
 
-		if scaleDownInCooldown {
+		if scaleDownInCooldown && maintenanceWindow != nil && maintenanceWindow.Mode == maintenancewindows.ScaleDownDisabled {
+			klog.V(1).Infof("Scale-down is disabled during maintenance window %s", maintenanceWindow.Name)
+			scaleDownStatus.Result = status.ScaleDownInMaintenanceWindow
+			scaleDownStatus.MaintenanceWindow = maintenanceWindow.Name
+		} else if scaleDownInCooldown {
 			scaleDownStatus.Result = status.ScaleDownInCooldown
-		} else if scaleDown.nodeDeletionTracker.IsNonEmptyNodeDeleteInProgress() {
+		} else if freeDrainSlots <= 0 {
 			scaleDownStatus.Result = status.ScaleDownInProgress
+		} else if a.consolidation != nil && a.consolidation.InProgress() && maintenanceWindow != nil {
+			// Consolidation drains nodes, so it waits until the window closes.
+			klog.V(1).Infof("Consolidation is paused during maintenance window %s", maintenanceWindow.Name)
+			scaleDownStatus.Result = status.ScaleDownInMaintenanceWindow
+			scaleDownStatus.MaintenanceWindow = maintenanceWindow.Name
+		} else if a.consolidation != nil && a.consolidation.InProgress() {
+			// Regular scale-down waits until the consolidation is done, so it doesn't
+			// remove the new nodes before the replaced ones are drained.
+			scaleDownStatus, typedErr := a.consolidation.Continue(allNodes, scaleDownPods(originalScheduledPods, scheduledPods), pdbs, currentTime)
+			scaleDownStatus.UnremovableNodes = scaleDown.GetUnremovableNodes()
+
+			if a.processors != nil && a.processors.ScaleDownStatusProcessor != nil {
+				a.processors.ScaleDownStatusProcessor.Process(autoscalingContext, scaleDownStatus)
+				scaleDownStatusProcessorAlreadyCalled = true
+			}
+
+			if typedErr != nil {
+				klog.Errorf("Failed to consolidate: %v", typedErr)
+				a.lastScaleDownFailTime = currentTime
+				return typedErr
+			}
 		} else {
 			klog.V(4).Infof("Starting scale down")
 
@@ -407,7 +525,20 @@ func (a *StaticAutoscaler) RunOnce(currentTime time.Time) errors.AutoscalerError
 
 			scaleDownStart := time.Now()
 			metrics.UpdateLastTime(metrics.ScaleDown, scaleDownStart)
-			scaleDownStatus, typedErr := scaleDown.TryToScaleDown(allNodes, originalScheduledPods, pdbs, currentTime)
+			pods := scaleDownPods(originalScheduledPods, scheduledPods)
+			scaleDownStatus, typedErr := scaleDown.TryToScaleDown(allNodes, pods, pdbs, freeDrainSlots, currentTime)
+			if typedErr == nil && a.consolidation != nil && (scaleDownStatus.Result == status.ScaleDownNoNodeDeleted ||
+				scaleDownStatus.Result == status.ScaleDownNoUnneeded) {
+				started, consolidationErr := a.consolidation.TryToConsolidate(allNodes, pods, pdbs, nodeInfosForGroups, currentTime)
+				if consolidationErr != nil {
+					typedErr = consolidationErr
+					scaleDownStatus.Result = status.ScaleDownError
+				} else if started {
+					// The replaced nodes are drained in the following loops, so nothing
+					// else is done to the unneeded nodes in this one.
+					scaleDownStatus.Result = status.ScaleDownInProgress
+				}
+			}
 			metrics.UpdateDurationFromStart(metrics.ScaleDown, scaleDownStart)
 
 			if scaleDownStatus.Result == status.ScaleDownNodeDeleted {
@@ -436,6 +567,13 @@ func (a *StaticAutoscaler) RunOnce(currentTime time.Time) errors.AutoscalerError
 	return nil
 }
 
+// scaleDownPods returns the pods scale-down and consolidation keep room for: the
+// scheduled pods and the headroom pods, so nodes hosting the headroom are not
+// removed. Pods are copied, so the slice returned by the lister isn't modified.
+func scaleDownPods(originalScheduledPods, scheduledPods []*apiv1.Pod) []*apiv1.Pod {
+	return append(append([]*apiv1.Pod{}, originalScheduledPods...), simulator.HeadroomPods(scheduledPods)...)
+}
+
 func (a *StaticAutoscaler) deleteCreatedNodesWithErrors() {
 	// We always schedule deleting of incoming errornous nodes
 	// TODO[lukaszos] Consider adding logic to not retry delete every loop iteration
@@ -17,6 +17,7 @@ limitations under the License.
 package core
 
 import (
+	"fmt"
 	"reflect"
 	"strings"
 	"testing"
@@ -28,13 +29,18 @@ import (
 	testprovider "k8s.io/autoscaler/cluster-autoscaler/cloudprovider/test"
 	"k8s.io/autoscaler/cluster-autoscaler/clusterstate"
 	"k8s.io/autoscaler/cluster-autoscaler/config"
+	"k8s.io/autoscaler/cluster-autoscaler/context"
+	"k8s.io/autoscaler/cluster-autoscaler/dryrun"
 	"k8s.io/autoscaler/cluster-autoscaler/estimator"
+	"k8s.io/autoscaler/cluster-autoscaler/maintenancewindows"
+	"k8s.io/autoscaler/cluster-autoscaler/processors/status"
 	kube_util "k8s.io/autoscaler/cluster-autoscaler/utils/kubernetes"
 	. "k8s.io/autoscaler/cluster-autoscaler/utils/test"
 
 	appsv1 "k8s.io/api/apps/v1"
 	apiv1 "k8s.io/api/core/v1"
 	policyv1 "k8s.io/api/policy/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/client-go/kubernetes/fake"
 	v1appslister "k8s.io/client-go/listers/apps/v1"
@@ -120,6 +126,16 @@ func (m *onScaleDownMock) ScaleDown(id string, name string) error {
 	return args.Error(0)
 }
 
+type scaleDownStatusRecorder struct {
+	last *status.ScaleDownStatus
+}
+
+func (r *scaleDownStatusRecorder) Process(context *context.AutoscalingContext, status *status.ScaleDownStatus) {
+	r.last = status
+}
+
+func (r *scaleDownStatusRecorder) CleanUp() {}
+
 type onNodeGroupCreateMock struct {
 	mock.Mock
 }
@@ -259,6 +275,31 @@ func TestStaticAutoscalerRunOnce(t *testing.T) {
 	mock.AssertExpectationsForObjects(t, readyNodeListerMock, allNodeListerMock, scheduledPodMock, unschedulablePodMock,
 		podDisruptionBudgetListerMock, daemonSetListerMock, onScaleUpMock, onScaleDownMock)
 
+	// No scale down in a maintenance window.
+	readyNodeListerMock.On("List").Return([]*apiv1.Node{n1, n2}, nil).Once()
+	allNodeListerMock.On("List").Return([]*apiv1.Node{n1, n2}, nil).Once()
+	scheduledPodMock.On("List").Return([]*apiv1.Pod{p1}, nil).Twice()
+	unschedulablePodMock.On("List").Return([]*apiv1.Pod{}, nil).Once()
+	daemonSetListerMock.On("List", labels.Everything()).Return([]*appsv1.DaemonSet{}, nil).Once()
+	podDisruptionBudgetListerMock.On("List").Return([]*policyv1.PodDisruptionBudget{}, nil).Once()
+
+	configMapLister, listerErr := kube_util.NewTestConfigMapLister([]*apiv1.ConfigMap{{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "kube-system", Name: maintenancewindows.MaintenanceWindowsConfigMapName},
+		Data: map[string]string{maintenancewindows.ConfigMapKey: fmt.Sprintf("- name: freeze\n  start: %q\n  end: %q\n",
+			time.Now().Add(150*time.Minute).Format(time.RFC3339), time.Now().Add(170*time.Minute).Format(time.RFC3339))},
+	}})
+	assert.NoError(t, listerErr)
+	autoscaler.maintenanceWindows = maintenancewindows.NewManager(configMapLister.ConfigMaps("kube-system"), context.Recorder)
+	statusRecorder := &scaleDownStatusRecorder{}
+	autoscaler.processors.ScaleDownStatusProcessor = statusRecorder
+
+	err = autoscaler.RunOnce(time.Now().Add(160 * time.Minute))
+	assert.NoError(t, err)
+	assert.Equal(t, status.ScaleDownInMaintenanceWindow, statusRecorder.last.Result)
+	assert.Equal(t, "freeze", statusRecorder.last.MaintenanceWindow)
+	mock.AssertExpectationsForObjects(t, readyNodeListerMock, allNodeListerMock, scheduledPodMock, unschedulablePodMock,
+		podDisruptionBudgetListerMock, daemonSetListerMock, onScaleUpMock, onScaleDownMock)
+
 	// Scale down.
 	readyNodeListerMock.On("List").Return([]*apiv1.Node{n1, n2}, nil).Once()
 	allNodeListerMock.On("List").Return([]*apiv1.Node{n1, n2}, nil).Once()
@@ -270,6 +311,7 @@ func TestStaticAutoscalerRunOnce(t *testing.T) {
 
 	err = autoscaler.RunOnce(time.Now().Add(3 * time.Hour))
 	waitForDeleteToFinish(t, autoscaler.scaleDown)
+	assert.Equal(t, status.ScaleDownNodeDeleteStarted, statusRecorder.last.Result)
 	assert.NoError(t, err)
 	mock.AssertExpectationsForObjects(t, readyNodeListerMock, allNodeListerMock, scheduledPodMock, unschedulablePodMock,
 		podDisruptionBudgetListerMock, daemonSetListerMock, onScaleUpMock, onScaleDownMock)
@@ -304,6 +346,86 @@ func TestStaticAutoscalerRunOnce(t *testing.T) {
 		podDisruptionBudgetListerMock, daemonSetListerMock, onScaleUpMock, onScaleDownMock)
 }
 
+func TestStaticAutoscalerRunOnceDryRun(t *testing.T) {
+	n1 := BuildTestNode("n1", 1000, 1000)
+	SetNodeReadyState(n1, true, time.Now())
+	n2 := BuildTestNode("n2", 1000, 1000)
+	SetNodeReadyState(n2, true, time.Now())
+
+	p1 := BuildTestPod("p1", 600, 100)
+	p1.Spec.NodeName = "n1"
+	// p2 only fits on a node from ng2.
+	p2 := BuildTestPod("p2", 1500, 100)
+
+	tn := BuildTestNode("tn", 2000, 2000)
+	SetNodeReadyState(tn, true, time.Now())
+	tni := schedulernodeinfo.NewNodeInfo()
+	tni.SetNode(tn)
+
+	provider := testprovider.NewTestAutoprovisioningCloudProvider(nil, nil, nil, nil, nil,
+		map[string]*schedulernodeinfo.NodeInfo{"ng2": tni})
+	provider.AddNodeGroup("ng1", 1, 10, 2)
+	provider.AddNode("ng1", n1)
+	provider.AddNode("ng1", n2)
+	provider.AddNodeGroup("ng2", 0, 10, 0)
+	recorder := dryrun.NewRecorder()
+	dryRunProvider := dryrun.NewCloudProvider(provider, recorder)
+
+	options := config.AutoscalingOptions{
+		EstimatorName:                       estimator.BinpackingEstimatorName,
+		ScaleDownEnabled:                    true,
+		ScaleDownUtilizationThreshold:       0.5,
+		ScaleDownUnneededTime:               time.Minute,
+		ScaleDownDelayAfterAdd:              10 * time.Minute,
+		MaxNodesTotal:                       10,
+		MaxCoresTotal:                       10,
+		MaxMemoryTotal:                      100000,
+		MaxEmptyBulkDelete:                  10,
+		FilterOutSchedulablePodsUsesPacking: true,
+		DryRun:                              true,
+	}
+	processorCallbacks := newStaticAutoscalerProcessorCallbacks()
+	context := NewScaleTestAutoscalingContext(options, &fake.Clientset{}, nil, dryRunProvider, processorCallbacks)
+	daemonSetLister, err := kube_util.NewTestDaemonSetLister(nil)
+	assert.NoError(t, err)
+	context.ListerRegistry = kube_util.NewListerRegistry(kube_util.NewTestNodeLister([]*apiv1.Node{n1, n2}),
+		kube_util.NewTestNodeLister([]*apiv1.Node{n1, n2}), kube_util.NewTestPodLister([]*apiv1.Pod{p1}),
+		kube_util.NewTestPodLister([]*apiv1.Pod{p2}), kube_util.NewTestPodDisruptionBudgetLister(nil), daemonSetLister,
+		nil, nil, nil, nil)
+
+	clusterState := clusterstate.NewClusterStateRegistry(dryRunProvider, clusterstate.ClusterStateRegistryConfig{
+		OkTotalUnreadyCount: 1,
+	}, context.LogRecorder, newBackoff())
+	statusRecorder := &scaleDownStatusRecorder{}
+	processors := NewTestProcessors()
+	processors.ScaleDownStatusProcessor = statusRecorder
+	now := time.Now()
+	autoscaler := &StaticAutoscaler{
+		AutoscalingContext:   &context,
+		clusterStateRegistry: clusterState,
+		lastScaleUpTime:      now.Add(-time.Hour),
+		scaleDown:            NewScaleDown(&context, clusterState),
+		processors:           processors,
+		processorCallbacks:   processorCallbacks,
+		initialized:          true,
+	}
+
+	// The scale-up is only recorded, so the next loop neither waits for it nor
+	// skips scale-down.
+	for i, ts := range []time.Time{now, now.Add(2 * time.Minute)} {
+		err = autoscaler.RunOnce(ts)
+		assert.NoError(t, err)
+		actions, _ := recorder.Flush()
+		assert.Equal(t, []dryrun.CloudProviderAction{{Operation: "IncreaseSize", NodeGroup: "ng2", Delta: 1}}, actions, "loop %d", i)
+		assert.False(t, clusterState.IsNodeGroupScalingUp("ng2"), "loop %d", i)
+	}
+	assert.Equal(t, now.Add(-time.Hour), autoscaler.lastScaleUpTime)
+	assert.Equal(t, status.ScaleDownNodeDeleteStarted, statusRecorder.last.Result)
+	if assert.Equal(t, 1, len(statusRecorder.last.ScaledDownNodes)) {
+		assert.Equal(t, "n2", statusRecorder.last.ScaledDownNodes[0].Node.Name)
+	}
+}
+
 func TestStaticAutoscalerRunOnceWithAutoprovisionedEnabled(t *testing.T) {
 	readyNodeListerMock := &nodeListerMock{}
 	allNodeListerMock := &nodeListerMock{}
@@ -25,6 +25,7 @@ import (
 	appsv1 "k8s.io/api/apps/v1"
 	apiv1 "k8s.io/api/core/v1"
 	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
 	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider"
 	"k8s.io/autoscaler/cluster-autoscaler/clusterstate"
 	"k8s.io/autoscaler/cluster-autoscaler/clusterstate/utils"
@@ -180,6 +181,14 @@ func checkPodsSchedulableOnNode(context *context.AutoscalingContext, pods []*api
 				klog.V(2).Infof("Pod %s can't be scheduled on %s, predicate failed: %v", pod.Name, nodeGroupId, err.VerboseError())
 			}
 		}
+		// Pods of the same controller may use different volumes, like stateful set pods
+		// do, so volume topology isn't cached.
+		if err == nil {
+			if err = context.PredicateChecker.CheckVolumeTopology(pod, nodeInfo.Node()); err != nil {
+				glogx.V(2).UpTo(loggingQuota).Infof("Pod %s can't be scheduled on %s: %v", pod.Name, nodeGroupId, err.VerboseError())
+				schedulingErrors[pod] = err
+			}
+		}
 	}
 
 	glogx.V(2).Over(loggingQuota).Infof("%v other pods can't be scheduled on %s.", -loggingQuota.Left(), nodeGroupId)
@@ -482,6 +491,60 @@ func fixNodeGroupSize(context *context.AutoscalingContext, clusterStateRegistry
 	return fixed, nil
 }
 
+// Increases the target size of node groups that are below their min size, which
+// is raised by active capacity profiles. Node groups that are backed off are
+// skipped and MaxNodesTotal is respected. A node group failing to scale up is
+// registered as such and doesn't stop the other ones, errors are returned
+// together. Returns true if any node group was scaled up.
+func scaleUpToMinSize(context *context.AutoscalingContext, clusterStateRegistry *clusterstate.ClusterStateRegistry, currentTime time.Time) (bool, error) {
+	nodeGroupSize := getNodeGroupSizeMap(context.CloudProvider)
+	totalSize := 0
+	for _, size := range nodeGroupSize {
+		totalSize += size
+	}
+
+	scaledUp := false
+	var errs []error
+	for _, nodeGroup := range context.CloudProvider.NodeGroups() {
+		size, found := nodeGroupSize[nodeGroup.Id()]
+		if !found {
+			continue
+		}
+		minSize := nodeGroup.MinSize()
+		delta := minSize - size
+		if delta <= 0 {
+			continue
+		}
+		if !clusterStateRegistry.IsNodeGroupSafeToScaleUp(nodeGroup, currentTime) {
+			klog.Warningf("Node group %s is below its min size %d but isn't safe to scale up", nodeGroup.Id(), minSize)
+			continue
+		}
+		if context.MaxNodesTotal > 0 && totalSize+delta > context.MaxNodesTotal {
+			delta = context.MaxNodesTotal - totalSize
+			if delta <= 0 {
+				klog.Warningf("Node group %s is below its min size %d but max total nodes in cluster reached", nodeGroup.Id(), minSize)
+				continue
+			}
+		}
+
+		klog.V(0).Infof("Scale-up: setting group %s size to %d to reach its min size %d", nodeGroup.Id(), size+delta, minSize)
+		context.LogRecorder.Eventf(apiv1.EventTypeNormal, "ScaledUpGroup",
+			"Scale-up: setting group %s size to %d to reach its min size %d", nodeGroup.Id(), size+delta, minSize)
+		if err := nodeGroup.IncreaseSize(delta); err != nil {
+			klog.Errorf("Failed to increase node group %s to its min size: %v", nodeGroup.Id(), err)
+			context.LogRecorder.Eventf(apiv1.EventTypeWarning, "FailedToScaleUpGroup", "Scale-up failed for group %s: %v", nodeGroup.Id(), err)
+			clusterStateRegistry.RegisterFailedScaleUp(nodeGroup, metrics.APIError, currentTime)
+			errs = append(errs, fmt.Errorf("failed to increase %s: %v", nodeGroup.Id(), err))
+			continue
+		}
+		clusterStateRegistry.RegisterOrUpdateScaleUp(nodeGroup, delta, currentTime)
+		metrics.RegisterScaleUp(delta, gpu.MetricsNoGPU)
+		totalSize += delta
+		scaledUp = true
+	}
+	return scaledUp, utilerrors.NewAggregate(errs)
+}
+
 // getPotentiallyUnneededNodes returns nodes that are:
 // - managed by the cluster autoscaler
 // - in groups with size > min size
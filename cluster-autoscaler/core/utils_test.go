@@ -570,6 +570,121 @@ func TestRemoveFixNodeTargetSize(t *testing.T) {
 	assert.Equal(t, "ng1/-2", change)
 }
 
+func TestScaleUpToMinSize(t *testing.T) {
+	sizeChanges := make(chan string, 10)
+	now := time.Now()
+
+	provider := testprovider.NewTestCloudProvider(func(nodegroup string, delta int) error {
+		sizeChanges <- fmt.Sprintf("%s/%d", nodegroup, delta)
+		return nil
+	}, nil)
+	// Test node groups accept target sizes below min size, as can happen once
+	// a capacity profile raises it.
+	provider.AddNodeGroup("ng1", 3, 10, 1)
+	provider.AddNodeGroup("ng2", 4, 10, 1)
+	provider.AddNodeGroup("ng3", 1, 10, 2)
+	var nodes []*apiv1.Node
+	for nodeGroup, count := range map[string]int{"ng1": 1, "ng2": 1, "ng3": 2} {
+		for i := 0; i < count; i++ {
+			node := BuildTestNode(fmt.Sprintf("%s-%d", nodeGroup, i), 1000, 1000)
+			node.Spec.ProviderID = node.Name
+			SetNodeReadyState(node, true, now.Add(-time.Hour))
+			provider.AddNode(nodeGroup, node)
+			nodes = append(nodes, node)
+		}
+	}
+
+	fakeClient := &fake.Clientset{}
+	fakeLogRecorder, _ := utils.NewStatusMapRecorder(fakeClient, "kube-system", kube_record.NewFakeRecorder(5), false)
+	clusterState := clusterstate.NewClusterStateRegistry(provider, clusterstate.ClusterStateRegistryConfig{
+		MaxTotalUnreadyPercentage: 10,
+		OkTotalUnreadyCount:       1,
+	}, fakeLogRecorder, newBackoff())
+	err := clusterState.UpdateNodes(nodes, nil, now)
+	assert.NoError(t, err)
+
+	context := &context.AutoscalingContext{
+		AutoscalingOptions: config.AutoscalingOptions{
+			MaxNodesTotal: 9,
+		},
+		CloudProvider: provider,
+		AutoscalingKubeClients: context.AutoscalingKubeClients{
+			LogRecorder: fakeLogRecorder,
+		},
+	}
+
+	scaledUp, err := scaleUpToMinSize(context, clusterState, now)
+	assert.NoError(t, err)
+	assert.True(t, scaledUp)
+	changes := []string{getStringFromChan(sizeChanges), getStringFromChan(sizeChanges)}
+	assert.ElementsMatch(t, []string{"ng1/2", "ng2/3"}, changes)
+	assert.Equal(t, nothingReturned, getStringFromChanImmediately(sizeChanges))
+	assert.NoError(t, clusterState.UpdateNodes(nodes, nil, now))
+	assert.True(t, clusterState.IsNodeGroupScalingUp("ng1"))
+
+	// Max total nodes reached.
+	provider.AddNodeGroup("ng4", 3, 10, 0)
+	assert.NoError(t, clusterState.UpdateNodes(nodes, nil, now))
+	scaledUp, err = scaleUpToMinSize(context, clusterState, now)
+	assert.NoError(t, err)
+	assert.False(t, scaledUp)
+	assert.Equal(t, nothingReturned, getStringFromChanImmediately(sizeChanges))
+
+	// Scaled up only to max total nodes.
+	context.MaxNodesTotal = 10
+	scaledUp, err = scaleUpToMinSize(context, clusterState, now)
+	assert.NoError(t, err)
+	assert.True(t, scaledUp)
+	assert.Equal(t, "ng4/1", getStringFromChan(sizeChanges))
+}
+
+func TestScaleUpToMinSizeFailedNodeGroup(t *testing.T) {
+	sizeChanges := make(chan string, 10)
+	now := time.Now()
+
+	provider := testprovider.NewTestCloudProvider(func(nodegroup string, delta int) error {
+		if nodegroup == "ng1" {
+			return fmt.Errorf("quota exceeded")
+		}
+		sizeChanges <- fmt.Sprintf("%s/%d", nodegroup, delta)
+		return nil
+	}, nil)
+	provider.AddNodeGroup("ng1", 3, 10, 1)
+	provider.AddNodeGroup("ng2", 4, 10, 1)
+	var nodes []*apiv1.Node
+	for _, nodeGroup := range []string{"ng1", "ng2"} {
+		node := BuildTestNode(nodeGroup+"-0", 1000, 1000)
+		node.Spec.ProviderID = node.Name
+		SetNodeReadyState(node, true, now.Add(-time.Hour))
+		provider.AddNode(nodeGroup, node)
+		nodes = append(nodes, node)
+	}
+
+	fakeClient := &fake.Clientset{}
+	fakeLogRecorder, _ := utils.NewStatusMapRecorder(fakeClient, "kube-system", kube_record.NewFakeRecorder(5), false)
+	clusterState := clusterstate.NewClusterStateRegistry(provider, clusterstate.ClusterStateRegistryConfig{
+		MaxTotalUnreadyPercentage: 10,
+		OkTotalUnreadyCount:       1,
+	}, fakeLogRecorder, newBackoff())
+	assert.NoError(t, clusterState.UpdateNodes(nodes, nil, now))
+
+	context := &context.AutoscalingContext{
+		CloudProvider: provider,
+		AutoscalingKubeClients: context.AutoscalingKubeClients{
+			LogRecorder: fakeLogRecorder,
+		},
+	}
+
+	// ng1 fails, ng2 is still scaled up.
+	scaledUp, err := scaleUpToMinSize(context, clusterState, now)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to increase ng1")
+	assert.True(t, scaledUp)
+	assert.Equal(t, "ng2/3", getStringFromChan(sizeChanges))
+	assert.Equal(t, nothingReturned, getStringFromChanImmediately(sizeChanges))
+	assert.False(t, clusterState.IsNodeGroupSafeToScaleUp(provider.GetNodeGroup("ng1"), now))
+}
+
 func TestGetPotentiallyUnneededNodes(t *testing.T) {
 	ng1_1 := BuildTestNode("ng1-1", 1000, 1000)
 	ng1_2 := BuildTestNode("ng1-2", 1000, 1000)
@@ -0,0 +1,89 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package debuginfo
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"k8s.io/klog"
+)
+
+const (
+	// NodeGroupsPath lists node groups with their sizes and backoff.
+	NodeGroupsPath = "/debug/nodegroups"
+	// UnneededNodesPath lists unneeded nodes and how long they are unneeded.
+	UnneededNodesPath = "/debug/unneeded"
+	// UnremovableNodesPath lists unremovable nodes with the reasons.
+	UnremovableNodesPath = "/debug/unremovable"
+	// ScaleUpPath shows the last scale-up status.
+	ScaleUpPath = "/debug/scaleup"
+	// ScaleDownPath shows the last scale-down status.
+	ScaleDownPath = "/debug/scaledown"
+	// PodsPath followed by <namespace>/<name> explains why a pending pod does or doesn't trigger scale-up.
+	PodsPath = "/debug/pods/"
+)
+
+// RegisterHandlers adds the debug endpoints to the given mux.
+func (r *Recorder) RegisterHandlers(mux *http.ServeMux) {
+	mux.HandleFunc(NodeGroupsPath, r.serveJSON(func() interface{} { return r.nodeGroups }))
+	mux.HandleFunc(UnneededNodesPath, r.serveJSON(func() interface{} { return r.unneededNodes }))
+	mux.HandleFunc(UnremovableNodesPath, r.serveJSON(func() interface{} { return r.unremovableNodes }))
+	mux.HandleFunc(ScaleUpPath, r.serveJSON(func() interface{} { return r.scaleUp }))
+	mux.HandleFunc(ScaleDownPath, r.serveJSON(func() interface{} { return r.scaleDown }))
+	mux.HandleFunc(PodsPath, r.servePod)
+}
+
+// serveJSON returns a handler writing the value returned by get, which is
+// called with the recorder locked.
+func (r *Recorder) serveJSON(get func() interface{}) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		r.Lock()
+		data, err := json.Marshal(get())
+		r.Unlock()
+		writeJSON(w, data, err)
+	}
+}
+
+func (r *Recorder) servePod(w http.ResponseWriter, req *http.Request) {
+	name := strings.TrimPrefix(req.URL.Path, PodsPath)
+	if strings.Count(name, "/") != 1 {
+		http.Error(w, fmt.Sprintf("expected %s<namespace>/<name>", PodsPath), http.StatusBadRequest)
+		return
+	}
+	r.Lock()
+	pod, found := r.pods[name]
+	data, err := json.Marshal(pod)
+	r.Unlock()
+	if !found {
+		http.Error(w, fmt.Sprintf("pod %s wasn't pending in the last loop", name), http.StatusNotFound)
+		return
+	}
+	writeJSON(w, data, err)
+}
+
+func writeJSON(w http.ResponseWriter, data []byte, err error) {
+	if err != nil {
+		klog.Errorf("Failed to encode debug info: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
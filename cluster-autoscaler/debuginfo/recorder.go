@@ -0,0 +1,259 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package debuginfo keeps the state of the last autoscaling loop and serves it
+// as read-only JSON for live debugging.
+package debuginfo
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"k8s.io/autoscaler/cluster-autoscaler/clusterstate"
+	"k8s.io/autoscaler/cluster-autoscaler/clusterstate/api"
+	"k8s.io/autoscaler/cluster-autoscaler/context"
+	"k8s.io/autoscaler/cluster-autoscaler/processors/status"
+	"k8s.io/klog"
+)
+
+// NodeGroup describes the size and backoff of a node group.
+type NodeGroup struct {
+	Id         string       `json:"id"`
+	MinSize    int          `json:"minSize"`
+	MaxSize    int          `json:"maxSize"`
+	TargetSize int          `json:"targetSize"`
+	Backoff    *api.Backoff `json:"backoff,omitempty"`
+}
+
+// UnneededNode describes a node that is a candidate for scale-down.
+type UnneededNode struct {
+	Node        string    `json:"node"`
+	Since       time.Time `json:"since"`
+	UnneededFor string    `json:"unneededFor"`
+}
+
+// UnremovableNode describes a node that can't be removed.
+type UnremovableNode struct {
+	Node    string `json:"node"`
+	Reason  string `json:"reason"`
+	Message string `json:"message,omitempty"`
+}
+
+// ScaleUpStatus describes the result of the last scale-up.
+type ScaleUpStatus struct {
+	Result                  string             `json:"result"`
+	NodeGroups              []ScaleUpNodeGroup `json:"nodeGroups,omitempty"`
+	PodsTriggeredScaleUp    []string           `json:"podsTriggeredScaleUp,omitempty"`
+	PodsRemainUnschedulable []string           `json:"podsRemainUnschedulable,omitempty"`
+	PodsAwaitEvaluation     []string           `json:"podsAwaitEvaluation,omitempty"`
+}
+
+// ScaleUpNodeGroup describes the resize of a single node group.
+type ScaleUpNodeGroup struct {
+	NodeGroup   string `json:"nodeGroup"`
+	CurrentSize int    `json:"currentSize"`
+	NewSize     int    `json:"newSize"`
+	MaxSize     int    `json:"maxSize"`
+}
+
+// ScaleDownStatus describes the result of the last scale-down.
+type ScaleDownStatus struct {
+	Result string          `json:"result"`
+	Nodes  []ScaleDownNode `json:"nodes,omitempty"`
+}
+
+// ScaleDownNode describes a single node removed in the last scale-down.
+type ScaleDownNode struct {
+	Node        string   `json:"node"`
+	NodeGroup   string   `json:"nodeGroup,omitempty"`
+	Utilization float64  `json:"utilization"`
+	EvictedPods []string `json:"evictedPods,omitempty"`
+}
+
+// Pod scale-up states.
+const (
+	// PodTriggeredScaleUp means the pod triggered the last scale-up.
+	PodTriggeredScaleUp = "TriggeredScaleUp"
+	// PodRemainsUnschedulable means no node group could be scaled up for the pod.
+	PodRemainsUnschedulable = "RemainsUnschedulable"
+	// PodAwaitsEvaluation means the pod wasn't considered in the last scale-up yet.
+	PodAwaitsEvaluation = "AwaitsEvaluation"
+)
+
+// PodStatus explains how a pending pod was handled by the last scale-up.
+type PodStatus struct {
+	Pod    string `json:"pod"`
+	Status string `json:"status"`
+	// RejectedNodeGroups lists the predicates failing for the pod on nodes of each node group.
+	RejectedNodeGroups map[string][]string `json:"rejectedNodeGroups,omitempty"`
+	// SkippedNodeGroups lists why node groups weren't considered for the pod at all.
+	SkippedNodeGroups map[string][]string `json:"skippedNodeGroups,omitempty"`
+}
+
+// Recorder keeps the state of the last autoscaling loop, fed by the status
+// processors and the scale-down logic.
+type Recorder struct {
+	sync.Mutex
+	nodeGroups       []NodeGroup
+	unneededNodes    []UnneededNode
+	unremovableNodes []UnremovableNode
+	scaleUp          *ScaleUpStatus
+	scaleDown        *ScaleDownStatus
+	pods             map[string]*PodStatus
+}
+
+// NewRecorder creates an empty Recorder.
+func NewRecorder() *Recorder {
+	return &Recorder{
+		nodeGroups:       []NodeGroup{},
+		unneededNodes:    []UnneededNode{},
+		unremovableNodes: []UnremovableNode{},
+		pods:             make(map[string]*PodStatus),
+	}
+}
+
+// WrapProcessors makes the scale-up, scale-down and autoscaling status
+// processors feed the recorder before calling the original processors.
+func (r *Recorder) WrapProcessors(scaleUp status.ScaleUpStatusProcessor, scaleDown status.ScaleDownStatusProcessor,
+	autoscaling status.AutoscalingStatusProcessor) (status.ScaleUpStatusProcessor, status.ScaleDownStatusProcessor, status.AutoscalingStatusProcessor) {
+	return status.NewCombinedScaleUpStatusProcessor(status.ScaleUpStatusProcessorFunc(r.setScaleUpStatus), scaleUp),
+		status.NewCombinedScaleDownStatusProcessor(status.ScaleDownStatusProcessorFunc(r.setScaleDownStatus), scaleDown),
+		status.NewCombinedAutoscalingStatusProcessor(status.AutoscalingStatusProcessorFunc(r.setNodeGroups), autoscaling)
+}
+
+// UpdateScaleDown sets the unneeded nodes with the time since when they are unneeded.
+func (r *Recorder) UpdateScaleDown(unneededNodes map[string]time.Time, now time.Time) {
+	unneeded := make([]UnneededNode, 0, len(unneededNodes))
+	for name, since := range unneededNodes {
+		unneeded = append(unneeded, UnneededNode{
+			Node:        name,
+			Since:       since,
+			UnneededFor: now.Sub(since).String(),
+		})
+	}
+	sort.Slice(unneeded, func(i, j int) bool { return unneeded[i].Node < unneeded[j].Node })
+
+	r.Lock()
+	defer r.Unlock()
+	r.unneededNodes = unneeded
+}
+
+func (r *Recorder) setScaleUpStatus(_ *context.AutoscalingContext, scaleUpStatus *status.ScaleUpStatus) {
+	result := &ScaleUpStatus{Result: scaleUpStatus.Result.String()}
+	for _, info := range scaleUpStatus.ScaleUpInfos {
+		result.NodeGroups = append(result.NodeGroups, ScaleUpNodeGroup{
+			NodeGroup:   info.Group.Id(),
+			CurrentSize: info.CurrentSize,
+			NewSize:     info.NewSize,
+			MaxSize:     info.MaxSize,
+		})
+	}
+	pods := make(map[string]*PodStatus)
+	for _, pod := range scaleUpStatus.PodsTriggeredScaleUp {
+		result.PodsTriggeredScaleUp = append(result.PodsTriggeredScaleUp, status.PodName(pod))
+		pods[status.PodName(pod)] = &PodStatus{Pod: status.PodName(pod), Status: PodTriggeredScaleUp}
+	}
+	for _, noScaleUpInfo := range scaleUpStatus.PodsRemainUnschedulable {
+		name := status.PodName(noScaleUpInfo.Pod)
+		result.PodsRemainUnschedulable = append(result.PodsRemainUnschedulable, name)
+		pods[name] = &PodStatus{
+			Pod:                name,
+			Status:             PodRemainsUnschedulable,
+			RejectedNodeGroups: nodeGroupReasons(noScaleUpInfo.RejectedNodeGroups),
+			SkippedNodeGroups:  nodeGroupReasons(noScaleUpInfo.SkippedNodeGroups),
+		}
+	}
+	for _, pod := range scaleUpStatus.PodsAwaitEvaluation {
+		result.PodsAwaitEvaluation = append(result.PodsAwaitEvaluation, status.PodName(pod))
+		pods[status.PodName(pod)] = &PodStatus{Pod: status.PodName(pod), Status: PodAwaitsEvaluation}
+	}
+
+	r.Lock()
+	defer r.Unlock()
+	r.scaleUp = result
+	r.pods = pods
+}
+
+func (r *Recorder) setScaleDownStatus(_ *context.AutoscalingContext, scaleDownStatus *status.ScaleDownStatus) {
+	result := &ScaleDownStatus{Result: scaleDownStatus.Result.String()}
+	for _, scaledDownNode := range scaleDownStatus.ScaledDownNodes {
+		node := ScaleDownNode{
+			Node:        scaledDownNode.Node.Name,
+			Utilization: scaledDownNode.UtilInfo.Utilization,
+		}
+		for _, pod := range scaledDownNode.EvictedPods {
+			node.EvictedPods = append(node.EvictedPods, status.PodName(pod))
+		}
+		if scaledDownNode.NodeGroup != nil {
+			node.NodeGroup = scaledDownNode.NodeGroup.Id()
+		}
+		result.Nodes = append(result.Nodes, node)
+	}
+	var unremovable []UnremovableNode
+	if scaleDownStatus.UnremovableNodes != nil {
+		unremovable = make([]UnremovableNode, 0, len(scaleDownStatus.UnremovableNodes))
+		for _, unremovableNode := range scaleDownStatus.UnremovableNodes {
+			unremovable = append(unremovable, UnremovableNode{
+				Node:    unremovableNode.Node.Name,
+				Reason:  unremovableNode.Reason.String(),
+				Message: unremovableNode.Message,
+			})
+		}
+	}
+
+	r.Lock()
+	defer r.Unlock()
+	r.scaleDown = result
+	// Unremovable nodes are kept from the last loop that checked them.
+	if unremovable != nil {
+		r.unremovableNodes = unremovable
+	}
+}
+
+func (r *Recorder) setNodeGroups(context *context.AutoscalingContext, csr *clusterstate.ClusterStateRegistry, now time.Time) error {
+	nodeGroups := make([]NodeGroup, 0)
+	for _, nodeGroup := range context.CloudProvider.NodeGroups() {
+		targetSize, err := nodeGroup.TargetSize()
+		if err != nil {
+			klog.Warningf("Failed to get target size of node group %s: %v", nodeGroup.Id(), err)
+		}
+		nodeGroups = append(nodeGroups, NodeGroup{
+			Id:         nodeGroup.Id(),
+			MinSize:    nodeGroup.MinSize(),
+			MaxSize:    nodeGroup.MaxSize(),
+			TargetSize: targetSize,
+			Backoff:    csr.GetBackoffStatus(nodeGroup, now),
+		})
+	}
+	sort.Slice(nodeGroups, func(i, j int) bool { return nodeGroups[i].Id < nodeGroups[j].Id })
+
+	r.Lock()
+	defer r.Unlock()
+	r.nodeGroups = nodeGroups
+	return nil
+}
+
+func nodeGroupReasons(reasons map[string]status.Reasons) map[string][]string {
+	if len(reasons) == 0 {
+		return nil
+	}
+	result := make(map[string][]string, len(reasons))
+	for nodeGroup, nodeGroupReasons := range reasons {
+		result[nodeGroup] = nodeGroupReasons.Reasons()
+	}
+	return result
+}
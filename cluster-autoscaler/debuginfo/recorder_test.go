@@ -0,0 +1,148 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package debuginfo
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	testprovider "k8s.io/autoscaler/cluster-autoscaler/cloudprovider/test"
+	"k8s.io/autoscaler/cluster-autoscaler/clusterstate"
+	"k8s.io/autoscaler/cluster-autoscaler/context"
+	"k8s.io/autoscaler/cluster-autoscaler/metrics"
+	"k8s.io/autoscaler/cluster-autoscaler/processors/nodegroupset"
+	"k8s.io/autoscaler/cluster-autoscaler/processors/status"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator"
+	"k8s.io/autoscaler/cluster-autoscaler/utils/backoff"
+	. "k8s.io/autoscaler/cluster-autoscaler/utils/test"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testReasons struct {
+	reasons []string
+}
+
+func (r *testReasons) Reasons() []string {
+	return r.reasons
+}
+
+func get(t *testing.T, mux *http.ServeMux, path string, result interface{}) int {
+	recorder := httptest.NewRecorder()
+	mux.ServeHTTP(recorder, httptest.NewRequest("GET", path, nil))
+	if recorder.Code == http.StatusOK {
+		assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), result))
+	}
+	return recorder.Code
+}
+
+func TestRecorder(t *testing.T) {
+	now := time.Now()
+	provider := testprovider.NewTestCloudProvider(nil, nil)
+	provider.AddNodeGroup("ng1", 1, 10, 3)
+	provider.AddNodeGroup("ng2", 0, 5, 0)
+	csr := clusterstate.NewClusterStateRegistry(provider, clusterstate.ClusterStateRegistryConfig{}, nil,
+		backoff.NewIdBasedExponentialBackoff(5*time.Minute, time.Hour, time.Hour))
+	csr.RegisterFailedScaleUp(provider.GetNodeGroup("ng2"), metrics.Timeout, now)
+	autoscalingContext := &context.AutoscalingContext{CloudProvider: provider}
+
+	r := NewRecorder()
+	mux := http.NewServeMux()
+	r.RegisterHandlers(mux)
+	scaleUp, scaleDown, autoscaling := r.WrapProcessors(&status.NoOpScaleUpStatusProcessor{},
+		&status.NoOpScaleDownStatusProcessor{}, &status.NoOpAutoscalingStatusProcessor{})
+
+	p1 := BuildTestPod("p1", 100, 0)
+	p2 := BuildTestPod("p2", 100, 0)
+	n1 := BuildTestNode("n1", 1000, 1000)
+	scaleUp.Process(autoscalingContext, &status.ScaleUpStatus{
+		Result: status.ScaleUpSuccessful,
+		ScaleUpInfos: []nodegroupset.ScaleUpInfo{
+			{Group: provider.GetNodeGroup("ng1"), CurrentSize: 3, NewSize: 4, MaxSize: 10},
+		},
+		PodsTriggeredScaleUp: []*apiv1.Pod{p1},
+		PodsRemainUnschedulable: []status.NoScaleUpInfo{{
+			Pod:                p2,
+			RejectedNodeGroups: map[string]status.Reasons{"ng1": &testReasons{[]string{"Insufficient cpu"}}},
+			SkippedNodeGroups:  map[string]status.Reasons{"ng2": &testReasons{[]string{"in backoff after failed scale-up"}}},
+		}},
+	})
+	n3 := BuildTestNode("n3", 1000, 1000)
+	scaleDown.Process(autoscalingContext, &status.ScaleDownStatus{
+		Result:          status.ScaleDownNodeDeleteStarted,
+		ScaledDownNodes: []*status.ScaleDownNode{{Node: n1, NodeGroup: provider.GetNodeGroup("ng1"), EvictedPods: []*apiv1.Pod{p1}}},
+		UnremovableNodes: []*simulator.UnremovableNode{
+			{Node: n3, Reason: simulator.NotUnderutilized, Message: "cpu utilization 0.800000"},
+		},
+	})
+	// Statuses that didn't check nodes keep the previous unremovable nodes.
+	scaleDown.Process(autoscalingContext, &status.ScaleDownStatus{Result: status.ScaleDownNodeDeleteStarted,
+		ScaledDownNodes: []*status.ScaleDownNode{{Node: n1, NodeGroup: provider.GetNodeGroup("ng1"), EvictedPods: []*apiv1.Pod{p1}}},
+	})
+	r.UpdateScaleDown(map[string]time.Time{"n2": now.Add(-5 * time.Minute)}, now)
+	assert.NoError(t, autoscaling.Process(autoscalingContext, csr, now))
+
+	var nodeGroups []NodeGroup
+	assert.Equal(t, http.StatusOK, get(t, mux, NodeGroupsPath, &nodeGroups))
+	assert.Equal(t, 2, len(nodeGroups))
+	assert.Equal(t, NodeGroup{Id: "ng1", MinSize: 1, MaxSize: 10, TargetSize: 3}, nodeGroups[0])
+	assert.Equal(t, "ng2", nodeGroups[1].Id)
+	assert.NotNil(t, nodeGroups[1].Backoff)
+
+	var unneeded []UnneededNode
+	assert.Equal(t, http.StatusOK, get(t, mux, UnneededNodesPath, &unneeded))
+	assert.Equal(t, 1, len(unneeded))
+	assert.Equal(t, "n2", unneeded[0].Node)
+	assert.Equal(t, "5m0s", unneeded[0].UnneededFor)
+
+	var unremovable []UnremovableNode
+	assert.Equal(t, http.StatusOK, get(t, mux, UnremovableNodesPath, &unremovable))
+	assert.Equal(t, []UnremovableNode{{Node: "n3", Reason: "NotUnderutilized", Message: "cpu utilization 0.800000"}}, unremovable)
+
+	var scaleUpStatus ScaleUpStatus
+	assert.Equal(t, http.StatusOK, get(t, mux, ScaleUpPath, &scaleUpStatus))
+	assert.Equal(t, ScaleUpStatus{
+		Result:                  "Successful",
+		NodeGroups:              []ScaleUpNodeGroup{{NodeGroup: "ng1", CurrentSize: 3, NewSize: 4, MaxSize: 10}},
+		PodsTriggeredScaleUp:    []string{"default/p1"},
+		PodsRemainUnschedulable: []string{"default/p2"},
+	}, scaleUpStatus)
+
+	var scaleDownStatus ScaleDownStatus
+	assert.Equal(t, http.StatusOK, get(t, mux, ScaleDownPath, &scaleDownStatus))
+	assert.Equal(t, ScaleDownStatus{
+		Result: "NodeDeleteStarted",
+		Nodes:  []ScaleDownNode{{Node: "n1", NodeGroup: "ng1", EvictedPods: []string{"default/p1"}}},
+	}, scaleDownStatus)
+
+	var pod PodStatus
+	assert.Equal(t, http.StatusOK, get(t, mux, PodsPath+"default/p2", &pod))
+	assert.Equal(t, PodStatus{
+		Pod:                "default/p2",
+		Status:             PodRemainsUnschedulable,
+		RejectedNodeGroups: map[string][]string{"ng1": {"Insufficient cpu"}},
+		SkippedNodeGroups:  map[string][]string{"ng2": {"in backoff after failed scale-up"}},
+	}, pod)
+	assert.Equal(t, http.StatusOK, get(t, mux, PodsPath+"default/p1", &pod))
+	assert.Equal(t, PodTriggeredScaleUp, pod.Status)
+	assert.Equal(t, http.StatusNotFound, get(t, mux, PodsPath+"default/p3", &pod))
+	assert.Equal(t, http.StatusBadRequest, get(t, mux, PodsPath+"p1", &pod))
+}
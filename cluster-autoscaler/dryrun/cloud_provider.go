@@ -0,0 +1,124 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dryrun
+
+import (
+	"reflect"
+
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider"
+	"k8s.io/klog"
+)
+
+// cloudProvider is a CloudProvider decorator that hands out recording node
+// groups. All read-only calls are passed to the underlying cloud provider.
+type cloudProvider struct {
+	cloudprovider.CloudProvider
+	recorder *Recorder
+}
+
+// NewCloudProvider wraps the given cloud provider so that node group
+// operations changing the cluster are recorded instead of being performed.
+func NewCloudProvider(delegate cloudprovider.CloudProvider, recorder *Recorder) cloudprovider.CloudProvider {
+	return &cloudProvider{CloudProvider: delegate, recorder: recorder}
+}
+
+// NodeGroups returns all node groups configured for this cloud provider.
+func (p *cloudProvider) NodeGroups() []cloudprovider.NodeGroup {
+	nodeGroups := p.CloudProvider.NodeGroups()
+	result := make([]cloudprovider.NodeGroup, 0, len(nodeGroups))
+	for _, ng := range nodeGroups {
+		result = append(result, p.wrap(ng))
+	}
+	return result
+}
+
+// NodeGroupForNode returns the node group for the given node.
+func (p *cloudProvider) NodeGroupForNode(node *apiv1.Node) (cloudprovider.NodeGroup, error) {
+	ng, err := p.CloudProvider.NodeGroupForNode(node)
+	if err != nil {
+		return nil, err
+	}
+	return p.wrap(ng), nil
+}
+
+// NewNodeGroup builds a theoretical node group based on the node definition provided.
+func (p *cloudProvider) NewNodeGroup(machineType string, labels map[string]string, systemLabels map[string]string,
+	taints []apiv1.Taint, extraResources map[string]resource.Quantity) (cloudprovider.NodeGroup, error) {
+	ng, err := p.CloudProvider.NewNodeGroup(machineType, labels, systemLabels, taints, extraResources)
+	if err != nil {
+		return nil, err
+	}
+	return p.wrap(ng), nil
+}
+
+func (p *cloudProvider) wrap(delegate cloudprovider.NodeGroup) cloudprovider.NodeGroup {
+	// Callers check for nil node groups, so a typed nil must not be hidden
+	// behind a non-nil decorator.
+	if delegate == nil || reflect.ValueOf(delegate).IsNil() {
+		return nil
+	}
+	return &nodeGroup{NodeGroup: delegate, recorder: p.recorder}
+}
+
+// nodeGroup is a NodeGroup decorator recording all operations that would
+// change the size or existence of the node group.
+type nodeGroup struct {
+	cloudprovider.NodeGroup
+	recorder *Recorder
+}
+
+// IncreaseSize records the request to increase the size of the node group.
+func (ng *nodeGroup) IncreaseSize(delta int) error {
+	klog.V(1).Infof("Dry run: would increase size of node group %s by %d", ng.Id(), delta)
+	ng.recorder.RecordCloudProviderAction(CloudProviderAction{Operation: "IncreaseSize", NodeGroup: ng.Id(), Delta: delta})
+	return nil
+}
+
+// DeleteNodes records the request to delete nodes from the node group.
+func (ng *nodeGroup) DeleteNodes(nodes []*apiv1.Node) error {
+	names := make([]string, 0, len(nodes))
+	for _, node := range nodes {
+		names = append(names, node.Name)
+	}
+	klog.V(1).Infof("Dry run: would delete nodes %v from node group %s", names, ng.Id())
+	ng.recorder.RecordCloudProviderAction(CloudProviderAction{Operation: "DeleteNodes", NodeGroup: ng.Id(), Nodes: names})
+	return nil
+}
+
+// DecreaseTargetSize records the request to decrease the target size of the node group.
+func (ng *nodeGroup) DecreaseTargetSize(delta int) error {
+	klog.V(1).Infof("Dry run: would decrease target size of node group %s by %d", ng.Id(), -delta)
+	ng.recorder.RecordCloudProviderAction(CloudProviderAction{Operation: "DecreaseTargetSize", NodeGroup: ng.Id(), Delta: delta})
+	return nil
+}
+
+// Create records the request to create the node group. The returned node
+// group is the same, not yet existing, node group.
+func (ng *nodeGroup) Create() (cloudprovider.NodeGroup, error) {
+	klog.V(1).Infof("Dry run: would create node group %s", ng.Id())
+	ng.recorder.RecordCloudProviderAction(CloudProviderAction{Operation: "Create", NodeGroup: ng.Id()})
+	return ng, nil
+}
+
+// Delete records the request to delete the node group.
+func (ng *nodeGroup) Delete() error {
+	klog.V(1).Infof("Dry run: would delete node group %s", ng.Id())
+	ng.recorder.RecordCloudProviderAction(CloudProviderAction{Operation: "Delete", NodeGroup: ng.Id()})
+	return nil
+}
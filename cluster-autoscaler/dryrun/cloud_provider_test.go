@@ -0,0 +1,71 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dryrun
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	apiv1 "k8s.io/api/core/v1"
+	testprovider "k8s.io/autoscaler/cluster-autoscaler/cloudprovider/test"
+	. "k8s.io/autoscaler/cluster-autoscaler/utils/test"
+)
+
+func TestCloudProviderRecordsChanges(t *testing.T) {
+	n1 := BuildTestNode("n1", 1000, 1000)
+	provider := testprovider.NewTestCloudProvider(func(id string, delta int) error {
+		t.Errorf("unexpected scale up of %s", id)
+		return nil
+	}, func(id string, node string) error {
+		t.Errorf("unexpected scale down of %s", id)
+		return nil
+	})
+	provider.AddNodeGroup("ng1", 1, 10, 2)
+	provider.AddNode("ng1", n1)
+	recorder := NewRecorder()
+	dryRunProvider := NewCloudProvider(provider, recorder)
+
+	nodeGroups := dryRunProvider.NodeGroups()
+	assert.Equal(t, 1, len(nodeGroups))
+	assert.Equal(t, "ng1", nodeGroups[0].Id())
+	assert.Equal(t, 10, nodeGroups[0].MaxSize())
+
+	ng, err := dryRunProvider.NodeGroupForNode(n1)
+	assert.NoError(t, err)
+	assert.NoError(t, ng.IncreaseSize(3))
+	assert.NoError(t, ng.DeleteNodes([]*apiv1.Node{n1}))
+	assert.NoError(t, ng.DecreaseTargetSize(-1))
+	size, err := ng.TargetSize()
+	assert.NoError(t, err)
+	assert.Equal(t, 2, size)
+
+	ng, err = dryRunProvider.NodeGroupForNode(BuildTestNode("unknown", 1000, 1000))
+	assert.NoError(t, err)
+	assert.Nil(t, ng)
+
+	actions, requests := recorder.Flush()
+	assert.Equal(t, []CloudProviderAction{
+		{Operation: "IncreaseSize", NodeGroup: "ng1", Delta: 3},
+		{Operation: "DeleteNodes", NodeGroup: "ng1", Nodes: []string{"n1"}},
+		{Operation: "DecreaseTargetSize", NodeGroup: "ng1", Delta: -1},
+	}, actions)
+	assert.Empty(t, requests)
+
+	actions, _ = recorder.Flush()
+	assert.Empty(t, actions)
+}
@@ -0,0 +1,121 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dryrun
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kube_client "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/klog"
+)
+
+// NewKubeClient creates a Kubernetes client that only sends read requests to
+// the API server. Requests that would modify objects (taints, evictions,
+// status ConfigMap, events, ...) are recorded and answered locally.
+func NewKubeClient(kubeConfig *rest.Config, recorder *Recorder) kube_client.Interface {
+	config := rest.CopyConfig(kubeConfig)
+	wrap := config.WrapTransport
+	config.WrapTransport = func(rt http.RoundTripper) http.RoundTripper {
+		if wrap != nil {
+			rt = wrap(rt)
+		}
+		return &roundTripper{delegate: rt, recorder: recorder}
+	}
+	return kube_client.NewForConfigOrDie(config)
+}
+
+// roundTripper passes read requests to the API server and answers mutating
+// ones on its own:
+// - POST and PUT get the sent object echoed back,
+// - PATCH gets the current, unmodified, object from the API server,
+// - DELETE gets a success status.
+type roundTripper struct {
+	delegate http.RoundTripper
+	recorder *Recorder
+}
+
+// RoundTrip executes a single HTTP transaction.
+func (rt *roundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return rt.delegate.RoundTrip(req)
+	}
+
+	klog.V(4).Infof("Dry run: not sending %s %s", req.Method, req.URL.Path)
+	rt.recorder.RecordKubeRequest(KubeRequest{Verb: req.Method, Path: req.URL.Path})
+
+	switch req.Method {
+	case http.MethodPatch:
+		get := new(http.Request)
+		*get = *req
+		get.Method = http.MethodGet
+		get.Body = nil
+		get.ContentLength = 0
+		get.Header = make(http.Header)
+		for key, values := range req.Header {
+			if key != "Content-Type" {
+				get.Header[key] = values
+			}
+		}
+		return rt.delegate.RoundTrip(get)
+	case http.MethodDelete:
+		body, err := json.Marshal(&metav1.Status{
+			TypeMeta: metav1.TypeMeta{Kind: "Status", APIVersion: "v1"},
+			Status:   metav1.StatusSuccess,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return response(req, http.StatusOK, "application/json", body), nil
+	default:
+		var body []byte
+		if req.Body != nil {
+			var err error
+			body, err = ioutil.ReadAll(req.Body)
+			req.Body.Close()
+			if err != nil {
+				return nil, err
+			}
+		}
+		code := http.StatusOK
+		if req.Method == http.MethodPost {
+			code = http.StatusCreated
+		}
+		return response(req, code, req.Header.Get("Content-Type"), body), nil
+	}
+}
+
+func response(req *http.Request, code int, contentType string, body []byte) *http.Response {
+	header := make(http.Header)
+	header.Set("Content-Type", contentType)
+	return &http.Response{
+		Status:        http.StatusText(code),
+		StatusCode:    code,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          ioutil.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
@@ -0,0 +1,85 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dryrun
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	apiv1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	. "k8s.io/autoscaler/cluster-autoscaler/utils/test"
+	"k8s.io/client-go/rest"
+)
+
+func TestKubeClientDoesNotModify(t *testing.T) {
+	node := BuildTestNode("n1", 1000, 1000)
+	node.Labels = map[string]string{"zone": "z1"}
+	var methods []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		methods = append(methods, req.Method)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(node)
+	}))
+	defer server.Close()
+
+	recorder := NewRecorder()
+	client := NewKubeClient(&rest.Config{Host: server.URL}, recorder)
+
+	got, err := client.CoreV1().Nodes().Get("n1", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, "n1", got.Name)
+
+	tainted := got.DeepCopy()
+	tainted.Spec.Taints = []apiv1.Taint{{Key: "ToBeDeletedByClusterAutoscaler", Effect: apiv1.TaintEffectNoSchedule}}
+	updated, err := client.CoreV1().Nodes().Update(tainted)
+	assert.NoError(t, err)
+	assert.Equal(t, tainted.Spec.Taints, updated.Spec.Taints)
+
+	patched, err := client.CoreV1().Nodes().Patch("n1", types.StrategicMergePatchType, []byte(`{"metadata":{"labels":{"a":"b"}}}`))
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"zone": "z1"}, patched.Labels)
+
+	err = client.PolicyV1beta1().Evictions("default").Evict(&policyv1.Eviction{
+		ObjectMeta: metav1.ObjectMeta{Name: "p1", Namespace: "default"},
+	})
+	assert.NoError(t, err)
+
+	_, err = client.CoreV1().ConfigMaps("kube-system").Create(&apiv1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "cluster-autoscaler-status", Namespace: "kube-system"},
+	})
+	assert.NoError(t, err)
+
+	assert.NoError(t, client.CoreV1().Nodes().Delete("n1", &metav1.DeleteOptions{}))
+
+	// Only reads reached the server.
+	assert.Equal(t, []string{http.MethodGet, http.MethodGet}, methods)
+	_, requests := recorder.Flush()
+	assert.Equal(t, []KubeRequest{
+		{Verb: http.MethodPut, Path: "/api/v1/nodes/n1"},
+		{Verb: http.MethodPatch, Path: "/api/v1/nodes/n1"},
+		{Verb: http.MethodPost, Path: "/api/v1/namespaces/default/pods/p1/eviction"},
+		{Verb: http.MethodPost, Path: "/api/v1/namespaces/kube-system/configmaps"},
+		{Verb: http.MethodDelete, Path: "/api/v1/nodes/n1"},
+	}, requests)
+}
@@ -0,0 +1,79 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dryrun
+
+import (
+	"sync"
+)
+
+// CloudProviderAction is a mutating cloud provider call that was recorded
+// instead of being performed.
+type CloudProviderAction struct {
+	// Operation is the name of the NodeGroup method, e.g. IncreaseSize.
+	Operation string `json:"operation"`
+	// NodeGroup is the id of the node group the operation was called on.
+	NodeGroup string `json:"nodeGroup"`
+	// Delta is the size change requested by IncreaseSize and DecreaseTargetSize.
+	Delta int `json:"delta,omitempty"`
+	// Nodes are the names of the nodes passed to DeleteNodes.
+	Nodes []string `json:"nodes,omitempty"`
+}
+
+// KubeRequest is a mutating Kubernetes API request that was recorded instead
+// of being sent to the API server.
+type KubeRequest struct {
+	// Verb is the HTTP method of the request.
+	Verb string `json:"verb"`
+	// Path is the URL path of the request.
+	Path string `json:"path"`
+}
+
+// Recorder collects the actions intercepted in dry-run mode. It is safe for
+// concurrent use.
+type Recorder struct {
+	sync.Mutex
+	cloudProviderActions []CloudProviderAction
+	kubeRequests         []KubeRequest
+}
+
+// NewRecorder creates an empty Recorder.
+func NewRecorder() *Recorder {
+	return &Recorder{}
+}
+
+// RecordCloudProviderAction records a cloud provider call.
+func (r *Recorder) RecordCloudProviderAction(action CloudProviderAction) {
+	r.Lock()
+	defer r.Unlock()
+	r.cloudProviderActions = append(r.cloudProviderActions, action)
+}
+
+// RecordKubeRequest records a Kubernetes API request.
+func (r *Recorder) RecordKubeRequest(request KubeRequest) {
+	r.Lock()
+	defer r.Unlock()
+	r.kubeRequests = append(r.kubeRequests, request)
+}
+
+// Flush returns all actions recorded since the previous call and clears them.
+func (r *Recorder) Flush() ([]CloudProviderAction, []KubeRequest) {
+	r.Lock()
+	defer r.Unlock()
+	cloudProviderActions, kubeRequests := r.cloudProviderActions, r.kubeRequests
+	r.cloudProviderActions, r.kubeRequests = nil, nil
+	return cloudProviderActions, kubeRequests
+}
@@ -0,0 +1,179 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dryrun
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/autoscaler/cluster-autoscaler/clusterstate"
+	"k8s.io/autoscaler/cluster-autoscaler/context"
+	"k8s.io/autoscaler/cluster-autoscaler/processors/status"
+	"k8s.io/klog"
+)
+
+// Report describes what a single autoscaling loop would have done if it
+// wasn't run in dry-run mode.
+type Report struct {
+	Time                 time.Time             `json:"time"`
+	ScaleUp              *ScaleUpReport        `json:"scaleUp,omitempty"`
+	ScaleDown            *ScaleDownReport      `json:"scaleDown,omitempty"`
+	CloudProviderActions []CloudProviderAction `json:"cloudProviderActions,omitempty"`
+	KubeRequests         []KubeRequest         `json:"kubeRequests,omitempty"`
+}
+
+// ScaleUpReport describes the scale-up option chosen by the expander.
+type ScaleUpReport struct {
+	Result                  string             `json:"result"`
+	NodeGroups              []ScaleUpNodeGroup `json:"nodeGroups,omitempty"`
+	PodsTriggeredScaleUp    []string           `json:"podsTriggeredScaleUp,omitempty"`
+	PodsRemainUnschedulable []string           `json:"podsRemainUnschedulable,omitempty"`
+}
+
+// ScaleUpNodeGroup describes the resize of a single node group.
+type ScaleUpNodeGroup struct {
+	NodeGroup   string `json:"nodeGroup"`
+	CurrentSize int    `json:"currentSize"`
+	NewSize     int    `json:"newSize"`
+	MaxSize     int    `json:"maxSize"`
+}
+
+// ScaleDownReport describes the nodes picked for removal.
+type ScaleDownReport struct {
+	Result string          `json:"result"`
+	Nodes  []ScaleDownNode `json:"nodes,omitempty"`
+}
+
+// ScaleDownNode describes a single node picked for removal and the pods that
+// would be evicted from it.
+type ScaleDownNode struct {
+	Node        string   `json:"node"`
+	NodeGroup   string   `json:"nodeGroup,omitempty"`
+	Utilization float64  `json:"utilization"`
+	EvictedPods []string `json:"evictedPods,omitempty"`
+}
+
+// Reporter builds a Report from the scale-up and scale-down statuses of an
+// autoscaling loop and the actions recorded during it. The report is written
+// at the end of each loop.
+type Reporter struct {
+	sync.Mutex
+	recorder *Recorder
+	// writer receives one JSON encoded report per line. Reports are logged
+	// if it's nil.
+	writer io.Writer
+	report Report
+}
+
+// NewReporter creates a Reporter writing reports to the given writer, or to
+// the log if the writer is nil.
+func NewReporter(recorder *Recorder, writer io.Writer) *Reporter {
+	return &Reporter{recorder: recorder, writer: writer}
+}
+
+// WrapProcessors makes the scale-up, scale-down and autoscaling status
+// processors feed the reporter before calling the original processors.
+func (r *Reporter) WrapProcessors(scaleUp status.ScaleUpStatusProcessor, scaleDown status.ScaleDownStatusProcessor,
+	autoscaling status.AutoscalingStatusProcessor) (status.ScaleUpStatusProcessor, status.ScaleDownStatusProcessor, status.AutoscalingStatusProcessor) {
+	return status.NewCombinedScaleUpStatusProcessor(status.ScaleUpStatusProcessorFunc(r.setScaleUpStatus), scaleUp),
+		status.NewCombinedScaleDownStatusProcessor(status.ScaleDownStatusProcessorFunc(r.setScaleDownStatus), scaleDown),
+		// The report is written after the original processor finishes the loop.
+		status.NewCombinedAutoscalingStatusProcessor(autoscaling, status.AutoscalingStatusProcessorFunc(r.writeReport))
+}
+
+func (r *Reporter) setScaleUpStatus(_ *context.AutoscalingContext, scaleUpStatus *status.ScaleUpStatus) {
+	report := &ScaleUpReport{Result: scaleUpStatus.Result.String()}
+	for _, info := range scaleUpStatus.ScaleUpInfos {
+		report.NodeGroups = append(report.NodeGroups, ScaleUpNodeGroup{
+			NodeGroup:   info.Group.Id(),
+			CurrentSize: info.CurrentSize,
+			NewSize:     info.NewSize,
+			MaxSize:     info.MaxSize,
+		})
+	}
+	report.PodsTriggeredScaleUp = podNames(scaleUpStatus.PodsTriggeredScaleUp)
+	for _, noScaleUpInfo := range scaleUpStatus.PodsRemainUnschedulable {
+		report.PodsRemainUnschedulable = append(report.PodsRemainUnschedulable, status.PodName(noScaleUpInfo.Pod))
+	}
+
+	r.Lock()
+	defer r.Unlock()
+	r.report.ScaleUp = report
+}
+
+func (r *Reporter) setScaleDownStatus(_ *context.AutoscalingContext, scaleDownStatus *status.ScaleDownStatus) {
+	report := &ScaleDownReport{Result: scaleDownStatus.Result.String()}
+	for _, scaledDownNode := range scaleDownStatus.ScaledDownNodes {
+		node := ScaleDownNode{
+			Node:        scaledDownNode.Node.Name,
+			Utilization: scaledDownNode.UtilInfo.Utilization,
+			EvictedPods: podNames(scaledDownNode.EvictedPods),
+		}
+		if scaledDownNode.NodeGroup != nil {
+			node.NodeGroup = scaledDownNode.NodeGroup.Id()
+		}
+		report.Nodes = append(report.Nodes, node)
+	}
+
+	r.Lock()
+	defer r.Unlock()
+	r.report.ScaleDown = report
+}
+
+// writeReport writes the report of the finished loop. Failures are only logged,
+// so they don't affect the autoscaling loop.
+func (r *Reporter) writeReport(_ *context.AutoscalingContext, _ *clusterstate.ClusterStateRegistry, now time.Time) error {
+	if err := r.flush(now); err != nil {
+		klog.Errorf("Failed to write dry run report: %v", err)
+	}
+	return nil
+}
+
+// flush writes the report of the finished loop and starts a new one.
+func (r *Reporter) flush(now time.Time) error {
+	r.Lock()
+	report := r.report
+	r.report = Report{}
+	r.Unlock()
+
+	report.Time = now
+	report.CloudProviderActions, report.KubeRequests = r.recorder.Flush()
+	data, err := json.Marshal(report)
+	if err != nil {
+		return err
+	}
+	if r.writer == nil {
+		klog.V(1).Infof("Dry run report: %s", data)
+		return nil
+	}
+	_, err = r.writer.Write(append(data, '\n'))
+	return err
+}
+
+func podNames(pods []*apiv1.Pod) []string {
+	if len(pods) == 0 {
+		return nil
+	}
+	names := make([]string, 0, len(pods))
+	for _, pod := range pods {
+		names = append(names, status.PodName(pod))
+	}
+	return names
+}
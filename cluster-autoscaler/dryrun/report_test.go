@@ -0,0 +1,93 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dryrun
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	apiv1 "k8s.io/api/core/v1"
+	testprovider "k8s.io/autoscaler/cluster-autoscaler/cloudprovider/test"
+	"k8s.io/autoscaler/cluster-autoscaler/processors/nodegroupset"
+	"k8s.io/autoscaler/cluster-autoscaler/processors/status"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator"
+	. "k8s.io/autoscaler/cluster-autoscaler/utils/test"
+)
+
+func TestReporter(t *testing.T) {
+	provider := testprovider.NewTestCloudProvider(nil, nil)
+	provider.AddNodeGroup("ng1", 1, 10, 2)
+	provider.AddNodeGroup("ng2", 1, 10, 3)
+	n1 := BuildTestNode("n1", 1000, 1000)
+	p1 := BuildTestPod("p1", 100, 0)
+	p1.Namespace = "default"
+	p2 := BuildTestPod("p2", 100, 0)
+	p2.Namespace = "default"
+
+	recorder := NewRecorder()
+	var buf bytes.Buffer
+	reporter := NewReporter(recorder, &buf)
+	scaleUp, scaleDown, autoscaling := reporter.WrapProcessors(&status.NoOpScaleUpStatusProcessor{},
+		&status.NoOpScaleDownStatusProcessor{}, &status.NoOpAutoscalingStatusProcessor{})
+
+	now := time.Date(2019, time.June, 1, 12, 0, 0, 0, time.UTC)
+	scaleUp.Process(nil, &status.ScaleUpStatus{
+		Result:               status.ScaleUpSuccessful,
+		ScaleUpInfos:         []nodegroupset.ScaleUpInfo{{Group: provider.GetNodeGroup("ng1"), CurrentSize: 2, NewSize: 4, MaxSize: 10}},
+		PodsTriggeredScaleUp: []*apiv1.Pod{p1},
+	})
+	scaleDown.Process(nil, &status.ScaleDownStatus{
+		Result: status.ScaleDownNodeDeleteStarted,
+		ScaledDownNodes: []*status.ScaleDownNode{{
+			Node:        n1,
+			NodeGroup:   provider.GetNodeGroup("ng2"),
+			EvictedPods: []*apiv1.Pod{p2},
+			UtilInfo:    simulator.UtilizationInfo{Utilization: 0.25},
+		}},
+	})
+	recorder.RecordCloudProviderAction(CloudProviderAction{Operation: "IncreaseSize", NodeGroup: "ng1", Delta: 2})
+	recorder.RecordKubeRequest(KubeRequest{Verb: "PUT", Path: "/api/v1/nodes/n1"})
+	assert.NoError(t, autoscaling.Process(nil, nil, now))
+	// The next loop starts with an empty report.
+	assert.NoError(t, autoscaling.Process(nil, nil, now.Add(time.Minute)))
+
+	decoder := json.NewDecoder(&buf)
+	var report Report
+	assert.NoError(t, decoder.Decode(&report))
+	assert.Equal(t, Report{
+		Time: now,
+		ScaleUp: &ScaleUpReport{
+			Result:               "Successful",
+			NodeGroups:           []ScaleUpNodeGroup{{NodeGroup: "ng1", CurrentSize: 2, NewSize: 4, MaxSize: 10}},
+			PodsTriggeredScaleUp: []string{"default/p1"},
+		},
+		ScaleDown: &ScaleDownReport{
+			Result: "NodeDeleteStarted",
+			Nodes:  []ScaleDownNode{{Node: "n1", NodeGroup: "ng2", Utilization: 0.25, EvictedPods: []string{"default/p2"}}},
+		},
+		CloudProviderActions: []CloudProviderAction{{Operation: "IncreaseSize", NodeGroup: "ng1", Delta: 2}},
+		KubeRequests:         []KubeRequest{{Verb: "PUT", Path: "/api/v1/nodes/n1"}},
+	}, report)
+
+	report = Report{}
+	assert.NoError(t, decoder.Decode(&report))
+	assert.Equal(t, Report{Time: now.Add(time.Minute)}, report)
+}
@@ -17,12 +17,13 @@ limitations under the License.
 package estimator
 
 import (
+	"fmt"
 	"sort"
 
 	apiv1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	"k8s.io/autoscaler/cluster-autoscaler/simulator"
-	schedulerUtils "k8s.io/autoscaler/cluster-autoscaler/utils/scheduler"
+	"k8s.io/klog"
 	schedulernodeinfo "k8s.io/kubernetes/pkg/scheduler/nodeinfo"
 )
 
@@ -57,23 +58,52 @@ func (estimator *BinpackingNodeEstimator) Estimate(pods []*apiv1.Pod, nodeTempla
 	podInfos := calculatePodScore(pods, nodeTemplate)
 	sort.Slice(podInfos, func(i, j int) bool { return podInfos[i].score > podInfos[j].score })
 
-	newNodes := make([]*schedulernodeinfo.NodeInfo, 0)
-	newNodes = append(newNodes, upcomingNodes...)
+	snapshot := simulator.NewClusterSnapshot()
+	// Names of nodes in the snapshot in the order they are tried, upcoming nodes first.
+	nodeNames := make([]string, 0, len(upcomingNodes))
+	addNode := func(nodeInfo *schedulernodeinfo.NodeInfo) (string, error) {
+		// Upcoming nodes and nodes built from the template share names, so they
+		// are renamed to be told apart in the snapshot.
+		node := *nodeInfo.Node()
+		node.Name = fmt.Sprintf("estimator-node-%d", len(nodeNames))
+		if err := snapshot.AddNode(&node); err != nil {
+			return "", err
+		}
+		for _, pod := range nodeInfo.Pods() {
+			if err := snapshot.AddPod(pod, node.Name); err != nil {
+				return "", err
+			}
+		}
+		nodeNames = append(nodeNames, node.Name)
+		return node.Name, nil
+	}
+	for _, nodeInfo := range upcomingNodes {
+		if _, err := addNode(nodeInfo); err != nil {
+			klog.Errorf("Failed to add upcoming node to estimation snapshot: %v", err)
+		}
+	}
+	upcomingCount := len(nodeNames)
 
 	for _, podInfo := range podInfos {
 		found := false
-		for i, nodeInfo := range newNodes {
+		for _, nodeName := range nodeNames {
+			nodeInfo, _ := snapshot.GetNodeInfo(nodeName)
 			if err := estimator.predicateChecker.CheckPredicates(podInfo.pod, nil, nodeInfo); err == nil {
-				found = true
-				newNodes[i] = schedulerUtils.NodeWithPod(nodeInfo, podInfo.pod)
+				found = snapshot.AddPod(podInfo.pod, nodeName) == nil
 				break
 			}
 		}
 		if !found {
-			newNodes = append(newNodes, schedulerUtils.NodeWithPod(nodeTemplate, podInfo.pod))
+			nodeName, err := addNode(nodeTemplate)
+			if err == nil {
+				err = snapshot.AddPod(podInfo.pod, nodeName)
+			}
+			if err != nil {
+				klog.Errorf("Failed to add new node to estimation snapshot: %v", err)
+			}
 		}
 	}
-	return len(newNodes) - len(upcomingNodes)
+	return len(nodeNames) - upcomingCount
 }
 
 // Calculates score for all pods and returns podInfo structure.
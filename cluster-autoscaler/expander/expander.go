@@ -24,7 +24,7 @@ import (
 
 var (
 	// AvailableExpanders is a list of available expander options
-	AvailableExpanders = []string{RandomExpanderName, MostPodsExpanderName, LeastWasteExpanderName, PriceBasedExpanderName, PriorityBasedExpanderName}
+	AvailableExpanders = []string{RandomExpanderName, MostPodsExpanderName, LeastWasteExpanderName, PriceBasedExpanderName, PriorityBasedExpanderName, ExternalExpanderName}
 	// RandomExpanderName selects a node group at random
 	RandomExpanderName = "random"
 	// MostPodsExpanderName selects a node group that fits the most pods
@@ -36,6 +36,8 @@ var (
 	PriceBasedExpanderName = "price"
 	// PriorityBasedExpanderName selects a node group based on a user-configured priorities assigned to group names
 	PriorityBasedExpanderName = "priority"
+	// ExternalExpanderName selects a node group chosen by an external decision service
+	ExternalExpanderName = "external"
 )
 
 // Option describes an option to expand the cluster.
@@ -48,5 +50,9 @@ type Option struct {
 
 // Strategy describes an interface for selecting the best option when scaling up
 type Strategy interface {
+	// BestOption selects the single best option.
 	BestOption(options []Option, nodeInfo map[string]*schedulernodeinfo.NodeInfo) *Option
+	// BestOptions narrows the options down to the ones the strategy considers
+	// equally good, so that another strategy can choose between them.
+	BestOptions(options []Option, nodeInfo map[string]*schedulernodeinfo.NodeInfo) []Option
 }
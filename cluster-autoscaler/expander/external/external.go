@@ -0,0 +1,263 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package external
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"gopkg.in/yaml.v2"
+
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/autoscaler/cluster-autoscaler/expander"
+	"k8s.io/autoscaler/cluster-autoscaler/utils/gpu"
+	"k8s.io/klog"
+	schedulernodeinfo "k8s.io/kubernetes/pkg/scheduler/nodeinfo"
+)
+
+const (
+	// defaultTimeout is the timeout of a single request to the decision service.
+	defaultTimeout = 2 * time.Second
+)
+
+// Config is the configuration of the external expander, read from the
+// --expander-external-config file.
+type Config struct {
+	// URL of the decision service, http or https.
+	URL string `yaml:"url"`
+	// Timeout of a single request to the decision service.
+	Timeout time.Duration `yaml:"timeout"`
+	// Cacert is the path to the CA certificate used to verify the server.
+	Cacert string `yaml:"cacert"`
+	// Cert is the path to the client certificate used for mutual TLS.
+	Cert string `yaml:"cert"`
+	// Key is the path to the client private key used for mutual TLS.
+	Key string `yaml:"key"`
+	// Fallback is the expander, or a comma separated list of expanders, used
+	// when the decision service is unavailable.
+	Fallback string `yaml:"fallback"`
+}
+
+// ReadConfig reads the external expander configuration.
+func ReadConfig(configReader io.Reader) (*Config, error) {
+	data, err := ioutil.ReadAll(configReader)
+	if err != nil {
+		return nil, err
+	}
+	cfg := &Config{}
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("can't parse YAML: %v", err)
+	}
+	if cfg.URL == "" {
+		return nil, fmt.Errorf("url of the decision service must be specified")
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultTimeout
+	}
+	if cfg.Fallback == "" {
+		cfg.Fallback = expander.RandomExpanderName
+	}
+	return cfg, nil
+}
+
+// Request is sent to the decision service as JSON in a POST request.
+type Request struct {
+	// Options are the possible scale-ups.
+	Options []OptionSummary `json:"options"`
+	// NodeInfos describe nodes of the node groups in Options, by node group id.
+	NodeInfos map[string]NodeInfoSummary `json:"nodeInfos"`
+}
+
+// OptionSummary describes a single scale-up option.
+type OptionSummary struct {
+	NodeGroup string `json:"nodeGroup"`
+	NodeCount int    `json:"nodeCount"`
+	// Pods that would be scheduled after the scale-up, as namespace/name.
+	Pods []string `json:"pods"`
+	// MilliCpu and Memory are the total requests of Pods.
+	MilliCpu int64  `json:"milliCpu"`
+	Memory   int64  `json:"memory"`
+	Debug    string `json:"debug,omitempty"`
+}
+
+// NodeInfoSummary describes a node the node group would be scaled up with.
+type NodeInfoSummary struct {
+	MilliCpu int64             `json:"milliCpu"`
+	Memory   int64             `json:"memory"`
+	Gpu      int64             `json:"gpu"`
+	Pods     int64             `json:"pods"`
+	Labels   map[string]string `json:"labels,omitempty"`
+	Taints   []apiv1.Taint     `json:"taints,omitempty"`
+}
+
+// Response is returned by the decision service as JSON.
+type Response struct {
+	// NodeGroup is the id of the node group of the chosen option.
+	NodeGroup string `json:"nodeGroup"`
+	// Reason is an optional explanation added to the option's debug message.
+	Reason string `json:"reason,omitempty"`
+}
+
+type external struct {
+	client   *http.Client
+	url      string
+	fallback expander.Strategy
+}
+
+// NewStrategy returns an expansion strategy that asks an external decision
+// service for the best option. The fallback strategy is used if the service
+// fails or returns an unknown node group.
+func NewStrategy(cfg *Config, fallback expander.Strategy) (expander.Strategy, error) {
+	transport := &http.Transport{}
+	if cfg.Cacert != "" || cfg.Cert != "" || cfg.Key != "" {
+		tlsConfig, err := buildTLSConfig(cfg)
+		if err != nil {
+			return nil, err
+		}
+		transport.TLSClientConfig = tlsConfig
+	}
+	return &external{
+		client:   &http.Client{Transport: transport, Timeout: cfg.Timeout},
+		url:      cfg.URL,
+		fallback: fallback,
+	}, nil
+}
+
+func buildTLSConfig(cfg *Config) (*tls.Config, error) {
+	tlsConfig := &tls.Config{}
+	if cfg.Cacert != "" {
+		caCert, err := ioutil.ReadFile(cfg.Cacert)
+		if err != nil {
+			return nil, fmt.Errorf("could not read CA certificate %s: %v", cfg.Cacert, err)
+		}
+		certPool := x509.NewCertPool()
+		if !certPool.AppendCertsFromPEM(caCert) {
+			return nil, fmt.Errorf("failed to append CA certificate %s", cfg.Cacert)
+		}
+		tlsConfig.RootCAs = certPool
+	}
+	if cfg.Cert != "" || cfg.Key != "" {
+		certificate, err := tls.LoadX509KeyPair(cfg.Cert, cfg.Key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client key pair: %v", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{certificate}
+	}
+	return tlsConfig, nil
+}
+
+// BestOption selects the option chosen by the decision service.
+func (e *external) BestOption(expansionOptions []expander.Option, nodeInfo map[string]*schedulernodeinfo.NodeInfo) *expander.Option {
+	if len(expansionOptions) == 0 {
+		return nil
+	}
+	best, err := e.ask(expansionOptions, nodeInfo)
+	if err != nil {
+		klog.Warningf("External expander failed, falling back: %v", err)
+		return e.fallback.BestOption(expansionOptions, nodeInfo)
+	}
+	return best
+}
+
+// BestOptions returns the option chosen by the decision service.
+func (e *external) BestOptions(expansionOptions []expander.Option, nodeInfo map[string]*schedulernodeinfo.NodeInfo) []expander.Option {
+	if len(expansionOptions) == 0 {
+		return nil
+	}
+	best, err := e.ask(expansionOptions, nodeInfo)
+	if err != nil {
+		klog.Warningf("External expander failed, falling back: %v", err)
+		return e.fallback.BestOptions(expansionOptions, nodeInfo)
+	}
+	return []expander.Option{*best}
+}
+
+func (e *external) ask(expansionOptions []expander.Option, nodeInfo map[string]*schedulernodeinfo.NodeInfo) (*expander.Option, error) {
+	body, err := json.Marshal(buildRequest(expansionOptions, nodeInfo))
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode request: %v", err)
+	}
+	httpResponse, err := e.client.Post(e.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	defer httpResponse.Body.Close()
+	if httpResponse.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("decision service returned %s", httpResponse.Status)
+	}
+	response := Response{}
+	if err := json.NewDecoder(httpResponse.Body).Decode(&response); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %v", err)
+	}
+	for _, option := range expansionOptions {
+		if option.NodeGroup.Id() == response.NodeGroup {
+			klog.V(2).Infof("External expander chose %s: %s", response.NodeGroup, response.Reason)
+			best := option
+			best.Debug = fmt.Sprintf("%s | external-expander: %s", option.Debug, response.Reason)
+			return &best, nil
+		}
+	}
+	return nil, fmt.Errorf("decision service chose unknown node group %q", response.NodeGroup)
+}
+
+func buildRequest(expansionOptions []expander.Option, nodeInfo map[string]*schedulernodeinfo.NodeInfo) *Request {
+	request := &Request{
+		Options:   make([]OptionSummary, 0, len(expansionOptions)),
+		NodeInfos: make(map[string]NodeInfoSummary),
+	}
+	for _, option := range expansionOptions {
+		id := option.NodeGroup.Id()
+		summary := OptionSummary{
+			NodeGroup: id,
+			NodeCount: option.NodeCount,
+			Pods:      make([]string, 0, len(option.Pods)),
+			Debug:     option.Debug,
+		}
+		for _, pod := range option.Pods {
+			summary.Pods = append(summary.Pods, pod.Namespace+"/"+pod.Name)
+			for _, container := range pod.Spec.Containers {
+				summary.MilliCpu += container.Resources.Requests.Cpu().MilliValue()
+				summary.Memory += container.Resources.Requests.Memory().Value()
+			}
+		}
+		request.Options = append(request.Options, summary)
+
+		if info, found := nodeInfo[id]; found && info.Node() != nil {
+			node := info.Node()
+			allocatable := node.Status.Allocatable
+			nodeSummary := NodeInfoSummary{
+				MilliCpu: allocatable.Cpu().MilliValue(),
+				Memory:   allocatable.Memory().Value(),
+				Pods:     allocatable.Pods().Value(),
+				Labels:   node.Labels,
+				Taints:   node.Spec.Taints,
+			}
+			if gpus, found := allocatable[gpu.ResourceNvidiaGPU]; found {
+				nodeSummary.Gpu = gpus.Value()
+			}
+			request.NodeInfos[id] = nodeSummary
+		}
+	}
+	return request
+}
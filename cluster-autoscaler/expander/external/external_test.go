@@ -0,0 +1,219 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package external
+
+import (
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider/test"
+	"k8s.io/autoscaler/cluster-autoscaler/expander"
+	. "k8s.io/autoscaler/cluster-autoscaler/utils/test"
+	schedulernodeinfo "k8s.io/kubernetes/pkg/scheduler/nodeinfo"
+)
+
+// standInServer is a local stand-in for the decision service.
+type standInServer struct {
+	sync.Mutex
+	requests []Request
+	// decide returns the response and HTTP status for a request.
+	decide func(request Request) (Response, int)
+	delay  time.Duration
+}
+
+func (s *standInServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	request := Request{}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	s.Lock()
+	s.requests = append(s.requests, request)
+	s.Unlock()
+	time.Sleep(s.delay)
+	response, status := s.decide(request)
+	if status != http.StatusOK {
+		http.Error(w, "failed", status)
+		return
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+// lastOption is a fallback strategy choosing the last option.
+type lastOption struct{}
+
+func (lastOption) BestOption(options []expander.Option, nodeInfo map[string]*schedulernodeinfo.NodeInfo) *expander.Option {
+	return &options[len(options)-1]
+}
+
+func (lastOption) BestOptions(options []expander.Option, nodeInfo map[string]*schedulernodeinfo.NodeInfo) []expander.Option {
+	return options[len(options)-1:]
+}
+
+func chooseNodeGroup(id string) func(Request) (Response, int) {
+	return func(Request) (Response, int) {
+		return Response{NodeGroup: id, Reason: "cheapest"}, http.StatusOK
+	}
+}
+
+func testOptions() ([]expander.Option, map[string]*schedulernodeinfo.NodeInfo) {
+	pod := BuildTestPod("p1", 500, 1000)
+	n1 := BuildTestNode("n1", 1000, 2000)
+	n1.Labels = map[string]string{"pool": "ng1"}
+	n2 := BuildTestNode("n2", 2000, 4000)
+	options := []expander.Option{
+		{NodeGroup: test.NewTestNodeGroup("ng1", 10, 1, 1, true, false, "", nil, nil), NodeCount: 1, Pods: []*apiv1.Pod{pod}, Debug: "ng1"},
+		{NodeGroup: test.NewTestNodeGroup("ng2", 10, 1, 1, true, false, "", nil, nil), NodeCount: 2, Pods: []*apiv1.Pod{pod, pod}, Debug: "ng2"},
+	}
+	nodeInfos := map[string]*schedulernodeinfo.NodeInfo{
+		"ng1": schedulernodeinfo.NewNodeInfo(),
+		"ng2": schedulernodeinfo.NewNodeInfo(),
+	}
+	nodeInfos["ng1"].SetNode(n1)
+	nodeInfos["ng2"].SetNode(n2)
+	return options, nodeInfos
+}
+
+func newTestStrategy(t *testing.T, cfg *Config) expander.Strategy {
+	if cfg.Timeout == 0 {
+		cfg.Timeout = time.Second
+	}
+	strategy, err := NewStrategy(cfg, lastOption{})
+	assert.NoError(t, err)
+	return strategy
+}
+
+func TestExternalBestOption(t *testing.T) {
+	handler := &standInServer{decide: chooseNodeGroup("ng1")}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+	options, nodeInfos := testOptions()
+
+	strategy := newTestStrategy(t, &Config{URL: server.URL})
+	best := strategy.BestOption(options, nodeInfos)
+	assert.Equal(t, "ng1", best.NodeGroup.Id())
+	assert.Equal(t, "ng1 | external-expander: cheapest", best.Debug)
+
+	bestOptions := strategy.BestOptions(options, nodeInfos)
+	assert.Equal(t, 1, len(bestOptions))
+	assert.Equal(t, "ng1", bestOptions[0].NodeGroup.Id())
+
+	assert.Equal(t, 2, len(handler.requests))
+	assert.Equal(t, Request{
+		Options: []OptionSummary{
+			{NodeGroup: "ng1", NodeCount: 1, Pods: []string{"default/p1"}, MilliCpu: 500, Memory: 1000, Debug: "ng1"},
+			{NodeGroup: "ng2", NodeCount: 2, Pods: []string{"default/p1", "default/p1"}, MilliCpu: 1000, Memory: 2000, Debug: "ng2"},
+		},
+		NodeInfos: map[string]NodeInfoSummary{
+			"ng1": {MilliCpu: 1000, Memory: 2000, Pods: 100, Labels: map[string]string{"pool": "ng1"}},
+			"ng2": {MilliCpu: 2000, Memory: 4000, Pods: 100},
+		},
+	}, handler.requests[0])
+}
+
+func TestExternalFallback(t *testing.T) {
+	options, nodeInfos := testOptions()
+
+	testCases := []struct {
+		name    string
+		handler *standInServer
+		timeout time.Duration
+	}{
+		{
+			name:    "unknown node group",
+			handler: &standInServer{decide: chooseNodeGroup("ng3")},
+		},
+		{
+			name: "server error",
+			handler: &standInServer{decide: func(Request) (Response, int) {
+				return Response{}, http.StatusInternalServerError
+			}},
+		},
+		{
+			name:    "timeout",
+			handler: &standInServer{decide: chooseNodeGroup("ng1"), delay: 500 * time.Millisecond},
+			timeout: 50 * time.Millisecond,
+		},
+	}
+
+	for _, tc := range testCases {
+		server := httptest.NewServer(tc.handler)
+		strategy := newTestStrategy(t, &Config{URL: server.URL, Timeout: tc.timeout})
+		assert.Equal(t, "ng2", strategy.BestOption(options, nodeInfos).NodeGroup.Id(), tc.name)
+		assert.Equal(t, "ng2", strategy.BestOptions(options, nodeInfos)[0].NodeGroup.Id(), tc.name)
+		server.Close()
+	}
+
+	// Service not running.
+	strategy := newTestStrategy(t, &Config{URL: "http://127.0.0.1:0"})
+	assert.Equal(t, "ng2", strategy.BestOption(options, nodeInfos).NodeGroup.Id())
+}
+
+func TestExternalTLS(t *testing.T) {
+	server := httptest.NewTLSServer(&standInServer{decide: chooseNodeGroup("ng1")})
+	defer server.Close()
+	options, nodeInfos := testOptions()
+
+	dir, err := ioutil.TempDir("", "external-expander")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	cacert := filepath.Join(dir, "ca.crt")
+	pemBytes := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	assert.NoError(t, ioutil.WriteFile(cacert, pemBytes, 0600))
+
+	strategy := newTestStrategy(t, &Config{URL: server.URL, Cacert: cacert})
+	assert.Equal(t, "ng1", strategy.BestOption(options, nodeInfos).NodeGroup.Id())
+
+	// The server can't be verified without the CA certificate.
+	strategy = newTestStrategy(t, &Config{URL: server.URL})
+	assert.Equal(t, "ng2", strategy.BestOption(options, nodeInfos).NodeGroup.Id())
+
+	_, err = NewStrategy(&Config{URL: server.URL, Cacert: filepath.Join(dir, "missing.crt")}, lastOption{})
+	assert.Error(t, err)
+}
+
+func TestReadConfig(t *testing.T) {
+	cfg, err := ReadConfig(strings.NewReader("url: http://decision-service:8080/best-option\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, &Config{
+		URL:      "http://decision-service:8080/best-option",
+		Timeout:  defaultTimeout,
+		Fallback: expander.RandomExpanderName,
+	}, cfg)
+
+	cfg, err = ReadConfig(strings.NewReader("url: https://decision-service\ntimeout: 500ms\nfallback: priority,least-waste\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, 500*time.Millisecond, cfg.Timeout)
+	assert.Equal(t, "priority,least-waste", cfg.Fallback)
+
+	_, err = ReadConfig(strings.NewReader("timeout: 1s\n"))
+	assert.Error(t, err)
+	_, err = ReadConfig(strings.NewReader("url: [\n"))
+	assert.Error(t, err)
+}
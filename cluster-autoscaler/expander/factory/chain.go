@@ -0,0 +1,61 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package factory
+
+import (
+	"k8s.io/autoscaler/cluster-autoscaler/expander"
+	schedulernodeinfo "k8s.io/kubernetes/pkg/scheduler/nodeinfo"
+)
+
+// chain narrows options down with each strategy in turn, so that every
+// strategy only breaks ties left by the strategies before it.
+type chain struct {
+	strategies []expander.Strategy
+}
+
+func newChain(strategies []expander.Strategy) expander.Strategy {
+	return &chain{strategies: strategies}
+}
+
+// BestOption selects the best option with the last strategy, out of the options
+// left by the strategies before it.
+func (c *chain) BestOption(options []expander.Option, nodeInfo map[string]*schedulernodeinfo.NodeInfo) *expander.Option {
+	last := len(c.strategies) - 1
+	options = c.filter(c.strategies[:last], options, nodeInfo)
+	switch len(options) {
+	case 0:
+		return nil
+	case 1:
+		return &options[0]
+	}
+	return c.strategies[last].BestOption(options, nodeInfo)
+}
+
+// BestOptions returns the options left by all the strategies.
+func (c *chain) BestOptions(options []expander.Option, nodeInfo map[string]*schedulernodeinfo.NodeInfo) []expander.Option {
+	return c.filter(c.strategies, options, nodeInfo)
+}
+
+func (c *chain) filter(strategies []expander.Strategy, options []expander.Option, nodeInfo map[string]*schedulernodeinfo.NodeInfo) []expander.Option {
+	for _, strategy := range strategies {
+		if len(options) <= 1 {
+			return options
+		}
+		options = strategy.BestOptions(options, nodeInfo)
+	}
+	return options
+}
@@ -0,0 +1,95 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package factory
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	apiv1 "k8s.io/api/core/v1"
+	testprovider "k8s.io/autoscaler/cluster-autoscaler/cloudprovider/test"
+	"k8s.io/autoscaler/cluster-autoscaler/expander"
+	schedulernodeinfo "k8s.io/kubernetes/pkg/scheduler/nodeinfo"
+)
+
+// keepStrategy keeps options whose debug string is in the set.
+type keepStrategy struct {
+	keep  map[string]bool
+	calls int
+}
+
+func (s *keepStrategy) BestOption(options []expander.Option, nodeInfo map[string]*schedulernodeinfo.NodeInfo) *expander.Option {
+	best := s.BestOptions(options, nodeInfo)
+	if len(best) == 0 {
+		return nil
+	}
+	return &best[0]
+}
+
+func (s *keepStrategy) BestOptions(options []expander.Option, nodeInfo map[string]*schedulernodeinfo.NodeInfo) []expander.Option {
+	s.calls++
+	var result []expander.Option
+	for _, option := range options {
+		if s.keep[option.Debug] {
+			result = append(result, option)
+		}
+	}
+	return result
+}
+
+func TestChain(t *testing.T) {
+	eo1 := expander.Option{Debug: "eo1"}
+	eo2 := expander.Option{Debug: "eo2"}
+	eo3 := expander.Option{Debug: "eo3"}
+	options := []expander.Option{eo1, eo2, eo3}
+
+	first := &keepStrategy{keep: map[string]bool{"eo2": true, "eo3": true}}
+	second := &keepStrategy{keep: map[string]bool{"eo1": true, "eo3": true}}
+	c := newChain([]expander.Strategy{first, second})
+	assert.Equal(t, []expander.Option{eo3}, c.BestOptions(options, nil))
+	assert.Equal(t, eo3, *c.BestOption(options, nil))
+
+	// A single option left isn't passed to the next strategies.
+	first = &keepStrategy{keep: map[string]bool{"eo2": true}}
+	second = &keepStrategy{keep: map[string]bool{"eo1": true}}
+	c = newChain([]expander.Strategy{first, second})
+	assert.Equal(t, eo2, *c.BestOption(options, nil))
+	assert.Equal(t, 0, second.calls)
+
+	// No options left.
+	first = &keepStrategy{}
+	c = newChain([]expander.Strategy{first, second})
+	assert.Nil(t, c.BestOption(options, nil))
+	assert.Empty(t, c.BestOptions(options, nil))
+}
+
+func TestExpanderStrategyFromString(t *testing.T) {
+	provider := testprovider.NewTestCloudProvider(nil, nil)
+	pod := &apiv1.Pod{}
+	eo1 := expander.Option{Debug: "eo1", NodeGroup: testprovider.NewTestNodeGroup("ng1", 10, 1, 1, true, false, "", nil, nil)}
+	eo2 := expander.Option{Debug: "eo2", NodeGroup: testprovider.NewTestNodeGroup("ng2", 10, 1, 1, true, false, "", nil, nil), Pods: []*apiv1.Pod{pod}}
+
+	strategy, err := ExpanderStrategyFromString("most-pods,random", provider, nil, nil, "kube-system", "")
+	assert.NoError(t, err)
+	assert.Equal(t, eo2, *strategy.BestOption([]expander.Option{eo1, eo2}, nil))
+
+	for _, name := range []string{"unknown", "random,unknown", "random,most-pods,random", "random,"} {
+		_, err = ExpanderStrategyFromString(name, provider, nil, nil, "kube-system", "")
+		assert.Error(t, err, name)
+	}
+}
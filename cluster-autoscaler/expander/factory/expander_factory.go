@@ -17,9 +17,13 @@ limitations under the License.
 package factory
 
 import (
+	"os"
+	"strings"
+
 	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider"
 	"k8s.io/autoscaler/cluster-autoscaler/context"
 	"k8s.io/autoscaler/cluster-autoscaler/expander"
+	"k8s.io/autoscaler/cluster-autoscaler/expander/external"
 	"k8s.io/autoscaler/cluster-autoscaler/expander/mostpods"
 	"k8s.io/autoscaler/cluster-autoscaler/expander/price"
 	"k8s.io/autoscaler/cluster-autoscaler/expander/priority"
@@ -31,10 +35,35 @@ import (
 	kube_client "k8s.io/client-go/kubernetes"
 )
 
-// ExpanderStrategyFromString creates an expander.Strategy according to its name
+// ExpanderStrategyFromString creates an expander.Strategy according to its name. The name
+// can also be a comma separated list of names, in which case each strategy chooses only
+// between the options considered equally good by the strategies before it.
 func ExpanderStrategyFromString(expanderFlag string, cloudProvider cloudprovider.CloudProvider,
 	autoscalingKubeClients *context.AutoscalingKubeClients, kubeClient kube_client.Interface,
-	configNamespace string) (expander.Strategy, errors.AutoscalerError) {
+	configNamespace string, externalExpanderConfig string) (expander.Strategy, errors.AutoscalerError) {
+	names := strings.Split(expanderFlag, ",")
+	if len(names) == 1 {
+		return expanderStrategyFromName(expanderFlag, cloudProvider, autoscalingKubeClients, kubeClient, configNamespace, externalExpanderConfig)
+	}
+	seen := make(map[string]bool)
+	strategies := make([]expander.Strategy, 0, len(names))
+	for _, name := range names {
+		if seen[name] {
+			return nil, errors.NewAutoscalerError(errors.InternalError, "Expander %s used more than once in %s", name, expanderFlag)
+		}
+		seen[name] = true
+		strategy, err := expanderStrategyFromName(name, cloudProvider, autoscalingKubeClients, kubeClient, configNamespace, externalExpanderConfig)
+		if err != nil {
+			return nil, err
+		}
+		strategies = append(strategies, strategy)
+	}
+	return newChain(strategies), nil
+}
+
+func expanderStrategyFromName(expanderFlag string, cloudProvider cloudprovider.CloudProvider,
+	autoscalingKubeClients *context.AutoscalingKubeClients, kubeClient kube_client.Interface,
+	configNamespace string, externalExpanderConfig string) (expander.Strategy, errors.AutoscalerError) {
 	switch expanderFlag {
 	case expander.RandomExpanderName:
 		return random.NewStrategy(), nil
@@ -55,6 +84,39 @@ func ExpanderStrategyFromString(expanderFlag string, cloudProvider cloudprovider
 		stopChannel := make(chan struct{})
 		lister := kubernetes.NewConfigMapListerForNamespace(kubeClient, stopChannel, configNamespace)
 		return priority.NewStrategy(lister.ConfigMaps(configNamespace), autoscalingKubeClients.Recorder)
+	case expander.ExternalExpanderName:
+		return externalStrategy(cloudProvider, autoscalingKubeClients, kubeClient, configNamespace, externalExpanderConfig)
 	}
 	return nil, errors.NewAutoscalerError(errors.InternalError, "Expander %s not supported", expanderFlag)
 }
+
+func externalStrategy(cloudProvider cloudprovider.CloudProvider,
+	autoscalingKubeClients *context.AutoscalingKubeClients, kubeClient kube_client.Interface,
+	configNamespace string, externalExpanderConfig string) (expander.Strategy, errors.AutoscalerError) {
+	if externalExpanderConfig == "" {
+		return nil, errors.NewAutoscalerError(errors.InternalError, "External expander requires a config file, please specify it via the --expander-external-config flag")
+	}
+	configFile, err := os.Open(externalExpanderConfig)
+	if err != nil {
+		return nil, errors.NewAutoscalerError(errors.InternalError, "Couldn't open external expander configuration %s: %v", externalExpanderConfig, err)
+	}
+	defer configFile.Close()
+	cfg, err := external.ReadConfig(configFile)
+	if err != nil {
+		return nil, errors.NewAutoscalerError(errors.InternalError, "Failed to read external expander configuration %s: %v", externalExpanderConfig, err)
+	}
+	for _, name := range strings.Split(cfg.Fallback, ",") {
+		if name == expander.ExternalExpanderName {
+			return nil, errors.NewAutoscalerError(errors.InternalError, "External expander can't fall back to itself")
+		}
+	}
+	fallback, typedErr := ExpanderStrategyFromString(cfg.Fallback, cloudProvider, autoscalingKubeClients, kubeClient, configNamespace, "")
+	if typedErr != nil {
+		return nil, typedErr.AddPrefix("failed to create external expander fallback: ")
+	}
+	strategy, err := external.NewStrategy(cfg, fallback)
+	if err != nil {
+		return nil, errors.NewAutoscalerError(errors.InternalError, "Failed to create external expander: %v", err)
+	}
+	return strategy, nil
+}
@@ -40,4 +40,7 @@ func TestMostPods(t *testing.T) {
 	ret = e.BestOption([]expander.Option{eo0, eo1, eo1b}, nil)
 	assert.NotEqual(t, *ret, eo0)
 	assert.True(t, assert.ObjectsAreEqual(*ret, eo1) || assert.ObjectsAreEqual(*ret, eo1b))
+
+	rets := e.BestOptions([]expander.Option{eo0, eo1, eo1b}, nil)
+	assert.Equal(t, []expander.Option{eo1, eo1b}, rets)
 }
@@ -88,7 +88,16 @@ func NewStrategy(cloudProvider cloudprovider.CloudProvider,
 
 // BestOption selects option based on cost and preferred node type.
 func (p *priceBased) BestOption(expansionOptions []expander.Option, nodeInfos map[string]*schedulernodeinfo.NodeInfo) *expander.Option {
-	var bestOption *expander.Option
+	bestOptions := p.BestOptions(expansionOptions, nodeInfos)
+	if len(bestOptions) == 0 {
+		return nil
+	}
+	return &bestOptions[0]
+}
+
+// BestOptions selects options with the best score based on cost and preferred node type.
+func (p *priceBased) BestOptions(expansionOptions []expander.Option, nodeInfos map[string]*schedulernodeinfo.NodeInfo) []expander.Option {
+	var bestOptions []expander.Option
 	bestOptionScore := 0.0
 	now := time.Now()
 	then := now.Add(time.Hour)
@@ -169,17 +178,20 @@ nextoption:
 
 		klog.V(5).Infof("Price expander for %s: %s", option.NodeGroup.Id(), debug)
 
-		if bestOption == nil || bestOptionScore > optionScore {
-			bestOption = &expander.Option{
-				NodeGroup: option.NodeGroup,
-				NodeCount: option.NodeCount,
-				Debug:     fmt.Sprintf("%s | price-expander: %s", option.Debug, debug),
-				Pods:      option.Pods,
-			}
+		scoredOption := expander.Option{
+			NodeGroup: option.NodeGroup,
+			NodeCount: option.NodeCount,
+			Debug:     fmt.Sprintf("%s | price-expander: %s", option.Debug, debug),
+			Pods:      option.Pods,
+		}
+		if bestOptions == nil || bestOptionScore > optionScore {
+			bestOptions = []expander.Option{scoredOption}
 			bestOptionScore = optionScore
+		} else if bestOptionScore == optionScore {
+			bestOptions = append(bestOptions, scoredOption)
 		}
 	}
-	return bestOption
+	return bestOptions
 }
 
 // buildPod creates a pod with specified resources.
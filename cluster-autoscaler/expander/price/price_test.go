@@ -255,6 +255,13 @@ func TestPriceExpander(t *testing.T) {
 		},
 		SimpleNodeUnfitness,
 	).BestOption(options2, nodeInfosForGroups))
+	assert.Empty(t, NewStrategy(
+		provider,
+		&testPreferredNodeProvider{
+			preferred: buildNode(2000, units.GiB),
+		},
+		SimpleNodeUnfitness,
+	).BestOptions(options2, nodeInfosForGroups))
 
 	// Add node info for autoprovisioned group.
 	nodeInfosForGroups["autoprovisioned-MT1"] = ni3
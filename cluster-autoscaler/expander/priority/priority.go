@@ -120,7 +120,14 @@ func (p *priority) parsePrioritiesYAMLString(prioritiesYAML string) (priorities,
 	return newPriorities, nil
 }
 
+// BestOption selects the option with the highest priority, at random if there are more of them.
 func (p *priority) BestOption(expansionOptions []expander.Option, nodeInfo map[string]*schedulernodeinfo.NodeInfo) *expander.Option {
+	return p.fallbackStrategy.BestOption(p.BestOptions(expansionOptions, nodeInfo), nodeInfo)
+}
+
+// BestOptions selects the options with the highest priority. All options are
+// returned if none of them matches the configuration.
+func (p *priority) BestOptions(expansionOptions []expander.Option, nodeInfo map[string]*schedulernodeinfo.NodeInfo) []expander.Option {
 	if len(expansionOptions) <= 0 {
 		return nil
 	}
@@ -158,15 +165,15 @@ func (p *priority) BestOption(expansionOptions []expander.Option, nodeInfo map[s
 	}
 
 	if len(best) == 0 {
-		msg := "Priority expander: no priorities info found for any of the expansion options. Falling back to random choice."
+		msg := "Priority expander: no priorities info found for any of the expansion options. Falling back to all of them."
 		p.logConfigWarning(cm, "PriorityConfigMapNoGroupMatched", msg)
-		return p.fallbackStrategy.BestOption(expansionOptions, nodeInfo)
+		return expansionOptions
 	}
 
 	for _, opt := range best {
 		klog.V(2).Infof("priority expander: %s chosen as the highest available", opt.NodeGroup.Id())
 	}
-	return p.fallbackStrategy.BestOption(best, nodeInfo)
+	return best
 }
 
 func (p *priority) groupIDMatchesList(id string, nameRegexpList []*regexp.Regexp) bool {
@@ -117,6 +117,12 @@ func TestPriorityExpanderCorrecltySelectsOneOfTwoMatchingOptionsOutOfMany(t *tes
 	}
 }
 
+func TestPriorityExpanderBestOptionsReturnsAllMatchingOptions(t *testing.T) {
+	s, _, _, _ := getStrategyInstance(t, config)
+	ret := s.BestOptions([]expander.Option{eoT2Large, eoT3Large, eoT2Micro}, nil)
+	assert.Equal(t, []expander.Option{eoT2Large, eoT3Large}, ret)
+}
+
 func TestPriorityExpanderCorrecltyFallsBackToRandomWhenNoMatches(t *testing.T) {
 	s, _, _, _ := getStrategyInstance(t, config)
 	for i := 0; i < 10; i++ {
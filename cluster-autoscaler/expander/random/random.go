@@ -40,3 +40,12 @@ func (r *random) BestOption(expansionOptions []expander.Option, nodeInfo map[str
 	pos := rand.Int31n(int32(len(expansionOptions)))
 	return &expansionOptions[pos]
 }
+
+// BestOptions selects a single option at random
+func (r *random) BestOptions(expansionOptions []expander.Option, nodeInfo map[string]*schedulernodeinfo.NodeInfo) []expander.Option {
+	best := r.BestOption(expansionOptions, nodeInfo)
+	if best == nil {
+		return nil
+	}
+	return []expander.Option{*best}
+}
@@ -37,4 +37,8 @@ func TestRandomExpander(t *testing.T) {
 
 	ret = e.BestOption([]expander.Option{}, nil)
 	assert.Nil(t, ret)
+
+	rets := e.BestOptions([]expander.Option{eo1a, eo1b}, nil)
+	assert.Equal(t, 1, len(rets))
+	assert.Empty(t, e.BestOptions([]expander.Option{}, nil))
 }
@@ -36,6 +36,11 @@ func NewStrategy() expander.Strategy {
 
 // BestOption Finds the option that wastes the least fraction of CPU and Memory
 func (l *leastwaste) BestOption(expansionOptions []expander.Option, nodeInfo map[string]*schedulernodeinfo.NodeInfo) *expander.Option {
+	return l.fallbackStrategy.BestOption(l.BestOptions(expansionOptions, nodeInfo), nodeInfo)
+}
+
+// BestOptions Finds the options that waste the least fraction of CPU and Memory
+func (l *leastwaste) BestOptions(expansionOptions []expander.Option, nodeInfo map[string]*schedulernodeinfo.NodeInfo) []expander.Option {
 	var leastWastedScore float64
 	var leastWastedOptions []expander.Option
 
@@ -66,11 +71,7 @@ func (l *leastwaste) BestOption(expansionOptions []expander.Option, nodeInfo map
 		}
 	}
 
-	if len(leastWastedOptions) == 0 {
-		return nil
-	}
-
-	return l.fallbackStrategy.BestOption(leastWastedOptions, nodeInfo)
+	return leastWastedOptions
 }
 
 func resourcesForPods(pods []*apiv1.Pod) (cpu resource.Quantity, memory resource.Quantity) {
@@ -21,6 +21,7 @@ import (
 	"time"
 
 	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider"
+	"k8s.io/autoscaler/cluster-autoscaler/config"
 	. "k8s.io/autoscaler/cluster-autoscaler/utils/test"
 
 	"github.com/stretchr/testify/assert"
@@ -54,6 +55,9 @@ func (f *FakeNodeGroup) Create() (cloudprovider.NodeGroup, error) {
 }
 func (f *FakeNodeGroup) Delete() error         { return cloudprovider.ErrNotImplemented }
 func (f *FakeNodeGroup) Autoprovisioned() bool { return false }
+func (f *FakeNodeGroup) GetOptions(defaults config.NodeGroupAutoscalingOptions) (*config.NodeGroupAutoscalingOptions, error) {
+	return nil, cloudprovider.ErrNotImplemented
+}
 
 func makeNodeInfo(cpu int64, memory int64, pods int64) *schedulernodeinfo.NodeInfo {
 	node := &apiv1.Node{
@@ -119,4 +123,10 @@ func TestLeastWaste(t *testing.T) {
 	lowcpuOption := expander.Option{NodeGroup: &FakeNodeGroup{"lowcpu"}, NodeCount: 1, Pods: []*apiv1.Pod{pod}}
 	ret = e.BestOption([]expander.Option{balancedOption, highmemOption, lowcpuOption}, nodeMap)
 	assert.Equal(t, *ret, lowcpuOption)
+
+	// Options wasting the same are all returned
+	lowcpuOption2 := expander.Option{NodeGroup: &FakeNodeGroup{"lowcpu2"}, NodeCount: 1, Pods: []*apiv1.Pod{pod}}
+	nodeMap["lowcpu2"] = lowcpuNodeInfo
+	rets := e.BestOptions([]expander.Option{balancedOption, lowcpuOption, lowcpuOption2}, nodeMap)
+	assert.Equal(t, []expander.Option{lowcpuOption, lowcpuOption2}, rets)
 }
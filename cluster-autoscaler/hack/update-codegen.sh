@@ -0,0 +1,36 @@
+#!/bin/bash
+
+# Copyright 2019 The Kubernetes Authors.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#     http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+
+set -o errexit
+set -o nounset
+set -o pipefail
+
+SCRIPT_ROOT=$(dirname ${BASH_SOURCE})/..
+CODEGEN_PKG=${CODEGEN_PKG:-$(cd ${SCRIPT_ROOT}; ls -d -1 ./vendor/k8s.io/code-generator 2>/dev/null || echo ../../code-generator)}
+OUTPUT_BASE="$(dirname ${BASH_SOURCE})/../../../.."
+
+# Only the clientset is generated, Cluster Autoscaler doesn't need listers nor informers.
+${CODEGEN_PKG}/generate-groups.sh deepcopy,client \
+  k8s.io/autoscaler/cluster-autoscaler/client k8s.io/autoscaler/cluster-autoscaler/apis \
+  "clusterautoscaler.autoscaling.k8s.io:v1alpha1" \
+  --output-base "${OUTPUT_BASE}"
+
+# The status types embedded in ClusterAutoscalerStatus live outside of apis.
+go run ${CODEGEN_PKG}/cmd/deepcopy-gen \
+  --input-dirs k8s.io/autoscaler/cluster-autoscaler/clusterstate/api \
+  -O zz_generated.deepcopy \
+  --go-header-file ${CODEGEN_PKG}/hack/boilerplate.go.txt \
+  --output-base "${OUTPUT_BASE}"
@@ -0,0 +1,60 @@
+#!/bin/bash
+
+# Copyright 2019 The Kubernetes Authors.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#     http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+
+# Regenerates the Go bindings of the externalgrpc cloud provider protocol.
+# Requires protoc (3.x) on PATH, or set PROTOC.
+
+set -o errexit
+set -o nounset
+set -o pipefail
+
+SCRIPT_ROOT=$(cd $(dirname ${BASH_SOURCE})/..; pwd)
+PROTOS_DIR=${SCRIPT_ROOT}/cloudprovider/externalgrpc/protos
+PROTOC=${PROTOC:-protoc}
+# protoc-gen-go needs to match the vendored github.com/golang/protobuf, newer
+# versions generate code it can't compile. gRPC services are generated with
+# its grpc plugin, protoc-gen-go-grpc needs a newer google.golang.org/grpc.
+PROTOC_GEN_GO_VERSION=v1.1.0
+
+TMP_DIR=$(mktemp -d)
+trap "rm -rf ${TMP_DIR}" EXIT
+
+(
+  cd ${TMP_DIR}
+  export GO111MODULE=on GOFLAGS=-mod=mod
+  go mod init protoc-gen-go >/dev/null 2>&1
+  go get github.com/golang/protobuf@${PROTOC_GEN_GO_VERSION}
+  go build -o ${TMP_DIR}/bin/protoc-gen-go github.com/golang/protobuf/protoc-gen-go
+)
+
+# Well-known types are mapped to the vendored packages, protoc releases may
+# declare a different go_package for them.
+GO_OPTS="plugins=grpc"
+GO_OPTS+=",Mgoogle/protobuf/duration.proto=github.com/golang/protobuf/ptypes/duration"
+GO_OPTS+=",Mgoogle/protobuf/timestamp.proto=github.com/golang/protobuf/ptypes/timestamp"
+
+cd ${PROTOS_DIR}
+${PROTOC} \
+  --plugin=protoc-gen-go=${TMP_DIR}/bin/protoc-gen-go \
+  -I . \
+  --go_out=${GO_OPTS}:${TMP_DIR} \
+  externalgrpc.proto
+
+(
+  sed "s/YEAR/2019/" ${SCRIPT_ROOT}/hack/boilerplate.go.txt
+  cat ${TMP_DIR}/externalgrpc.pb.go
+) > ${PROTOS_DIR}/externalgrpc.pb.go
+gofmt -w ${PROTOS_DIR}/externalgrpc.pb.go
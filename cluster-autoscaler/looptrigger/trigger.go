@@ -0,0 +1,153 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package looptrigger starts autoscaling loops early in reaction to cluster
+// events, in addition to the regular scan interval.
+package looptrigger
+
+import (
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/util/clock"
+	kube_util "k8s.io/autoscaler/cluster-autoscaler/utils/kubernetes"
+	client "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	podv1 "k8s.io/kubernetes/pkg/api/v1/pod"
+
+	"k8s.io/klog"
+)
+
+// Trigger starts autoscaling loops before the scan interval passes, when new
+// unschedulable pods appear or node readiness changes. Events are debounced:
+// all events seen within the debounce period after the first one start a
+// single loop. Loops never start more often than once per the minimal interval.
+type Trigger struct {
+	debounce    time.Duration
+	minInterval time.Duration
+	events      chan struct{}
+	lastLoop    time.Time
+	clock       clock.Clock
+}
+
+// NewTrigger builds new Trigger.
+func NewTrigger(debounce, minInterval time.Duration) *Trigger {
+	return &Trigger{
+		debounce:    debounce,
+		minInterval: minInterval,
+		events:      make(chan struct{}, 1),
+		clock:       clock.RealClock{},
+	}
+}
+
+// Notify records an event that should start the next loop early.
+func (t *Trigger) Notify() {
+	select {
+	case t.events <- struct{}{}:
+	default:
+		// A loop is already pending.
+	}
+}
+
+// Wait blocks until the next autoscaling loop should start, either because
+// interval passed or because of an event. It returns true in the latter case.
+func (t *Trigger) Wait(interval time.Duration) bool {
+	timeout := t.clock.NewTimer(interval)
+	defer timeout.Stop()
+	var fire <-chan time.Time
+	for {
+		select {
+		case <-timeout.C():
+			t.lastLoop = t.clock.Now()
+			return false
+		case <-t.events:
+			if fire != nil {
+				continue
+			}
+			now := t.clock.Now()
+			delay := t.debounce
+			if earliest := t.lastLoop.Add(t.minInterval); now.Add(delay).Before(earliest) {
+				delay = earliest.Sub(now)
+			}
+			fireTimer := t.clock.NewTimer(delay)
+			defer fireTimer.Stop()
+			fire = fireTimer.C()
+		case <-fire:
+			t.lastLoop = t.clock.Now()
+			return true
+		}
+	}
+}
+
+// Watch starts informers notifying the trigger about new unschedulable pods
+// and node readiness changes.
+func (t *Trigger) Watch(kubeClient client.Interface, stop <-chan struct{}) {
+	selector := fields.ParseSelectorOrDie("spec.nodeName==" + "" + ",status.phase!=" +
+		string(apiv1.PodSucceeded) + ",status.phase!=" + string(apiv1.PodFailed))
+	podListWatch := cache.NewListWatchFromClient(kubeClient.CoreV1().RESTClient(), "pods", apiv1.NamespaceAll, selector)
+	_, podController := cache.NewInformer(podListWatch, &apiv1.Pod{}, 0, cache.ResourceEventHandlerFuncs{
+		AddFunc:    t.onPodAdd,
+		UpdateFunc: t.onPodUpdate,
+	})
+	go podController.Run(stop)
+
+	nodeListWatch := cache.NewListWatchFromClient(kubeClient.CoreV1().RESTClient(), "nodes", apiv1.NamespaceAll, fields.Everything())
+	_, nodeController := cache.NewInformer(nodeListWatch, &apiv1.Node{}, 0, cache.ResourceEventHandlerFuncs{
+		UpdateFunc: t.onNodeUpdate,
+	})
+	go nodeController.Run(stop)
+}
+
+func (t *Trigger) onPodAdd(obj interface{}) {
+	pod, ok := obj.(*apiv1.Pod)
+	if ok && isUnschedulable(pod) {
+		klog.V(5).Infof("Pod %s/%s is unschedulable, triggering autoscaling loop", pod.Namespace, pod.Name)
+		t.Notify()
+	}
+}
+
+func (t *Trigger) onPodUpdate(oldObj, newObj interface{}) {
+	oldPod, ok := oldObj.(*apiv1.Pod)
+	if !ok {
+		return
+	}
+	if !isUnschedulable(oldPod) {
+		t.onPodAdd(newObj)
+	}
+}
+
+func (t *Trigger) onNodeUpdate(oldObj, newObj interface{}) {
+	oldNode, ok := oldObj.(*apiv1.Node)
+	if !ok {
+		return
+	}
+	newNode, ok := newObj.(*apiv1.Node)
+	if !ok {
+		return
+	}
+	oldReady, _, _ := kube_util.GetReadinessState(oldNode)
+	newReady, _, _ := kube_util.GetReadinessState(newNode)
+	if oldReady != newReady {
+		klog.V(5).Infof("Readiness of node %s changed to %v, triggering autoscaling loop", newNode.Name, newReady)
+		t.Notify()
+	}
+}
+
+func isUnschedulable(pod *apiv1.Pod) bool {
+	_, condition := podv1.GetPodCondition(&pod.Status, apiv1.PodScheduled)
+	return condition != nil && condition.Status == apiv1.ConditionFalse && condition.Reason == apiv1.PodReasonUnschedulable
+}
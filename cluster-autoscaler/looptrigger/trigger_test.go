@@ -0,0 +1,154 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package looptrigger
+
+import (
+	"testing"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/clock"
+	. "k8s.io/autoscaler/cluster-autoscaler/utils/test"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// timerClock is a fake clock reporting the duration of every timer started
+// by the trigger, so that tests move time only after the trigger waits on it.
+type timerClock struct {
+	*clock.FakeClock
+	timers chan time.Duration
+}
+
+func newTimerClock() *timerClock {
+	return &timerClock{
+		FakeClock: clock.NewFakeClock(time.Now()),
+		timers:    make(chan time.Duration, 10),
+	}
+}
+
+func (c *timerClock) NewTimer(d time.Duration) clock.Timer {
+	timer := c.FakeClock.NewTimer(d)
+	c.timers <- d
+	return timer
+}
+
+func startWait(trigger *Trigger, interval time.Duration) <-chan bool {
+	result := make(chan bool)
+	go func() {
+		result <- trigger.Wait(interval)
+	}()
+	return result
+}
+
+func assertWaiting(t *testing.T, result <-chan bool) {
+	select {
+	case <-result:
+		t.Fatal("Wait returned too early")
+	default:
+	}
+}
+
+func TestWaitTimeout(t *testing.T) {
+	fakeClock := newTimerClock()
+	trigger := NewTrigger(time.Millisecond, time.Millisecond)
+	trigger.clock = fakeClock
+
+	result := startWait(trigger, 10*time.Millisecond)
+	assert.Equal(t, 10*time.Millisecond, <-fakeClock.timers)
+	fakeClock.Step(9 * time.Millisecond)
+	assertWaiting(t, result)
+	fakeClock.Step(time.Millisecond)
+	assert.False(t, <-result)
+}
+
+func TestWaitDebounce(t *testing.T) {
+	fakeClock := newTimerClock()
+	trigger := NewTrigger(50*time.Millisecond, 0)
+	trigger.clock = fakeClock
+
+	result := startWait(trigger, time.Hour)
+	assert.Equal(t, time.Hour, <-fakeClock.timers)
+	trigger.Notify()
+	assert.Equal(t, 50*time.Millisecond, <-fakeClock.timers)
+	for i := 0; i < 9; i++ {
+		fakeClock.Step(5 * time.Millisecond)
+		// Blocks until the trigger picks up the previous event.
+		trigger.events <- struct{}{}
+	}
+	assertWaiting(t, result)
+	fakeClock.Step(5 * time.Millisecond)
+	assert.True(t, <-result)
+
+	// All of the events started a single loop.
+	assert.Equal(t, 0, len(fakeClock.timers))
+}
+
+func TestWaitMinInterval(t *testing.T) {
+	fakeClock := newTimerClock()
+	trigger := NewTrigger(time.Millisecond, 100*time.Millisecond)
+	trigger.clock = fakeClock
+
+	result := startWait(trigger, time.Millisecond)
+	<-fakeClock.timers
+	fakeClock.Step(time.Millisecond)
+	assert.False(t, <-result)
+
+	trigger.Notify()
+	result = startWait(trigger, time.Hour)
+	assert.Equal(t, time.Hour, <-fakeClock.timers)
+	assert.Equal(t, 100*time.Millisecond, <-fakeClock.timers)
+	fakeClock.Step(99 * time.Millisecond)
+	assertWaiting(t, result)
+	fakeClock.Step(time.Millisecond)
+	assert.True(t, <-result)
+}
+
+func TestPodHandlers(t *testing.T) {
+	pending := BuildTestPod("p1", 100, 0)
+	unschedulable := pending.DeepCopy()
+	unschedulable.Status.Conditions = []apiv1.PodCondition{{
+		Type:   apiv1.PodScheduled,
+		Status: apiv1.ConditionFalse,
+		Reason: apiv1.PodReasonUnschedulable,
+	}}
+
+	trigger := NewTrigger(0, 0)
+	trigger.onPodAdd(pending)
+	assert.Equal(t, 0, len(trigger.events))
+	trigger.onPodAdd(unschedulable)
+	assert.Equal(t, 1, len(trigger.events))
+
+	trigger = NewTrigger(0, 0)
+	trigger.onPodUpdate(unschedulable, unschedulable)
+	assert.Equal(t, 0, len(trigger.events))
+	trigger.onPodUpdate(pending, unschedulable)
+	assert.Equal(t, 1, len(trigger.events))
+}
+
+func TestNodeHandlers(t *testing.T) {
+	ready := BuildTestNode("n1", 1000, 1000)
+	SetNodeReadyState(ready, true, time.Time{})
+	unready := BuildTestNode("n1", 1000, 1000)
+	SetNodeReadyState(unready, false, time.Time{})
+
+	trigger := NewTrigger(0, 0)
+	trigger.onNodeUpdate(ready, ready)
+	assert.Equal(t, 0, len(trigger.events))
+	trigger.onNodeUpdate(ready, unready)
+	assert.Equal(t, 1, len(trigger.events))
+}
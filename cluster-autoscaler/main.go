@@ -20,6 +20,7 @@ import (
 	ctx "context"
 	"flag"
 	"fmt"
+	"io"
 	"net/http"
 	"net/url"
 	"os"
@@ -29,14 +30,22 @@ import (
 	"syscall"
 	"time"
 
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/autoscaler/cluster-autoscaler/client/clientset/versioned"
 	cloudBuilder "k8s.io/autoscaler/cluster-autoscaler/cloudprovider/builder"
 	"k8s.io/autoscaler/cluster-autoscaler/config"
 	"k8s.io/autoscaler/cluster-autoscaler/core"
+	"k8s.io/autoscaler/cluster-autoscaler/debuginfo"
+	"k8s.io/autoscaler/cluster-autoscaler/dryrun"
 	"k8s.io/autoscaler/cluster-autoscaler/estimator"
 	"k8s.io/autoscaler/cluster-autoscaler/expander"
+	"k8s.io/autoscaler/cluster-autoscaler/looptrigger"
 	"k8s.io/autoscaler/cluster-autoscaler/metrics"
-	ca_processors "k8s.io/autoscaler/cluster-autoscaler/processors"
+	"k8s.io/autoscaler/cluster-autoscaler/processors/status"
+	"k8s.io/autoscaler/cluster-autoscaler/scaledownorder"
+	"k8s.io/autoscaler/cluster-autoscaler/snapshot"
+	"k8s.io/autoscaler/cluster-autoscaler/softstate"
 	"k8s.io/autoscaler/cluster-autoscaler/utils/errors"
 	kube_util "k8s.io/autoscaler/cluster-autoscaler/utils/kubernetes"
 	"k8s.io/autoscaler/cluster-autoscaler/utils/units"
@@ -78,6 +87,7 @@ func multiStringFlag(name string, usage string) *MultiStringFlag {
 var (
 	clusterName            = flag.String("cluster-name", "", "Autoscaled cluster name, if available")
 	address                = flag.String("address", ":8085", "The address to expose prometheus metrics.")
+	debugInfoEnabled       = flag.Bool("debug-info-enabled", false, "Should CA serve read-only JSON debug endpoints under /debug/ on the metrics address")
 	kubernetes             = flag.String("kubernetes", "", "Kubernetes master location. Leave blank for default")
 	kubeConfigFile         = flag.String("kubeconfig", "", "Path to kubeconfig file with authorization and master location information.")
 	cloudConfig            = flag.String("cloud-config", "", "The path to the cloud provider configuration file.  Empty string for no configuration file.")
@@ -114,8 +124,16 @@ var (
 			"for scale down when some candidates from previous iteration are no longer valid."+
 			"When calculating the pool size for additional candidates we take"+
 			"max(#nodes * scale-down-candidates-pool-ratio, scale-down-candidates-pool-min-count).")
+	consolidationEnabled = flag.Bool("consolidation-enabled", false,
+		"Should CA replace underutilized nodes with fewer nodes from a cheaper node group. Requires cloud provider pricing")
+	maxConsolidatedNodes    = flag.Int("max-consolidated-nodes", 3, "Maximum number of nodes replaced in a single consolidation")
+	consolidationMinSavings = flag.Float64("consolidation-min-savings", 0.2,
+		"Minimum fraction of the price of replaced nodes a consolidation has to save")
 	nodeDeletionDelayTimeout = flag.Duration("node-deletion-delay-timeout", 2*time.Minute, "Maximum time CA waits for removing delay-deletion.cluster-autoscaler.kubernetes.io/ annotations before deleting the node.")
 	scanInterval             = flag.Duration("scan-interval", 10*time.Second, "How often cluster is reevaluated for scale up or down")
+	scanTriggerEnabled       = flag.Bool("scan-trigger-enabled", false, "Should CA start a loop before scan-interval passes when new unschedulable pods appear or node readiness changes")
+	scanTriggerDebounce      = flag.Duration("scan-trigger-debounce", 2*time.Second, "How long CA collects events before starting a loop triggered by them")
+	scanTriggerMinInterval   = flag.Duration("scan-trigger-min-interval", 5*time.Second, "Minimal time between the starts of two loops when loops are triggered by events")
 	maxNodesTotal            = flag.Int("max-nodes-total", 0, "Maximum number of nodes in all node groups. Cluster autoscaler will not grow the cluster beyond this number.")
 	coresTotal               = flag.String("cores-total", minMaxFlagString(0, config.DefaultMaxClusterCores), "Minimum and maximum number of cores in cluster, in the format <min>:<max>. Cluster autoscaler will not scale the cluster beyond these numbers.")
 	memoryTotal              = flag.String("memory-total", minMaxFlagString(0, config.DefaultMaxClusterMemory), "Minimum and maximum number of gigabytes of memory in cluster, in the format <min>:<max>. Cluster autoscaler will not scale the cluster beyond these numbers.")
@@ -125,6 +143,10 @@ var (
 	maxBulkSoftTaintCount      = flag.Int("max-bulk-soft-taint-count", 10, "Maximum number of nodes that can be tainted/untainted PreferNoSchedule at the same time. Set to 0 to turn off such tainting.")
 	maxBulkSoftTaintTime       = flag.Duration("max-bulk-soft-taint-time", 3*time.Second, "Maximum duration of tainting/untainting nodes as PreferNoSchedule at the same time.")
 	maxEmptyBulkDeleteFlag     = flag.Int("max-empty-bulk-delete", 10, "Maximum number of empty nodes that can be deleted at the same time.")
+	scaleDownBudgetMaxNodes    = flag.Int("scale-down-budget-max-nodes", 0, "Maximum number of nodes removed from a node group within scale-down-budget-window. 0 means no limit. Can be overridden per node group.")
+	scaleDownBudgetMaxRatio    = flag.Float64("scale-down-budget-max-ratio", 0, "Maximum fraction of a node group removed within scale-down-budget-window, rounded up. 0 means no limit. Can be overridden per node group.")
+	scaleDownBudgetWindow      = flag.Duration("scale-down-budget-window", time.Hour, "Sliding time window in which node deletions count against scale-down budgets.")
+	maxDrainParallelismFlag    = flag.Int("max-drain-parallelism", 1, "Maximum number of non-empty nodes that can be drained at the same time.")
 	maxGracefulTerminationFlag = flag.Int("max-graceful-termination-sec", 10*60, "Maximum number of seconds CA waits for pod termination when trying to scale down a node.")
 	maxTotalUnreadyPercentage  = flag.Float64("max-total-unready-percentage", 45, "Maximum percentage of unready nodes in the cluster.  After this is exceeded, CA halts operations")
 	okTotalUnreadyCount        = flag.Int("ok-total-unready-count", 3, "Number of allowed unready nodes, irrespective of max-total-unready-percentage")
@@ -144,17 +166,26 @@ var (
 		"Type of resource estimator to be used in scale up. Available values: ["+strings.Join(estimator.AvailableEstimators, ",")+"]")
 
 	expanderFlag = flag.String("expander", expander.RandomExpanderName,
-		"Type of node group expander to be used in scale up. Available values: ["+strings.Join(expander.AvailableExpanders, ",")+"]")
+		"Type of node group expander to be used in scale up. Available values: ["+strings.Join(expander.AvailableExpanders, ",")+"]. "+
+			"Can be a comma separated list, in which case each expander only breaks ties left by the expanders before it.")
+	scaleDownOrderFlag = flag.String("scale-down-order", scaledownorder.DefaultOrderName,
+		"Order in which nodes are considered for scale down. Available values: ["+strings.Join(scaledownorder.AvailableOrders, ",")+"]")
+	externalExpanderConfigFlag = flag.String("expander-external-config", "", "Path to the configuration of the external expander, "+
+		"with the URL of the decision service, timeout, TLS settings and the fallback expander")
 
 	ignoreDaemonSetsUtilization = flag.Bool("ignore-daemonsets-utilization", false,
 		"Should CA ignore DaemonSet pods when calculating resource utilization for scaling down")
 	ignoreMirrorPodsUtilization = flag.Bool("ignore-mirror-pods-utilization", false,
 		"Should CA ignore Mirror pods when calculating resource utilization for scaling down")
 
-	writeStatusConfigMapFlag         = flag.Bool("write-status-configmap", true, "Should CA write status information to a configmap")
+	writeStatusConfigMapFlag         = flag.Bool("write-status-configmap", true, "Should CA write human-readable status information to a configmap. This is the legacy status output, see write-status-crd")
+	writeStatusCRDFlag               = flag.Bool("write-status-crd", false, "Should CA write status information to a ClusterAutoscalerStatus object. Requires the ClusterAutoscalerStatus CRD to be installed")
+	recordUnremovableNodeReasons     = flag.Bool("record-unremovable-node-reasons", false, "Should CA record why nodes can't be scaled down as node events and the "+status.UnremovableReasonAnnotationKey+" node annotation")
 	maxInactivityTimeFlag            = flag.Duration("max-inactivity", 10*time.Minute, "Maximum time from last recorded autoscaler activity before automatic restart")
 	maxFailingTimeFlag               = flag.Duration("max-failing-time", 15*time.Minute, "Maximum time from last recorded successful autoscaler run before automatic restart")
 	balanceSimilarNodeGroupsFlag     = flag.Bool("balance-similar-node-groups", false, "Detect similar node groups and balance the number of nodes between them")
+	rebalanceSimilarNodeGroupsFlag   = flag.Bool("rebalance-similar-node-groups", false, "Don't let scale-down make a node group smaller than similar node groups, and remove nodes from node groups larger than similar ones regardless of their utilization. Requires --balance-similar-node-groups")
+	maxParallelScaleUps              = flag.Int("max-parallel-scale-ups", 1, "Maximum number of node groups scaled up in a single loop for pods with different requirements")
 	nodeAutoprovisioningEnabled      = flag.Bool("node-autoprovisioning-enabled", false, "Should CA autoprovision node groups when needed")
 	maxAutoprovisionedNodeGroupCount = flag.Int("max-autoprovisioned-node-group-count", 15, "The maximum number of autoprovisioned groups in the cluster.")
 
@@ -167,6 +198,34 @@ var (
 			"Setting it to false employs a more lenient filtering approach that does not try to pack the pods on the nodes."+
 			"Pods with nominatedNodeName set are always filtered out.")
 	ignoreTaintsFlag = multiStringFlag("ignore-taint", "Specifies a taint to ignore in node templates when considering to scale a node group")
+	dryRun           = flag.Bool("dry-run", false, "Run the whole autoscaling loop without changing the cluster. "+
+		"Cloud provider and Kubernetes API writes are recorded and a report of what would have been done is written after each loop.")
+	dryRunReportFile = flag.String("dry-run-report-file", "", "File to which dry run reports are appended as JSON lines. Reports are logged if empty.")
+	snapshotFile     = flag.String("snapshot-file", "", "If set, CA captures the cluster state its first autoscaling loop would read into this file and exits. "+
+		"The snapshot can be replayed offline with the snapshot/replay command.")
+	headroomFlag = multiStringFlag("headroom", "Spare capacity CA keeps free for pods that don't exist yet, as comma separated key=value pairs: "+
+		"nodeGroup (cluster-wide if empty), cpu, memory and gpu (amounts), percentage (of allocatable resources, overrides amounts) "+
+		"and pods (number of equal virtual pods the headroom is split into, 1 by default). Can be used multiple times.")
+	softStateStore = flag.String("soft-state-store", "", "Where CA keeps soft state, like unneeded node timers and node group backoffs, across restarts. "+
+		"Available values: ["+strings.Join(softstate.AvailableStores, ",")+"]. Soft state isn't kept if empty.")
+	softStateFile               = flag.String("soft-state-file", "", "File used by the file soft state store")
+	softStateCheckpointInterval = flag.Duration("soft-state-checkpoint-interval", time.Minute, "How often CA saves soft state")
+	softStateMaxAge             = flag.Duration("soft-state-max-age", 10*time.Minute, "Maximum age of saved soft state that is restored on startup")
+	capacityProfilesEnabled     = flag.Bool("capacity-profiles-enabled", false, "Should CA raise minimum sizes of node groups according to scheduled "+
+		"capacity profiles from the cluster-autoscaler-capacity-profiles ConfigMap")
+	maintenanceWindowsEnabled = flag.Bool("maintenance-windows-enabled", false, "Should CA disable scale-down, or limit it to empty nodes, "+
+		"during maintenance windows from the cluster-autoscaler-maintenance-windows ConfigMap")
+	preDeletionHookFlag = multiStringFlag("pre-deletion-hook", "HTTP webhook called before a node is drained and before it's deleted, "+
+		"which can approve, delay or veto the deletion, as comma separated key=value pairs: url, timeout (10s by default), "+
+		"failurePolicy (Fail or Ignore, Fail by default) and maxDelay (node-deletion-delay-timeout by default). "+
+		"Can be used multiple times, hooks are called in order.")
+	balancingIgnoreLabelFlag = multiStringFlag("balancing-ignore-label", "Label whose values may differ between similar node groups, "+
+		"in addition to the hostname, zone and region labels. Can be used multiple times.")
+	balancingResourceToleranceFlag = flag.String("balancing-resource-tolerance", "", "Ratios by which allocatable and free resources may differ "+
+		"between similar node groups, as comma separated <resource>=<ratio> pairs, like memory=0.1. The ratio is 0.05 for other resources.")
+	balancingCompareTaintsFlag  = flag.Bool("balancing-compare-taints", false, "Consider only node groups with the same taints similar")
+	balancingProviderLabelsFlag = flag.Bool("balancing-provider-labels", false, "Also ignore labels the cloud provider sets to values specific to each node group "+
+		"when looking for similar node groups. Supported on aws and azure.")
 )
 
 func createAutoscalingOptions() config.AutoscalingOptions {
@@ -186,6 +245,18 @@ func createAutoscalingOptions() config.AutoscalingOptions {
 	if err != nil {
 		klog.Fatalf("Failed to parse flags: %v", err)
 	}
+	parsedHeadroom, err := parseMultipleHeadroom(*headroomFlag)
+	if err != nil {
+		klog.Fatalf("Failed to parse flags: %v", err)
+	}
+	parsedPreDeletionHooks, err := parseMultiplePreDeletionHooks(*preDeletionHookFlag, *nodeDeletionDelayTimeout)
+	if err != nil {
+		klog.Fatalf("Failed to parse flags: %v", err)
+	}
+	parsedResourceTolerances, err := parseResourceTolerances(*balancingResourceToleranceFlag)
+	if err != nil {
+		klog.Fatalf("Failed to parse flags: %v", err)
+	}
 	return config.AutoscalingOptions{
 		CloudConfig:                         *cloudConfig,
 		CloudProviderName:                   *cloudProviderFlag,
@@ -194,11 +265,17 @@ func createAutoscalingOptions() config.AutoscalingOptions {
 		OkTotalUnreadyCount:                 *okTotalUnreadyCount,
 		EstimatorName:                       *estimatorFlag,
 		ExpanderName:                        *expanderFlag,
+		ExternalExpanderConfig:              *externalExpanderConfigFlag,
+		ScaleDownOrderName:                  *scaleDownOrderFlag,
 		IgnoreDaemonSetsUtilization:         *ignoreDaemonSetsUtilization,
 		IgnoreMirrorPodsUtilization:         *ignoreMirrorPodsUtilization,
 		MaxBulkSoftTaintCount:               *maxBulkSoftTaintCount,
 		MaxBulkSoftTaintTime:                *maxBulkSoftTaintTime,
 		MaxEmptyBulkDelete:                  *maxEmptyBulkDeleteFlag,
+		ScaleDownBudgetMaxNodes:             *scaleDownBudgetMaxNodes,
+		ScaleDownBudgetMaxRatio:             *scaleDownBudgetMaxRatio,
+		ScaleDownBudgetWindow:               *scaleDownBudgetWindow,
+		MaxDrainParallelism:                 *maxDrainParallelismFlag,
 		MaxGracefulTerminationSec:           *maxGracefulTerminationFlag,
 		MaxNodeProvisionTime:                *maxNodeProvisionTime,
 		MaxNodesTotal:                       *maxNodesTotal,
@@ -220,7 +297,15 @@ func createAutoscalingOptions() config.AutoscalingOptions {
 		ScaleDownCandidatesPoolRatio:        *scaleDownCandidatesPoolRatio,
 		ScaleDownCandidatesPoolMinCount:     *scaleDownCandidatesPoolMinCount,
 		WriteStatusConfigMap:                *writeStatusConfigMapFlag,
+		WriteStatusCRD:                      *writeStatusCRDFlag,
+		RecordUnremovableNodeReasons:        *recordUnremovableNodeReasons,
 		BalanceSimilarNodeGroups:            *balanceSimilarNodeGroupsFlag,
+		RebalanceSimilarNodeGroups:          *rebalanceSimilarNodeGroupsFlag,
+		BalancingIgnoredLabels:              *balancingIgnoreLabelFlag,
+		BalancingResourceTolerances:         parsedResourceTolerances,
+		BalancingCompareTaints:              *balancingCompareTaintsFlag,
+		BalancingProviderLabels:             *balancingProviderLabelsFlag,
+		MaxParallelScaleUps:                 *maxParallelScaleUps,
 		ConfigNamespace:                     *namespace,
 		ClusterName:                         *clusterName,
 		NodeAutoprovisioningEnabled:         *nodeAutoprovisioningEnabled,
@@ -232,6 +317,18 @@ func createAutoscalingOptions() config.AutoscalingOptions {
 		FilterOutSchedulablePodsUsesPacking: *filterOutSchedulablePodsUsesPacking,
 		IgnoredTaints:                       *ignoreTaintsFlag,
 		NodeDeletionDelayTimeout:            *nodeDeletionDelayTimeout,
+		DryRun:                              *dryRun,
+		CapacityProfilesEnabled:             *capacityProfilesEnabled,
+		Headroom:                            parsedHeadroom,
+		ConsolidationEnabled:                *consolidationEnabled,
+		MaxConsolidatedNodes:                *maxConsolidatedNodes,
+		ConsolidationMinSavings:             *consolidationMinSavings,
+		SoftStateStoreName:                  *softStateStore,
+		SoftStateFile:                       *softStateFile,
+		SoftStateCheckpointInterval:         *softStateCheckpointInterval,
+		SoftStateMaxAge:                     *softStateMaxAge,
+		MaintenanceWindowsEnabled:           *maintenanceWindowsEnabled,
+		PreDeletionHooks:                    parsedPreDeletionHooks,
 	}
 }
 
@@ -283,8 +380,14 @@ func buildAutoscaler() (core.Autoscaler, error) {
 	kubeClient := createKubeClient(getKubeConfig())
 	eventsKubeClient := createKubeClient(getKubeConfig())
 
-	processors := ca_processors.DefaultProcessors()
-	processors.PodListProcessor = core.NewFilterOutSchedulablePodListProcessor()
+	processors := core.NewProcessors(autoscalingOptions)
+	if autoscalingOptions.WriteStatusCRD && !autoscalingOptions.DryRun {
+		processors.AutoscalingStatusProcessor = status.NewCRDStatusWriter(
+			versioned.NewForConfigOrDie(getKubeConfig()), autoscalingOptions.ConfigNamespace, processors.AutoscalingStatusProcessor)
+	}
+	if autoscalingOptions.RecordUnremovableNodeReasons {
+		processors.ScaleDownStatusProcessor = status.NewUnremovableNodesRecorder(processors.ScaleDownStatusProcessor)
+	}
 
 	opts := core.AutoscalerOptions{
 		AutoscalingOptions: autoscalingOptions,
@@ -293,6 +396,19 @@ func buildAutoscaler() (core.Autoscaler, error) {
 		Processors:         processors,
 	}
 
+	if *debugInfoEnabled {
+		opts.DebugInfo = debuginfo.NewRecorder()
+		opts.DebugInfo.RegisterHandlers(http.DefaultServeMux)
+		opts.Processors.ScaleUpStatusProcessor, opts.Processors.ScaleDownStatusProcessor, opts.Processors.AutoscalingStatusProcessor =
+			opts.DebugInfo.WrapProcessors(opts.Processors.ScaleUpStatusProcessor, opts.Processors.ScaleDownStatusProcessor, opts.Processors.AutoscalingStatusProcessor)
+	}
+
+	if autoscalingOptions.DryRun {
+		if err := setUpDryRun(&opts); err != nil {
+			return nil, err
+		}
+	}
+
 	// This metric should be published only once.
 	metrics.UpdateNapEnabled(autoscalingOptions.NodeAutoprovisioningEnabled)
 
@@ -300,6 +416,58 @@ func buildAutoscaler() (core.Autoscaler, error) {
 	return core.NewAutoscaler(opts)
 }
 
+// setUpDryRun replaces the cloud provider and Kubernetes clients with ones
+// recording changes instead of applying them, and makes the status processors
+// report what each loop would have done.
+func setUpDryRun(opts *core.AutoscalerOptions) error {
+	klog.Warning("Running in dry run mode, the cluster won't be changed")
+	var reportWriter io.Writer
+	if *dryRunReportFile != "" {
+		file, err := os.OpenFile(*dryRunReportFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			return fmt.Errorf("failed to open dry run report file: %v", err)
+		}
+		reportWriter = file
+	}
+	recorder := dryrun.NewRecorder()
+	opts.CloudProvider = dryrun.NewCloudProvider(cloudBuilder.NewCloudProvider(opts.AutoscalingOptions), recorder)
+	opts.KubeClient = dryrun.NewKubeClient(getKubeConfig(), recorder)
+	opts.EventsKubeClient = dryrun.NewKubeClient(getKubeConfig(), recorder)
+	reporter := dryrun.NewReporter(recorder, reportWriter)
+	opts.Processors.ScaleUpStatusProcessor, opts.Processors.ScaleDownStatusProcessor, opts.Processors.AutoscalingStatusProcessor =
+		reporter.WrapProcessors(opts.Processors.ScaleUpStatusProcessor, opts.Processors.ScaleDownStatusProcessor, opts.Processors.AutoscalingStatusProcessor)
+	return nil
+}
+
+// captureSnapshot writes the state read by the first autoscaling loop to the
+// given file.
+func captureSnapshot(path string) error {
+	autoscalingOptions := createAutoscalingOptions()
+	kubeClient := createKubeClient(getKubeConfig())
+	stop := make(chan struct{})
+	defer close(stop)
+	listerRegistry := kube_util.NewListerRegistryWithDefaultListers(kubeClient, stop)
+	cloudProvider := cloudBuilder.NewCloudProvider(autoscalingOptions)
+	defer cloudProvider.Cleanup()
+
+	// Listers get the same time to sync as they get before the first loop.
+	time.Sleep(*scanInterval)
+	captured, err := snapshot.Capture(autoscalingOptions, listerRegistry, cloudProvider, time.Now())
+	if err != nil {
+		return err
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if err := snapshot.Write(file, captured); err != nil {
+		return err
+	}
+	klog.V(1).Infof("Captured %d nodes and %d node groups into %s", len(captured.Nodes), len(captured.NodeGroups), path)
+	return nil
+}
+
 func run(healthCheck *metrics.HealthCheck) {
 	metrics.RegisterAll()
 
@@ -314,25 +482,34 @@ func run(healthCheck *metrics.HealthCheck) {
 	// Start updating health check endpoint.
 	healthCheck.StartMonitoring()
 
+	var trigger *looptrigger.Trigger
+	if *scanTriggerEnabled {
+		trigger = looptrigger.NewTrigger(*scanTriggerDebounce, *scanTriggerMinInterval)
+		trigger.Watch(createKubeClient(getKubeConfig()), make(chan struct{}))
+	}
+
 	// Autoscale ad infinitum.
 	for {
-		select {
-		case <-time.After(*scanInterval):
-			{
-				loopStart := time.Now()
-				metrics.UpdateLastTime(metrics.Main, loopStart)
-				healthCheck.UpdateLastActivity(loopStart)
-
-				err := autoscaler.RunOnce(loopStart)
-				if err != nil && err.Type() != errors.TransientError {
-					metrics.RegisterError(err)
-				} else {
-					healthCheck.UpdateLastSuccessfulRun(time.Now())
-				}
-
-				metrics.UpdateDurationFromStart(metrics.Main, loopStart)
+		if trigger != nil {
+			if trigger.Wait(*scanInterval) {
+				klog.V(4).Info("Autoscaling loop triggered by cluster events")
 			}
+		} else {
+			time.Sleep(*scanInterval)
 		}
+
+		loopStart := time.Now()
+		metrics.UpdateLastTime(metrics.Main, loopStart)
+		healthCheck.UpdateLastActivity(loopStart)
+
+		err := autoscaler.RunOnce(loopStart)
+		if err != nil && err.Type() != errors.TransientError {
+			metrics.RegisterError(err)
+		} else {
+			healthCheck.UpdateLastSuccessfulRun(time.Now())
+		}
+
+		metrics.UpdateDurationFromStart(metrics.Main, loopStart)
 	}
 }
 
@@ -348,6 +525,13 @@ func main() {
 
 	klog.V(1).Infof("Cluster Autoscaler %s", version.ClusterAutoscalerVersion)
 
+	if *snapshotFile != "" {
+		if err := captureSnapshot(*snapshotFile); err != nil {
+			klog.Fatalf("Failed to capture snapshot: %v", err)
+		}
+		return
+	}
+
 	go func() {
 		http.Handle("/metrics", prometheus.Handler())
 		http.Handle("/health-check", healthCheck)
@@ -355,7 +539,9 @@ func main() {
 		klog.Fatalf("Failed to start metrics: %v", err)
 	}()
 
-	if !leaderElection.LeaderElect {
+	// A dry run instance is usually run next to a real one, so it must not
+	// take over the leader election lock.
+	if !leaderElection.LeaderElect || *dryRun {
 		run(healthCheck)
 	} else {
 		id, err := os.Hostname()
@@ -471,6 +657,142 @@ func parseMultipleGpuLimits(flags MultiStringFlag) ([]config.GpuLimits, error) {
 	return parsedFlags, nil
 }
 
+func parseMultipleHeadroom(flags MultiStringFlag) ([]config.Headroom, error) {
+	parsedFlags := make([]config.Headroom, 0, len(flags))
+	for _, flag := range flags {
+		parsedFlag, err := parseSingleHeadroom(flag)
+		if err != nil {
+			return nil, err
+		}
+		parsedFlags = append(parsedFlags, parsedFlag)
+	}
+	return parsedFlags, nil
+}
+
+func parseSingleHeadroom(spec string) (config.Headroom, error) {
+	headroom := config.Headroom{Pods: 1}
+	for _, pair := range strings.Split(spec, ",") {
+		parts := strings.SplitN(pair, "=", 2)
+		if len(parts) != 2 {
+			return config.Headroom{}, fmt.Errorf("incorrect headroom specification: %v", spec)
+		}
+		key, value := parts[0], parts[1]
+		if key == "nodeGroup" {
+			headroom.NodeGroup = value
+			continue
+		}
+		quantity, err := resource.ParseQuantity(value)
+		if err != nil {
+			return config.Headroom{}, fmt.Errorf("incorrect headroom - %s is not a quantity: %v", key, spec)
+		}
+		if quantity.Sign() < 0 {
+			return config.Headroom{}, fmt.Errorf("incorrect headroom - %s is less than 0: %v", key, spec)
+		}
+		switch key {
+		case "cpu":
+			headroom.MilliCpu = quantity.MilliValue()
+		case "memory":
+			headroom.Memory = quantity.Value()
+		case "gpu":
+			headroom.Gpu = quantity.Value()
+		case "percentage":
+			headroom.Percentage = quantity.Value()
+			if headroom.Percentage > 100 {
+				return config.Headroom{}, fmt.Errorf("incorrect headroom - percentage is greater than 100: %v", spec)
+			}
+		case "pods":
+			headroom.Pods = int(quantity.Value())
+			if headroom.Pods < 1 {
+				return config.Headroom{}, fmt.Errorf("incorrect headroom - pods is less than 1: %v", spec)
+			}
+		default:
+			return config.Headroom{}, fmt.Errorf("incorrect headroom - unknown key %s: %v", key, spec)
+		}
+	}
+	if headroom.MilliCpu == 0 && headroom.Memory == 0 && headroom.Gpu == 0 && headroom.Percentage == 0 {
+		return config.Headroom{}, fmt.Errorf("incorrect headroom - no resources: %v", spec)
+	}
+	return headroom, nil
+}
+
+func parseResourceTolerances(spec string) (map[string]float64, error) {
+	tolerances := make(map[string]float64)
+	if spec == "" {
+		return tolerances, nil
+	}
+	for _, pair := range strings.Split(spec, ",") {
+		parts := strings.SplitN(pair, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("incorrect resource tolerance specification: %v", spec)
+		}
+		tolerance, err := strconv.ParseFloat(parts[1], 64)
+		if err != nil {
+			return nil, fmt.Errorf("incorrect resource tolerance - %s is not a number: %v", parts[0], spec)
+		}
+		if tolerance < 0 || tolerance > 1 {
+			return nil, fmt.Errorf("incorrect resource tolerance - %s is not between 0 and 1: %v", parts[0], spec)
+		}
+		tolerances[parts[0]] = tolerance
+	}
+	return tolerances, nil
+}
+
+func parseMultiplePreDeletionHooks(flags MultiStringFlag, defaultMaxDelay time.Duration) ([]config.PreDeletionHook, error) {
+	parsedFlags := make([]config.PreDeletionHook, 0, len(flags))
+	for _, flag := range flags {
+		parsedFlag, err := parseSinglePreDeletionHook(flag, defaultMaxDelay)
+		if err != nil {
+			return nil, err
+		}
+		parsedFlags = append(parsedFlags, parsedFlag)
+	}
+	return parsedFlags, nil
+}
+
+func parseSinglePreDeletionHook(spec string, defaultMaxDelay time.Duration) (config.PreDeletionHook, error) {
+	hook := config.PreDeletionHook{
+		Timeout:       10 * time.Second,
+		FailurePolicy: config.PreDeletionHookFail,
+		MaxDelay:      defaultMaxDelay,
+	}
+	for _, pair := range strings.Split(spec, ",") {
+		parts := strings.SplitN(pair, "=", 2)
+		if len(parts) != 2 {
+			return config.PreDeletionHook{}, fmt.Errorf("incorrect pre-deletion hook specification: %v", spec)
+		}
+		key, value := parts[0], parts[1]
+		switch key {
+		case "url":
+			parsedUrl, err := url.Parse(value)
+			if err != nil || (parsedUrl.Scheme != "http" && parsedUrl.Scheme != "https") || parsedUrl.Host == "" {
+				return config.PreDeletionHook{}, fmt.Errorf("incorrect pre-deletion hook - url is not an HTTP(S) URL: %v", spec)
+			}
+			hook.URL = value
+		case "timeout", "maxDelay":
+			duration, err := time.ParseDuration(value)
+			if err != nil || duration < 0 {
+				return config.PreDeletionHook{}, fmt.Errorf("incorrect pre-deletion hook - %s is not a non-negative duration: %v", key, spec)
+			}
+			if key == "timeout" {
+				hook.Timeout = duration
+			} else {
+				hook.MaxDelay = duration
+			}
+		case "failurePolicy":
+			hook.FailurePolicy = config.PreDeletionHookFailurePolicy(value)
+			if hook.FailurePolicy != config.PreDeletionHookFail && hook.FailurePolicy != config.PreDeletionHookIgnore {
+				return config.PreDeletionHook{}, fmt.Errorf("incorrect pre-deletion hook - failurePolicy is neither Fail nor Ignore: %v", spec)
+			}
+		default:
+			return config.PreDeletionHook{}, fmt.Errorf("incorrect pre-deletion hook - unknown key %s: %v", key, spec)
+		}
+	}
+	if hook.URL == "" {
+		return config.PreDeletionHook{}, fmt.Errorf("incorrect pre-deletion hook - no url: %v", spec)
+	}
+	return hook, nil
+}
+
 func parseSingleGpuLimit(limits string) (config.GpuLimits, error) {
 	parts := strings.Split(limits, ":")
 	if len(parts) != 3 {
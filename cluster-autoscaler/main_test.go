@@ -18,6 +18,7 @@ package main
 
 import (
 	"testing"
+	"time"
 
 	"k8s.io/autoscaler/cluster-autoscaler/config"
 
@@ -91,3 +92,205 @@ func TestParseSingleGpuLimit(t *testing.T) {
 		}
 	}
 }
+
+func TestParseSingleHeadroom(t *testing.T) {
+	type testcase struct {
+		input                string
+		expectError          bool
+		expectedHeadroom     config.Headroom
+		expectedErrorMessage string
+	}
+
+	testcases := []testcase{
+		{
+			input: "cpu=4,memory=16Gi,gpu=1",
+			expectedHeadroom: config.Headroom{
+				MilliCpu: 4000,
+				Memory:   16 * 1024 * 1024 * 1024,
+				Gpu:      1,
+				Pods:     1,
+			},
+		},
+		{
+			input: "nodeGroup=ng1,percentage=10,pods=3",
+			expectedHeadroom: config.Headroom{
+				NodeGroup:  "ng1",
+				Percentage: 10,
+				Pods:       3,
+			},
+		},
+		{
+			input:            "cpu=500m",
+			expectedHeadroom: config.Headroom{MilliCpu: 500, Pods: 1},
+		},
+		{
+			input:                "cpu",
+			expectError:          true,
+			expectedErrorMessage: "incorrect headroom specification: cpu",
+		},
+		{
+			input:                "cpu=x",
+			expectError:          true,
+			expectedErrorMessage: "incorrect headroom - cpu is not a quantity: cpu=x",
+		},
+		{
+			input:                "memory=-1Gi",
+			expectError:          true,
+			expectedErrorMessage: "incorrect headroom - memory is less than 0: memory=-1Gi",
+		},
+		{
+			input:                "percentage=101",
+			expectError:          true,
+			expectedErrorMessage: "incorrect headroom - percentage is greater than 100: percentage=101",
+		},
+		{
+			input:                "cpu=1,pods=0",
+			expectError:          true,
+			expectedErrorMessage: "incorrect headroom - pods is less than 1: cpu=1,pods=0",
+		},
+		{
+			input:                "disk=1",
+			expectError:          true,
+			expectedErrorMessage: "incorrect headroom - unknown key disk: disk=1",
+		},
+		{
+			input:                "nodeGroup=ng1",
+			expectError:          true,
+			expectedErrorMessage: "incorrect headroom - no resources: nodeGroup=ng1",
+		},
+	}
+
+	for _, testcase := range testcases {
+		headroom, err := parseSingleHeadroom(testcase.input)
+		if testcase.expectError {
+			assert.NotNil(t, err)
+			if err != nil {
+				assert.Equal(t, testcase.expectedErrorMessage, err.Error())
+			}
+		} else {
+			assert.NoError(t, err)
+			assert.Equal(t, testcase.expectedHeadroom, headroom)
+		}
+	}
+}
+
+func TestParseSinglePreDeletionHook(t *testing.T) {
+	type testcase struct {
+		input                string
+		expectError          bool
+		expectedHook         config.PreDeletionHook
+		expectedErrorMessage string
+	}
+
+	testcases := []testcase{
+		{
+			input: "url=http://localhost:8080/hook",
+			expectedHook: config.PreDeletionHook{
+				URL:           "http://localhost:8080/hook",
+				Timeout:       10 * time.Second,
+				FailurePolicy: config.PreDeletionHookFail,
+				MaxDelay:      2 * time.Minute,
+			},
+		},
+		{
+			input: "url=https://cmdb.example.com/nodes,timeout=3s,failurePolicy=Ignore,maxDelay=15m",
+			expectedHook: config.PreDeletionHook{
+				URL:           "https://cmdb.example.com/nodes",
+				Timeout:       3 * time.Second,
+				FailurePolicy: config.PreDeletionHookIgnore,
+				MaxDelay:      15 * time.Minute,
+			},
+		},
+		{
+			input:                "url",
+			expectError:          true,
+			expectedErrorMessage: "incorrect pre-deletion hook specification: url",
+		},
+		{
+			input:                "url=localhost:8080",
+			expectError:          true,
+			expectedErrorMessage: "incorrect pre-deletion hook - url is not an HTTP(S) URL: url=localhost:8080",
+		},
+		{
+			input:                "url=http://localhost,timeout=x",
+			expectError:          true,
+			expectedErrorMessage: "incorrect pre-deletion hook - timeout is not a non-negative duration: url=http://localhost,timeout=x",
+		},
+		{
+			input:                "url=http://localhost,failurePolicy=Retry",
+			expectError:          true,
+			expectedErrorMessage: "incorrect pre-deletion hook - failurePolicy is neither Fail nor Ignore: url=http://localhost,failurePolicy=Retry",
+		},
+		{
+			input:                "url=http://localhost,method=PUT",
+			expectError:          true,
+			expectedErrorMessage: "incorrect pre-deletion hook - unknown key method: url=http://localhost,method=PUT",
+		},
+		{
+			input:                "timeout=1s",
+			expectError:          true,
+			expectedErrorMessage: "incorrect pre-deletion hook - no url: timeout=1s",
+		},
+	}
+
+	for _, testcase := range testcases {
+		hook, err := parseSinglePreDeletionHook(testcase.input, 2*time.Minute)
+		if testcase.expectError {
+			assert.NotNil(t, err)
+			if err != nil {
+				assert.Equal(t, testcase.expectedErrorMessage, err.Error())
+			}
+		} else {
+			assert.NoError(t, err)
+			assert.Equal(t, testcase.expectedHook, hook)
+		}
+	}
+}
+
+func TestParseResourceTolerances(t *testing.T) {
+	type testcase struct {
+		input                string
+		expectError          bool
+		expectedTolerances   map[string]float64
+		expectedErrorMessage string
+	}
+
+	testcases := []testcase{
+		{
+			input:              "",
+			expectedTolerances: map[string]float64{},
+		},
+		{
+			input:              "memory=0.1,nvidia.com/gpu=0",
+			expectedTolerances: map[string]float64{"memory": 0.1, "nvidia.com/gpu": 0},
+		},
+		{
+			input:                "memory",
+			expectError:          true,
+			expectedErrorMessage: "incorrect resource tolerance specification: memory",
+		},
+		{
+			input:                "cpu=0.1,memory=10%",
+			expectError:          true,
+			expectedErrorMessage: "incorrect resource tolerance - memory is not a number: cpu=0.1,memory=10%",
+		},
+		{
+			input:                "cpu=1.5",
+			expectError:          true,
+			expectedErrorMessage: "incorrect resource tolerance - cpu is not between 0 and 1: cpu=1.5",
+		},
+	}
+
+	for _, testcase := range testcases {
+		tolerances, err := parseResourceTolerances(testcase.input)
+		if testcase.expectError {
+			assert.NotNil(t, err)
+			if err != nil {
+				assert.Equal(t, testcase.expectedErrorMessage, err.Error())
+			}
+		} else {
+			assert.NoError(t, err)
+			assert.Equal(t, testcase.expectedTolerances, tolerances)
+		}
+	}
+}
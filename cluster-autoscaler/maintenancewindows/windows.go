@@ -0,0 +1,256 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package maintenancewindows implements maintenance and blackout windows for
+// scale-down. A window is either recurring, defined by a cron schedule and a
+// duration, or one-off, defined by its start and end. While a window is open,
+// scale-down is either disabled altogether or limited to empty nodes, so no
+// pods are evicted.
+package maintenancewindows
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v2"
+
+	kube_util "k8s.io/autoscaler/cluster-autoscaler/utils/kubernetes"
+	"k8s.io/autoscaler/cluster-autoscaler/utils/schedule"
+	v1lister "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/klog"
+)
+
+const (
+	// MaintenanceWindowsConfigMapName is the name of the ConfigMap holding maintenance windows.
+	MaintenanceWindowsConfigMapName = "cluster-autoscaler-maintenance-windows"
+	// ConfigMapKey is the key in the ConfigMap under which windows are stored.
+	ConfigMapKey = "windows"
+	// MaxDuration is the maximum duration of a recurring window.
+	MaxDuration = 7 * 24 * time.Hour
+)
+
+// Mode defines what scale-down is allowed to do while a window is open.
+type Mode string
+
+const (
+	// ScaleDownDisabled - no nodes are removed. It's the default mode.
+	ScaleDownDisabled Mode = "disabled"
+	// EmptyNodesOnly - only empty nodes are removed, no pods are evicted.
+	EmptyNodesOnly Mode = "emptyNodesOnly"
+)
+
+// oneOffTimeLayouts are the accepted formats of one-off window bounds. Times
+// without an offset are in the time zone of the window.
+var oneOffTimeLayouts = []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02T15:04"}
+
+// Window limits scale-down either every time Schedule fires, for Duration,
+// or once, from Start to End.
+type Window struct {
+	// Name identifies the window in status and logs.
+	Name string `yaml:"name"`
+	// Schedule is a five field cron expression, see schedule.Schedule.
+	Schedule string `yaml:"schedule"`
+	// Duration is how long the window stays open after Schedule fires.
+	Duration time.Duration `yaml:"duration"`
+	// Start is the beginning of a one-off window.
+	Start string `yaml:"start"`
+	// End is the end of a one-off window.
+	End string `yaml:"end"`
+	// TimeZone is the IANA name of the time zone in which Schedule and Start
+	// and End without an offset are evaluated, UTC if empty.
+	TimeZone string `yaml:"timeZone"`
+	// Mode defines what scale-down may do while the window is open.
+	Mode Mode `yaml:"mode"`
+}
+
+type parsedWindow struct {
+	Window
+	location *time.Location
+	// Set for recurring windows.
+	schedule *schedule.Schedule
+	// Set for one-off windows.
+	start, end time.Time
+}
+
+// ActiveWindow is a window open at the time of the last Refresh.
+type ActiveWindow struct {
+	Name  string
+	Mode  Mode
+	Until time.Time
+}
+
+// parseWindows parses and validates a YAML list of windows.
+func parseWindows(config string) ([]parsedWindow, error) {
+	var windows []Window
+	if err := yaml.Unmarshal([]byte(config), &windows); err != nil {
+		return nil, fmt.Errorf("can't parse YAML: %v", err)
+	}
+	result := make([]parsedWindow, 0, len(windows))
+	names := make(map[string]bool)
+	for _, window := range windows {
+		if window.Name == "" {
+			return nil, fmt.Errorf("maintenance window without a name")
+		}
+		if names[window.Name] {
+			return nil, fmt.Errorf("duplicate maintenance window %s", window.Name)
+		}
+		names[window.Name] = true
+		parsed, err := parseWindow(window)
+		if err != nil {
+			return nil, fmt.Errorf("invalid maintenance window %s: %v", window.Name, err)
+		}
+		result = append(result, parsed)
+	}
+	return result, nil
+}
+
+func parseWindow(window Window) (parsedWindow, error) {
+	parsed := parsedWindow{Window: window}
+	switch window.Mode {
+	case "":
+		parsed.Mode = ScaleDownDisabled
+	case ScaleDownDisabled, EmptyNodesOnly:
+	default:
+		return parsed, fmt.Errorf("unknown mode %q, expected %q or %q", window.Mode, ScaleDownDisabled, EmptyNodesOnly)
+	}
+	location, err := time.LoadLocation(window.TimeZone)
+	if err != nil {
+		return parsed, fmt.Errorf("unknown time zone %q: %v", window.TimeZone, err)
+	}
+	parsed.location = location
+
+	recurring := window.Schedule != "" || window.Duration != 0
+	oneOff := window.Start != "" || window.End != ""
+	switch {
+	case recurring && oneOff:
+		return parsed, fmt.Errorf("either schedule and duration or start and end have to be set, not both")
+	case recurring:
+		if parsed.schedule, err = schedule.Parse(window.Schedule); err != nil {
+			return parsed, fmt.Errorf("invalid schedule: %v", err)
+		}
+		if window.Duration <= 0 || window.Duration > MaxDuration {
+			return parsed, fmt.Errorf("duration has to be positive and not longer than %v, got %v", MaxDuration, window.Duration)
+		}
+	case oneOff:
+		if parsed.start, err = parseTime(window.Start, location); err != nil {
+			return parsed, fmt.Errorf("invalid start: %v", err)
+		}
+		if parsed.end, err = parseTime(window.End, location); err != nil {
+			return parsed, fmt.Errorf("invalid end: %v", err)
+		}
+		if !parsed.end.After(parsed.start) {
+			return parsed, fmt.Errorf("end %v is not after start %v", parsed.end, parsed.start)
+		}
+	default:
+		return parsed, fmt.Errorf("either schedule and duration or start and end have to be set")
+	}
+	return parsed, nil
+}
+
+func parseTime(value string, location *time.Location) (time.Time, error) {
+	for _, layout := range oneOffTimeLayouts {
+		if t, err := time.ParseInLocation(layout, value, location); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("%q doesn't match any of the formats %v", value, oneOffTimeLayouts)
+}
+
+// openUntil returns the end of the window if it's open at now.
+func (w *parsedWindow) openUntil(now time.Time) (time.Time, bool) {
+	if w.schedule == nil {
+		if now.Before(w.start) || !now.Before(w.end) {
+			return time.Time{}, false
+		}
+		return w.end, true
+	}
+	start, found := w.schedule.LastStart(now.In(w.location), w.Duration)
+	if !found {
+		return time.Time{}, false
+	}
+	return start.Add(w.Duration), true
+}
+
+// Manager keeps track of maintenance windows configured in a ConfigMap and of
+// the one limiting scale-down at the time of the last Refresh.
+type Manager struct {
+	sync.Mutex
+	configLoader *kube_util.ConfigMapLoader
+	windows      []parsedWindow
+	active       *ActiveWindow
+}
+
+// NewManager creates a Manager reading windows from the maintenance windows
+// ConfigMap.
+func NewManager(configMapLister v1lister.ConfigMapNamespaceLister, logRecorder record.EventRecorder) *Manager {
+	return &Manager{
+		configLoader: kube_util.NewConfigMapLoader(configMapLister, logRecorder, MaintenanceWindowsConfigMapName, ConfigMapKey,
+			"maintenance windows", "MaintenanceWindowsConfigMapInvalid"),
+	}
+}
+
+// Refresh reloads the ConfigMap and determines the window limiting scale-down
+// at now. If the ConfigMap is invalid, previously loaded windows are used.
+func (m *Manager) Refresh(now time.Time) {
+	var windows []parsedWindow
+	err := m.configLoader.Load(func(config string) error {
+		var err error
+		windows, err = parseWindows(config)
+		return err
+	})
+	m.Lock()
+	defer m.Unlock()
+	if err == nil {
+		m.windows = windows
+	}
+
+	var active *ActiveWindow
+	for _, window := range m.windows {
+		until, found := window.openUntil(now)
+		if !found {
+			continue
+		}
+		// A window disabling scale-down wins over one limiting it, the one
+		// open longer wins among windows of the same mode.
+		if active != nil && (active.Mode == ScaleDownDisabled && window.Mode != ScaleDownDisabled ||
+			active.Mode == window.Mode && !until.After(active.Until)) {
+			continue
+		}
+		active = &ActiveWindow{Name: window.Name, Mode: window.Mode, Until: until}
+	}
+	if active != nil && (m.active == nil || m.active.Name != active.Name) {
+		klog.V(1).Infof("Maintenance window %s is open until %v, scale-down mode: %s", active.Name, active.Until, active.Mode)
+	}
+	if active == nil && m.active != nil {
+		klog.V(1).Infof("Maintenance window %s is no longer open", m.active.Name)
+	}
+	m.active = active
+}
+
+// ActiveWindow returns the window limiting scale-down at the time of the last
+// Refresh, or nil if there is none. If several windows are open, one disabling
+// scale-down is returned over one limiting it to empty nodes.
+func (m *Manager) ActiveWindow() *ActiveWindow {
+	m.Lock()
+	defer m.Unlock()
+	if m.active == nil {
+		return nil
+	}
+	active := *m.active
+	return &active
+}
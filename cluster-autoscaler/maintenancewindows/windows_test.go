@@ -0,0 +1,146 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package maintenancewindows
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kube_util "k8s.io/autoscaler/cluster-autoscaler/utils/kubernetes"
+	"k8s.io/client-go/tools/record"
+)
+
+const (
+	testNamespace = "kube-system"
+	config        = `
+- name: nightly-backup
+  schedule: "0 1 * * *"
+  duration: 2h
+  timeZone: Europe/Warsaw
+  mode: emptyNodesOnly
+- name: release-freeze
+  start: "2019-06-03T02:00"
+  end: "2019-06-04T00:00"
+  timeZone: Europe/Warsaw
+- name: migration
+  start: "2019-06-10T00:00:00Z"
+  end: "2019-06-10T06:00:00Z"
+  mode: emptyNodesOnly
+`
+)
+
+var (
+	// 1:00 in Warsaw, which is UTC+2 in June.
+	backupStart = time.Date(2019, time.June, 2, 23, 0, 0, 0, time.UTC)
+)
+
+func newTestManager(t *testing.T, data map[string]string) (*Manager, *record.FakeRecorder) {
+	var configMaps []*apiv1.ConfigMap
+	if data != nil {
+		configMaps = append(configMaps, &apiv1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: testNamespace,
+				Name:      MaintenanceWindowsConfigMapName,
+			},
+			Data: data,
+		})
+	}
+	lister, err := kube_util.NewTestConfigMapLister(configMaps)
+	assert.NoError(t, err)
+	recorder := record.NewFakeRecorder(10)
+	return NewManager(lister.ConfigMaps(testNamespace), recorder), recorder
+}
+
+func TestManagerActiveWindow(t *testing.T) {
+	manager, _ := newTestManager(t, map[string]string{ConfigMapKey: config})
+	freezeStart := backupStart.Add(time.Hour)
+	freezeEnd := time.Date(2019, time.June, 3, 22, 0, 0, 0, time.UTC)
+
+	manager.Refresh(backupStart.Add(-time.Minute))
+	assert.Nil(t, manager.ActiveWindow())
+
+	manager.Refresh(backupStart)
+	assert.Equal(t, &ActiveWindow{Name: "nightly-backup", Mode: EmptyNodesOnly, Until: backupStart.Add(2 * time.Hour)}, normalize(manager.ActiveWindow()))
+
+	// A window disabling scale-down wins over one limiting it.
+	manager.Refresh(freezeStart)
+	assert.Equal(t, &ActiveWindow{Name: "release-freeze", Mode: ScaleDownDisabled, Until: freezeEnd}, normalize(manager.ActiveWindow()))
+
+	manager.Refresh(freezeEnd.Add(-time.Minute))
+	assert.Equal(t, "release-freeze", manager.ActiveWindow().Name)
+	manager.Refresh(freezeEnd)
+	assert.Nil(t, manager.ActiveWindow())
+
+	// The recurring window opens again the next night.
+	manager.Refresh(backupStart.Add(24*time.Hour + 90*time.Minute))
+	assert.Equal(t, "nightly-backup", manager.ActiveWindow().Name)
+
+	manager.Refresh(time.Date(2019, time.June, 10, 3, 0, 0, 0, time.UTC))
+	assert.Equal(t, &ActiveWindow{Name: "migration", Mode: EmptyNodesOnly, Until: time.Date(2019, time.June, 10, 6, 0, 0, 0, time.UTC)},
+		normalize(manager.ActiveWindow()))
+}
+
+// normalize converts the end of the window to UTC so it can be compared.
+func normalize(window *ActiveWindow) *ActiveWindow {
+	if window != nil {
+		window.Until = window.Until.UTC()
+	}
+	return window
+}
+
+func TestManagerNoConfigMap(t *testing.T) {
+	manager, _ := newTestManager(t, nil)
+	manager.Refresh(backupStart)
+	assert.Nil(t, manager.ActiveWindow())
+}
+
+func TestManagerInvalidConfig(t *testing.T) {
+	for _, data := range []map[string]string{
+		{"other": config},
+		{ConfigMapKey: "not a list"},
+		{ConfigMapKey: "- schedule: \"* * * * *\"\n  duration: 1h"},
+		{ConfigMapKey: "- name: w"},
+		{ConfigMapKey: "- name: w\n  schedule: \"* * * *\"\n  duration: 1h"},
+		{ConfigMapKey: "- name: w\n  schedule: \"* * * * *\""},
+		{ConfigMapKey: "- name: w\n  schedule: \"* * * * *\"\n  duration: 200h"},
+		{ConfigMapKey: "- name: w\n  schedule: \"* * * * *\"\n  duration: 1h\n  start: \"2019-06-03T02:00\""},
+		{ConfigMapKey: "- name: w\n  start: \"2019-06-03T02:00\""},
+		{ConfigMapKey: "- name: w\n  start: \"2019-06-03T02:00\"\n  end: \"2019-06-03T01:00\""},
+		{ConfigMapKey: "- name: w\n  start: \"June 3rd\"\n  end: \"2019-06-03T01:00\""},
+		{ConfigMapKey: "- name: w\n  schedule: \"* * * * *\"\n  duration: 1h\n  timeZone: Mars/Olympus"},
+		{ConfigMapKey: "- name: w\n  schedule: \"* * * * *\"\n  duration: 1h\n  mode: drainOnly"},
+		{ConfigMapKey: "- name: w\n  schedule: \"* * * * *\"\n  duration: 1h\n- name: w\n  schedule: \"* * * * *\"\n  duration: 1h"},
+	} {
+		manager, recorder := newTestManager(t, data)
+		// Windows loaded before are kept.
+		manager.windows, _ = parseWindows(config)
+		manager.Refresh(backupStart)
+		if assert.NotNil(t, manager.ActiveWindow(), "config %v", data) {
+			assert.Equal(t, "nightly-backup", manager.ActiveWindow().Name)
+		}
+		select {
+		case event := <-recorder.Events:
+			assert.Contains(t, event, "MaintenanceWindowsConfigMapInvalid")
+		default:
+			t.Errorf("no warning event for config %v", data)
+		}
+	}
+}
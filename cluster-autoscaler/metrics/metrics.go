@@ -54,6 +54,8 @@ const (
 	Empty NodeScaleDownReason = "empty"
 	// Unready node was removed
 	Unready NodeScaleDownReason = "unready"
+	// Consolidated node was replaced with a cheaper one
+	Consolidated NodeScaleDownReason = "consolidated"
 
 	// APIError caused scale-up to fail
 	APIError FailedScaleUpReason = "apiCallError"
@@ -207,6 +209,22 @@ var (
 		},
 	)
 
+	unremovableNodesCount = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: caNamespace,
+			Name:      "unremovable_nodes_count",
+			Help:      "Number of nodes checked for scale-down that can't be removed, by reason.",
+		}, []string{"reason"},
+	)
+
+	nonEmptyNodeDeletionsInProgress = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: caNamespace,
+			Name:      "non_empty_node_deletions_in_progress",
+			Help:      "Number of non-empty nodes currently drained and deleted by CA.",
+		},
+	)
+
 	/**** Metrics related to NodeAutoprovisioning ****/
 	napEnabled = prometheus.NewGauge(
 		prometheus.GaugeOpts{
@@ -249,6 +267,8 @@ func RegisterAll() {
 	prometheus.MustRegister(gpuScaleDownCount)
 	prometheus.MustRegister(evictionsCount)
 	prometheus.MustRegister(unneededNodesCount)
+	prometheus.MustRegister(unremovableNodesCount)
+	prometheus.MustRegister(nonEmptyNodeDeletionsInProgress)
 	prometheus.MustRegister(napEnabled)
 	prometheus.MustRegister(nodeGroupCreationCount)
 	prometheus.MustRegister(nodeGroupDeletionCount)
@@ -342,6 +362,20 @@ func UpdateUnneededNodesCount(nodesCount int) {
 	unneededNodesCount.Set(float64(nodesCount))
 }
 
+// UpdateUnremovableNodesCount records number of nodes that can't be removed, by reason.
+// Reasons missing from the map are reset to zero.
+func UpdateUnremovableNodesCount(countByReason map[string]int) {
+	unremovableNodesCount.Reset()
+	for reason, count := range countByReason {
+		unremovableNodesCount.WithLabelValues(reason).Set(float64(count))
+	}
+}
+
+// UpdateNonEmptyNodeDeletionsInProgress records number of non-empty node deletions in progress
+func UpdateNonEmptyNodeDeletionsInProgress(deletionsCount int) {
+	nonEmptyNodeDeletionsInProgress.Set(float64(deletionsCount))
+}
+
 // UpdateNapEnabled records if NodeAutoprovisioning is enabled
 func UpdateNapEnabled(enabled bool) {
 	if enabled {
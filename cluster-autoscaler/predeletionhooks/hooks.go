@@ -0,0 +1,199 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package predeletionhooks calls HTTP webhooks before CA drains or deletes a
+// node, so external systems, like load balancers or inventories, can
+// deregister the node first. Each hook approves, delays or vetoes the deletion.
+package predeletionhooks
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/autoscaler/cluster-autoscaler/config"
+	"k8s.io/klog"
+)
+
+const (
+	// DefaultRetryAfter is how long CA waits before calling a hook that delayed a deletion again,
+	// if the hook didn't say.
+	DefaultRetryAfter = 10 * time.Second
+	// maxResponseSize limits how much of a hook response is read.
+	maxResponseSize = 1 << 20
+)
+
+// Stage is the step of a node deletion a hook is called before.
+type Stage string
+
+const (
+	// DrainStage - the node is about to be drained. Empty nodes aren't drained.
+	DrainStage Stage = "Drain"
+	// DeleteStage - the node is about to be deleted from the cloud provider.
+	DeleteStage Stage = "Delete"
+)
+
+// Decision is a hook's answer to a deletion request.
+type Decision string
+
+const (
+	// Approve - the deletion can go on.
+	Approve Decision = "Approve"
+	// Delay - the hook isn't ready yet and has to be asked again later.
+	Delay Decision = "Delay"
+	// Veto - the node must not be deleted.
+	Veto Decision = "Veto"
+)
+
+// Request is posted as JSON to hooks.
+type Request struct {
+	Stage      Stage  `json:"stage"`
+	Node       string `json:"node"`
+	ProviderID string `json:"providerID"`
+	NodeGroup  string `json:"nodeGroup"`
+}
+
+// Response is returned as JSON by hooks.
+type Response struct {
+	Decision Decision `json:"decision"`
+	// RetryAfterSeconds is how long to wait before asking again after a Delay decision.
+	RetryAfterSeconds int `json:"retryAfterSeconds,omitempty"`
+	// Message explains the decision, it's reported in events and logs.
+	Message string `json:"message,omitempty"`
+}
+
+// VetoError is returned when a hook vetoes a deletion.
+type VetoError struct {
+	URL     string
+	Message string
+}
+
+func (e *VetoError) Error() string {
+	return fmt.Sprintf("vetoed by pre-deletion hook %s: %s", e.URL, e.Message)
+}
+
+// Runner calls the configured hooks.
+type Runner struct {
+	hooks   []config.PreDeletionHook
+	clients []*http.Client
+	now     func() time.Time
+	sleep   func(time.Duration)
+}
+
+// NewRunner creates a Runner calling the given hooks in order.
+func NewRunner(hooks []config.PreDeletionHook) *Runner {
+	clients := make([]*http.Client, 0, len(hooks))
+	for _, hook := range hooks {
+		clients = append(clients, &http.Client{Timeout: hook.Timeout})
+	}
+	return &Runner{
+		hooks:   hooks,
+		clients: clients,
+		now:     time.Now,
+		sleep:   time.Sleep,
+	}
+}
+
+// Run calls the hooks one by one before the given stage of the deletion of the node,
+// waiting while they delay it. A hook that keeps delaying the deletion for longer than
+// its MaxDelay is treated as if it approved it. Run returns a VetoError if any hook
+// vetoes the deletion, or fails and has the Fail failure policy.
+func (r *Runner) Run(node *apiv1.Node, nodeGroup string, stage Stage) error {
+	request := Request{
+		Stage:      stage,
+		Node:       node.Name,
+		ProviderID: node.Spec.ProviderID,
+		NodeGroup:  nodeGroup,
+	}
+	body, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+	for i, hook := range r.hooks {
+		if err := r.runHook(hook, r.clients[i], request, body); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *Runner) runHook(hook config.PreDeletionHook, client *http.Client, request Request, body []byte) error {
+	deadline := r.now().Add(hook.MaxDelay)
+	for {
+		response, err := call(client, hook.URL, body)
+		if err != nil {
+			if hook.FailurePolicy == config.PreDeletionHookIgnore {
+				klog.Warningf("Pre-deletion hook %s failed for %s node %s, ignoring: %v", hook.URL, request.Stage, request.Node, err)
+				return nil
+			}
+			return &VetoError{URL: hook.URL, Message: fmt.Sprintf("hook failed: %v", err)}
+		}
+		switch response.Decision {
+		case Approve:
+			klog.V(2).Infof("Pre-deletion hook %s approved %s node %s", hook.URL, request.Stage, request.Node)
+			return nil
+		case Veto:
+			return &VetoError{URL: hook.URL, Message: response.Message}
+		}
+
+		retryAfter := DefaultRetryAfter
+		if response.RetryAfterSeconds > 0 {
+			retryAfter = time.Duration(response.RetryAfterSeconds) * time.Second
+		}
+		left := deadline.Sub(r.now())
+		if left <= 0 {
+			klog.Warningf("Pre-deletion hook %s delayed %s node %s for longer than %v, going on: %s",
+				hook.URL, request.Stage, request.Node, hook.MaxDelay, response.Message)
+			return nil
+		}
+		if retryAfter > left {
+			retryAfter = left
+		}
+		klog.V(1).Infof("Pre-deletion hook %s delayed %s node %s, asking again in %v: %s",
+			hook.URL, request.Stage, request.Node, retryAfter, response.Message)
+		r.sleep(retryAfter)
+	}
+}
+
+// call posts the request to the hook and returns its valid response.
+func call(client *http.Client, url string, body []byte) (*Response, error) {
+	httpResponse, err := client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	defer httpResponse.Body.Close()
+	data, err := ioutil.ReadAll(io.LimitReader(httpResponse.Body, maxResponseSize))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %v", err)
+	}
+	if httpResponse.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", httpResponse.Status)
+	}
+	response := &Response{}
+	if err := json.Unmarshal(data, response); err != nil {
+		return nil, fmt.Errorf("invalid response: %v", err)
+	}
+	switch response.Decision {
+	case Approve, Delay, Veto:
+		return response, nil
+	}
+	return nil, fmt.Errorf("unknown decision %q", response.Decision)
+}
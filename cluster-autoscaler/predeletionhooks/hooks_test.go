@@ -0,0 +1,165 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package predeletionhooks
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"k8s.io/autoscaler/cluster-autoscaler/config"
+	. "k8s.io/autoscaler/cluster-autoscaler/utils/test"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testHook is a stand-in hook server answering with the given responses in order,
+// repeating the last one.
+type testHook struct {
+	sync.Mutex
+	server    *httptest.Server
+	responses []string
+	status    int
+	requests  []Request
+}
+
+func newTestHook(responses ...string) *testHook {
+	hook := &testHook{responses: responses, status: http.StatusOK}
+	hook.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hook.Lock()
+		defer hook.Unlock()
+		var request Request
+		if err := json.NewDecoder(r.Body).Decode(&request); err == nil {
+			hook.requests = append(hook.requests, request)
+		}
+		response := hook.responses[0]
+		if len(hook.responses) > 1 {
+			hook.responses = hook.responses[1:]
+		}
+		w.WriteHeader(hook.status)
+		w.Write([]byte(response))
+	}))
+	return hook
+}
+
+func (h *testHook) config(failurePolicy config.PreDeletionHookFailurePolicy) config.PreDeletionHook {
+	return config.PreDeletionHook{URL: h.server.URL, Timeout: 5 * time.Second, FailurePolicy: failurePolicy, MaxDelay: time.Minute}
+}
+
+// newTestRunner creates a Runner with a fake clock advanced by sleeps.
+func newTestRunner(hooks ...config.PreDeletionHook) (*Runner, *[]time.Duration) {
+	r := NewRunner(hooks)
+	now := time.Date(2019, time.June, 3, 0, 0, 0, 0, time.UTC)
+	var sleeps []time.Duration
+	r.now = func() time.Time { return now }
+	r.sleep = func(d time.Duration) {
+		sleeps = append(sleeps, d)
+		now = now.Add(d)
+	}
+	return r, &sleeps
+}
+
+func TestRunApprove(t *testing.T) {
+	hook1 := newTestHook(`{"decision": "Approve"}`)
+	defer hook1.server.Close()
+	hook2 := newTestHook(`{"decision": "Approve"}`)
+	defer hook2.server.Close()
+	r, sleeps := newTestRunner(hook1.config(config.PreDeletionHookFail), hook2.config(config.PreDeletionHookFail))
+
+	n1 := BuildTestNode("n1", 1000, 1000)
+	n1.Spec.ProviderID = "aws:///us-east-1a/i-1"
+	assert.NoError(t, r.Run(n1, "ng1", DrainStage))
+	expected := []Request{{Stage: DrainStage, Node: "n1", ProviderID: "aws:///us-east-1a/i-1", NodeGroup: "ng1"}}
+	assert.Equal(t, expected, hook1.requests)
+	assert.Equal(t, expected, hook2.requests)
+	assert.Empty(t, *sleeps)
+}
+
+func TestRunVeto(t *testing.T) {
+	hook1 := newTestHook(`{"decision": "Veto", "message": "node is a load balancer backend"}`)
+	defer hook1.server.Close()
+	hook2 := newTestHook(`{"decision": "Approve"}`)
+	defer hook2.server.Close()
+	r, _ := newTestRunner(hook1.config(config.PreDeletionHookIgnore), hook2.config(config.PreDeletionHookFail))
+
+	err := r.Run(BuildTestNode("n1", 1000, 1000), "ng1", DeleteStage)
+	if assert.IsType(t, &VetoError{}, err) {
+		assert.Equal(t, "node is a load balancer backend", err.(*VetoError).Message)
+	}
+	// Hooks after the vetoing one aren't called.
+	assert.Empty(t, hook2.requests)
+}
+
+func TestRunDelay(t *testing.T) {
+	hook := newTestHook(`{"decision": "Delay", "retryAfterSeconds": 20}`, `{"decision": "Delay"}`, `{"decision": "Approve"}`)
+	defer hook.server.Close()
+	r, sleeps := newTestRunner(hook.config(config.PreDeletionHookFail))
+
+	assert.NoError(t, r.Run(BuildTestNode("n1", 1000, 1000), "ng1", DeleteStage))
+	assert.Equal(t, 3, len(hook.requests))
+	assert.Equal(t, []time.Duration{20 * time.Second, DefaultRetryAfter}, *sleeps)
+}
+
+func TestRunDelayTimeout(t *testing.T) {
+	hook := newTestHook(`{"decision": "Delay", "retryAfterSeconds": 25}`)
+	defer hook.server.Close()
+	r, sleeps := newTestRunner(hook.config(config.PreDeletionHookFail))
+
+	// The deletion goes on after the max delay, like with the delay-deletion annotation.
+	assert.NoError(t, r.Run(BuildTestNode("n1", 1000, 1000), "ng1", DeleteStage))
+	assert.Equal(t, []time.Duration{25 * time.Second, 25 * time.Second, 10 * time.Second}, *sleeps)
+	assert.Equal(t, 4, len(hook.requests))
+}
+
+func TestRunFailurePolicy(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		status   int
+		response string
+	}{
+		{name: "server error", status: http.StatusInternalServerError, response: `{"decision": "Approve"}`},
+		{name: "invalid JSON", status: http.StatusOK, response: `approved`},
+		{name: "unknown decision", status: http.StatusOK, response: `{"decision": "Maybe"}`},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			hook := newTestHook(tc.response)
+			hook.status = tc.status
+			defer hook.server.Close()
+
+			r, _ := newTestRunner(hook.config(config.PreDeletionHookIgnore))
+			assert.NoError(t, r.Run(BuildTestNode("n1", 1000, 1000), "ng1", DeleteStage))
+
+			r, _ = newTestRunner(hook.config(config.PreDeletionHookFail))
+			assert.IsType(t, &VetoError{}, r.Run(BuildTestNode("n1", 1000, 1000), "ng1", DeleteStage))
+		})
+	}
+}
+
+func TestRunTimeout(t *testing.T) {
+	done := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-done
+	}))
+	defer server.Close()
+	defer close(done)
+
+	r, _ := newTestRunner(config.PreDeletionHook{URL: server.URL, Timeout: 100 * time.Millisecond, FailurePolicy: config.PreDeletionHookFail})
+	assert.IsType(t, &VetoError{}, r.Run(BuildTestNode("n1", 1000, 1000), "ng1", DeleteStage))
+}
@@ -0,0 +1,32 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nodegroupset
+
+// awsIgnoredLabels are labels EKS and eksctl set to values specific to each node
+// group or instance.
+var awsIgnoredLabels = []string{
+	"alpha.eksctl.io/instance-id",
+	"alpha.eksctl.io/nodegroup-name",
+	"eks.amazonaws.com/nodegroup",
+	"k8s.amazonaws.com/eniConfig",
+}
+
+// CreateAwsNodeInfoComparator returns a comparator for AWS node groups, which also
+// ignores labels set by EKS and eksctl.
+func CreateAwsNodeInfoComparator(options NodeInfoComparatorOptions) NodeInfoComparator {
+	return CreateGenericNodeInfoComparator(withIgnoredLabels(options, awsIgnoredLabels...))
+}
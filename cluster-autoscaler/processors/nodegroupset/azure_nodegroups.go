@@ -0,0 +1,29 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nodegroupset
+
+// azureIgnoredLabels are labels AKS sets to the name of the agent pool of a node.
+var azureIgnoredLabels = []string{
+	"agentpool",
+	"kubernetes.azure.com/agentpool",
+}
+
+// CreateAzureNodeInfoComparator returns a comparator for Azure node groups, which
+// also ignores agent pool labels.
+func CreateAzureNodeInfoComparator(options NodeInfoComparatorOptions) NodeInfoComparator {
+	return CreateGenericNodeInfoComparator(withIgnoredLabels(options, azureIgnoredLabels...))
+}
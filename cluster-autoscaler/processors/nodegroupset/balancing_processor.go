@@ -33,7 +33,7 @@ type BalancingNodeGroupSetProcessor struct {
 }
 
 // FindSimilarNodeGroups returns a list of NodeGroups similar to the given one.
-// Two groups are similar if the NodeInfos for them compare equal using IsNodeInfoSimilar.
+// Two groups are similar if the NodeInfos for them compare equal using Comparator, or IsNodeInfoSimilar if it's nil.
 func (b *BalancingNodeGroupSetProcessor) FindSimilarNodeGroups(context *context.AutoscalingContext, nodeGroup cloudprovider.NodeGroup,
 	nodeInfosForGroups map[string]*schedulernodeinfo.NodeInfo) ([]cloudprovider.NodeGroup, errors.AutoscalerError) {
 
@@ -17,10 +17,12 @@ limitations under the License.
 package nodegroupset
 
 import (
+	"fmt"
 	"math"
 
 	apiv1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/util/sets"
 	schedulernodeinfo "k8s.io/kubernetes/pkg/scheduler/nodeinfo"
 )
 
@@ -37,27 +39,89 @@ const (
 // similar enough to be considered a part of a single NodeGroupSet.
 type NodeInfoComparator func(n1, n2 *schedulernodeinfo.NodeInfo) bool
 
+// NodeInfoComparatorOptions configure what differences between nodes are allowed
+// by comparators created with CreateGenericNodeInfoComparator.
+type NodeInfoComparatorOptions struct {
+	// IgnoredLabels are labels whose values may differ between nodes, in addition
+	// to the hostname, zone and region labels.
+	IgnoredLabels []string
+	// ResourceTolerances are the ratios by which allocatable and free resources may
+	// differ, by resource name. MaxAllocatableDifferenceRatio and MaxFreeDifferenceRatio
+	// are used for other resources.
+	ResourceTolerances map[apiv1.ResourceName]float64
+	// CompareTaints tells if nodes need to have the same taints.
+	CompareTaints bool
+}
+
+// basicIgnoredLabels are labels that are expected to differ between similar node groups.
+var basicIgnoredLabels = []string{
+	apiv1.LabelHostname,
+	apiv1.LabelZoneFailureDomain,
+	apiv1.LabelZoneRegion,
+	"beta.kubernetes.io/fluentd-ds-ready", // this is internal label used for determining if fluentd should be installed as deamon set. Used for migration 1.8 to 1.9.
+}
+
+var defaultNodeInfoComparator = CreateGenericNodeInfoComparator(NodeInfoComparatorOptions{})
+
 func compareResourceMapsWithTolerance(resources map[apiv1.ResourceName][]resource.Quantity,
-	maxDifferenceRatio float64) bool {
-	for _, qtyList := range resources {
+	maxDifferenceRatio float64, tolerances map[apiv1.ResourceName]float64) bool {
+	for res, qtyList := range resources {
 		if len(qtyList) != 2 {
 			return false
 		}
+		ratio := maxDifferenceRatio
+		if tolerance, found := tolerances[res]; found {
+			ratio = tolerance
+		}
 		larger := math.Max(float64(qtyList[0].MilliValue()), float64(qtyList[1].MilliValue()))
 		smaller := math.Min(float64(qtyList[0].MilliValue()), float64(qtyList[1].MilliValue()))
-		if larger-smaller > larger*maxDifferenceRatio {
+		if larger-smaller > larger*ratio {
 			return false
 		}
 	}
 	return true
 }
 
+func taintSet(node *apiv1.Node) sets.String {
+	taints := sets.NewString()
+	for _, taint := range node.Spec.Taints {
+		taints.Insert(fmt.Sprintf("%s=%s:%s", taint.Key, taint.Value, taint.Effect))
+	}
+	return taints
+}
+
 // IsNodeInfoSimilar returns true if two NodeInfos are similar enough to consider
 // that the NodeGroups they come from are part of the same NodeGroupSet. The criteria are
 // somewhat arbitrary, but generally we check if resources provided by both nodes
 // are similar enough to likely be the same type of machine and if the set of labels
 // is the same (except for a pre-defined set of labels like hostname or zone).
 func IsNodeInfoSimilar(n1, n2 *schedulernodeinfo.NodeInfo) bool {
+	return defaultNodeInfoComparator(n1, n2)
+}
+
+// CreateGenericNodeInfoComparator returns a NodeInfoComparator checking the same
+// criteria as IsNodeInfoSimilar, with the differences allowed by options.
+func CreateGenericNodeInfoComparator(options NodeInfoComparatorOptions) NodeInfoComparator {
+	ignoredLabels := make(map[string]bool)
+	for _, label := range basicIgnoredLabels {
+		ignoredLabels[label] = true
+	}
+	for _, label := range options.IgnoredLabels {
+		ignoredLabels[label] = true
+	}
+	tolerances := make(map[apiv1.ResourceName]float64, len(options.ResourceTolerances))
+	for res, tolerance := range options.ResourceTolerances {
+		tolerances[res] = tolerance
+	}
+	compareTaints := options.CompareTaints
+
+	return func(n1, n2 *schedulernodeinfo.NodeInfo) bool {
+		return isNodeInfoSimilar(n1, n2, ignoredLabels, tolerances, compareTaints)
+	}
+}
+
+func isNodeInfoSimilar(n1, n2 *schedulernodeinfo.NodeInfo, ignoredLabels map[string]bool,
+	tolerances map[apiv1.ResourceName]float64, compareTaints bool) bool {
 	capacity := make(map[apiv1.ResourceName][]resource.Quantity)
 	allocatable := make(map[apiv1.ResourceName][]resource.Quantity)
 	free := make(map[apiv1.ResourceName][]resource.Quantity)
@@ -85,18 +149,15 @@ func IsNodeInfoSimilar(n1, n2 *schedulernodeinfo.NodeInfo) bool {
 		}
 	}
 	// For allocatable and free we allow resource quantities to be within a few % of each other
-	if !compareResourceMapsWithTolerance(allocatable, MaxAllocatableDifferenceRatio) {
+	if !compareResourceMapsWithTolerance(allocatable, MaxAllocatableDifferenceRatio, tolerances) {
 		return false
 	}
-	if !compareResourceMapsWithTolerance(free, MaxFreeDifferenceRatio) {
+	if !compareResourceMapsWithTolerance(free, MaxFreeDifferenceRatio, tolerances) {
 		return false
 	}
 
-	ignoredLabels := map[string]bool{
-		apiv1.LabelHostname:                   true,
-		apiv1.LabelZoneFailureDomain:          true,
-		apiv1.LabelZoneRegion:                 true,
-		"beta.kubernetes.io/fluentd-ds-ready": true, // this is internal label used for determining if fluentd should be installed as deamon set. Used for migration 1.8 to 1.9.
+	if compareTaints && !taintSet(n1.Node()).Equal(taintSet(n2.Node())) {
+		return false
 	}
 
 	labels := make(map[string][]string)
@@ -115,3 +176,38 @@ func IsNodeInfoSimilar(n1, n2 *schedulernodeinfo.NodeInfo) bool {
 	}
 	return true
 }
+
+// NodeInfoComparatorBuilder creates a NodeInfoComparator allowing at least the
+// differences allowed by options.
+type NodeInfoComparatorBuilder func(options NodeInfoComparatorOptions) NodeInfoComparator
+
+// nodeInfoComparatorBuilders are builders of comparators for node groups of cloud
+// providers which need their own, by cloud provider name.
+var nodeInfoComparatorBuilders = map[string]NodeInfoComparatorBuilder{
+	"aws":   CreateAwsNodeInfoComparator,
+	"azure": CreateAzureNodeInfoComparator,
+}
+
+// RegisterNodeInfoComparatorBuilder makes CreateNodeInfoComparator use the builder
+// for node groups of the given cloud provider. It isn't safe to call concurrently
+// with CreateNodeInfoComparator.
+func RegisterNodeInfoComparatorBuilder(providerName string, builder NodeInfoComparatorBuilder) {
+	nodeInfoComparatorBuilders[providerName] = builder
+}
+
+// CreateNodeInfoComparator returns a NodeInfoComparator for node groups of the given
+// cloud provider. Cloud providers without a registered builder use the generic one.
+func CreateNodeInfoComparator(providerName string, options NodeInfoComparatorOptions) NodeInfoComparator {
+	if builder, found := nodeInfoComparatorBuilders[providerName]; found {
+		return builder(options)
+	}
+	return CreateGenericNodeInfoComparator(options)
+}
+
+// withIgnoredLabels returns options that also ignore the given labels.
+func withIgnoredLabels(options NodeInfoComparatorOptions, labels ...string) NodeInfoComparatorOptions {
+	ignoredLabels := make([]string, 0, len(options.IgnoredLabels)+len(labels))
+	ignoredLabels = append(ignoredLabels, options.IgnoredLabels...)
+	options.IgnoredLabels = append(ignoredLabels, labels...)
+	return options
+}
@@ -129,3 +129,120 @@ func TestNodesSimilarVariousLabels(t *testing.T) {
 	delete(n2.ObjectMeta.Labels, "beta.kubernetes.io/fluentd-ds-ready")
 	checkNodesSimilar(t, n1, n2, IsNodeInfoSimilar, true)
 }
+
+func TestNodesSimilarIgnoredLabels(t *testing.T) {
+	n1 := BuildTestNode("node1", 1000, 2000)
+	n1.ObjectMeta.Labels["nodepool-id"] = "pool1"
+	n2 := BuildTestNode("node2", 1000, 2000)
+	n2.ObjectMeta.Labels["nodepool-id"] = "pool2"
+	checkNodesSimilar(t, n1, n2, IsNodeInfoSimilar, false)
+
+	comparator := CreateGenericNodeInfoComparator(NodeInfoComparatorOptions{IgnoredLabels: []string{"nodepool-id"}})
+	checkNodesSimilar(t, n1, n2, comparator, true)
+
+	// Basic ignored labels are still ignored
+	n1.ObjectMeta.Labels[apiv1.LabelZoneFailureDomain] = "us-east1-a"
+	n2.ObjectMeta.Labels[apiv1.LabelZoneFailureDomain] = "us-east1-b"
+	checkNodesSimilar(t, n1, n2, comparator, true)
+
+	// Other labels aren't
+	n1.ObjectMeta.Labels["character"] = "winnie the pooh"
+	checkNodesSimilar(t, n1, n2, comparator, false)
+}
+
+func TestNodesSimilarResourceTolerances(t *testing.T) {
+	n1 := BuildTestNode("node1", 1000, 2000)
+	n2 := BuildTestNode("node2", 1000, 2000)
+	n2.Status.Allocatable[apiv1.ResourceMemory] = *resource.NewQuantity(1800, resource.DecimalSI)
+	checkNodesSimilar(t, n1, n2, IsNodeInfoSimilar, false)
+
+	comparator := CreateGenericNodeInfoComparator(NodeInfoComparatorOptions{
+		ResourceTolerances: map[apiv1.ResourceName]float64{apiv1.ResourceMemory: 0.1},
+	})
+	checkNodesSimilar(t, n1, n2, comparator, true)
+
+	// Tolerances apply to free resources too
+	p1 := BuildTestPod("pod1", 0, 1000)
+	p1.Spec.NodeName = "node1"
+	checkNodesSimilarWithPods(t, n1, n2, []*apiv1.Pod{p1}, []*apiv1.Pod{}, comparator, false)
+
+	// Other resources use the default tolerance
+	n3 := BuildTestNode("node3", 1000, 2000)
+	n3.Status.Allocatable[apiv1.ResourceCPU] = *resource.NewMilliQuantity(900, resource.DecimalSI)
+	checkNodesSimilar(t, n1, n3, comparator, false)
+
+	// Tolerances can be stricter than the default
+	n4 := BuildTestNode("node4", 1000, 2000)
+	n4.Status.Allocatable[apiv1.ResourceCPU] = *resource.NewMilliQuantity(999, resource.DecimalSI)
+	strictComparator := CreateGenericNodeInfoComparator(NodeInfoComparatorOptions{
+		ResourceTolerances: map[apiv1.ResourceName]float64{apiv1.ResourceCPU: 0},
+	})
+	checkNodesSimilar(t, n1, n4, IsNodeInfoSimilar, true)
+	checkNodesSimilar(t, n1, n4, strictComparator, false)
+}
+
+func TestNodesSimilarTaints(t *testing.T) {
+	n1 := BuildTestNode("node1", 1000, 2000)
+	n1.Spec.Taints = []apiv1.Taint{
+		{Key: "dedicated", Value: "batch", Effect: apiv1.TaintEffectNoSchedule},
+		{Key: "gpu", Effect: apiv1.TaintEffectNoSchedule},
+	}
+	n2 := BuildTestNode("node2", 1000, 2000)
+	comparator := CreateGenericNodeInfoComparator(NodeInfoComparatorOptions{CompareTaints: true})
+
+	// Taints are ignored by default
+	checkNodesSimilar(t, n1, n2, IsNodeInfoSimilar, true)
+	checkNodesSimilar(t, n1, n2, comparator, false)
+
+	// Order doesn't matter
+	n2.Spec.Taints = []apiv1.Taint{n1.Spec.Taints[1], n1.Spec.Taints[0]}
+	checkNodesSimilar(t, n1, n2, comparator, true)
+
+	// Different value
+	n2.Spec.Taints = []apiv1.Taint{
+		{Key: "dedicated", Value: "web", Effect: apiv1.TaintEffectNoSchedule},
+		{Key: "gpu", Effect: apiv1.TaintEffectNoSchedule},
+	}
+	checkNodesSimilar(t, n1, n2, comparator, false)
+
+	// Different effect
+	n2.Spec.Taints = []apiv1.Taint{
+		{Key: "dedicated", Value: "batch", Effect: apiv1.TaintEffectNoExecute},
+		{Key: "gpu", Effect: apiv1.TaintEffectNoSchedule},
+	}
+	checkNodesSimilar(t, n1, n2, comparator, false)
+}
+
+func TestCreateNodeInfoComparator(t *testing.T) {
+	options := NodeInfoComparatorOptions{IgnoredLabels: []string{"nodepool-id"}}
+
+	n1 := BuildTestNode("node1", 1000, 2000)
+	n1.ObjectMeta.Labels["nodepool-id"] = "pool1"
+	n1.ObjectMeta.Labels["eks.amazonaws.com/nodegroup"] = "ng1"
+	n1.ObjectMeta.Labels["kubernetes.azure.com/agentpool"] = "pool1"
+	n2 := BuildTestNode("node2", 1000, 2000)
+	n2.ObjectMeta.Labels["nodepool-id"] = "pool2"
+	n2.ObjectMeta.Labels["eks.amazonaws.com/nodegroup"] = "ng2"
+	n2.ObjectMeta.Labels["kubernetes.azure.com/agentpool"] = "pool2"
+	checkNodesSimilar(t, n1, n2, CreateNodeInfoComparator("gce", options), false)
+
+	// Cloud providers ignoring one of the labels
+	delete(n1.ObjectMeta.Labels, "kubernetes.azure.com/agentpool")
+	delete(n2.ObjectMeta.Labels, "kubernetes.azure.com/agentpool")
+	checkNodesSimilar(t, n1, n2, CreateNodeInfoComparator("aws", options), true)
+	checkNodesSimilar(t, n1, n2, CreateNodeInfoComparator("azure", options), false)
+	n1.ObjectMeta.Labels["kubernetes.azure.com/agentpool"] = "pool1"
+	n2.ObjectMeta.Labels["kubernetes.azure.com/agentpool"] = "pool2"
+	delete(n1.ObjectMeta.Labels, "eks.amazonaws.com/nodegroup")
+	delete(n2.ObjectMeta.Labels, "eks.amazonaws.com/nodegroup")
+	checkNodesSimilar(t, n1, n2, CreateNodeInfoComparator("azure", options), true)
+
+	// Options given to cloud provider comparators aren't modified
+	assert.Equal(t, []string{"nodepool-id"}, options.IgnoredLabels)
+
+	RegisterNodeInfoComparatorBuilder("test", func(options NodeInfoComparatorOptions) NodeInfoComparator {
+		return func(n1, n2 *schedulernodeinfo.NodeInfo) bool { return true }
+	})
+	defer delete(nodeInfoComparatorBuilders, "test")
+	checkNodesSimilar(t, n1, BuildTestNode("node3", 2000, 2000), CreateNodeInfoComparator("test", options), true)
+}
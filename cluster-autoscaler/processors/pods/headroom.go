@@ -0,0 +1,247 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pods
+
+import (
+	"fmt"
+	"reflect"
+
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider"
+	"k8s.io/autoscaler/cluster-autoscaler/config"
+	"k8s.io/autoscaler/cluster-autoscaler/context"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator"
+	"k8s.io/autoscaler/cluster-autoscaler/utils/deletetaint"
+	"k8s.io/autoscaler/cluster-autoscaler/utils/gpu"
+	"k8s.io/klog"
+)
+
+// Labels that differ between nodes of the same node group.
+var headroomIgnoredLabels = map[string]bool{
+	apiv1.LabelHostname:                   true,
+	apiv1.LabelZoneFailureDomain:          true,
+	apiv1.LabelZoneRegion:                 true,
+	"beta.kubernetes.io/fluentd-ds-ready": true,
+}
+
+// HeadroomPodListProcessor keeps spare capacity in the cluster. It builds
+// virtual headroom pods requesting the configured headroom and places them on
+// free capacity of ready nodes. Pods that fit are added to scheduled pods, so
+// scale-down keeps room for them. Pods that don't fit are added to
+// unschedulable pods, so scale-up adds nodes for them.
+//
+// It should run after filtering out schedulable pods, so headroom pods don't
+// disable scale-down.
+type HeadroomPodListProcessor struct {
+	headroom []config.Headroom
+}
+
+// NewHeadroomPodListProcessor creates a HeadroomPodListProcessor keeping the given headroom.
+func NewHeadroomPodListProcessor(headroom []config.Headroom) *HeadroomPodListProcessor {
+	return &HeadroomPodListProcessor{headroom: headroom}
+}
+
+// Process adds headroom pods to the lists of unschedulable and scheduled pods.
+func (p *HeadroomPodListProcessor) Process(context *context.AutoscalingContext,
+	unschedulablePods []*apiv1.Pod, allScheduledPods []*apiv1.Pod,
+	allNodes []*apiv1.Node, readyNodes []*apiv1.Node) ([]*apiv1.Pod, []*apiv1.Pod, error) {
+	headroomPods := p.buildHeadroomPods(context, readyNodes)
+	if len(headroomPods) == 0 {
+		return unschedulablePods, allScheduledPods, nil
+	}
+
+	var nonExpendableScheduled []*apiv1.Pod
+	for _, pod := range allScheduledPods {
+		if pod.Spec.Priority == nil || int(*pod.Spec.Priority) >= context.ExpendablePodsPriorityCutoff {
+			nonExpendableScheduled = append(nonExpendableScheduled, pod)
+		}
+	}
+	snapshot := simulator.NewClusterSnapshotFromPods(nonExpendableScheduled, readyNodes)
+
+	unschedulablePods = append([]*apiv1.Pod{}, unschedulablePods...)
+	allScheduledPods = append([]*apiv1.Pod{}, allScheduledPods...)
+	missing := 0
+	for _, pod := range headroomPods {
+		nodeName, err := context.PredicateChecker.FitsAny(pod, snapshot.NodeInfos())
+		if err != nil {
+			unschedulablePods = append(unschedulablePods, pod)
+			missing++
+			continue
+		}
+		pod.Spec.NodeName = nodeName
+		if err := snapshot.AddPod(pod, nodeName); err != nil {
+			return nil, nil, err
+		}
+		allScheduledPods = append(allScheduledPods, pod)
+	}
+	if missing > 0 {
+		klog.V(1).Infof("%d of %d headroom pods don't fit in the cluster", missing, len(headroomPods))
+	} else {
+		klog.V(4).Infof("All %d headroom pods fit in the cluster", len(headroomPods))
+	}
+	return unschedulablePods, allScheduledPods, nil
+}
+
+// CleanUp cleans up the processor's internal structures.
+func (p *HeadroomPodListProcessor) CleanUp() {
+}
+
+func (p *HeadroomPodListProcessor) buildHeadroomPods(context *context.AutoscalingContext, readyNodes []*apiv1.Node) []*apiv1.Pod {
+	var result []*apiv1.Pod
+	for _, headroom := range p.headroom {
+		nodes := readyNodes
+		var template *apiv1.Node
+		scope := "cluster"
+		if headroom.NodeGroup != "" {
+			scope = headroom.NodeGroup
+			nodes = nodesInNodeGroup(context.CloudProvider, readyNodes, headroom.NodeGroup)
+			if len(nodes) > 0 {
+				template = nodes[0]
+			} else {
+				template = templateNode(context.CloudProvider, headroom.NodeGroup)
+			}
+			if template == nil {
+				klog.Warningf("Can't keep headroom in node group %s: no ready nodes and no template", headroom.NodeGroup)
+				continue
+			}
+		}
+
+		milliCpu, memory, gpus := headroom.MilliCpu, headroom.Memory, headroom.Gpu
+		if headroom.Percentage > 0 {
+			milliCpu, memory, gpus = 0, 0, 0
+			for _, node := range nodes {
+				milliCpu += node.Status.Allocatable.Cpu().MilliValue()
+				memory += node.Status.Allocatable.Memory().Value()
+				if quantity, found := node.Status.Allocatable[gpu.ResourceNvidiaGPU]; found {
+					gpus += quantity.Value()
+				}
+			}
+			milliCpu = milliCpu * headroom.Percentage / 100
+			memory = memory * headroom.Percentage / 100
+			gpus = gpus * headroom.Percentage / 100
+		}
+
+		count := int64(headroom.Pods)
+		if count < 1 {
+			count = 1
+		}
+		requests := apiv1.ResourceList{}
+		if milliCpu > 0 {
+			requests[apiv1.ResourceCPU] = *resource.NewMilliQuantity(divideRoundingUp(milliCpu, count), resource.DecimalSI)
+		}
+		if memory > 0 {
+			requests[apiv1.ResourceMemory] = *resource.NewQuantity(divideRoundingUp(memory, count), resource.BinarySI)
+		}
+		if gpus > 0 {
+			requests[gpu.ResourceNvidiaGPU] = *resource.NewQuantity(divideRoundingUp(gpus, count), resource.DecimalSI)
+		}
+		if len(requests) == 0 {
+			continue
+		}
+
+		for i := int64(0); i < count; i++ {
+			pod := buildHeadroomPod(fmt.Sprintf("headroom-%s-%d", scope, i), context.ConfigNamespace, requests)
+			if template != nil {
+				restrictToNode(pod, template)
+			}
+			result = append(result, pod)
+		}
+	}
+	return result
+}
+
+func buildHeadroomPod(name, namespace string, requests apiv1.ResourceList) *apiv1.Pod {
+	return &apiv1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Namespace:   namespace,
+			UID:         types.UID(namespace + "/" + name),
+			Annotations: map[string]string{simulator.HeadroomPodAnnotationKey: "true"},
+		},
+		Spec: apiv1.PodSpec{
+			Containers: []apiv1.Container{
+				{
+					Name:      "headroom",
+					Resources: apiv1.ResourceRequirements{Requests: requests.DeepCopy()},
+				},
+			},
+		},
+	}
+}
+
+// restrictToNode makes the pod schedulable only on nodes looking like the
+// given node of a node group, including template nodes used in scale-up.
+func restrictToNode(pod *apiv1.Pod, node *apiv1.Node) {
+	pod.Spec.NodeSelector = make(map[string]string)
+	for key, value := range node.Labels {
+		if !headroomIgnoredLabels[key] {
+			pod.Spec.NodeSelector[key] = value
+		}
+	}
+	for _, taint := range node.Spec.Taints {
+		if taint.Key == deletetaint.ToBeDeletedTaint || taint.Key == deletetaint.DeletionCandidateTaint {
+			continue
+		}
+		pod.Spec.Tolerations = append(pod.Spec.Tolerations, apiv1.Toleration{
+			Key:      taint.Key,
+			Operator: apiv1.TolerationOpExists,
+			Effect:   taint.Effect,
+		})
+	}
+}
+
+func nodesInNodeGroup(cloudProvider cloudprovider.CloudProvider, nodes []*apiv1.Node, nodeGroupId string) []*apiv1.Node {
+	var result []*apiv1.Node
+	for _, node := range nodes {
+		nodeGroup, err := cloudProvider.NodeGroupForNode(node)
+		if err != nil {
+			klog.Warningf("Failed to get node group for %s: %v", node.Name, err)
+			continue
+		}
+		if nodeGroup == nil || reflect.ValueOf(nodeGroup).IsNil() {
+			continue
+		}
+		if nodeGroup.Id() == nodeGroupId {
+			result = append(result, node)
+		}
+	}
+	return result
+}
+
+func templateNode(cloudProvider cloudprovider.CloudProvider, nodeGroupId string) *apiv1.Node {
+	for _, nodeGroup := range cloudProvider.NodeGroups() {
+		if nodeGroup.Id() != nodeGroupId {
+			continue
+		}
+		nodeInfo, err := nodeGroup.TemplateNodeInfo()
+		if err != nil {
+			if err != cloudprovider.ErrNotImplemented {
+				klog.Warningf("Failed to get template node info for %s: %v", nodeGroupId, err)
+			}
+			return nil
+		}
+		return nodeInfo.Node()
+	}
+	return nil
+}
+
+func divideRoundingUp(value, divisor int64) int64 {
+	return (value + divisor - 1) / divisor
+}
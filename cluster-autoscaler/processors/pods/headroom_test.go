@@ -0,0 +1,152 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pods
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	apiv1 "k8s.io/api/core/v1"
+	testprovider "k8s.io/autoscaler/cluster-autoscaler/cloudprovider/test"
+	"k8s.io/autoscaler/cluster-autoscaler/config"
+	"k8s.io/autoscaler/cluster-autoscaler/context"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator"
+	"k8s.io/autoscaler/cluster-autoscaler/utils/deletetaint"
+	. "k8s.io/autoscaler/cluster-autoscaler/utils/test"
+)
+
+func TestHeadroomPodListProcessor(t *testing.T) {
+	n1 := BuildTestNode("n1", 1000, 1000)
+	n1.Labels = map[string]string{"pool": "a", apiv1.LabelHostname: "n1"}
+	n1.Spec.Taints = []apiv1.Taint{
+		{Key: "dedicated", Value: "a", Effect: apiv1.TaintEffectNoSchedule},
+		{Key: deletetaint.DeletionCandidateTaint, Effect: apiv1.TaintEffectPreferNoSchedule},
+	}
+	SetNodeReadyState(n1, true, time.Time{})
+	n2 := BuildTestNode("n2", 1000, 1000)
+	n2.Labels = map[string]string{"pool": "b"}
+	SetNodeReadyState(n2, true, time.Time{})
+	nodes := []*apiv1.Node{n1, n2}
+
+	provider := testprovider.NewTestCloudProvider(nil, nil)
+	provider.AddNodeGroup("ng1", 1, 10, 1)
+	provider.AddNode("ng1", n1)
+	provider.AddNodeGroup("ng2", 1, 10, 1)
+	provider.AddNode("ng2", n2)
+
+	p1 := BuildTestPod("p1", 600, 0)
+	p1.Spec.NodeName = "n1"
+	p1.Spec.Tolerations = []apiv1.Toleration{{Key: "dedicated", Operator: apiv1.TolerationOpExists}}
+	p2 := BuildTestPod("p2", 100, 0)
+
+	testCases := []struct {
+		name              string
+		headroom          config.Headroom
+		scheduled         map[string]string
+		unschedulable     []string
+		nodeSelector      map[string]string
+		tolerations       []apiv1.Toleration
+		expectedMilliCpu  int64
+		expectedMemoryReq int64
+	}{
+		{
+			name:             "cluster-wide headroom split into pods",
+			headroom:         config.Headroom{MilliCpu: 800, Pods: 2},
+			scheduled:        map[string]string{"headroom-cluster-0": "", "headroom-cluster-1": ""},
+			expectedMilliCpu: 400,
+		},
+		{
+			name:          "node group headroom not fitting",
+			headroom:      config.Headroom{NodeGroup: "ng1", MilliCpu: 800, Pods: 1},
+			unschedulable: []string{"headroom-ng1-0"},
+			nodeSelector:  map[string]string{"pool": "a"},
+			tolerations: []apiv1.Toleration{
+				{Key: "dedicated", Operator: apiv1.TolerationOpExists, Effect: apiv1.TaintEffectNoSchedule},
+			},
+			expectedMilliCpu: 800,
+		},
+		{
+			name:              "node group headroom as percentage",
+			headroom:          config.Headroom{NodeGroup: "ng2", Percentage: 10, Pods: 1},
+			scheduled:         map[string]string{"headroom-ng2-0": "n2"},
+			nodeSelector:      map[string]string{"pool": "b"},
+			expectedMilliCpu:  100,
+			expectedMemoryReq: 100,
+		},
+	}
+
+	for _, tc := range testCases {
+		ctx := &context.AutoscalingContext{
+			AutoscalingOptions: config.AutoscalingOptions{ConfigNamespace: "kube-system", ExpendablePodsPriorityCutoff: -10},
+			CloudProvider:      provider,
+			PredicateChecker:   simulator.NewTestPredicateChecker(),
+		}
+		processor := NewHeadroomPodListProcessor([]config.Headroom{tc.headroom})
+		unschedulable, scheduled, err := processor.Process(ctx, []*apiv1.Pod{p2}, []*apiv1.Pod{p1}, nodes, nodes)
+		assert.NoError(t, err, tc.name)
+
+		headroomPods := simulator.HeadroomPods(append(unschedulable, scheduled...))
+		assert.Equal(t, len(tc.scheduled)+len(tc.unschedulable), len(headroomPods), tc.name)
+		for _, pod := range headroomPods {
+			assert.Equal(t, "kube-system", pod.Namespace, tc.name)
+			requests := pod.Spec.Containers[0].Resources.Requests
+			assert.Equal(t, tc.expectedMilliCpu, requests.Cpu().MilliValue(), tc.name)
+			assert.Equal(t, tc.expectedMemoryReq, requests.Memory().Value(), tc.name)
+			assert.Equal(t, tc.nodeSelector, pod.Spec.NodeSelector, tc.name)
+			assert.Equal(t, tc.tolerations, pod.Spec.Tolerations, tc.name)
+		}
+
+		assert.Equal(t, p2, unschedulable[0], tc.name)
+		var unschedulableNames []string
+		for _, pod := range unschedulable[1:] {
+			unschedulableNames = append(unschedulableNames, pod.Name)
+		}
+		assert.Equal(t, tc.unschedulable, unschedulableNames, tc.name)
+
+		assert.Equal(t, p1, scheduled[0], tc.name)
+		assert.Equal(t, len(tc.scheduled), len(scheduled)-1, tc.name)
+		for _, pod := range scheduled[1:] {
+			nodeName, found := tc.scheduled[pod.Name]
+			assert.True(t, found, tc.name)
+			if nodeName != "" {
+				assert.Equal(t, nodeName, pod.Spec.NodeName, tc.name)
+			} else {
+				assert.NotEmpty(t, pod.Spec.NodeName, tc.name)
+			}
+		}
+	}
+}
+
+func TestCombinedPodListProcessor(t *testing.T) {
+	n1 := BuildTestNode("n1", 1000, 1000)
+	SetNodeReadyState(n1, true, time.Time{})
+	ctx := &context.AutoscalingContext{
+		CloudProvider:    testprovider.NewTestCloudProvider(nil, nil),
+		PredicateChecker: simulator.NewTestPredicateChecker(),
+	}
+	processor := NewCombinedPodListProcessor([]PodListProcessor{
+		NewDefaultPodListProcessor(),
+		NewHeadroomPodListProcessor([]config.Headroom{{MilliCpu: 2000, Pods: 1}}),
+	})
+	unschedulable, scheduled, err := processor.Process(ctx, nil, nil, []*apiv1.Node{n1}, []*apiv1.Node{n1})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(unschedulable))
+	assert.True(t, simulator.IsHeadroomPod(unschedulable[0]))
+	assert.Empty(t, scheduled)
+}
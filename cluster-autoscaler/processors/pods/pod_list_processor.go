@@ -48,3 +48,34 @@ func (p *NoOpPodListProcessor) Process(context *context.AutoscalingContext,
 // CleanUp cleans up the processor's internal structures.
 func (p *NoOpPodListProcessor) CleanUp() {
 }
+
+// CombinedPodListProcessor runs a list of PodListProcessors one after another.
+type CombinedPodListProcessor struct {
+	processors []PodListProcessor
+}
+
+// NewCombinedPodListProcessor creates an instance of PodListProcessor running the given processors in order.
+func NewCombinedPodListProcessor(processors []PodListProcessor) PodListProcessor {
+	return &CombinedPodListProcessor{processors: processors}
+}
+
+// Process processes lists of unschedulable and scheduled pods before scaling of the cluster.
+func (p *CombinedPodListProcessor) Process(context *context.AutoscalingContext,
+	unschedulablePods []*apiv1.Pod, allScheduledPods []*apiv1.Pod,
+	allNodes []*apiv1.Node, readyNodes []*apiv1.Node) ([]*apiv1.Pod, []*apiv1.Pod, error) {
+	var err error
+	for _, processor := range p.processors {
+		unschedulablePods, allScheduledPods, err = processor.Process(context, unschedulablePods, allScheduledPods, allNodes, readyNodes)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	return unschedulablePods, allScheduledPods, nil
+}
+
+// CleanUp cleans up the processor's internal structures.
+func (p *CombinedPodListProcessor) CleanUp() {
+	for _, processor := range p.processors {
+		processor.CleanUp()
+	}
+}
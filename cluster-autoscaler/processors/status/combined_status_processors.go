@@ -0,0 +1,138 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package status
+
+import (
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/autoscaler/cluster-autoscaler/clusterstate"
+	"k8s.io/autoscaler/cluster-autoscaler/context"
+)
+
+// ScaleUpStatusProcessorFunc is a ScaleUpStatusProcessor calling a function with each status.
+type ScaleUpStatusProcessorFunc func(context *context.AutoscalingContext, status *ScaleUpStatus)
+
+// Process calls the function.
+func (f ScaleUpStatusProcessorFunc) Process(context *context.AutoscalingContext, status *ScaleUpStatus) {
+	f(context, status)
+}
+
+// CleanUp cleans up the processor's internal structures.
+func (f ScaleUpStatusProcessorFunc) CleanUp() {
+}
+
+// ScaleDownStatusProcessorFunc is a ScaleDownStatusProcessor calling a function with each status.
+type ScaleDownStatusProcessorFunc func(context *context.AutoscalingContext, status *ScaleDownStatus)
+
+// Process calls the function.
+func (f ScaleDownStatusProcessorFunc) Process(context *context.AutoscalingContext, status *ScaleDownStatus) {
+	f(context, status)
+}
+
+// CleanUp cleans up the processor's internal structures.
+func (f ScaleDownStatusProcessorFunc) CleanUp() {
+}
+
+// AutoscalingStatusProcessorFunc is an AutoscalingStatusProcessor calling a function at the end of each loop.
+type AutoscalingStatusProcessorFunc func(context *context.AutoscalingContext, csr *clusterstate.ClusterStateRegistry, now time.Time) error
+
+// Process calls the function.
+func (f AutoscalingStatusProcessorFunc) Process(context *context.AutoscalingContext, csr *clusterstate.ClusterStateRegistry, now time.Time) error {
+	return f(context, csr, now)
+}
+
+// CleanUp cleans up the processor's internal structures.
+func (f AutoscalingStatusProcessorFunc) CleanUp() {
+}
+
+type combinedScaleUpStatusProcessor struct {
+	first  ScaleUpStatusProcessor
+	second ScaleUpStatusProcessor
+}
+
+// NewCombinedScaleUpStatusProcessor builds a ScaleUpStatusProcessor passing each status
+// to the first and then to the second processor.
+func NewCombinedScaleUpStatusProcessor(first, second ScaleUpStatusProcessor) ScaleUpStatusProcessor {
+	return &combinedScaleUpStatusProcessor{first: first, second: second}
+}
+
+// Process calls both processors.
+func (p *combinedScaleUpStatusProcessor) Process(context *context.AutoscalingContext, status *ScaleUpStatus) {
+	p.first.Process(context, status)
+	p.second.Process(context, status)
+}
+
+// CleanUp cleans up both processors.
+func (p *combinedScaleUpStatusProcessor) CleanUp() {
+	p.first.CleanUp()
+	p.second.CleanUp()
+}
+
+type combinedScaleDownStatusProcessor struct {
+	first  ScaleDownStatusProcessor
+	second ScaleDownStatusProcessor
+}
+
+// NewCombinedScaleDownStatusProcessor builds a ScaleDownStatusProcessor passing each status
+// to the first and then to the second processor.
+func NewCombinedScaleDownStatusProcessor(first, second ScaleDownStatusProcessor) ScaleDownStatusProcessor {
+	return &combinedScaleDownStatusProcessor{first: first, second: second}
+}
+
+// Process calls both processors.
+func (p *combinedScaleDownStatusProcessor) Process(context *context.AutoscalingContext, status *ScaleDownStatus) {
+	p.first.Process(context, status)
+	p.second.Process(context, status)
+}
+
+// CleanUp cleans up both processors.
+func (p *combinedScaleDownStatusProcessor) CleanUp() {
+	p.first.CleanUp()
+	p.second.CleanUp()
+}
+
+type combinedAutoscalingStatusProcessor struct {
+	first  AutoscalingStatusProcessor
+	second AutoscalingStatusProcessor
+}
+
+// NewCombinedAutoscalingStatusProcessor builds an AutoscalingStatusProcessor calling the
+// first and then the second processor.
+func NewCombinedAutoscalingStatusProcessor(first, second AutoscalingStatusProcessor) AutoscalingStatusProcessor {
+	return &combinedAutoscalingStatusProcessor{first: first, second: second}
+}
+
+// Process calls both processors, even if the first one fails, and returns the first error.
+func (p *combinedAutoscalingStatusProcessor) Process(context *context.AutoscalingContext, csr *clusterstate.ClusterStateRegistry, now time.Time) error {
+	err := p.first.Process(context, csr, now)
+	if secondErr := p.second.Process(context, csr, now); err == nil {
+		err = secondErr
+	}
+	return err
+}
+
+// CleanUp cleans up both processors.
+func (p *combinedAutoscalingStatusProcessor) CleanUp() {
+	p.first.CleanUp()
+	p.second.CleanUp()
+}
+
+// PodName returns the name under which the pod is reported, namespace/name.
+func PodName(pod *apiv1.Pod) string {
+	return pod.Namespace + "/" + pod.Name
+}
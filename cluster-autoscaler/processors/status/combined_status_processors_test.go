@@ -0,0 +1,74 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package status
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"k8s.io/autoscaler/cluster-autoscaler/clusterstate"
+	"k8s.io/autoscaler/cluster-autoscaler/context"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCombinedStatusProcessors(t *testing.T) {
+	var calls []string
+	scaleUp := NewCombinedScaleUpStatusProcessor(
+		ScaleUpStatusProcessorFunc(func(_ *context.AutoscalingContext, status *ScaleUpStatus) {
+			calls = append(calls, "first "+status.Result.String())
+		}),
+		ScaleUpStatusProcessorFunc(func(_ *context.AutoscalingContext, status *ScaleUpStatus) {
+			calls = append(calls, "second "+status.Result.String())
+		}))
+	scaleUp.Process(nil, &ScaleUpStatus{Result: ScaleUpSuccessful})
+	scaleUp.CleanUp()
+	assert.Equal(t, []string{"first Successful", "second Successful"}, calls)
+
+	calls = nil
+	scaleDown := NewCombinedScaleDownStatusProcessor(
+		ScaleDownStatusProcessorFunc(func(_ *context.AutoscalingContext, status *ScaleDownStatus) {
+			calls = append(calls, "first "+status.Result.String())
+		}),
+		ScaleDownStatusProcessorFunc(func(_ *context.AutoscalingContext, status *ScaleDownStatus) {
+			calls = append(calls, "second "+status.Result.String())
+		}))
+	scaleDown.Process(nil, &ScaleDownStatus{Result: ScaleDownNodeDeleted})
+	scaleDown.CleanUp()
+	assert.Equal(t, []string{"first NodeDeleted", "second NodeDeleted"}, calls)
+
+	calls = nil
+	autoscalingFunc := func(name string, err error) AutoscalingStatusProcessor {
+		return AutoscalingStatusProcessorFunc(func(_ *context.AutoscalingContext, _ *clusterstate.ClusterStateRegistry, _ time.Time) error {
+			calls = append(calls, name)
+			return err
+		})
+	}
+	autoscaling := NewCombinedAutoscalingStatusProcessor(autoscalingFunc("first", nil), autoscalingFunc("second", nil))
+	assert.NoError(t, autoscaling.Process(nil, nil, time.Now()))
+	assert.Equal(t, []string{"first", "second"}, calls)
+
+	// The second processor is called even if the first one fails.
+	calls = nil
+	autoscaling = NewCombinedAutoscalingStatusProcessor(autoscalingFunc("first", fmt.Errorf("first failed")),
+		autoscalingFunc("second", fmt.Errorf("second failed")))
+	assert.EqualError(t, autoscaling.Process(nil, nil, time.Now()), "first failed")
+	assert.Equal(t, []string{"first", "second"}, calls)
+	autoscaling = NewCombinedAutoscalingStatusProcessor(autoscalingFunc("first", nil), autoscalingFunc("second", fmt.Errorf("second failed")))
+	assert.EqualError(t, autoscaling.Process(nil, nil, time.Now()), "second failed")
+}
@@ -0,0 +1,93 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package status
+
+import (
+	"fmt"
+	"time"
+
+	"k8s.io/autoscaler/cluster-autoscaler/apis/clusterautoscaler.autoscaling.k8s.io/v1alpha1"
+	"k8s.io/autoscaler/cluster-autoscaler/client/clientset/versioned"
+	"k8s.io/autoscaler/cluster-autoscaler/clusterstate"
+	"k8s.io/autoscaler/cluster-autoscaler/context"
+
+	kube_errors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog"
+)
+
+// StatusObjectName is the name of the ClusterAutoscalerStatus object written by CRDStatusWriter.
+const StatusObjectName = "cluster-autoscaler-status"
+
+// CRDStatusWriter is an AutoscalingStatusProcessor publishing the status of the cluster
+// as a ClusterAutoscalerStatus object after each autoscaling iteration.
+type CRDStatusWriter struct {
+	client    versioned.Interface
+	namespace string
+	next      AutoscalingStatusProcessor
+}
+
+// NewCRDStatusWriter builds a CRDStatusWriter writing to the given namespace and
+// then calling the next processor.
+func NewCRDStatusWriter(client versioned.Interface, namespace string, next AutoscalingStatusProcessor) *CRDStatusWriter {
+	return &CRDStatusWriter{
+		client:    client,
+		namespace: namespace,
+		next:      next,
+	}
+}
+
+// Process writes the status of the cluster, creating the ClusterAutoscalerStatus object if needed.
+// The object is written even if the next processor fails and vice versa.
+func (w *CRDStatusWriter) Process(context *context.AutoscalingContext, csr *clusterstate.ClusterStateRegistry, now time.Time) error {
+	err := w.writeStatus(csr, now)
+	if nextErr := w.next.Process(context, csr, now); nextErr != nil {
+		return nextErr
+	}
+	return err
+}
+
+func (w *CRDStatusWriter) writeStatus(csr *clusterstate.ClusterStateRegistry, now time.Time) error {
+	statuses := w.client.ClusterAutoscalerV1alpha1().ClusterAutoscalerStatuses(w.namespace)
+	object, err := statuses.Get(StatusObjectName, metav1.GetOptions{})
+	if kube_errors.IsNotFound(err) {
+		object, err = statuses.Create(&v1alpha1.ClusterAutoscalerStatus{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: w.namespace,
+				Name:      StatusObjectName,
+			},
+		})
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get or create ClusterAutoscalerStatus %s/%s: %v", w.namespace, StatusObjectName, err)
+	}
+	object.Status = *csr.GetStatus(now)
+	if _, err := statuses.UpdateStatus(object); err != nil {
+		return fmt.Errorf("failed to update ClusterAutoscalerStatus %s/%s: %v", w.namespace, StatusObjectName, err)
+	}
+	return nil
+}
+
+// CleanUp deletes the ClusterAutoscalerStatus object, so a stale status isn't left
+// behind when Cluster Autoscaler stops.
+func (w *CRDStatusWriter) CleanUp() {
+	err := w.client.ClusterAutoscalerV1alpha1().ClusterAutoscalerStatuses(w.namespace).Delete(StatusObjectName, &metav1.DeleteOptions{})
+	if err != nil && !kube_errors.IsNotFound(err) {
+		klog.Errorf("Failed to delete ClusterAutoscalerStatus %s/%s: %v", w.namespace, StatusObjectName, err)
+	}
+	w.next.CleanUp()
+}
@@ -0,0 +1,73 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package status
+
+import (
+	"testing"
+	"time"
+
+	"k8s.io/autoscaler/cluster-autoscaler/client/clientset/versioned/fake"
+	testprovider "k8s.io/autoscaler/cluster-autoscaler/cloudprovider/test"
+	"k8s.io/autoscaler/cluster-autoscaler/clusterstate"
+	"k8s.io/autoscaler/cluster-autoscaler/clusterstate/api"
+	"k8s.io/autoscaler/cluster-autoscaler/utils/backoff"
+	. "k8s.io/autoscaler/cluster-autoscaler/utils/test"
+
+	apiv1 "k8s.io/api/core/v1"
+	kube_errors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCRDStatusWriter(t *testing.T) {
+	now := time.Now()
+	n1 := BuildTestNode("n1", 1000, 1000)
+	SetNodeReadyState(n1, true, now.Add(-time.Minute))
+	provider := testprovider.NewTestCloudProvider(nil, nil)
+	provider.AddNodeGroup("ng1", 1, 10, 1)
+	provider.AddNode("ng1", n1)
+	csr := clusterstate.NewClusterStateRegistry(provider, clusterstate.ClusterStateRegistryConfig{}, nil,
+		backoff.NewIdBasedExponentialBackoff(time.Minute, time.Hour, time.Hour))
+	assert.NoError(t, csr.UpdateNodes([]*apiv1.Node{n1}, nil, now))
+
+	client := fake.NewSimpleClientset()
+	writer := NewCRDStatusWriter(client, "kube-system", &NoOpAutoscalingStatusProcessor{})
+	statuses := client.ClusterAutoscalerV1alpha1().ClusterAutoscalerStatuses("kube-system")
+
+	// The object is created in the first loop.
+	assert.NoError(t, writer.Process(nil, csr, now))
+	object, err := statuses.Get(StatusObjectName, metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, &api.Readiness{Ready: 1, Registered: 1}, object.Status.Readiness)
+	assert.Equal(t, 1, len(object.Status.NodeGroupStatuses))
+	assert.Equal(t, "ng1", object.Status.NodeGroupStatuses[0].ProviderID)
+	assert.Equal(t, api.ClusterAutoscalerHealthy,
+		api.GetConditionByType(api.ClusterAutoscalerHealth, object.Status.ClusterwideConditions).Status)
+
+	// And updated in the next ones.
+	provider.AddNodeGroup("ng2", 1, 10, 1)
+	later := now.Add(time.Minute)
+	assert.NoError(t, writer.Process(nil, csr, later))
+	object, err = statuses.Get(StatusObjectName, metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(object.Status.NodeGroupStatuses))
+
+	writer.CleanUp()
+	_, err = statuses.Get(StatusObjectName, metav1.GetOptions{})
+	assert.True(t, kube_errors.IsNotFound(err))
+}
@@ -22,6 +22,8 @@ import (
 
 	apiv1 "k8s.io/api/core/v1"
 	"k8s.io/autoscaler/cluster-autoscaler/context"
+	"k8s.io/autoscaler/cluster-autoscaler/processors/nodegroupset"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator"
 )
 
 // EventingScaleUpStatusProcessor processes the state of the cluster after
@@ -33,14 +35,29 @@ type EventingScaleUpStatusProcessor struct{}
 // relevant events for pods depending on their post scale-up status.
 func (p *EventingScaleUpStatusProcessor) Process(context *context.AutoscalingContext, status *ScaleUpStatus) {
 	for _, noScaleUpInfo := range status.PodsRemainUnschedulable {
+		if simulator.IsHeadroomPod(noScaleUpInfo.Pod) {
+			continue
+		}
 		context.Recorder.Event(noScaleUpInfo.Pod, apiv1.EventTypeNormal, "NotTriggerScaleUp",
 			fmt.Sprintf("pod didn't trigger scale-up (it wouldn't fit if a new node is added): %s", ReasonsMessage(noScaleUpInfo)))
 	}
-	if len(status.ScaleUpInfos) > 0 {
-		for _, pod := range status.PodsTriggeredScaleUp {
-			context.Recorder.Eventf(pod, apiv1.EventTypeNormal, "TriggeredScaleUp",
-				"pod triggered scale-up: %v", status.ScaleUpInfos)
+	if len(status.ScaleUpOptions) > 0 {
+		// Each pod only reports the node groups resized for it.
+		for _, option := range status.ScaleUpOptions {
+			recordTriggeredScaleUp(context, option.Pods, option.ScaleUpInfos)
+		}
+	} else if len(status.ScaleUpInfos) > 0 {
+		recordTriggeredScaleUp(context, status.PodsTriggeredScaleUp, status.ScaleUpInfos)
+	}
+}
+
+func recordTriggeredScaleUp(context *context.AutoscalingContext, pods []*apiv1.Pod, scaleUpInfos []nodegroupset.ScaleUpInfo) {
+	for _, pod := range pods {
+		if simulator.IsHeadroomPod(pod) {
+			continue
 		}
+		context.Recorder.Eventf(pod, apiv1.EventTypeNormal, "TriggeredScaleUp",
+			"pod triggered scale-up: %v", scaleUpInfos)
 	}
 }
 
@@ -81,6 +81,18 @@ func TestEventingScaleUpStatusProcessor(t *testing.T) {
 			expectedTriggered:   1,
 			expectedNoTriggered: 2,
 		},
+		{
+			caseName: "Parallel scale up",
+			state: &ScaleUpStatus{
+				ScaleUpInfos: []nodegroupset.ScaleUpInfo{{}, {}},
+				ScaleUpOptions: []ScaleUpOption{
+					{ScaleUpInfos: []nodegroupset.ScaleUpInfo{{}}, Pods: []*apiv1.Pod{p1}},
+					{ScaleUpInfos: []nodegroupset.ScaleUpInfo{{}}, Pods: []*apiv1.Pod{p2, p3}},
+				},
+				PodsTriggeredScaleUp: []*apiv1.Pod{p1, p2, p3},
+			},
+			expectedTriggered: 3,
+		},
 	}
 
 	for _, tc := range testCases {
@@ -28,6 +28,12 @@ type ScaleDownStatus struct {
 	Result            ScaleDownResult
 	ScaledDownNodes   []*ScaleDownNode
 	NodeDeleteResults map[string]NodeDeleteResult
+	// UnremovableNodes lists the nodes checked for scale-down in this loop that can't be
+	// removed, sorted by name. It's nil if the nodes weren't checked.
+	UnremovableNodes []*simulator.UnremovableNode
+	// MaintenanceWindow is the name of the maintenance window that limited scale-down, if the
+	// result is ScaleDownInMaintenanceWindow.
+	MaintenanceWindow string
 }
 
 // ScaleDownNode represents the state of a node that's being scaled down.
@@ -59,8 +65,28 @@ const (
 	ScaleDownInCooldown
 	// ScaleDownInProgress - the scale down wasn't attempted, because a previous scale-down was still in progress.
 	ScaleDownInProgress
+	// ScaleDownInMaintenanceWindow - the scale down wasn't attempted, or only empty nodes were considered and none
+	// were found, because a maintenance window is open.
+	ScaleDownInMaintenanceWindow
 )
 
+var scaleDownResultNames = map[ScaleDownResult]string{
+	ScaleDownError:               "Error",
+	ScaleDownNoUnneeded:          "NoUnneeded",
+	ScaleDownNoNodeDeleted:       "NoNodeDeleted",
+	ScaleDownNodeDeleted:         "NodeDeleted",
+	ScaleDownNodeDeleteStarted:   "NodeDeleteStarted",
+	ScaleDownNotTried:            "NotTried",
+	ScaleDownInCooldown:          "InCooldown",
+	ScaleDownInProgress:          "InProgress",
+	ScaleDownInMaintenanceWindow: "InMaintenanceWindow",
+}
+
+// String returns the CamelCase name of the result.
+func (r ScaleDownResult) String() string {
+	return scaleDownResultNames[r]
+}
+
 // NodeDeleteResultType denotes the type of the result of node deletion. It provides deeper
 // insight into why the node failed to be deleted.
 type NodeDeleteResultType int
@@ -75,6 +101,8 @@ const (
 	NodeDeleteErrorFailedToEvictPods
 	// NodeDeleteErrorFailedToDelete - failed to delete the node from the cloud provider.
 	NodeDeleteErrorFailedToDelete
+	// NodeDeleteErrorVetoedByHook - a pre-deletion hook vetoed the deletion.
+	NodeDeleteErrorVetoedByHook
 )
 
 // NodeDeleteResult contains information about the result of a node deletion.
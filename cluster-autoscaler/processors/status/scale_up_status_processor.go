@@ -28,11 +28,22 @@ import (
 type ScaleUpStatus struct {
 	Result                  ScaleUpResult
 	ScaleUpInfos            []nodegroupset.ScaleUpInfo
+	ScaleUpOptions          []ScaleUpOption
 	PodsTriggeredScaleUp    []*apiv1.Pod
 	PodsRemainUnschedulable []NoScaleUpInfo
 	PodsAwaitEvaluation     []*apiv1.Pod
 }
 
+// ScaleUpOption describes one of the expansion options executed in a scale-up.
+type ScaleUpOption struct {
+	// ScaleUpInfos lists the node groups resized for the option.
+	ScaleUpInfos []nodegroupset.ScaleUpInfo
+	// Pods are the pods the option was chosen for.
+	Pods []*apiv1.Pod
+	// Debug is the explanation of the choice given by the expander.
+	Debug string
+}
+
 // NoScaleUpInfo contains information about a pod that didn't trigger scale-up.
 type NoScaleUpInfo struct {
 	Pod                *apiv1.Pod
@@ -59,6 +70,20 @@ const (
 	ScaleUpInCooldown
 )
 
+var scaleUpResultNames = map[ScaleUpResult]string{
+	ScaleUpSuccessful:         "Successful",
+	ScaleUpError:              "Error",
+	ScaleUpNoOptionsAvailable: "NoOptionsAvailable",
+	ScaleUpNotNeeded:          "NotNeeded",
+	ScaleUpNotTried:           "NotTried",
+	ScaleUpInCooldown:         "InCooldown",
+}
+
+// String returns the CamelCase name of the result.
+func (r ScaleUpResult) String() string {
+	return scaleUpResultNames[r]
+}
+
 // WasSuccessful returns true if the scale-up was successful.
 func (s *ScaleUpStatus) WasSuccessful() bool {
 	return s.Result == ScaleUpSuccessful
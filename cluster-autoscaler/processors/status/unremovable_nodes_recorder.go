@@ -0,0 +1,154 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package status
+
+import (
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	kube_errors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/autoscaler/cluster-autoscaler/context"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator"
+	kube_client "k8s.io/client-go/kubernetes"
+
+	"k8s.io/klog"
+)
+
+const (
+	// UnremovableReasonAnnotationKey is the node annotation holding the reason why the node can't be scaled down.
+	UnremovableReasonAnnotationKey = "cluster-autoscaler.kubernetes.io/scale-down-unremovable-reason"
+	// UnremovableNodeEventInterval is how often the event and the annotation are written for a
+	// node which can't be scaled down for the same reason.
+	UnremovableNodeEventInterval = 30 * time.Minute
+)
+
+// UnremovableNodesRecorder is a ScaleDownStatusProcessor recording why nodes can't be scaled
+// down as node events and annotations. Nodes that aren't removed just because they are
+// utilized above the threshold are not reported, as this is the case for most of the nodes.
+// Both events and annotation updates for the same node and reason are rate limited, so
+// reasons changing back and forth between loops don't flood the API server.
+type UnremovableNodesRecorder struct {
+	// recorded keeps the last time each reason was recorded, by node name.
+	recorded map[string]map[simulator.UnremovableReason]time.Time
+	next     ScaleDownStatusProcessor
+	now      func() time.Time
+}
+
+// NewUnremovableNodesRecorder builds an UnremovableNodesRecorder calling the next processor
+// after recording the reasons.
+func NewUnremovableNodesRecorder(next ScaleDownStatusProcessor) *UnremovableNodesRecorder {
+	return &UnremovableNodesRecorder{
+		recorded: make(map[string]map[simulator.UnremovableReason]time.Time),
+		next:     next,
+		now:      time.Now,
+	}
+}
+
+// Process records the reasons from the status if the nodes were checked in this loop.
+func (r *UnremovableNodesRecorder) Process(context *context.AutoscalingContext, status *ScaleDownStatus) {
+	if status.UnremovableNodes != nil {
+		r.record(context, status.UnremovableNodes, r.now())
+	}
+	r.next.Process(context, status)
+}
+
+func (r *UnremovableNodesRecorder) record(context *context.AutoscalingContext, unremovableNodes []*simulator.UnremovableNode, now time.Time) {
+	reported := make(map[string]bool, len(unremovableNodes))
+	for _, unremovable := range unremovableNodes {
+		if unremovable.Reason == simulator.NotUnderutilized {
+			continue
+		}
+		node := unremovable.Node
+		reported[node.Name] = true
+		if last, found := r.recorded[node.Name][unremovable.Reason]; found && now.Sub(last) < UnremovableNodeEventInterval {
+			continue
+		}
+
+		reason := unremovable.Reason.String()
+		if node.Annotations[UnremovableReasonAnnotationKey] != reason {
+			if err := setUnremovableReasonAnnotation(context.ClientSet, node.Name, &reason); err != nil {
+				klog.Warningf("Failed to set unremovable reason annotation on %s: %v", node.Name, err)
+			}
+		}
+		if unremovable.Message != "" {
+			context.Recorder.Eventf(node, apiv1.EventTypeNormal, "ScaleDownBlocked", "node can't be removed: %s: %s", reason, unremovable.Message)
+		} else {
+			context.Recorder.Eventf(node, apiv1.EventTypeNormal, "ScaleDownBlocked", "node can't be removed: %s", reason)
+		}
+		if r.recorded[node.Name] == nil {
+			r.recorded[node.Name] = make(map[simulator.UnremovableReason]time.Time)
+		}
+		r.recorded[node.Name][unremovable.Reason] = now
+	}
+	r.clearUnreported(context, reported)
+
+	for name, reasons := range r.recorded {
+		for reason, last := range reasons {
+			if now.Sub(last) >= UnremovableNodeEventInterval {
+				delete(reasons, reason)
+			}
+		}
+		if len(reasons) == 0 {
+			delete(r.recorded, name)
+		}
+	}
+}
+
+// clearUnreported removes the annotation from all nodes which are no longer reported,
+// including the ones annotated before a restart.
+func (r *UnremovableNodesRecorder) clearUnreported(context *context.AutoscalingContext, reported map[string]bool) {
+	nodes, err := context.ListerRegistry.AllNodeLister().List()
+	if err != nil {
+		klog.Warningf("Failed to list nodes, not removing unremovable reason annotations: %v", err)
+		return
+	}
+	for _, node := range nodes {
+		if _, found := node.Annotations[UnremovableReasonAnnotationKey]; !found || reported[node.Name] {
+			continue
+		}
+		if err := setUnremovableReasonAnnotation(context.ClientSet, node.Name, nil); err != nil && !kube_errors.IsNotFound(err) {
+			klog.Warningf("Failed to remove unremovable reason annotation from %s: %v", node.Name, err)
+		}
+	}
+}
+
+// setUnremovableReasonAnnotation sets the annotation to the reason, or removes it if the reason is nil.
+func setUnremovableReasonAnnotation(client kube_client.Interface, nodeName string, reason *string) error {
+	node, err := client.CoreV1().Nodes().Get(nodeName, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	if reason == nil {
+		if _, found := node.Annotations[UnremovableReasonAnnotationKey]; !found {
+			return nil
+		}
+		delete(node.Annotations, UnremovableReasonAnnotationKey)
+	} else {
+		if node.Annotations == nil {
+			node.Annotations = make(map[string]string)
+		}
+		node.Annotations[UnremovableReasonAnnotationKey] = *reason
+	}
+	_, err = client.CoreV1().Nodes().Update(node)
+	return err
+}
+
+// CleanUp cleans up the processor's internal structures.
+func (r *UnremovableNodesRecorder) CleanUp() {
+	r.next.CleanUp()
+}
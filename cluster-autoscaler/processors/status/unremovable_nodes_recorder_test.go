@@ -0,0 +1,163 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package status
+
+import (
+	"testing"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/autoscaler/cluster-autoscaler/context"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator"
+	kube_util "k8s.io/autoscaler/cluster-autoscaler/utils/kubernetes"
+	. "k8s.io/autoscaler/cluster-autoscaler/utils/test"
+	kube_client "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+	kube_record "k8s.io/client-go/tools/record"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// clientNodeLister lists the nodes currently stored by the client.
+type clientNodeLister struct {
+	client kube_client.Interface
+}
+
+func (l *clientNodeLister) List() ([]*apiv1.Node, error) {
+	nodeList, err := l.client.CoreV1().Nodes().List(metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	nodes := make([]*apiv1.Node, 0, len(nodeList.Items))
+	for i := range nodeList.Items {
+		nodes = append(nodes, &nodeList.Items[i])
+	}
+	return nodes, nil
+}
+
+func (l *clientNodeLister) Get(name string) (*apiv1.Node, error) {
+	return l.client.CoreV1().Nodes().Get(name, metav1.GetOptions{})
+}
+
+func TestUnremovableNodesRecorder(t *testing.T) {
+	n1 := BuildTestNode("n1", 1000, 1000)
+	n2 := BuildTestNode("n2", 1000, 1000)
+	n3 := BuildTestNode("n3", 1000, 1000)
+	client := fake.NewSimpleClientset(n1, n2, n3)
+	recorder := kube_record.NewFakeRecorder(10)
+	autoscalingContext := &context.AutoscalingContext{
+		ClientSet:      client,
+		Recorder:       recorder,
+		ListerRegistry: kube_util.NewListerRegistry(&clientNodeLister{client: client}, nil, nil, nil, nil, nil, nil, nil, nil, nil),
+	}
+
+	now := time.Now()
+	r := NewUnremovableNodesRecorder(&NoOpScaleDownStatusProcessor{})
+	r.now = func() time.Time { return now }
+	annotation := func(name string) (string, bool) {
+		node, err := client.CoreV1().Nodes().Get(name, metav1.GetOptions{})
+		assert.NoError(t, err)
+		value, found := node.Annotations[UnremovableReasonAnnotationKey]
+		return value, found
+	}
+	nodeUpdates := func() int {
+		count := 0
+		for _, action := range client.Actions() {
+			if action.GetVerb() == "update" {
+				count++
+			}
+		}
+		return count
+	}
+	process := func(status *ScaleDownStatus) {
+		r.Process(autoscalingContext, status)
+		// Nodes passed in the next status are up to date.
+		for _, unremovable := range status.UnremovableNodes {
+			node, err := client.CoreV1().Nodes().Get(unremovable.Node.Name, metav1.GetOptions{})
+			assert.NoError(t, err)
+			*unremovable.Node = *node
+		}
+	}
+
+	status := &ScaleDownStatus{UnremovableNodes: []*simulator.UnremovableNode{
+		{Node: n1, Reason: simulator.BlockedByPod, Message: "pod default/p1 is not replicated"},
+		{Node: n2, Reason: simulator.NodeGroupMinSizeReached},
+		{Node: n3, Reason: simulator.NotUnderutilized},
+	}}
+	process(status)
+	assert.Equal(t, "Normal ScaleDownBlocked node can't be removed: BlockedByPod: pod default/p1 is not replicated", <-recorder.Events)
+	assert.Equal(t, "Normal ScaleDownBlocked node can't be removed: NodeGroupMinSizeReached", <-recorder.Events)
+	assert.Empty(t, recorder.Events)
+	reason, _ := annotation("n1")
+	assert.Equal(t, "BlockedByPod", reason)
+	reason, _ = annotation("n2")
+	assert.Equal(t, "NodeGroupMinSizeReached", reason)
+	_, found := annotation("n3")
+	assert.False(t, found)
+
+	// The same reasons aren't reported again until the interval passes.
+	now = now.Add(time.Minute)
+	process(status)
+	assert.Empty(t, recorder.Events)
+
+	// Statuses without checked nodes don't change anything.
+	process(&ScaleDownStatus{Result: ScaleDownInCooldown})
+	assert.Empty(t, recorder.Events)
+	_, found = annotation("n1")
+	assert.True(t, found)
+
+	// A changed reason is reported right away, nodes no longer reported are cleaned up.
+	status = &ScaleDownStatus{UnremovableNodes: []*simulator.UnremovableNode{
+		{Node: n1, Reason: simulator.NotEnoughPdb},
+	}}
+	process(status)
+	assert.Equal(t, "Normal ScaleDownBlocked node can't be removed: NotEnoughPdb", <-recorder.Events)
+	reason, _ = annotation("n1")
+	assert.Equal(t, "NotEnoughPdb", reason)
+	_, found = annotation("n2")
+	assert.False(t, found)
+
+	// A reason changing back within the interval doesn't update the node again.
+	updates := nodeUpdates()
+	process(&ScaleDownStatus{UnremovableNodes: []*simulator.UnremovableNode{
+		{Node: n1, Reason: simulator.BlockedByPod, Message: "pod default/p1 is not replicated"},
+	}})
+	assert.Empty(t, recorder.Events)
+	assert.Equal(t, updates, nodeUpdates())
+	reason, _ = annotation("n1")
+	assert.Equal(t, "NotEnoughPdb", reason)
+
+	now = now.Add(UnremovableNodeEventInterval)
+	process(status)
+	assert.Equal(t, "Normal ScaleDownBlocked node can't be removed: NotEnoughPdb", <-recorder.Events)
+	assert.Empty(t, recorder.Events)
+
+	// Annotations left by a previous instance are cleaned up too.
+	node, err := client.CoreV1().Nodes().Get("n3", metav1.GetOptions{})
+	assert.NoError(t, err)
+	node.Annotations = map[string]string{UnremovableReasonAnnotationKey: "BlockedByPod"}
+	_, err = client.CoreV1().Nodes().Update(node)
+	assert.NoError(t, err)
+	r = NewUnremovableNodesRecorder(&NoOpScaleDownStatusProcessor{})
+	process(status)
+	assert.Equal(t, "Normal ScaleDownBlocked node can't be removed: NotEnoughPdb", <-recorder.Events)
+	_, found = annotation("n3")
+	assert.False(t, found)
+	reason, _ = annotation("n1")
+	assert.Equal(t, "NotEnoughPdb", reason)
+}
@@ -0,0 +1,91 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scaledownorder
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator"
+	"k8s.io/klog"
+)
+
+// minUtilization is used instead of lower utilization, so that empty nodes
+// are ordered by price.
+const minUtilization = 0.01
+
+type priceOrder struct {
+	cloudProvider cloudprovider.CloudProvider
+}
+
+// NewPriceOrder returns a scale-down order preferring removal of nodes with
+// the highest price per unit of utilization. Nodes are left in the order they
+// were listed if the cloud provider doesn't support pricing.
+func NewPriceOrder(cloudProvider cloudprovider.CloudProvider) Strategy {
+	return &priceOrder{cloudProvider: cloudProvider}
+}
+
+// Order sorts nodes by price per unit of utilization, the highest first.
+// Nodes without a price go last, in the order they were listed.
+func (p *priceOrder) Order(nodes []*apiv1.Node, utilization map[string]simulator.UtilizationInfo, now time.Time) []*apiv1.Node {
+	pricingModel, err := p.cloudProvider.Pricing()
+	if err != nil {
+		if err != cloudprovider.ErrNotImplemented {
+			klog.Warningf("Failed to get pricing model, not ordering nodes for scale-down: %v", err)
+		}
+		return nodes
+	}
+
+	scores := make(map[string]float64, len(nodes))
+	failed := 0
+	var firstErr error
+	for _, node := range nodes {
+		price, err := pricingModel.NodePrice(node, now, now.Add(time.Hour))
+		if err != nil {
+			if failed == 0 {
+				firstErr = fmt.Errorf("%s: %v", node.Name, err)
+			}
+			failed++
+			continue
+		}
+		nodeUtilization := utilization[node.Name].Utilization
+		if nodeUtilization < minUtilization {
+			nodeUtilization = minUtilization
+		}
+		scores[node.Name] = price / nodeUtilization
+		klog.V(4).Infof("Node %s costs %f per unit of utilization %f", node.Name, scores[node.Name], nodeUtilization)
+	}
+
+	if failed > 0 {
+		klog.Warningf("Failed to calculate node price for %d of %d nodes, considering them last for scale-down, first error: %v",
+			failed, len(nodes), firstErr)
+	}
+
+	result := append([]*apiv1.Node{}, nodes...)
+	sort.SliceStable(result, func(i, j int) bool {
+		scoreI, foundI := scores[result[i].Name]
+		scoreJ, foundJ := scores[result[j].Name]
+		if foundI != foundJ {
+			return foundI
+		}
+		return scoreI > scoreJ
+	})
+	return result
+}
@@ -0,0 +1,98 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scaledownorder
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	apiv1 "k8s.io/api/core/v1"
+	testprovider "k8s.io/autoscaler/cluster-autoscaler/cloudprovider/test"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator"
+	. "k8s.io/autoscaler/cluster-autoscaler/utils/test"
+)
+
+type testPricingModel struct {
+	nodePrice map[string]float64
+}
+
+func (tpm *testPricingModel) NodePrice(node *apiv1.Node, startTime time.Time, endTime time.Time) (float64, error) {
+	if price, found := tpm.nodePrice[node.Name]; found {
+		return price, nil
+	}
+	return 0.0, fmt.Errorf("price for node %v not found", node.Name)
+}
+
+func (tpm *testPricingModel) PodPrice(pod *apiv1.Pod, startTime time.Time, endTime time.Time) (float64, error) {
+	return 0.0, nil
+}
+
+func nodeNames(nodes []*apiv1.Node) []string {
+	names := make([]string, 0, len(nodes))
+	for _, node := range nodes {
+		names = append(names, node.Name)
+	}
+	return names
+}
+
+func TestPriceOrder(t *testing.T) {
+	spot := BuildTestNode("spot", 1000, 1000)
+	onDemand := BuildTestNode("on-demand", 1000, 1000)
+	busyOnDemand := BuildTestNode("busy-on-demand", 1000, 1000)
+	emptySpot := BuildTestNode("empty-spot", 1000, 1000)
+	unknown := BuildTestNode("unknown", 1000, 1000)
+	nodes := []*apiv1.Node{unknown, spot, busyOnDemand, onDemand, emptySpot}
+	utilization := map[string]simulator.UtilizationInfo{
+		"spot":           {Utilization: 0.2},
+		"on-demand":      {Utilization: 0.2},
+		"busy-on-demand": {Utilization: 0.5},
+		"unknown":        {Utilization: 0.1},
+	}
+
+	provider := testprovider.NewTestCloudProvider(nil, nil)
+	order, err := NewStrategy(PriceOrderName, provider)
+	assert.NoError(t, err)
+
+	// Pricing not implemented.
+	assert.Equal(t, nodes, order.Order(nodes, utilization, time.Now()))
+
+	provider.SetPricingModel(&testPricingModel{nodePrice: map[string]float64{
+		"spot":           0.3,
+		"on-demand":      1.0,
+		"busy-on-demand": 1.0,
+		"empty-spot":     0.3,
+	}})
+	// Price per unit of utilization: empty-spot 30, on-demand 5, busy-on-demand 2, spot 1.5.
+	assert.Equal(t, []string{"empty-spot", "on-demand", "busy-on-demand", "spot", "unknown"},
+		nodeNames(order.Order(nodes, utilization, time.Now())))
+	// The input isn't modified.
+	assert.Equal(t, []string{"unknown", "spot", "busy-on-demand", "on-demand", "empty-spot"}, nodeNames(nodes))
+}
+
+func TestNewStrategy(t *testing.T) {
+	nodes := []*apiv1.Node{BuildTestNode("n1", 1000, 1000), BuildTestNode("n2", 1000, 1000)}
+	for _, name := range []string{DefaultOrderName, ""} {
+		order, err := NewStrategy(name, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, nodes, order.Order(nodes, nil, time.Now()))
+	}
+	_, err := NewStrategy("oldest", nil)
+	assert.Error(t, err)
+}
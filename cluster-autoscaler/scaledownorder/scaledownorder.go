@@ -0,0 +1,61 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scaledownorder
+
+import (
+	"fmt"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator"
+)
+
+const (
+	// DefaultOrderName is the name of the order keeping nodes in the order they are listed.
+	DefaultOrderName = "default"
+	// PriceOrderName is the name of the order removing nodes with the highest price per unit of utilization first.
+	PriceOrderName = "price"
+)
+
+// AvailableOrders is a list of available scale-down orders.
+var AvailableOrders = []string{DefaultOrderName, PriceOrderName}
+
+// Strategy orders nodes considered for scale-down. Nodes earlier in the
+// returned list are preferred for removal.
+type Strategy interface {
+	Order(nodes []*apiv1.Node, utilization map[string]simulator.UtilizationInfo, now time.Time) []*apiv1.Node
+}
+
+// NewStrategy creates a scale-down order from flag. Nodes are not reordered if
+// the name is empty.
+func NewStrategy(name string, cloudProvider cloudprovider.CloudProvider) (Strategy, error) {
+	switch name {
+	case DefaultOrderName, "":
+		return &defaultOrder{}, nil
+	case PriceOrderName:
+		return NewPriceOrder(cloudProvider), nil
+	}
+	return nil, fmt.Errorf("unknown scale-down order: %s", name)
+}
+
+type defaultOrder struct{}
+
+// Order returns nodes in the order they were listed.
+func (defaultOrder) Order(nodes []*apiv1.Node, utilization map[string]simulator.UtilizationInfo, now time.Time) []*apiv1.Node {
+	return nodes
+}
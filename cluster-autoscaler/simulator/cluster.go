@@ -20,6 +20,8 @@ import (
 	"flag"
 	"fmt"
 	"math/rand"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"k8s.io/autoscaler/cluster-autoscaler/utils/drain"
@@ -48,6 +50,10 @@ var (
 
 	minReplicaCount = flag.Int("min-replica-count", 0,
 		"Minimum number or replicas that a replica set or replication controller should have to allow their pods deletion in scale down")
+
+	scaleDownSimulationWorkers = flag.Int("scale-down-simulation-workers", 1,
+		"Number of goroutines simulating the removal of scale down candidates in parallel. 1 means candidates are evaluated sequentially. "+
+			"Nodes removed together, e.g. with max-drain-parallelism above 1, are always evaluated sequentially")
 )
 
 // NodeToBeRemoved contain information about a node that can be removed.
@@ -58,6 +64,76 @@ type NodeToBeRemoved struct {
 	PodsToReschedule []*apiv1.Pod
 }
 
+// UnremovableReason represents a reason why a node can't be removed by scale-down.
+type UnremovableReason int
+
+const (
+	// NoReason is the zero value, it's never reported.
+	NoReason UnremovableReason = iota
+	// ScaleDownDisabledAnnotation - the node is annotated as not to be removed.
+	ScaleDownDisabledAnnotation
+	// NotAutoscaled - the node doesn't belong to any node group.
+	NotAutoscaled
+	// CurrentlyBeingDeleted - the node is already being deleted.
+	CurrentlyBeingDeleted
+	// NotUnderutilized - the utilization of the node is above the scale-down threshold.
+	NotUnderutilized
+	// NotUnneededLongEnough - the node hasn't been unneeded for the scale-down unneeded time yet.
+	NotUnneededLongEnough
+	// NotUnreadyLongEnough - the node hasn't been unready for the scale-down unready time yet.
+	NotUnreadyLongEnough
+	// NodeGroupMinSizeReached - the node group of the node is at its minimum size.
+	NodeGroupMinSizeReached
+	// MinimalResourceLimitExceeded - removing the node would go below a cluster-wide resource limit.
+	MinimalResourceLimitExceeded
+	// ScaleDownBudgetExceeded - the node group of the node has used up its scale-down budget for the current window.
+	ScaleDownBudgetExceeded
+	// SimilarNodeGroupsUnbalanced - removing the node would leave its node group smaller than similar node groups.
+	SimilarNodeGroupsUnbalanced
+	// RecentlyUnremovable - the node was found unremovable recently and isn't checked again yet.
+	RecentlyUnremovable
+	// BlockedByPod - a pod on the node can't be evicted, e.g. it's not replicated or uses local storage.
+	BlockedByPod
+	// NotEnoughPdb - evicting the pods from the node would violate a pod disruption budget.
+	NotEnoughPdb
+	// NoPlaceToMovePods - the pods from the node don't fit anywhere else in the cluster.
+	NoPlaceToMovePods
+	// UnexpectedError - the node couldn't be checked because of an error.
+	UnexpectedError
+)
+
+var unremovableReasonNames = map[UnremovableReason]string{
+	NoReason:                     "NoReason",
+	ScaleDownDisabledAnnotation:  "ScaleDownDisabledAnnotation",
+	NotAutoscaled:                "NotAutoscaled",
+	CurrentlyBeingDeleted:        "CurrentlyBeingDeleted",
+	NotUnderutilized:             "NotUnderutilized",
+	NotUnneededLongEnough:        "NotUnneededLongEnough",
+	NotUnreadyLongEnough:         "NotUnreadyLongEnough",
+	NodeGroupMinSizeReached:      "NodeGroupMinSizeReached",
+	MinimalResourceLimitExceeded: "MinimalResourceLimitExceeded",
+	ScaleDownBudgetExceeded:      "ScaleDownBudgetExceeded",
+	SimilarNodeGroupsUnbalanced:  "SimilarNodeGroupsUnbalanced",
+	RecentlyUnremovable:          "RecentlyUnremovable",
+	BlockedByPod:                 "BlockedByPod",
+	NotEnoughPdb:                 "NotEnoughPdb",
+	NoPlaceToMovePods:            "NoPlaceToMovePods",
+	UnexpectedError:              "UnexpectedError",
+}
+
+// String returns the CamelCase name of the reason.
+func (r UnremovableReason) String() string {
+	return unremovableReasonNames[r]
+}
+
+// UnremovableNode contains information about a node that can't be removed.
+type UnremovableNode struct {
+	Node   *apiv1.Node
+	Reason UnremovableReason
+	// Message gives details about the reason, e.g. which pod blocks the removal.
+	Message string
+}
+
 // UtilizationInfo contains utilization information for a node.
 type UtilizationInfo struct {
 	CpuUtil float64
@@ -70,69 +146,319 @@ type UtilizationInfo struct {
 }
 
 // FindNodesToRemove finds nodes that can be removed. Returns also an information about good
-// rescheduling location for each of the pods.
+// rescheduling location for each of the pods. If pods include headroom pods, the returned
+// nodes can be removed all together without leaving any headroom pod without a place.
 func FindNodesToRemove(candidates []*apiv1.Node, allNodes []*apiv1.Node, pods []*apiv1.Pod,
 	listers kube_util.ListerRegistry, predicateChecker *PredicateChecker, maxCount int,
 	fastCheck bool, oldHints map[string]string, usageTracker *UsageTracker,
 	timestamp time.Time,
 	podDisruptionBudgets []*policyv1.PodDisruptionBudget,
-) (nodesToRemove []NodeToBeRemoved, unremovableNodes []*apiv1.Node, podReschedulingHints map[string]string, finalError errors.AutoscalerError) {
+) (nodesToRemove []NodeToBeRemoved, unremovableNodes []*UnremovableNode, podReschedulingHints map[string]string, finalError errors.AutoscalerError) {
+	// Headroom pods have to fit in the cluster after all of the returned nodes
+	// are removed, not only after each of them separately.
+	_, headroomPods := splitHeadroomPods(pods)
+	return findNodesToRemove(candidates, allNodes, pods, listers, predicateChecker, maxCount, fastCheck, oldHints, usageTracker,
+		timestamp, podDisruptionBudgets, len(headroomPods) > 0)
+}
+
+// FindNodesToRemoveTogether finds nodes that can be removed at the same time.
+// Unlike in FindNodesToRemove, pods from each of the returned nodes are placed
+// taking into account the pods moved from the previous ones, and pods evicted
+// from all of the returned nodes together have to fit in the pod disruption budgets.
+func FindNodesToRemoveTogether(candidates []*apiv1.Node, allNodes []*apiv1.Node, pods []*apiv1.Pod,
+	listers kube_util.ListerRegistry, predicateChecker *PredicateChecker, maxCount int,
+	fastCheck bool, oldHints map[string]string, usageTracker *UsageTracker,
+	timestamp time.Time,
+	podDisruptionBudgets []*policyv1.PodDisruptionBudget,
+) (nodesToRemove []NodeToBeRemoved, unremovableNodes []*UnremovableNode, podReschedulingHints map[string]string, finalError errors.AutoscalerError) {
+	return findNodesToRemove(candidates, allNodes, pods, listers, predicateChecker, maxCount, fastCheck, oldHints, usageTracker,
+		timestamp, podDisruptionBudgets, true)
+}
 
-	nodeNameToNodeInfo := scheduler_util.CreateNodeNameToInfoMap(pods, allNodes)
+func findNodesToRemove(candidates []*apiv1.Node, allNodes []*apiv1.Node, pods []*apiv1.Pod,
+	listers kube_util.ListerRegistry, predicateChecker *PredicateChecker, maxCount int,
+	fastCheck bool, oldHints map[string]string, usageTracker *UsageTracker,
+	timestamp time.Time,
+	podDisruptionBudgets []*policyv1.PodDisruptionBudget,
+	cumulative bool,
+) (nodesToRemove []NodeToBeRemoved, unremovableNodes []*UnremovableNode, podReschedulingHints map[string]string, finalError errors.AutoscalerError) {
+
+	snapshot := NewClusterSnapshotFromPods(pods, allNodes)
+	// Disruptions used by pods evicted from the nodes already selected for removal.
+	usedDisruptions := make([]int32, len(podDisruptionBudgets))
 	result := make([]NodeToBeRemoved, 0)
-	unremovable := make([]*apiv1.Node, 0)
+	unremovable := make([]*UnremovableNode, 0)
 
 	evaluationType := "Detailed evaluation"
 	if fastCheck {
 		evaluationType = "Fast evaluation"
 	}
+	evaluator := &candidateEvaluator{
+		allNodes:             allNodes,
+		listers:              listers,
+		predicateChecker:     predicateChecker,
+		fastCheck:            fastCheck,
+		podDisruptionBudgets: podDisruptionBudgets,
+		evaluationType:       evaluationType,
+	}
 	newHints := make(map[string]string, len(oldHints))
 
-candidateloop:
-	for _, node := range candidates {
-		klog.V(2).Infof("%s: %s for removal", evaluationType, node.Name)
-
-		var podsToRemove []*apiv1.Pod
+	// Candidates evaluated independently of each other are evaluated on a worker pool.
+	// In cumulative mode each node is evaluated with the pods moved from the nodes selected
+	// before it, so the nodes are evaluated one by one below.
+	var evaluations []*candidateEvaluation
+	if workers := *scaleDownSimulationWorkers; workers > 1 && len(candidates) > 1 && !cumulative {
 		var err error
+		if evaluations, err = evaluator.evaluateInParallel(candidates, snapshot, oldHints, workers, maxCount); err != nil {
+			return nil, nil, nil, errors.ToAutoscalerError(errors.InternalError, err)
+		}
+	}
 
-		if nodeInfo, found := nodeNameToNodeInfo[node.Name]; found {
-			if fastCheck {
-				podsToRemove, err = FastGetPodsToMove(nodeInfo, *skipNodesWithSystemPods, *skipNodesWithLocalStorage,
-					podDisruptionBudgets)
-			} else {
-				podsToRemove, err = DetailedGetPodsForMove(nodeInfo, *skipNodesWithSystemPods, *skipNodesWithLocalStorage, listers, int32(*minReplicaCount),
-					podDisruptionBudgets)
+	for i, node := range candidates {
+		if evaluations != nil {
+			evaluation := evaluations[i]
+			if evaluation == nil {
+				// Not evaluated, enough nodes were found among the previous ones.
+				break
+			}
+			mergeHints(newHints, evaluation.hints)
+			registerUsage(usageTracker, node.Name, evaluation.hints, timestamp)
+			if evaluation.unremovable != nil {
+				unremovable = append(unremovable, evaluation.unremovable)
+				continue
+			}
+			result = append(result, NodeToBeRemoved{Node: node, PodsToReschedule: evaluation.podsToRemove})
+			klog.V(2).Infof("%s: node %s may be removed", evaluationType, node.Name)
+			if len(result) >= maxCount {
+				break
+			}
+			continue
+		}
+
+		klog.V(2).Infof("%s: %s for removal", evaluationType, node.Name)
+		podsToRemove, unremovableNode := evaluator.podsToRemove(node, snapshot)
+		if unremovableNode != nil {
+			unremovable = append(unremovable, unremovableNode)
+			continue
+		}
+		// Pods moved to the node earlier in the simulation are placed again,
+		// but only the ones running on it are evicted.
+		podsToEvict := podsToRemove
+		var disruptions []int32
+		if cumulative {
+			var err error
+			podsToEvict = filterPodsOnNode(podsToRemove, node.Name)
+			disruptions, err = countPdbDisruptions(podsToEvict, podDisruptionBudgets)
+			if err == nil {
+				err = checkPdbDisruptionsLeft(podDisruptionBudgets, usedDisruptions, disruptions)
 			}
 			if err != nil {
-				klog.V(2).Infof("%s: node %s cannot be removed: %v", evaluationType, node.Name, err)
-				unremovable = append(unremovable, node)
-				continue candidateloop
+				klog.V(2).Infof("%s: node %s cannot be removed together with the previous ones: %v", evaluationType, node.Name, err)
+				reason := UnexpectedError
+				if _, ok := err.(*notEnoughPdbError); ok {
+					reason = NotEnoughPdb
+				}
+				unremovable = append(unremovable, &UnremovableNode{Node: node, Reason: reason, Message: err.Error()})
+				continue
 			}
-		} else {
-			klog.V(2).Infof("%s: nodeInfo for %s not found", evaluationType, node.Name)
-			unremovable = append(unremovable, node)
-			continue candidateloop
 		}
-		findProblems := findPlaceFor(node.Name, podsToRemove, allNodes, nodeNameToNodeInfo, predicateChecker, oldHints, newHints,
-			usageTracker, timestamp)
+		if err := snapshot.Fork(); err != nil {
+			return nil, nil, nil, errors.ToAutoscalerError(errors.InternalError, err)
+		}
+		nodeHints := make(map[string]string)
+		findProblems := findPlaceFor(node.Name, podsToRemove, allNodes, snapshot, predicateChecker, oldHints, nodeHints)
+		mergeHints(newHints, nodeHints)
+		registerUsage(usageTracker, node.Name, nodeHints, timestamp)
 
 		if findProblems == nil {
+			if cumulative {
+				for j := range usedDisruptions {
+					usedDisruptions[j] += disruptions[j]
+				}
+				err := snapshot.RemoveNode(node.Name)
+				if err == nil {
+					err = snapshot.Commit()
+				}
+				if err != nil {
+					return nil, nil, nil, errors.ToAutoscalerError(errors.InternalError, err)
+				}
+				// Headroom pods are virtual, so they are never evicted.
+				podsToEvict, _ = splitHeadroomPods(podsToEvict)
+				if podsToEvict == nil {
+					podsToEvict = []*apiv1.Pod{}
+				}
+			} else if err := snapshot.Revert(); err != nil {
+				return nil, nil, nil, errors.ToAutoscalerError(errors.InternalError, err)
+			}
 			result = append(result, NodeToBeRemoved{
 				Node:             node,
-				PodsToReschedule: podsToRemove,
+				PodsToReschedule: podsToEvict,
 			})
 			klog.V(2).Infof("%s: node %s may be removed", evaluationType, node.Name)
 			if len(result) >= maxCount {
-				break candidateloop
+				break
 			}
 		} else {
+			if err := snapshot.Revert(); err != nil {
+				return nil, nil, nil, errors.ToAutoscalerError(errors.InternalError, err)
+			}
 			klog.V(2).Infof("%s: node %s is not suitable for removal: %v", evaluationType, node.Name, findProblems)
-			unremovable = append(unremovable, node)
+			unremovable = append(unremovable, &UnremovableNode{Node: node, Reason: NoPlaceToMovePods, Message: findProblems.Error()})
 		}
 	}
 	return result, unremovable, newHints, nil
 }
 
+// candidateEvaluator checks whether candidates for removal can be removed.
+type candidateEvaluator struct {
+	allNodes             []*apiv1.Node
+	listers              kube_util.ListerRegistry
+	predicateChecker     *PredicateChecker
+	fastCheck            bool
+	podDisruptionBudgets []*policyv1.PodDisruptionBudget
+	evaluationType       string
+}
+
+// candidateEvaluation is the result of evaluating a candidate independently of other candidates.
+type candidateEvaluation struct {
+	// Set if the node can't be removed.
+	unremovable  *UnremovableNode
+	podsToRemove []*apiv1.Pod
+	// Nodes the pods were placed on, by pod key.
+	hints map[string]string
+}
+
+// podsToRemove returns pods that have to be moved from the node to remove it, or why it can't be removed.
+func (e *candidateEvaluator) podsToRemove(node *apiv1.Node, snapshot *ClusterSnapshot) ([]*apiv1.Pod, *UnremovableNode) {
+	nodeInfo, found := snapshot.GetNodeInfo(node.Name)
+	if !found {
+		klog.V(2).Infof("%s: nodeInfo for %s not found", e.evaluationType, node.Name)
+		return nil, &UnremovableNode{Node: node, Reason: UnexpectedError, Message: "node info not found"}
+	}
+	var podsToRemove []*apiv1.Pod
+	var err error
+	if e.fastCheck {
+		podsToRemove, err = FastGetPodsToMove(nodeInfo, *skipNodesWithSystemPods, *skipNodesWithLocalStorage,
+			e.podDisruptionBudgets)
+	} else {
+		podsToRemove, err = DetailedGetPodsForMove(nodeInfo, *skipNodesWithSystemPods, *skipNodesWithLocalStorage, e.listers, int32(*minReplicaCount),
+			e.podDisruptionBudgets)
+	}
+	if err != nil {
+		klog.V(2).Infof("%s: node %s cannot be removed: %v", e.evaluationType, node.Name, err)
+		reason := BlockedByPod
+		if _, ok := err.(*notEnoughPdbError); ok {
+			reason = NotEnoughPdb
+		}
+		return nil, &UnremovableNode{Node: node, Reason: reason, Message: err.Error()}
+	}
+	return podsToRemove, nil
+}
+
+// evaluate checks whether the node can be removed from the snapshot, leaving the snapshot unchanged.
+func (e *candidateEvaluator) evaluate(node *apiv1.Node, snapshot *ClusterSnapshot, oldHints map[string]string) (*candidateEvaluation, error) {
+	klog.V(2).Infof("%s: %s for removal", e.evaluationType, node.Name)
+	podsToRemove, unremovableNode := e.podsToRemove(node, snapshot)
+	if unremovableNode != nil {
+		return &candidateEvaluation{unremovable: unremovableNode}, nil
+	}
+	if err := snapshot.Fork(); err != nil {
+		return nil, err
+	}
+	hints := make(map[string]string)
+	findProblems := findPlaceFor(node.Name, podsToRemove, e.allNodes, snapshot, e.predicateChecker, oldHints, hints)
+	if err := snapshot.Revert(); err != nil {
+		return nil, err
+	}
+	if findProblems != nil {
+		klog.V(2).Infof("%s: node %s is not suitable for removal: %v", e.evaluationType, node.Name, findProblems)
+		return &candidateEvaluation{
+			unremovable: &UnremovableNode{Node: node, Reason: NoPlaceToMovePods, Message: findProblems.Error()},
+			hints:       hints,
+		}, nil
+	}
+	return &candidateEvaluation{podsToRemove: podsToRemove, hints: hints}, nil
+}
+
+// evaluateInParallel evaluates the candidates independently on the given number of workers,
+// each with its own copy of the snapshot. Evaluations are returned in the order of candidates.
+// Once stopAfter candidates are found removable, the remaining ones aren't evaluated and their
+// evaluations are nil. As candidates are picked up in order, all of them come after the found ones.
+func (e *candidateEvaluator) evaluateInParallel(candidates []*apiv1.Node, snapshot *ClusterSnapshot, oldHints map[string]string,
+	workers int, stopAfter int) ([]*candidateEvaluation, error) {
+	if workers > len(candidates) {
+		workers = len(candidates)
+	}
+	evaluations := make([]*candidateEvaluation, len(candidates))
+	errs := make([]error, workers)
+	var next, removable int32
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		workerSnapshot, err := snapshot.Clone()
+		if err != nil {
+			return nil, err
+		}
+		wg.Add(1)
+		go func(w int, snapshot *ClusterSnapshot) {
+			defer wg.Done()
+			for atomic.LoadInt32(&removable) < int32(stopAfter) {
+				i := int(atomic.AddInt32(&next, 1) - 1)
+				if i >= len(candidates) {
+					return
+				}
+				evaluation, err := e.evaluate(candidates[i], snapshot, oldHints)
+				if err != nil {
+					errs[w] = err
+					return
+				}
+				evaluations[i] = evaluation
+				if evaluation.unremovable == nil {
+					atomic.AddInt32(&removable, 1)
+				}
+			}
+		}(w, workerSnapshot)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return evaluations, nil
+}
+
+// registerUsage records the nodes pods from the removed node were placed on.
+func registerUsage(usageTracker *UsageTracker, removedNode string, hints map[string]string, timestamp time.Time) {
+	for _, targetNode := range hints {
+		usageTracker.RegisterUsage(removedNode, targetNode, timestamp)
+	}
+}
+
+func mergeHints(dst, src map[string]string) {
+	for key, node := range src {
+		dst[key] = node
+	}
+}
+
+// filterPodsOnNode returns the pods bound to the given node.
+func filterPodsOnNode(pods []*apiv1.Pod, nodeName string) []*apiv1.Pod {
+	result := make([]*apiv1.Pod, 0, len(pods))
+	for _, pod := range pods {
+		if pod.Spec.NodeName == nodeName {
+			result = append(result, pod)
+		}
+	}
+	return result
+}
+
+// GetPodsToMove returns pods that have to be moved elsewhere if the node is drained,
+// using the same rules as FindNodesToRemove.
+func GetPodsToMove(nodeInfo *schedulernodeinfo.NodeInfo, listers kube_util.ListerRegistry,
+	podDisruptionBudgets []*policyv1.PodDisruptionBudget) ([]*apiv1.Pod, error) {
+	return DetailedGetPodsForMove(nodeInfo, *skipNodesWithSystemPods, *skipNodesWithLocalStorage, listers, int32(*minReplicaCount),
+		podDisruptionBudgets)
+}
+
 // FindEmptyNodesToRemove finds empty nodes that can be removed.
 func FindEmptyNodesToRemove(candidates []*apiv1.Node, pods []*apiv1.Pod) []*apiv1.Node {
 	nodeNameToNodeInfo := scheduler_util.CreateNodeNameToInfoMap(pods, candidates)
@@ -217,24 +543,16 @@ func calculateUtilizationOfResource(node *apiv1.Node, nodeInfo *schedulernodeinf
 	return float64(podsRequest.MilliValue()) / float64(nodeAllocatable.MilliValue()), nil
 }
 
-// TODO: We don't need to pass list of nodes here as they are already available in nodeInfos.
-func findPlaceFor(removedNode string, pods []*apiv1.Pod, nodes []*apiv1.Node, nodeInfos map[string]*schedulernodeinfo.NodeInfo,
-	predicateChecker *PredicateChecker, oldHints map[string]string, newHints map[string]string, usageTracker *UsageTracker,
-	timestamp time.Time) error {
-
-	newNodeInfos := make(map[string]*schedulernodeinfo.NodeInfo)
-	for k, v := range nodeInfos {
-		newNodeInfos[k] = v
-	}
-
-	podKey := func(pod *apiv1.Pod) string {
-		return fmt.Sprintf("%s/%s", pod.Namespace, pod.Name)
-	}
+// findPlaceFor places the pods on other nodes than removedNode in the snapshot. If it fails,
+// some of the pods may have been placed already, so it should be called on a forked snapshot.
+// TODO: We don't need to pass list of nodes here as they are already available in the snapshot.
+func findPlaceFor(removedNode string, pods []*apiv1.Pod, nodes []*apiv1.Node, snapshot *ClusterSnapshot,
+	predicateChecker *PredicateChecker, oldHints map[string]string, newHints map[string]string) error {
 
 	loggingQuota := glogx.PodsLoggingQuota()
 
 	tryNodeForPod := func(nodename string, pod *apiv1.Pod, predicateMeta predicates.PredicateMetadata) bool {
-		nodeInfo, found := newNodeInfos[nodename]
+		nodeInfo, found := snapshot.GetNodeInfo(nodename)
 		if found {
 			if nodeInfo.Node() == nil {
 				// NodeInfo is generated based on pods. It is possible that node is removed from
@@ -247,13 +565,11 @@ func findPlaceFor(removedNode string, pods []*apiv1.Pod, nodes []*apiv1.Node, no
 			if err != nil {
 				glogx.V(4).UpTo(loggingQuota).Infof("Evaluation %s for %s/%s -> %v", nodename, pod.Namespace, pod.Name, err.VerboseError())
 			} else {
-				// TODO(mwielgus): Optimize it.
 				klog.V(4).Infof("Pod %s/%s can be moved to %s", pod.Namespace, pod.Name, nodename)
-				podsOnNode := nodeInfo.Pods()
-				podsOnNode = append(podsOnNode, pod)
-				newNodeInfo := schedulernodeinfo.NewNodeInfo(podsOnNode...)
-				newNodeInfo.SetNode(nodeInfo.Node())
-				newNodeInfos[nodename] = newNodeInfo
+				if err := snapshot.AddPod(pod, nodename); err != nil {
+					klog.Errorf("Failed to add pod %s/%s to %s in snapshot: %v", pod.Namespace, pod.Name, nodename, err)
+					return false
+				}
 				newHints[podKey(pod)] = nodename
 				return true
 			}
@@ -272,8 +588,7 @@ func findPlaceFor(removedNode string, pods []*apiv1.Pod, nodes []*apiv1.Node, no
 		pod := &newpod
 
 		foundPlace := false
-		targetNode := ""
-		predicateMeta := predicateChecker.GetPredicateMetadata(pod, newNodeInfos)
+		predicateMeta := predicateChecker.GetPredicateMetadata(pod, snapshot.NodeInfos())
 		loggingQuota.Reset()
 
 		klog.V(5).Infof("Looking for place for %s/%s", pod.Namespace, pod.Name)
@@ -282,7 +597,6 @@ func findPlaceFor(removedNode string, pods []*apiv1.Pod, nodes []*apiv1.Node, no
 		if hasHint {
 			if hintedNode != removedNode && tryNodeForPod(hintedNode, pod, predicateMeta) {
 				foundPlace = true
-				targetNode = hintedNode
 			}
 		}
 		if !foundPlace {
@@ -292,7 +606,6 @@ func findPlaceFor(removedNode string, pods []*apiv1.Pod, nodes []*apiv1.Node, no
 				}
 				if tryNodeForPod(node.Name, pod, predicateMeta) {
 					foundPlace = true
-					targetNode = node.Name
 					break
 				}
 			}
@@ -301,12 +614,14 @@ func findPlaceFor(removedNode string, pods []*apiv1.Pod, nodes []*apiv1.Node, no
 				return fmt.Errorf("failed to find place for %s", podKey(pod))
 			}
 		}
-
-		usageTracker.RegisterUsage(removedNode, targetNode, timestamp)
 	}
 	return nil
 }
 
+func podKey(pod *apiv1.Pod) string {
+	return fmt.Sprintf("%s/%s", pod.Namespace, pod.Name)
+}
+
 func shuffleNodes(nodes []*apiv1.Node) []*apiv1.Node {
 	result := make([]*apiv1.Node, len(nodes))
 	for i := range nodes {
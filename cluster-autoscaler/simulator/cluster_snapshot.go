@@ -0,0 +1,243 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package simulator
+
+import (
+	"fmt"
+
+	apiv1 "k8s.io/api/core/v1"
+	scheduler_util "k8s.io/autoscaler/cluster-autoscaler/utils/scheduler"
+	schedulernodeinfo "k8s.io/kubernetes/pkg/scheduler/nodeinfo"
+)
+
+// ClusterSnapshot is the simulated state of the cluster, node infos by node name,
+// which pods and nodes can be added to and removed from.
+//
+// Changes can be made on a fork of the snapshot and then either committed or
+// reverted. Forking is cheap: node infos are copied only when they are modified
+// for the first time after the fork, and reverting restores just the modified
+// ones, so simulations don't have to copy the whole cluster state.
+//
+// Node infos added with AddNodeInfo are copied before they are modified, so they
+// can be shared with other snapshots. ClusterSnapshot isn't thread safe.
+type ClusterSnapshot struct {
+	nodeInfos map[string]*schedulernodeinfo.NodeInfo
+	// owned marks node infos created or copied by the snapshot since the last
+	// fork, which can be modified in place.
+	owned  map[string]bool
+	forked bool
+	// Set while forked: ownership of node infos before the fork, and node infos
+	// modified since the fork as they were before it, nil if they didn't exist.
+	baseOwned map[string]bool
+	saved     map[string]*schedulernodeinfo.NodeInfo
+}
+
+// NewClusterSnapshot creates an empty ClusterSnapshot.
+func NewClusterSnapshot() *ClusterSnapshot {
+	return &ClusterSnapshot{
+		nodeInfos: make(map[string]*schedulernodeinfo.NodeInfo),
+		owned:     make(map[string]bool),
+	}
+}
+
+// NewClusterSnapshotFromPods creates a ClusterSnapshot with the given nodes and pods.
+// Pods are put on the nodes the same way as in scheduler_util.CreateNodeNameToInfoMap.
+func NewClusterSnapshotFromPods(pods []*apiv1.Pod, nodes []*apiv1.Node) *ClusterSnapshot {
+	nodeInfos := scheduler_util.CreateNodeNameToInfoMap(pods, nodes)
+	owned := make(map[string]bool, len(nodeInfos))
+	for name := range nodeInfos {
+		owned[name] = true
+	}
+	return &ClusterSnapshot{
+		nodeInfos: nodeInfos,
+		owned:     owned,
+	}
+}
+
+// Clone returns a copy of the snapshot, which can be changed independently of it.
+// Node infos are shared by both snapshots until either of them modifies them.
+// A forked snapshot can't be cloned.
+func (s *ClusterSnapshot) Clone() (*ClusterSnapshot, error) {
+	if s.forked {
+		return nil, fmt.Errorf("can't clone forked snapshot")
+	}
+	nodeInfos := make(map[string]*schedulernodeinfo.NodeInfo, len(s.nodeInfos))
+	for name, nodeInfo := range s.nodeInfos {
+		nodeInfos[name] = nodeInfo
+	}
+	// Shared node infos are copied before they are modified.
+	s.owned = make(map[string]bool)
+	return &ClusterSnapshot{
+		nodeInfos: nodeInfos,
+		owned:     make(map[string]bool),
+	}, nil
+}
+
+// NodeInfos returns the node infos in the snapshot by node name. The map and the
+// node infos must not be modified and are valid only until the snapshot changes.
+func (s *ClusterSnapshot) NodeInfos() map[string]*schedulernodeinfo.NodeInfo {
+	return s.nodeInfos
+}
+
+// GetNodeInfo returns the node info of the given node. It must not be modified.
+func (s *ClusterSnapshot) GetNodeInfo(nodeName string) (*schedulernodeinfo.NodeInfo, bool) {
+	nodeInfo, found := s.nodeInfos[nodeName]
+	return nodeInfo, found
+}
+
+// AddNode adds a node without pods to the snapshot.
+func (s *ClusterSnapshot) AddNode(node *apiv1.Node) error {
+	if _, found := s.nodeInfos[node.Name]; found {
+		return fmt.Errorf("node %s already in snapshot", node.Name)
+	}
+	nodeInfo := schedulernodeinfo.NewNodeInfo()
+	if err := nodeInfo.SetNode(node); err != nil {
+		return err
+	}
+	s.save(node.Name)
+	s.nodeInfos[node.Name] = nodeInfo
+	s.owned[node.Name] = true
+	return nil
+}
+
+// AddNodeInfo adds a node with its pods to the snapshot. The node info is copied
+// before it's modified.
+func (s *ClusterSnapshot) AddNodeInfo(nodeInfo *schedulernodeinfo.NodeInfo) error {
+	if nodeInfo.Node() == nil {
+		return fmt.Errorf("node info without node")
+	}
+	name := nodeInfo.Node().Name
+	if _, found := s.nodeInfos[name]; found {
+		return fmt.Errorf("node %s already in snapshot", name)
+	}
+	s.save(name)
+	s.nodeInfos[name] = nodeInfo
+	delete(s.owned, name)
+	return nil
+}
+
+// RemoveNode removes a node with its pods from the snapshot.
+func (s *ClusterSnapshot) RemoveNode(nodeName string) error {
+	if _, found := s.nodeInfos[nodeName]; !found {
+		return fmt.Errorf("node %s not found in snapshot", nodeName)
+	}
+	s.save(nodeName)
+	delete(s.nodeInfos, nodeName)
+	delete(s.owned, nodeName)
+	return nil
+}
+
+// AddPod adds a pod to the given node. The pod isn't modified, in particular
+// its NodeName isn't set.
+func (s *ClusterSnapshot) AddPod(pod *apiv1.Pod, nodeName string) error {
+	nodeInfo, err := s.writable(nodeName)
+	if err != nil {
+		return err
+	}
+	nodeInfo.AddPod(pod)
+	return nil
+}
+
+// RemovePod removes a pod from the given node.
+func (s *ClusterSnapshot) RemovePod(pod *apiv1.Pod, nodeName string) error {
+	nodeInfo, err := s.writable(nodeName)
+	if err != nil {
+		return err
+	}
+	return nodeInfo.RemovePod(pod)
+}
+
+// Fork starts recording changes, so they can be reverted. Only one fork at
+// a time is supported.
+func (s *ClusterSnapshot) Fork() error {
+	if s.forked {
+		return fmt.Errorf("snapshot already forked")
+	}
+	s.forked = true
+	s.baseOwned = s.owned
+	s.owned = make(map[string]bool)
+	s.saved = make(map[string]*schedulernodeinfo.NodeInfo)
+	return nil
+}
+
+// Revert undoes the changes made since the fork.
+func (s *ClusterSnapshot) Revert() error {
+	if !s.forked {
+		return fmt.Errorf("snapshot not forked")
+	}
+	for name, nodeInfo := range s.saved {
+		if nodeInfo == nil {
+			delete(s.nodeInfos, name)
+		} else {
+			s.nodeInfos[name] = nodeInfo
+		}
+	}
+	s.owned = s.baseOwned
+	s.endFork()
+	return nil
+}
+
+// Commit keeps the changes made since the fork.
+func (s *ClusterSnapshot) Commit() error {
+	if !s.forked {
+		return fmt.Errorf("snapshot not forked")
+	}
+	for name := range s.saved {
+		if s.owned[name] {
+			s.baseOwned[name] = true
+		} else {
+			delete(s.baseOwned, name)
+		}
+	}
+	s.owned = s.baseOwned
+	s.endFork()
+	return nil
+}
+
+func (s *ClusterSnapshot) endFork() {
+	s.forked = false
+	s.baseOwned = nil
+	s.saved = nil
+}
+
+// save remembers the node info as it was before the fork, if it's the first
+// change to it since the fork.
+func (s *ClusterSnapshot) save(nodeName string) {
+	if !s.forked {
+		return
+	}
+	if _, saved := s.saved[nodeName]; !saved {
+		s.saved[nodeName] = s.nodeInfos[nodeName]
+	}
+}
+
+// writable returns the node info of the given node, copying it first if it
+// isn't owned by the snapshot.
+func (s *ClusterSnapshot) writable(nodeName string) (*schedulernodeinfo.NodeInfo, error) {
+	nodeInfo, found := s.nodeInfos[nodeName]
+	if !found {
+		return nil, fmt.Errorf("node %s not found in snapshot", nodeName)
+	}
+	if s.owned[nodeName] {
+		return nodeInfo, nil
+	}
+	s.save(nodeName)
+	nodeInfo = nodeInfo.Clone()
+	s.nodeInfos[nodeName] = nodeInfo
+	s.owned[nodeName] = true
+	return nodeInfo, nil
+}
@@ -0,0 +1,257 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package simulator
+
+import (
+	"fmt"
+	"testing"
+
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	scheduler_util "k8s.io/autoscaler/cluster-autoscaler/utils/scheduler"
+	. "k8s.io/autoscaler/cluster-autoscaler/utils/test"
+	schedulernodeinfo "k8s.io/kubernetes/pkg/scheduler/nodeinfo"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestSnapshot(t *testing.T, nodeInfos map[string]*schedulernodeinfo.NodeInfo) *ClusterSnapshot {
+	snapshot := NewClusterSnapshot()
+	for _, nodeInfo := range nodeInfos {
+		assert.NoError(t, snapshot.AddNodeInfo(nodeInfo))
+	}
+	return snapshot
+}
+
+func buildScheduledTestPod(name string, cpu int64, nodeName string) *apiv1.Pod {
+	pod := BuildTestPod(name, cpu, 0)
+	pod.UID = types.UID(name)
+	pod.Spec.NodeName = nodeName
+	return pod
+}
+
+// podNames returns the names of pods in the snapshot by node name.
+func podNames(snapshot *ClusterSnapshot) map[string][]string {
+	result := make(map[string][]string)
+	for name, nodeInfo := range snapshot.NodeInfos() {
+		result[name] = []string{}
+		for _, pod := range nodeInfo.Pods() {
+			result[name] = append(result[name], pod.Name)
+		}
+	}
+	return result
+}
+
+func TestClusterSnapshotForkRevert(t *testing.T) {
+	n1 := BuildTestNode("n1", 1000, 1000)
+	n2 := BuildTestNode("n2", 1000, 1000)
+	p1 := buildScheduledTestPod("p1", 100, "n1")
+	p2 := buildScheduledTestPod("p2", 100, "n2")
+	snapshot := NewClusterSnapshotFromPods([]*apiv1.Pod{p1, p2}, []*apiv1.Node{n1, n2})
+	n1Info, _ := snapshot.GetNodeInfo("n1")
+	before := podNames(snapshot)
+
+	assert.NoError(t, snapshot.Fork())
+	assert.NoError(t, snapshot.AddPod(buildScheduledTestPod("p3", 100, ""), "n1"))
+	assert.NoError(t, snapshot.RemovePod(p2, "n2"))
+	assert.NoError(t, snapshot.AddNode(BuildTestNode("n3", 1000, 1000)))
+	assert.NoError(t, snapshot.AddPod(buildScheduledTestPod("p4", 100, ""), "n3"))
+	assert.NoError(t, snapshot.RemoveNode("n1"))
+	assert.Equal(t, map[string][]string{"n2": {}, "n3": {"p4"}}, podNames(snapshot))
+
+	assert.NoError(t, snapshot.Revert())
+	assert.Equal(t, before, podNames(snapshot))
+	// Node infos from before the fork aren't modified.
+	restored, _ := snapshot.GetNodeInfo("n1")
+	assert.True(t, n1Info == restored)
+	assert.Equal(t, int64(100), n1Info.RequestedResource().MilliCPU)
+
+	// The snapshot can be modified after the revert.
+	assert.NoError(t, snapshot.AddPod(buildScheduledTestPod("p5", 100, ""), "n1"))
+	assert.Equal(t, map[string][]string{"n1": {"p1", "p5"}, "n2": {"p2"}}, podNames(snapshot))
+}
+
+func TestClusterSnapshotForkCommit(t *testing.T) {
+	n1 := BuildTestNode("n1", 1000, 1000)
+	n2 := BuildTestNode("n2", 1000, 1000)
+	p1 := buildScheduledTestPod("p1", 100, "n1")
+	snapshot := NewClusterSnapshotFromPods([]*apiv1.Pod{p1}, []*apiv1.Node{n1, n2})
+
+	assert.NoError(t, snapshot.Fork())
+	assert.NoError(t, snapshot.AddPod(buildScheduledTestPod("p2", 100, ""), "n2"))
+	assert.NoError(t, snapshot.RemoveNode("n1"))
+	assert.NoError(t, snapshot.Commit())
+	committed := podNames(snapshot)
+	assert.Equal(t, map[string][]string{"n2": {"p2"}}, committed)
+
+	// Changes made on the next fork are reverted to the committed state.
+	assert.NoError(t, snapshot.Fork())
+	assert.NoError(t, snapshot.AddPod(buildScheduledTestPod("p3", 100, ""), "n2"))
+	assert.NoError(t, snapshot.AddNode(n1))
+	assert.NoError(t, snapshot.Revert())
+	assert.Equal(t, committed, podNames(snapshot))
+}
+
+func TestClusterSnapshotAddNodeInfo(t *testing.T) {
+	n1 := BuildTestNode("n1", 1000, 1000)
+	nodeInfo := schedulernodeinfo.NewNodeInfo(buildScheduledTestPod("p1", 100, "n1"))
+	nodeInfo.SetNode(n1)
+
+	snapshot := NewClusterSnapshot()
+	assert.NoError(t, snapshot.AddNodeInfo(nodeInfo))
+	assert.NoError(t, snapshot.AddPod(buildScheduledTestPod("p2", 100, ""), "n1"))
+	assert.Equal(t, map[string][]string{"n1": {"p1", "p2"}}, podNames(snapshot))
+	// The added node info is copied before it's modified.
+	assert.Equal(t, 1, len(nodeInfo.Pods()))
+
+	assert.NoError(t, snapshot.Fork())
+	assert.NoError(t, snapshot.RemoveNode("n1"))
+	assert.NoError(t, snapshot.AddNodeInfo(nodeInfo))
+	assert.NoError(t, snapshot.AddPod(buildScheduledTestPod("p3", 100, ""), "n1"))
+	assert.NoError(t, snapshot.Commit())
+	assert.Equal(t, map[string][]string{"n1": {"p1", "p3"}}, podNames(snapshot))
+	assert.Equal(t, 1, len(nodeInfo.Pods()))
+}
+
+func TestClusterSnapshotClone(t *testing.T) {
+	n1 := BuildTestNode("n1", 1000, 1000)
+	n2 := BuildTestNode("n2", 1000, 1000)
+	p1 := buildScheduledTestPod("p1", 100, "n1")
+	snapshot := NewClusterSnapshotFromPods([]*apiv1.Pod{p1}, []*apiv1.Node{n1, n2})
+
+	clone, err := snapshot.Clone()
+	assert.NoError(t, err)
+	assert.NoError(t, clone.AddPod(buildScheduledTestPod("p2", 100, ""), "n1"))
+	assert.NoError(t, clone.RemoveNode("n2"))
+	assert.NoError(t, snapshot.AddPod(buildScheduledTestPod("p3", 100, ""), "n1"))
+	assert.Equal(t, map[string][]string{"n1": {"p1", "p2"}}, podNames(clone))
+	assert.Equal(t, map[string][]string{"n1": {"p1", "p3"}, "n2": {}}, podNames(snapshot))
+
+	assert.NoError(t, snapshot.Fork())
+	_, err = snapshot.Clone()
+	assert.Error(t, err)
+}
+
+func TestClusterSnapshotErrors(t *testing.T) {
+	n1 := BuildTestNode("n1", 1000, 1000)
+	snapshot := NewClusterSnapshotFromPods(nil, []*apiv1.Node{n1})
+
+	assert.Error(t, snapshot.AddNode(n1))
+	assert.Error(t, snapshot.RemoveNode("n2"))
+	assert.Error(t, snapshot.AddPod(buildScheduledTestPod("p1", 100, ""), "n2"))
+	assert.Error(t, snapshot.RemovePod(buildScheduledTestPod("p1", 100, ""), "n2"))
+	assert.Error(t, snapshot.AddNodeInfo(schedulernodeinfo.NewNodeInfo()))
+	assert.Error(t, snapshot.Revert())
+	assert.Error(t, snapshot.Commit())
+	assert.NoError(t, snapshot.Fork())
+	assert.Error(t, snapshot.Fork())
+	assert.NoError(t, snapshot.Commit())
+}
+
+const (
+	benchmarkNodes       = 5000
+	benchmarkPodsPerNode = 20
+)
+
+// buildBenchmarkCluster builds 5k nodes with 100k pods.
+func buildBenchmarkCluster() ([]*apiv1.Node, []*apiv1.Pod) {
+	nodes := make([]*apiv1.Node, 0, benchmarkNodes)
+	pods := make([]*apiv1.Pod, 0, benchmarkNodes*benchmarkPodsPerNode)
+	for i := 0; i < benchmarkNodes; i++ {
+		node := BuildTestNode(fmt.Sprintf("n%d", i), 64000, 256*1024*1024*1024)
+		nodes = append(nodes, node)
+		for j := 0; j < benchmarkPodsPerNode; j++ {
+			pods = append(pods, buildScheduledTestPod(fmt.Sprintf("p%d-%d", i, j), 100, node.Name))
+		}
+	}
+	return nodes, pods
+}
+
+// The benchmarks below compare the snapshot with copying the map of node infos and
+// rebuilding node infos with scheduler_util.NodeWithPod, as simulations did before.
+
+// Simulates removing a node: its pods are moved to other nodes and the change is dropped.
+func BenchmarkMoveNodePodsMapCopy(b *testing.B) {
+	nodes, pods := buildBenchmarkCluster()
+	nodeInfos := scheduler_util.CreateNodeNameToInfoMap(pods, nodes)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		removed := nodes[i%len(nodes)].Name
+		newNodeInfos := make(map[string]*schedulernodeinfo.NodeInfo, len(nodeInfos))
+		for name, nodeInfo := range nodeInfos {
+			newNodeInfos[name] = nodeInfo
+		}
+		for j, pod := range nodeInfos[removed].Pods() {
+			target := nodes[(i+j+1)%len(nodes)].Name
+			newNodeInfos[target] = scheduler_util.NodeWithPod(newNodeInfos[target], pod)
+		}
+	}
+}
+
+func BenchmarkMoveNodePodsSnapshot(b *testing.B) {
+	nodes, pods := buildBenchmarkCluster()
+	snapshot := NewClusterSnapshotFromPods(pods, nodes)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		removed := nodes[i%len(nodes)].Name
+		snapshot.Fork()
+		nodeInfo, _ := snapshot.GetNodeInfo(removed)
+		for j, pod := range nodeInfo.Pods() {
+			snapshot.AddPod(pod, nodes[(i+j+1)%len(nodes)].Name)
+		}
+		snapshot.Revert()
+	}
+}
+
+// Simulates packing pods on existing nodes: 1000 pods are added to 50 nodes.
+func BenchmarkPackPodsNodeWithPod(b *testing.B) {
+	nodes, pods := buildBenchmarkCluster()
+	nodeInfos := scheduler_util.CreateNodeNameToInfoMap(pods, nodes)
+	newPods := make([]*apiv1.Pod, 1000)
+	for i := range newPods {
+		newPods[i] = buildScheduledTestPod(fmt.Sprintf("new%d", i), 100, "")
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		changed := make(map[string]*schedulernodeinfo.NodeInfo)
+		for j, pod := range newPods {
+			target := nodes[j%50].Name
+			nodeInfo, found := changed[target]
+			if !found {
+				nodeInfo = nodeInfos[target]
+			}
+			changed[target] = scheduler_util.NodeWithPod(nodeInfo, pod)
+		}
+	}
+}
+
+func BenchmarkPackPodsSnapshot(b *testing.B) {
+	nodes, pods := buildBenchmarkCluster()
+	snapshot := NewClusterSnapshotFromPods(pods, nodes)
+	newPods := make([]*apiv1.Pod, 1000)
+	for i := range newPods {
+		newPods[i] = buildScheduledTestPod(fmt.Sprintf("new%d", i), 100, "")
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		snapshot.Fork()
+		for j, pod := range newPods {
+			snapshot.AddPod(pod, nodes[j%50].Name)
+		}
+		snapshot.Revert()
+	}
+}
@@ -23,6 +23,9 @@ import (
 
 	apiv1 "k8s.io/api/core/v1"
 	policyv1 "k8s.io/api/policy/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/autoscaler/cluster-autoscaler/utils/errors"
+	kube_util "k8s.io/autoscaler/cluster-autoscaler/utils/kubernetes"
 	. "k8s.io/autoscaler/cluster-autoscaler/utils/test"
 	"k8s.io/kubernetes/pkg/kubelet/types"
 	schedulernodeinfo "k8s.io/kubernetes/pkg/scheduler/nodeinfo"
@@ -112,14 +115,13 @@ func TestFindPlaceAllOk(t *testing.T) {
 
 	oldHints := make(map[string]string)
 	newHints := make(map[string]string)
-	tracker := NewUsageTracker()
 
 	err := findPlaceFor(
 		"x",
 		[]*apiv1.Pod{new1, new2},
 		[]*apiv1.Node{node1, node2},
-		nodeInfos, NewTestPredicateChecker(),
-		oldHints, newHints, tracker, time.Now())
+		newTestSnapshot(t, nodeInfos), NewTestPredicateChecker(),
+		oldHints, newHints)
 
 	assert.Len(t, newHints, 2)
 	assert.Contains(t, newHints, new1.Namespace+"/"+new1.Name)
@@ -151,14 +153,13 @@ func TestFindPlaceAllBas(t *testing.T) {
 
 	oldHints := make(map[string]string)
 	newHints := make(map[string]string)
-	tracker := NewUsageTracker()
 
 	err := findPlaceFor(
 		"nbad",
 		[]*apiv1.Pod{new1, new2, new3},
 		[]*apiv1.Node{nodebad, node1, node2},
-		nodeInfos, NewTestPredicateChecker(),
-		oldHints, newHints, tracker, time.Now())
+		newTestSnapshot(t, nodeInfos), NewTestPredicateChecker(),
+		oldHints, newHints)
 
 	assert.Error(t, err)
 	assert.True(t, len(newHints) == 2)
@@ -186,11 +187,9 @@ func TestFindNone(t *testing.T) {
 		"x",
 		[]*apiv1.Pod{},
 		[]*apiv1.Node{node1, node2},
-		nodeInfos, NewTestPredicateChecker(),
-		make(map[string]string),
+		newTestSnapshot(t, nodeInfos), NewTestPredicateChecker(),
 		make(map[string]string),
-		NewUsageTracker(),
-		time.Now())
+		make(map[string]string))
 	assert.NoError(t, err)
 }
 
@@ -238,7 +237,7 @@ type findNodesToRemoveTestConfig struct {
 	candidates  []*apiv1.Node
 	allNodes    []*apiv1.Node
 	toRemove    []NodeToBeRemoved
-	unremovable []*apiv1.Node
+	unremovable []*UnremovableNode
 }
 
 func TestFindNodesToRemove(t *testing.T) {
@@ -291,7 +290,7 @@ func TestFindNodesToRemove(t *testing.T) {
 			candidates:  []*apiv1.Node{emptyNode},
 			allNodes:    []*apiv1.Node{emptyNode},
 			toRemove:    []NodeToBeRemoved{emptyNodeToRemove},
-			unremovable: []*apiv1.Node{},
+			unremovable: []*UnremovableNode{},
 		},
 		// just a drainable node, but nowhere for pods to go to
 		{
@@ -299,7 +298,7 @@ func TestFindNodesToRemove(t *testing.T) {
 			candidates:  []*apiv1.Node{drainableNode},
 			allNodes:    []*apiv1.Node{drainableNode},
 			toRemove:    []NodeToBeRemoved{},
-			unremovable: []*apiv1.Node{drainableNode},
+			unremovable: []*UnremovableNode{{Node: drainableNode, Reason: NoPlaceToMovePods}},
 		},
 		// drainable node, and a mostly empty node that can take its pods
 		{
@@ -307,7 +306,7 @@ func TestFindNodesToRemove(t *testing.T) {
 			candidates:  []*apiv1.Node{drainableNode, nonDrainableNode},
 			allNodes:    []*apiv1.Node{drainableNode, nonDrainableNode},
 			toRemove:    []NodeToBeRemoved{drainableNodeToRemove},
-			unremovable: []*apiv1.Node{nonDrainableNode},
+			unremovable: []*UnremovableNode{{Node: nonDrainableNode, Reason: BlockedByPod}},
 		},
 		// drainable node, and a full node that cannot fit anymore pods
 		{
@@ -315,7 +314,7 @@ func TestFindNodesToRemove(t *testing.T) {
 			candidates:  []*apiv1.Node{drainableNode},
 			allNodes:    []*apiv1.Node{drainableNode, fullNode},
 			toRemove:    []NodeToBeRemoved{},
-			unremovable: []*apiv1.Node{drainableNode},
+			unremovable: []*UnremovableNode{{Node: drainableNode, Reason: NoPlaceToMovePods}},
 		},
 		// 4 nodes, 1 empty, 1 drainable
 		{
@@ -323,7 +322,7 @@ func TestFindNodesToRemove(t *testing.T) {
 			candidates:  []*apiv1.Node{emptyNode, drainableNode},
 			allNodes:    []*apiv1.Node{emptyNode, drainableNode, fullNode, nonDrainableNode},
 			toRemove:    []NodeToBeRemoved{emptyNodeToRemove, drainableNodeToRemove},
-			unremovable: []*apiv1.Node{},
+			unremovable: []*UnremovableNode{},
 		},
 	}
 
@@ -335,7 +334,192 @@ func TestFindNodesToRemove(t *testing.T) {
 		assert.NoError(t, err)
 		fmt.Printf("Test scenario: %s, found len(toRemove)=%v, expected len(test.toRemove)=%v\n", test.name, len(toRemove), len(test.toRemove))
 		assert.Equal(t, toRemove, test.toRemove)
+		for _, node := range unremovable {
+			assert.NotEmpty(t, node.Message)
+			// Messages come from the predicates and drain checks, only compare the reasons.
+			node.Message = ""
+		}
 		assert.Equal(t, unremovable, test.unremovable)
 	}
 
 }
+
+func TestFindNodesToRemoveWithHeadroom(t *testing.T) {
+	n1 := BuildTestNode("n1", 1000, 2000000)
+	n2 := BuildTestNode("n2", 1000, 2000000)
+	n3 := BuildTestNode("n3", 1000, 2000000)
+	nodes := []*apiv1.Node{n1, n2, n3}
+	for _, node := range nodes {
+		SetNodeReadyState(node, true, time.Time{})
+	}
+
+	// Headroom pods are not backed by anything, but can still be moved.
+	headroomPod := BuildTestPod("headroom", 600, 100000)
+	headroomPod.Annotations = map[string]string{HeadroomPodAnnotationKey: "true"}
+	headroomPod.Spec.NodeName = "n1"
+
+	toRemove, unremovable, _, err := FindNodesToRemove(
+		nodes, nodes, []*apiv1.Pod{headroomPod}, nil,
+		NewTestPredicateChecker(), len(nodes), true, map[string]string{},
+		NewUsageTracker(), time.Now(), []*policyv1.PodDisruptionBudget{})
+	assert.NoError(t, err)
+	// Every node could be removed on its own, but one of them has to stay for the headroom pod.
+	assert.Equal(t, 2, len(toRemove))
+	assert.Equal(t, 1, len(unremovable))
+	for _, node := range toRemove {
+		// Headroom pods are never evicted.
+		assert.Empty(t, node.PodsToReschedule)
+	}
+}
+
+func TestFindNodesToRemoveTogether(t *testing.T) {
+	n1 := BuildTestNode("n1", 1000, 2000000)
+	n2 := BuildTestNode("n2", 1000, 2000000)
+	n3 := BuildTestNode("n3", 1200, 2000000)
+	nodes := []*apiv1.Node{n1, n2, n3}
+	for _, node := range nodes {
+		SetNodeReadyState(node, true, time.Time{})
+	}
+
+	ownerRefs := GenerateOwnerReferences("rs", "ReplicaSet", "extensions/v1beta1", "")
+	pod1 := BuildTestPod("p1", 400, 100000)
+	pod1.OwnerReferences = ownerRefs
+	pod1.Labels = map[string]string{"app": "a"}
+	pod1.Spec.NodeName = "n1"
+	pod2 := BuildTestPod("p2", 700, 100000)
+	pod2.OwnerReferences = ownerRefs
+	pod2.Labels = map[string]string{"app": "a"}
+	pod2.Spec.NodeName = "n2"
+	pod3 := BuildTestPod("p3", 500, 100000)
+	pod3.Spec.NodeName = "n3"
+	pods := []*apiv1.Pod{pod1, pod2, pod3}
+
+	pdb := &policyv1.PodDisruptionBudget{
+		ObjectMeta: metav1.ObjectMeta{Name: "pdb", Namespace: "default"},
+		Spec: policyv1.PodDisruptionBudgetSpec{
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "a"}},
+		},
+		Status: policyv1.PodDisruptionBudgetStatus{PodDisruptionsAllowed: 2},
+	}
+	candidates := []*apiv1.Node{n1, n2}
+
+	// Each of the nodes can be removed on its own.
+	toRemove, unremovable, _, err := FindNodesToRemove(candidates, nodes, pods, nil,
+		NewTestPredicateChecker(), len(candidates), true, map[string]string{},
+		NewUsageTracker(), time.Now(), []*policyv1.PodDisruptionBudget{pdb})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(toRemove))
+	assert.Empty(t, unremovable)
+
+	// Pods from both nodes can't be moved to n3 at the same time.
+	toRemove, unremovable, _, err = FindNodesToRemoveTogether(candidates, nodes, pods, nil,
+		NewTestPredicateChecker(), len(candidates), true, map[string]string{},
+		NewUsageTracker(), time.Now(), []*policyv1.PodDisruptionBudget{pdb})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(toRemove))
+	assert.Equal(t, 1, len(unremovable))
+	assert.Equal(t, NoPlaceToMovePods, unremovable[0].Reason)
+
+	// With enough room for both pods, the disruption budget still allows only one of them to be evicted.
+	pod3.Spec.NodeName = ""
+	pdb.Status.PodDisruptionsAllowed = 1
+	toRemove, unremovable, _, err = FindNodesToRemoveTogether(candidates, nodes, pods, nil,
+		NewTestPredicateChecker(), len(candidates), true, map[string]string{},
+		NewUsageTracker(), time.Now(), []*policyv1.PodDisruptionBudget{pdb})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(toRemove))
+	assert.Equal(t, 1, len(unremovable))
+	assert.Equal(t, n2, unremovable[0].Node)
+	assert.Equal(t, NotEnoughPdb, unremovable[0].Reason)
+
+	pdb.Status.PodDisruptionsAllowed = 2
+	toRemove, unremovable, _, err = FindNodesToRemoveTogether(candidates, nodes, pods, nil,
+		NewTestPredicateChecker(), len(candidates), true, map[string]string{},
+		NewUsageTracker(), time.Now(), []*policyv1.PodDisruptionBudget{pdb})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(toRemove))
+	assert.Empty(t, unremovable)
+	assert.Equal(t, []*apiv1.Pod{pod1}, toRemove[0].PodsToReschedule)
+	assert.Equal(t, []*apiv1.Pod{pod2}, toRemove[1].PodsToReschedule)
+}
+
+func TestFindNodesToRemoveInParallel(t *testing.T) {
+	ownerRefs := GenerateOwnerReferences("rs", "ReplicaSet", "extensions/v1beta1", "")
+	var candidates, nodes []*apiv1.Node
+	var pods []*apiv1.Pod
+	// Candidates are full, so their pods can only go to the 3 empty nodes, which
+	// have room for 6 of them.
+	for i := 0; i < 12; i++ {
+		name := fmt.Sprintf("c%d", i)
+		pod := BuildTestPod(fmt.Sprintf("p%d", i), 500, 100000)
+		pod.OwnerReferences = ownerRefs
+		pod.Spec.NodeName = name
+		node := BuildTestNode(name, 500, 2000000)
+		switch i {
+		case 2:
+			// Not backed by anything.
+			pod.OwnerReferences = nil
+		case 4:
+			// Doesn't fit on any other node.
+			node = BuildTestNode(name, 1500, 2000000)
+			pod = BuildTestPod(pod.Name, 1500, 100000)
+			pod.OwnerReferences = ownerRefs
+			pod.Spec.NodeName = name
+		}
+		SetNodeReadyState(node, true, time.Time{})
+		candidates = append(candidates, node)
+		pods = append(pods, pod)
+	}
+	nodes = append(nodes, candidates...)
+	for i := 0; i < 3; i++ {
+		node := BuildTestNode(fmt.Sprintf("n%d", i), 1000, 2000000)
+		SetNodeReadyState(node, true, time.Time{})
+		nodes = append(nodes, node)
+	}
+
+	type summary struct {
+		toRemove    []string
+		unremovable []string
+	}
+	find := func(findNodesToRemove func([]*apiv1.Node, []*apiv1.Node, []*apiv1.Pod, kube_util.ListerRegistry,
+		*PredicateChecker, int, bool, map[string]string, *UsageTracker, time.Time, []*policyv1.PodDisruptionBudget,
+	) ([]NodeToBeRemoved, []*UnremovableNode, map[string]string, errors.AutoscalerError), maxCount int, workers int) summary {
+		defer func(workers int) { *scaleDownSimulationWorkers = workers }(*scaleDownSimulationWorkers)
+		*scaleDownSimulationWorkers = workers
+		toRemove, unremovable, hints, err := findNodesToRemove(candidates, nodes, pods, nil,
+			NewTestPredicateChecker(), maxCount, true, map[string]string{},
+			NewUsageTracker(), time.Now(), []*policyv1.PodDisruptionBudget{})
+		assert.NoError(t, err)
+		result := summary{toRemove: []string{}, unremovable: []string{}}
+		for _, node := range toRemove {
+			result.toRemove = append(result.toRemove, node.Node.Name)
+			if assert.Equal(t, 1, len(node.PodsToReschedule)) {
+				assert.Contains(t, hints, podKey(node.PodsToReschedule[0]))
+			}
+		}
+		for _, node := range unremovable {
+			result.unremovable = append(result.unremovable, fmt.Sprintf("%s:%v", node.Node.Name, node.Reason))
+		}
+		return result
+	}
+
+	for _, workers := range []int{1, 3, 16} {
+		assert.Equal(t, summary{
+			toRemove:    []string{"c0", "c1", "c3", "c5", "c6", "c7", "c8", "c9", "c10", "c11"},
+			unremovable: []string{"c2:BlockedByPod", "c4:NoPlaceToMovePods"},
+		}, find(FindNodesToRemove, len(candidates), workers), "%d workers", workers)
+
+		// Candidates after the ones found aren't evaluated.
+		assert.Equal(t, summary{
+			toRemove:    []string{"c0", "c1", "c3"},
+			unremovable: []string{"c2:BlockedByPod"},
+		}, find(FindNodesToRemove, 3, workers), "%d workers", workers)
+
+		// Only 6 pods fit on the empty nodes together.
+		assert.Equal(t, summary{
+			toRemove: []string{"c0", "c1", "c3", "c5", "c6", "c7"},
+			unremovable: []string{"c2:BlockedByPod", "c4:NoPlaceToMovePods", "c8:NoPlaceToMovePods", "c9:NoPlaceToMovePods",
+				"c10:NoPlaceToMovePods", "c11:NoPlaceToMovePods"},
+		}, find(FindNodesToRemoveTogether, len(candidates), workers), "%d workers", workers)
+	}
+}
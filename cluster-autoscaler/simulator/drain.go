@@ -30,14 +30,16 @@ import (
 )
 
 // FastGetPodsToMove returns a list of pods that should be moved elsewhere if the node
-// is drained. Raises error if there is an unreplicated pod.
+// is drained. Raises error if there is an unreplicated pod. Headroom pods are always
+// moved.
 // Based on kubectl drain code. It makes an assumption that RC, DS, Jobs and RS were deleted
 // along with their pods (no abandoned pods with dangling created-by annotation). Useful for fast
 // checks.
 func FastGetPodsToMove(nodeInfo *schedulernodeinfo.NodeInfo, skipNodesWithSystemPods bool, skipNodesWithLocalStorage bool,
 	pdbs []*policyv1.PodDisruptionBudget) ([]*apiv1.Pod, error) {
+	realPods, headroomPods := splitHeadroomPods(nodeInfo.Pods())
 	pods, err := drain.GetPodsForDeletionOnNodeDrain(
-		nodeInfo.Pods(),
+		realPods,
 		pdbs,
 		false,
 		skipNodesWithSystemPods,
@@ -54,18 +56,20 @@ func FastGetPodsToMove(nodeInfo *schedulernodeinfo.NodeInfo, skipNodesWithSystem
 		return []*apiv1.Pod{}, err
 	}
 
-	return pods, nil
+	return append(pods, headroomPods...), nil
 }
 
 // DetailedGetPodsForMove returns a list of pods that should be moved elsewhere if the node
-// is drained. Raises error if there is an unreplicated pod.
+// is drained. Raises error if there is an unreplicated pod. Headroom pods are always
+// moved.
 // Based on kubectl drain code. It checks whether RC, DS, Jobs and RS that created these pods
 // still exist.
 func DetailedGetPodsForMove(nodeInfo *schedulernodeinfo.NodeInfo, skipNodesWithSystemPods bool,
 	skipNodesWithLocalStorage bool, listers kube_util.ListerRegistry, minReplicaCount int32,
 	pdbs []*policyv1.PodDisruptionBudget) ([]*apiv1.Pod, error) {
+	realPods, headroomPods := splitHeadroomPods(nodeInfo.Pods())
 	pods, err := drain.GetPodsForDeletionOnNodeDrain(
-		nodeInfo.Pods(),
+		realPods,
 		pdbs,
 		false,
 		skipNodesWithSystemPods,
@@ -81,7 +85,17 @@ func DetailedGetPodsForMove(nodeInfo *schedulernodeinfo.NodeInfo, skipNodesWithS
 		return []*apiv1.Pod{}, err
 	}
 
-	return pods, nil
+	return append(pods, headroomPods...), nil
+}
+
+// notEnoughPdbError is returned when pods can't be evicted without violating
+// their pod disruption budgets.
+type notEnoughPdbError struct {
+	message string
+}
+
+func (e *notEnoughPdbError) Error() string {
+	return e.message
 }
 
 func checkPdbs(pods []*apiv1.Pod, pdbs []*policyv1.PodDisruptionBudget) error {
@@ -94,10 +108,40 @@ func checkPdbs(pods []*apiv1.Pod, pdbs []*policyv1.PodDisruptionBudget) error {
 		for _, pod := range pods {
 			if pod.Namespace == pdb.Namespace && selector.Matches(labels.Set(pod.Labels)) {
 				if pdb.Status.PodDisruptionsAllowed < 1 {
-					return fmt.Errorf("not enough pod disruption budget to move %s/%s", pod.Namespace, pod.Name)
+					return &notEnoughPdbError{fmt.Sprintf("not enough pod disruption budget to move %s/%s", pod.Namespace, pod.Name)}
 				}
 			}
 		}
 	}
 	return nil
 }
+
+// countPdbDisruptions returns the number of pods matched by each of the pod disruption budgets.
+// Headroom pods are virtual, so they are never counted.
+func countPdbDisruptions(pods []*apiv1.Pod, pdbs []*policyv1.PodDisruptionBudget) ([]int32, error) {
+	pods, _ = splitHeadroomPods(pods)
+	result := make([]int32, len(pdbs))
+	for i, pdb := range pdbs {
+		selector, err := metav1.LabelSelectorAsSelector(pdb.Spec.Selector)
+		if err != nil {
+			return nil, err
+		}
+		for _, pod := range pods {
+			if pod.Namespace == pdb.Namespace && selector.Matches(labels.Set(pod.Labels)) {
+				result[i]++
+			}
+		}
+	}
+	return result, nil
+}
+
+// checkPdbDisruptionsLeft checks if the pod disruption budgets allow the given
+// disruptions on top of the ones already used.
+func checkPdbDisruptionsLeft(pdbs []*policyv1.PodDisruptionBudget, used []int32, disruptions []int32) error {
+	for i, pdb := range pdbs {
+		if disruptions[i] > 0 && used[i]+disruptions[i] > pdb.Status.PodDisruptionsAllowed {
+			return &notEnoughPdbError{fmt.Sprintf("not enough pod disruption budget left in %s/%s", pdb.Namespace, pdb.Name)}
+		}
+	}
+	return nil
+}
@@ -203,4 +203,18 @@ func TestFastGetPodsToMove(t *testing.T) {
 	r9, err := FastGetPodsToMove(schedulernodeinfo.NewNodeInfo(pod9), true, true, []*policyv1.PodDisruptionBudget{pdb9})
 	assert.NoError(t, err)
 	assert.Equal(t, 1, len(r9))
+
+	// Unreplicated headroom pod
+	pod10 := &apiv1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "pod10",
+			Namespace: "kube-system",
+			Annotations: map[string]string{
+				HeadroomPodAnnotationKey: "true",
+			},
+		},
+	}
+	r10, err := FastGetPodsToMove(schedulernodeinfo.NewNodeInfo(pod10, pod2), true, true, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []*apiv1.Pod{pod2, pod10}, r10)
 }
@@ -0,0 +1,50 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package simulator
+
+import (
+	apiv1 "k8s.io/api/core/v1"
+)
+
+const (
+	// HeadroomPodAnnotationKey marks virtual pods representing spare capacity
+	// CA keeps free. Such pods never exist in the cluster.
+	HeadroomPodAnnotationKey = "cluster-autoscaler.kubernetes.io/headroom-pod"
+)
+
+// IsHeadroomPod returns true if the pod is a virtual pod representing spare capacity.
+func IsHeadroomPod(pod *apiv1.Pod) bool {
+	return pod.Annotations[HeadroomPodAnnotationKey] == "true"
+}
+
+// HeadroomPods returns headroom pods from the given list.
+func HeadroomPods(pods []*apiv1.Pod) []*apiv1.Pod {
+	_, headroomPods := splitHeadroomPods(pods)
+	return headroomPods
+}
+
+// splitHeadroomPods splits pods into real and headroom pods.
+func splitHeadroomPods(pods []*apiv1.Pod) (realPods []*apiv1.Pod, headroomPods []*apiv1.Pod) {
+	for _, pod := range pods {
+		if IsHeadroomPod(pod) {
+			headroomPods = append(headroomPods, pod)
+		} else {
+			realPods = append(realPods, pod)
+		}
+	}
+	return realPods, headroomPods
+}
@@ -55,6 +55,7 @@ type PredicateChecker struct {
 	predicates                []PredicateInfo
 	predicateMetadataProducer predicates.PredicateMetadataProducer
 	enableAffinityPredicate   bool
+	volumeTopologyChecker     *VolumeTopologyChecker
 }
 
 // We run some predicates first as they are cheap to check and they should be enough
@@ -171,6 +172,8 @@ func NewPredicateChecker(kubeClient kube_client.Interface, stop <-chan struct{})
 		klog.V(1).Infof("Using predicate %s", predInfo.Name)
 	}
 
+	volumeTopologyChecker := NewVolumeTopologyChecker(pvcInformer.Lister(), pvInformer.Lister(), storageClassInformer.Lister())
+
 	informerFactory.Start(stop)
 
 	metadataProducer, err := configurator.GetPredicateMetadataProducer()
@@ -182,6 +185,7 @@ func NewPredicateChecker(kubeClient kube_client.Interface, stop <-chan struct{})
 		predicates:                predicateList,
 		predicateMetadataProducer: metadataProducer,
 		enableAffinityPredicate:   true,
+		volumeTopologyChecker:     volumeTopologyChecker,
 	}, nil
 }
 
@@ -232,6 +236,24 @@ func (p *PredicateChecker) IsAffinityPredicateEnabled() bool {
 	return p.enableAffinityPredicate
 }
 
+// SetVolumeTopologyChecker sets the checker used by CheckVolumeTopology.
+func (p *PredicateChecker) SetVolumeTopologyChecker(checker *VolumeTopologyChecker) {
+	p.volumeTopologyChecker = checker
+}
+
+// CheckVolumeTopology checks if persistent volumes of the given pod allow placing it on the given
+// node. Unlike predicates, it reads claims, volumes and storage classes of the pod itself, so it also
+// works for template nodes. Test predicate checkers don't check volume topology unless a checker is set.
+func (p *PredicateChecker) CheckVolumeTopology(pod *apiv1.Pod, node *apiv1.Node) *PredicateError {
+	if p.volumeTopologyChecker == nil {
+		return nil
+	}
+	if err := p.volumeTopologyChecker.CheckPod(pod, node); err != nil {
+		return NewPredicateError(volumeTopologyPredicateName, nil, []string{err.Error()}, nil)
+	}
+	return nil
+}
+
 // GetPredicateMetadata precomputes some information useful for running predicates on a given pod in a given state
 // of the cluster (represented by nodeInfos map). Passing the result of this function to CheckPredicates can significantly
 // improve the performance of running predicates, especially MatchInterPodAffinity predicate. However, calculating
@@ -131,6 +131,51 @@ func (tracker *UsageTracker) CleanUp(cutoff time.Time) {
 	}
 }
 
+// UsageRecordState is the content of a UsageRecord, kept across restarts.
+type UsageRecordState struct {
+	UsingTooMany  bool                 `json:"usingTooMany,omitempty"`
+	Using         map[string]time.Time `json:"using,omitempty"`
+	UsedByTooMany bool                 `json:"usedByTooMany,omitempty"`
+	UsedBy        map[string]time.Time `json:"usedBy,omitempty"`
+}
+
+// GetState returns copies of all usage records.
+func (tracker *UsageTracker) GetState() map[string]UsageRecordState {
+	state := make(map[string]UsageRecordState, len(tracker.usage))
+	for node, record := range tracker.usage {
+		state[node] = UsageRecordState{
+			UsingTooMany:  record.usingTooMany,
+			Using:         copyTimestamps(record.using),
+			UsedByTooMany: record.usedByTooMany,
+			UsedBy:        copyTimestamps(record.usedBy),
+		}
+	}
+	return state
+}
+
+// Restore replaces all usage records with ones previously returned by GetState and
+// removes relations updated before the cutoff time.
+func (tracker *UsageTracker) Restore(state map[string]UsageRecordState, cutoff time.Time) {
+	tracker.usage = make(map[string]*UsageRecord, len(state))
+	for node, recordState := range state {
+		tracker.usage[node] = &UsageRecord{
+			usingTooMany:  recordState.UsingTooMany,
+			using:         copyTimestamps(recordState.Using),
+			usedByTooMany: recordState.UsedByTooMany,
+			usedBy:        copyTimestamps(recordState.UsedBy),
+		}
+	}
+	tracker.CleanUp(cutoff)
+}
+
+func copyTimestamps(timestampMap map[string]time.Time) map[string]time.Time {
+	result := make(map[string]time.Time, len(timestampMap))
+	for key, timestamp := range timestampMap {
+		result[key] = timestamp
+	}
+	return result
+}
+
 // RemoveNodeFromTracker removes node from tracker and also cleans the passed utilization map.
 func RemoveNodeFromTracker(tracker *UsageTracker, node string, utilization map[string]time.Time) {
 	keysToRemove := make([]string, 0)
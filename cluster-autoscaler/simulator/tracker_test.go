@@ -110,3 +110,33 @@ func TestRemove(t *testing.T) {
 	assert.True(t, foundC)
 	assert.False(t, foundX)
 }
+
+func TestUsageTrackerRestore(t *testing.T) {
+	tracker := NewUsageTracker()
+	now := time.Now()
+	tracker.RegisterUsage("A", "B", now.Add(-5*time.Minute))
+	tracker.RegisterUsage("C", "B", now.Add(-20*time.Minute))
+	for i := 0; i < maxUsageRecorded+5; i++ {
+		tracker.RegisterUsage("Y", fmt.Sprintf("X%d", i), now)
+	}
+
+	restored := NewUsageTracker()
+	restored.Restore(tracker.GetState(), now.Add(-10*time.Minute))
+
+	A, foundA := restored.Get("A")
+	B, foundB := restored.Get("B")
+	_, foundC := restored.Get("C")
+	Y, foundY := restored.Get("Y")
+	assert.True(t, foundA)
+	assert.Contains(t, A.using, "B")
+	assert.True(t, foundB)
+	assert.Contains(t, B.usedBy, "A")
+	assert.NotContains(t, B.usedBy, "C")
+	assert.False(t, foundC)
+	assert.True(t, foundY)
+	assert.True(t, Y.usingTooMany)
+
+	// The restored records don't share maps with the original tracker.
+	tracker.RegisterUsage("A", "D", now)
+	assert.NotContains(t, A.using, "D")
+}
@@ -0,0 +1,147 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package simulator
+
+import (
+	"fmt"
+	"strings"
+
+	apiv1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	kube_errors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	v1lister "k8s.io/client-go/listers/core/v1"
+	v1storagelister "k8s.io/client-go/listers/storage/v1"
+	"k8s.io/klog"
+	v1helper "k8s.io/kubernetes/pkg/apis/core/v1/helper"
+)
+
+const (
+	// volumeTopologyPredicateName is the name of the volume topology check in predicate errors.
+	volumeTopologyPredicateName = "VolumeTopology"
+	// zonesSeparator separates zones in zone labels of volumes spanning multiple zones.
+	zonesSeparator = "__"
+)
+
+// VolumeTopologyChecker checks whether persistent volumes used by a pod allow it
+// to run on a node. Volumes bound to claims have to be accessible from the node,
+// according to their node affinity and zone labels. Volumes of claims waiting for
+// their first consumer will be provisioned in the topology of the node, so it has
+// to be allowed by their storage class.
+//
+// Template nodes usually carry the zone labels of their node group, so this lets
+// scale-up pick node groups in the zones the volumes of pending pods are in.
+type VolumeTopologyChecker struct {
+	pvcLister          v1lister.PersistentVolumeClaimLister
+	pvLister           v1lister.PersistentVolumeLister
+	storageClassLister v1storagelister.StorageClassLister
+}
+
+// NewVolumeTopologyChecker creates a VolumeTopologyChecker using the given listers.
+func NewVolumeTopologyChecker(pvcLister v1lister.PersistentVolumeClaimLister, pvLister v1lister.PersistentVolumeLister,
+	storageClassLister v1storagelister.StorageClassLister) *VolumeTopologyChecker {
+	return &VolumeTopologyChecker{
+		pvcLister:          pvcLister,
+		pvLister:           pvLister,
+		storageClassLister: storageClassLister,
+	}
+}
+
+// CheckPod returns an error if volumes of the pod don't allow it to run on the node.
+// Claims, volumes and storage classes that can't be found are ignored, as no node
+// would help the pod then.
+func (c *VolumeTopologyChecker) CheckPod(pod *apiv1.Pod, node *apiv1.Node) error {
+	for _, volume := range pod.Spec.Volumes {
+		if volume.PersistentVolumeClaim == nil {
+			continue
+		}
+		claimName := volume.PersistentVolumeClaim.ClaimName
+		pvc, err := c.pvcLister.PersistentVolumeClaims(pod.Namespace).Get(claimName)
+		if err != nil {
+			logListerError(err, "persistent volume claim %s/%s", pod.Namespace, claimName)
+			continue
+		}
+		if pvc.Spec.VolumeName != "" {
+			pv, err := c.pvLister.Get(pvc.Spec.VolumeName)
+			if err != nil {
+				logListerError(err, "persistent volume %s", pvc.Spec.VolumeName)
+				continue
+			}
+			if err := checkVolumeAccessible(pv, node); err != nil {
+				return err
+			}
+			continue
+		}
+
+		className := v1helper.GetPersistentVolumeClaimClass(pvc)
+		if className == "" {
+			continue
+		}
+		class, err := c.storageClassLister.Get(className)
+		if err != nil {
+			logListerError(err, "storage class %s", className)
+			continue
+		}
+		// Claims with immediate binding are bound to volumes regardless of pods,
+		// their topology isn't known until then.
+		if class.VolumeBindingMode == nil || *class.VolumeBindingMode != storagev1.VolumeBindingWaitForFirstConsumer {
+			continue
+		}
+		if len(class.AllowedTopologies) > 0 && !v1helper.MatchTopologySelectorTerms(class.AllowedTopologies, labels.Set(node.Labels)) {
+			return fmt.Errorf("storage class %s of claim %s/%s doesn't allow provisioning volumes for node %s", className, pod.Namespace, claimName, node.Name)
+		}
+	}
+	return nil
+}
+
+// checkVolumeAccessible returns an error if the volume isn't accessible from the node.
+func checkVolumeAccessible(pv *apiv1.PersistentVolume, node *apiv1.Node) error {
+	if pv.Spec.NodeAffinity != nil && pv.Spec.NodeAffinity.Required != nil {
+		nodeFields := fields.Set{"metadata.name": node.Name}
+		if !v1helper.MatchNodeSelectorTerms(pv.Spec.NodeAffinity.Required.NodeSelectorTerms, labels.Set(node.Labels), nodeFields) {
+			return fmt.Errorf("node affinity of persistent volume %s doesn't match node %s", pv.Name, node.Name)
+		}
+	}
+	for _, key := range []string{apiv1.LabelZoneFailureDomain, apiv1.LabelZoneRegion} {
+		pvValue, found := pv.Labels[key]
+		if !found {
+			continue
+		}
+		if !containsString(strings.Split(pvValue, zonesSeparator), node.Labels[key]) {
+			return fmt.Errorf("persistent volume %s is in %s %s, node %s isn't", pv.Name, key, pvValue, node.Name)
+		}
+	}
+	return nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func logListerError(err error, format string, args ...interface{}) {
+	if kube_errors.IsNotFound(err) {
+		klog.V(4).Infof("Volume topology check: %s not found", fmt.Sprintf(format, args...))
+	} else {
+		klog.Warningf("Volume topology check: failed to get %s: %v", fmt.Sprintf(format, args...), err)
+	}
+}
@@ -0,0 +1,186 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package simulator
+
+import (
+	"testing"
+
+	apiv1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kube_util "k8s.io/autoscaler/cluster-autoscaler/utils/kubernetes"
+	. "k8s.io/autoscaler/cluster-autoscaler/utils/test"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func buildTestZonalNode(name, zone string) *apiv1.Node {
+	node := BuildTestNode(name, 1000, 1000)
+	node.Labels = map[string]string{
+		apiv1.LabelZoneRegion:        "us-east1",
+		apiv1.LabelZoneFailureDomain: zone,
+	}
+	return node
+}
+
+func buildTestPodWithClaims(name string, claims ...string) *apiv1.Pod {
+	pod := BuildTestPod(name, 100, 0)
+	for _, claim := range claims {
+		pod.Spec.Volumes = append(pod.Spec.Volumes, apiv1.Volume{
+			Name: claim,
+			VolumeSource: apiv1.VolumeSource{
+				PersistentVolumeClaim: &apiv1.PersistentVolumeClaimVolumeSource{ClaimName: claim},
+			},
+		})
+	}
+	return pod
+}
+
+func buildTestClaim(name, volumeName, className string) *apiv1.PersistentVolumeClaim {
+	return &apiv1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		Spec: apiv1.PersistentVolumeClaimSpec{
+			VolumeName:       volumeName,
+			StorageClassName: &className,
+		},
+	}
+}
+
+func buildTestStorageClass(name string, mode storagev1.VolumeBindingMode, zones ...string) *storagev1.StorageClass {
+	class := &storagev1.StorageClass{
+		ObjectMeta:        metav1.ObjectMeta{Name: name},
+		VolumeBindingMode: &mode,
+	}
+	if len(zones) > 0 {
+		class.AllowedTopologies = []apiv1.TopologySelectorTerm{{
+			MatchLabelExpressions: []apiv1.TopologySelectorLabelRequirement{{Key: apiv1.LabelZoneFailureDomain, Values: zones}},
+		}}
+	}
+	return class
+}
+
+func TestVolumeTopologyChecker(t *testing.T) {
+	labeledPV := &apiv1.PersistentVolume{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   "pv-labeled",
+			Labels: map[string]string{apiv1.LabelZoneRegion: "us-east1", apiv1.LabelZoneFailureDomain: "us-east1-a"},
+		},
+	}
+	multiZonePV := &apiv1.PersistentVolume{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   "pv-multizone",
+			Labels: map[string]string{apiv1.LabelZoneFailureDomain: "us-east1-a__us-east1-b"},
+		},
+	}
+	affinityPV := &apiv1.PersistentVolume{
+		ObjectMeta: metav1.ObjectMeta{Name: "pv-affinity"},
+		Spec: apiv1.PersistentVolumeSpec{
+			NodeAffinity: &apiv1.VolumeNodeAffinity{
+				Required: &apiv1.NodeSelector{
+					NodeSelectorTerms: []apiv1.NodeSelectorTerm{{
+						MatchExpressions: []apiv1.NodeSelectorRequirement{{
+							Key:      apiv1.LabelZoneFailureDomain,
+							Operator: apiv1.NodeSelectorOpIn,
+							Values:   []string{"us-east1-b"},
+						}},
+					}},
+				},
+			},
+		},
+	}
+	pvcLister, err := kube_util.NewTestPersistentVolumeClaimLister([]*apiv1.PersistentVolumeClaim{
+		buildTestClaim("claim-labeled", "pv-labeled", ""),
+		buildTestClaim("claim-multizone", "pv-multizone", ""),
+		buildTestClaim("claim-affinity", "pv-affinity", ""),
+		buildTestClaim("claim-missing-pv", "pv-missing", ""),
+		buildTestClaim("claim-wffc", "", "wffc"),
+		buildTestClaim("claim-wffc-any", "", "wffc-any"),
+		buildTestClaim("claim-immediate", "", "immediate"),
+	})
+	assert.NoError(t, err)
+	pvLister, err := kube_util.NewTestPersistentVolumeLister([]*apiv1.PersistentVolume{labeledPV, multiZonePV, affinityPV})
+	assert.NoError(t, err)
+	classLister, err := kube_util.NewTestStorageClassLister([]*storagev1.StorageClass{
+		buildTestStorageClass("wffc", storagev1.VolumeBindingWaitForFirstConsumer, "us-east1-c"),
+		buildTestStorageClass("wffc-any", storagev1.VolumeBindingWaitForFirstConsumer),
+		buildTestStorageClass("immediate", storagev1.VolumeBindingImmediate, "us-east1-c"),
+	})
+	assert.NoError(t, err)
+	checker := NewVolumeTopologyChecker(pvcLister, pvLister, classLister)
+
+	nodeA := buildTestZonalNode("node-a", "us-east1-a")
+	nodeB := buildTestZonalNode("node-b", "us-east1-b")
+	nodeC := buildTestZonalNode("node-c", "us-east1-c")
+
+	for _, tc := range []struct {
+		name    string
+		claims  []string
+		allowed []*apiv1.Node
+	}{
+		{name: "no claims", allowed: []*apiv1.Node{nodeA, nodeB, nodeC}},
+		{name: "zone labels", claims: []string{"claim-labeled"}, allowed: []*apiv1.Node{nodeA}},
+		{name: "multi-zone labels", claims: []string{"claim-multizone"}, allowed: []*apiv1.Node{nodeA, nodeB}},
+		{name: "node affinity", claims: []string{"claim-affinity"}, allowed: []*apiv1.Node{nodeB}},
+		{name: "multiple claims", claims: []string{"claim-multizone", "claim-affinity"}, allowed: []*apiv1.Node{nodeB}},
+		{name: "wait for first consumer", claims: []string{"claim-wffc"}, allowed: []*apiv1.Node{nodeC}},
+		{name: "wait for first consumer without topologies", claims: []string{"claim-wffc-any"}, allowed: []*apiv1.Node{nodeA, nodeB, nodeC}},
+		{name: "immediate binding", claims: []string{"claim-immediate"}, allowed: []*apiv1.Node{nodeA, nodeB, nodeC}},
+		{name: "missing claim", claims: []string{"claim-missing"}, allowed: []*apiv1.Node{nodeA, nodeB, nodeC}},
+		{name: "missing volume", claims: []string{"claim-missing-pv"}, allowed: []*apiv1.Node{nodeA, nodeB, nodeC}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			pod := buildTestPodWithClaims("p1", tc.claims...)
+			for _, node := range []*apiv1.Node{nodeA, nodeB, nodeC} {
+				allowed := false
+				for _, allowedNode := range tc.allowed {
+					allowed = allowed || allowedNode == node
+				}
+				err := checker.CheckPod(pod, node)
+				if allowed {
+					assert.NoError(t, err, node.Name)
+				} else {
+					assert.Error(t, err, node.Name)
+				}
+			}
+		})
+	}
+}
+
+func TestCheckVolumeTopology(t *testing.T) {
+	pvcLister, err := kube_util.NewTestPersistentVolumeClaimLister([]*apiv1.PersistentVolumeClaim{
+		buildTestClaim("claim-wffc", "", "wffc"),
+	})
+	assert.NoError(t, err)
+	pvLister, err := kube_util.NewTestPersistentVolumeLister(nil)
+	assert.NoError(t, err)
+	classLister, err := kube_util.NewTestStorageClassLister([]*storagev1.StorageClass{
+		buildTestStorageClass("wffc", storagev1.VolumeBindingWaitForFirstConsumer, "us-east1-c"),
+	})
+	assert.NoError(t, err)
+
+	pod := buildTestPodWithClaims("p1", "claim-wffc")
+	node := buildTestZonalNode("node-a", "us-east1-a")
+	predicateChecker := NewTestPredicateChecker()
+	// Without a volume topology checker all nodes pass.
+	assert.Nil(t, predicateChecker.CheckVolumeTopology(pod, node))
+
+	predicateChecker.SetVolumeTopologyChecker(NewVolumeTopologyChecker(pvcLister, pvLister, classLister))
+	predicateErr := predicateChecker.CheckVolumeTopology(pod, node)
+	if assert.NotNil(t, predicateErr) {
+		assert.Equal(t, volumeTopologyPredicateName, predicateErr.PredicateName())
+	}
+}
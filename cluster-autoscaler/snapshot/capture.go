@@ -0,0 +1,125 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package snapshot
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider"
+	"k8s.io/autoscaler/cluster-autoscaler/config"
+	kube_util "k8s.io/autoscaler/cluster-autoscaler/utils/kubernetes"
+	"k8s.io/klog"
+)
+
+// Capture reads the current state of the cluster from the listers and the
+// cloud provider. The cloud provider is refreshed first, the same way it is
+// at the beginning of each autoscaling loop.
+func Capture(options config.AutoscalingOptions, listers kube_util.ListerRegistry,
+	provider cloudprovider.CloudProvider, now time.Time) (*Snapshot, error) {
+	if err := provider.Refresh(); err != nil {
+		return nil, fmt.Errorf("failed to refresh cloud provider: %v", err)
+	}
+
+	var err error
+	snapshot := &Snapshot{Version: CurrentVersion, Time: now, Options: options}
+	if snapshot.Nodes, err = listers.AllNodeLister().List(); err != nil {
+		return nil, fmt.Errorf("failed to list nodes: %v", err)
+	}
+	if snapshot.ScheduledPods, err = listers.ScheduledPodLister().List(); err != nil {
+		return nil, fmt.Errorf("failed to list scheduled pods: %v", err)
+	}
+	if snapshot.UnschedulablePods, err = listers.UnschedulablePodLister().List(); err != nil {
+		return nil, fmt.Errorf("failed to list unschedulable pods: %v", err)
+	}
+	if snapshot.PodDisruptionBudgets, err = listers.PodDisruptionBudgetLister().List(); err != nil {
+		return nil, fmt.Errorf("failed to list pod disruption budgets: %v", err)
+	}
+	if snapshot.DaemonSets, err = listers.DaemonSetLister().List(labels.Everything()); err != nil {
+		return nil, fmt.Errorf("failed to list daemon sets: %v", err)
+	}
+	if snapshot.ReplicationControllers, err = listers.ReplicationControllerLister().List(labels.Everything()); err != nil {
+		return nil, fmt.Errorf("failed to list replication controllers: %v", err)
+	}
+	if snapshot.Jobs, err = listers.JobLister().List(labels.Everything()); err != nil {
+		return nil, fmt.Errorf("failed to list jobs: %v", err)
+	}
+	if snapshot.ReplicaSets, err = listers.ReplicaSetLister().List(labels.Everything()); err != nil {
+		return nil, fmt.Errorf("failed to list replica sets: %v", err)
+	}
+	if snapshot.StatefulSets, err = listers.StatefulSetLister().List(labels.Everything()); err != nil {
+		return nil, fmt.Errorf("failed to list stateful sets: %v", err)
+	}
+
+	nodesInGroups := make(map[string][]string)
+	for _, node := range snapshot.Nodes {
+		nodeGroup, err := provider.NodeGroupForNode(node)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get node group for %s: %v", node.Name, err)
+		}
+		if nodeGroup == nil || reflect.ValueOf(nodeGroup).IsNil() {
+			continue
+		}
+		nodesInGroups[nodeGroup.Id()] = append(nodesInGroups[nodeGroup.Id()], node.Name)
+	}
+
+	defaults := options.NodeGroupDefaults()
+	for _, nodeGroup := range provider.NodeGroups() {
+		targetSize, err := nodeGroup.TargetSize()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get target size of %s: %v", nodeGroup.Id(), err)
+		}
+		captured := NodeGroup{
+			Id:         nodeGroup.Id(),
+			MinSize:    nodeGroup.MinSize(),
+			MaxSize:    nodeGroup.MaxSize(),
+			TargetSize: targetSize,
+			Nodes:      nodesInGroups[nodeGroup.Id()],
+		}
+		if nodeInfo, err := nodeGroup.TemplateNodeInfo(); err == nil {
+			captured.Template = &NodeTemplate{Node: nodeInfo.Node(), Pods: nodeInfo.Pods()}
+		} else if err != cloudprovider.ErrNotImplemented {
+			klog.Warningf("Failed to get template node info for %s: %v", nodeGroup.Id(), err)
+		}
+		if nodeGroupOptions, err := nodeGroup.GetOptions(defaults); err == nil {
+			captured.Options = nodeGroupOptions
+		} else if err != cloudprovider.ErrNotImplemented {
+			return nil, fmt.Errorf("failed to get options of %s: %v", nodeGroup.Id(), err)
+		}
+		snapshot.NodeGroups = append(snapshot.NodeGroups, captured)
+	}
+	// Cloud providers don't guarantee the order of node groups, keep snapshots of the same state equal.
+	sort.Slice(snapshot.NodeGroups, func(i, j int) bool {
+		return snapshot.NodeGroups[i].Id < snapshot.NodeGroups[j].Id
+	})
+
+	resourceLimiter, err := provider.GetResourceLimiter()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get resource limits: %v", err)
+	}
+	if resourceLimiter != nil {
+		snapshot.ResourceLimits = ResourceLimits{Min: make(map[string]int64), Max: make(map[string]int64)}
+		for _, resource := range resourceLimiter.GetResources() {
+			snapshot.ResourceLimits.Min[resource] = resourceLimiter.GetMin(resource)
+			snapshot.ResourceLimits.Max[resource] = resourceLimiter.GetMax(resource)
+		}
+	}
+	return snapshot, nil
+}
@@ -0,0 +1,174 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package snapshot
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider"
+	testprovider "k8s.io/autoscaler/cluster-autoscaler/cloudprovider/test"
+	"k8s.io/autoscaler/cluster-autoscaler/clusterstate/utils"
+	"k8s.io/autoscaler/cluster-autoscaler/context"
+	"k8s.io/autoscaler/cluster-autoscaler/core"
+	"k8s.io/autoscaler/cluster-autoscaler/dryrun"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator"
+	kube_util "k8s.io/autoscaler/cluster-autoscaler/utils/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/klog"
+	schedulernodeinfo "k8s.io/kubernetes/pkg/scheduler/nodeinfo"
+)
+
+// Replay runs the given number of autoscaling loops, interval apart, against
+// the captured state and writes a dry run report of each loop to out. The
+// autoscaler runs in dry run mode, so every loop sees the same cluster state,
+// and the first loop runs at the time the snapshot was captured, so replays
+// of a snapshot are deterministic. Processors are set up the same way as in
+// the autoscaler that captured the snapshot, from the captured options.
+// As for a freshly started autoscaler, scale-down is in cooldown for the
+// first ScaleDownDelayAfterAdd and nodes have to stay unneeded for
+// ScaleDownUnneededTime before they are removed.
+func Replay(snapshot *Snapshot, loops int, interval time.Duration, out io.Writer) error {
+	options := snapshot.Options
+	options.DryRun = true
+	options.WriteStatusConfigMap = false
+
+	listers, err := snapshot.listerRegistry()
+	if err != nil {
+		return err
+	}
+	fakeClient := fake.NewSimpleClientset()
+	eventRecorder := simulator.NoOpEventRecorder{}
+	logRecorder, err := utils.NewStatusMapRecorder(fakeClient, options.ConfigNamespace, eventRecorder, false)
+	if err != nil {
+		return err
+	}
+	stop := make(chan struct{})
+	defer close(stop)
+	predicateChecker, err := simulator.NewPredicateChecker(fakeClient, stop)
+	if err != nil {
+		return err
+	}
+
+	recorder := dryrun.NewRecorder()
+	reporter := dryrun.NewReporter(recorder, out)
+	processors := core.NewProcessors(options)
+	processors.ScaleUpStatusProcessor, processors.ScaleDownStatusProcessor, processors.AutoscalingStatusProcessor =
+		reporter.WrapProcessors(processors.ScaleUpStatusProcessor, processors.ScaleDownStatusProcessor, processors.AutoscalingStatusProcessor)
+
+	autoscaler, typedErr := core.NewAutoscaler(core.AutoscalerOptions{
+		AutoscalingOptions: options,
+		KubeClient:         fakeClient,
+		EventsKubeClient:   fakeClient,
+		AutoscalingKubeClients: &context.AutoscalingKubeClients{
+			ListerRegistry: listers,
+			ClientSet:      fakeClient,
+			Recorder:       eventRecorder,
+			LogRecorder:    logRecorder,
+		},
+		CloudProvider:    dryrun.NewCloudProvider(snapshot.cloudProvider(), recorder),
+		PredicateChecker: predicateChecker,
+		Processors:       processors,
+	})
+	if typedErr != nil {
+		return typedErr
+	}
+
+	for i := 0; i < loops; i++ {
+		if typedErr := autoscaler.RunOnce(snapshot.Time.Add(time.Duration(i) * interval)); typedErr != nil {
+			klog.Warningf("Replayed loop %d failed: %v", i, typedErr)
+		}
+	}
+	return nil
+}
+
+// cloudProvider builds a TestCloudProvider returning the captured node groups.
+func (s *Snapshot) cloudProvider() *testprovider.TestCloudProvider {
+	templates := make(map[string]*schedulernodeinfo.NodeInfo)
+	for _, nodeGroup := range s.NodeGroups {
+		if nodeGroup.Template != nil {
+			nodeInfo := schedulernodeinfo.NewNodeInfo(nodeGroup.Template.Pods...)
+			nodeInfo.SetNode(nodeGroup.Template.Node)
+			templates[nodeGroup.Id] = nodeInfo
+		}
+	}
+	if len(templates) == 0 {
+		// Makes TemplateNodeInfo return ErrNotImplemented.
+		templates = nil
+	}
+
+	provider := testprovider.NewTestAutoprovisioningCloudProvider(nil, nil, nil, nil, nil, templates)
+	for _, nodeGroup := range s.NodeGroups {
+		testNodeGroup := provider.BuildNodeGroup(nodeGroup.Id, nodeGroup.MinSize, nodeGroup.MaxSize, nodeGroup.TargetSize, false, "")
+		if nodeGroup.Options != nil {
+			testNodeGroup.SetOptions(nodeGroup.Options)
+		}
+		provider.InsertNodeGroup(testNodeGroup)
+		for _, name := range nodeGroup.Nodes {
+			provider.AddNode(nodeGroup.Id, &apiv1.Node{ObjectMeta: metav1.ObjectMeta{Name: name}})
+		}
+	}
+	provider.SetResourceLimiter(cloudprovider.NewResourceLimiter(s.ResourceLimits.Min, s.ResourceLimits.Max))
+	return provider
+}
+
+// listerRegistry builds listers returning the captured objects.
+func (s *Snapshot) listerRegistry() (kube_util.ListerRegistry, error) {
+	// TestCloudProvider identifies instances by node names, so provider ids
+	// have to be rewritten for the nodes to be registered.
+	allNodes := make([]*apiv1.Node, 0, len(s.Nodes))
+	readyNodes := make([]*apiv1.Node, 0, len(s.Nodes))
+	for _, node := range s.Nodes {
+		node = node.DeepCopy()
+		node.Spec.ProviderID = node.Name
+		allNodes = append(allNodes, node)
+		if kube_util.IsNodeReadyAndSchedulable(node) {
+			readyNodes = append(readyNodes, node)
+		}
+	}
+
+	daemonSetLister, err := kube_util.NewTestDaemonSetLister(s.DaemonSets)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build daemon set lister: %v", err)
+	}
+	replicationControllerLister, err := kube_util.NewTestReplicationControllerLister(s.ReplicationControllers)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build replication controller lister: %v", err)
+	}
+	jobLister, err := kube_util.NewTestJobLister(s.Jobs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build job lister: %v", err)
+	}
+	replicaSetLister, err := kube_util.NewTestReplicaSetLister(s.ReplicaSets)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build replica set lister: %v", err)
+	}
+	statefulSetLister, err := kube_util.NewTestStatefulSetLister(s.StatefulSets)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build stateful set lister: %v", err)
+	}
+	return kube_util.NewListerRegistry(
+		kube_util.NewTestNodeLister(allNodes),
+		kube_util.NewTestNodeLister(readyNodes),
+		kube_util.NewTestPodLister(s.ScheduledPods),
+		kube_util.NewTestPodLister(s.UnschedulablePods),
+		kube_util.NewTestPodDisruptionBudgetLister(s.PodDisruptionBudgets),
+		daemonSetLister, replicationControllerLister, jobLister, replicaSetLister, statefulSetLister), nil
+}
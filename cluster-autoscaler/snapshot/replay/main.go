@@ -0,0 +1,57 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// replay runs Cluster Autoscaler against a snapshot captured with the
+// --snapshot-file flag and prints the decisions it makes, one JSON dry run
+// report per autoscaling loop.
+package main
+
+import (
+	"flag"
+	"os"
+	"time"
+
+	"k8s.io/autoscaler/cluster-autoscaler/snapshot"
+	"k8s.io/klog"
+)
+
+var (
+	snapshotFile = flag.String("snapshot", "", "The snapshot file to replay.")
+	loops        = flag.Int("loops", 1, "Number of autoscaling loops to run.")
+	loopInterval = flag.Duration("loop-interval", 10*time.Second, "Simulated time between autoscaling loops.")
+)
+
+func main() {
+	klog.InitFlags(nil)
+	flag.Parse()
+
+	if *snapshotFile == "" {
+		klog.Fatalf("--snapshot is required")
+	}
+	file, err := os.Open(*snapshotFile)
+	if err != nil {
+		klog.Fatalf("Failed to open snapshot: %v", err)
+	}
+	captured, err := snapshot.Read(file)
+	file.Close()
+	if err != nil {
+		klog.Fatalf("Failed to read snapshot: %v", err)
+	}
+	klog.V(1).Infof("Replaying snapshot captured at %v", captured.Time)
+	if err := snapshot.Replay(captured, *loops, *loopInterval, os.Stdout); err != nil {
+		klog.Fatalf("Failed to replay snapshot: %v", err)
+	}
+}
@@ -0,0 +1,110 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package snapshot captures the state consumed by a single autoscaling loop
+// into a file and replays it offline. Snapshots make surprising autoscaling
+// decisions reproducible.
+package snapshot
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	apiv1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1beta1"
+	"k8s.io/autoscaler/cluster-autoscaler/config"
+)
+
+// CurrentVersion is the version of the snapshot format written by this
+// version of Cluster Autoscaler. It has to be bumped on incompatible changes
+// of the Snapshot structure.
+const CurrentVersion = 1
+
+// Snapshot is everything a single autoscaling loop reads from the cluster and
+// the cloud provider.
+type Snapshot struct {
+	// Version of the snapshot format.
+	Version int `json:"version"`
+	// Time at which the snapshot was captured.
+	Time time.Time `json:"time"`
+	// Options the autoscaler was running with.
+	Options config.AutoscalingOptions `json:"options"`
+
+	Nodes                  []*apiv1.Node                   `json:"nodes"`
+	ScheduledPods          []*apiv1.Pod                    `json:"scheduledPods"`
+	UnschedulablePods      []*apiv1.Pod                    `json:"unschedulablePods"`
+	PodDisruptionBudgets   []*policyv1.PodDisruptionBudget `json:"podDisruptionBudgets,omitempty"`
+	DaemonSets             []*appsv1.DaemonSet             `json:"daemonSets,omitempty"`
+	ReplicationControllers []*apiv1.ReplicationController  `json:"replicationControllers,omitempty"`
+	Jobs                   []*batchv1.Job                  `json:"jobs,omitempty"`
+	ReplicaSets            []*appsv1.ReplicaSet            `json:"replicaSets,omitempty"`
+	StatefulSets           []*appsv1.StatefulSet           `json:"statefulSets,omitempty"`
+
+	// NodeGroups are the node groups returned by the cloud provider.
+	NodeGroups []NodeGroup `json:"nodeGroups"`
+	// ResourceLimits are the cluster wide resource limits of the cloud provider.
+	ResourceLimits ResourceLimits `json:"resourceLimits"`
+}
+
+// NodeGroup is the state of a single node group.
+type NodeGroup struct {
+	Id         string `json:"id"`
+	MinSize    int    `json:"minSize"`
+	MaxSize    int    `json:"maxSize"`
+	TargetSize int    `json:"targetSize"`
+	// Nodes are the names of the nodes that belong to the node group.
+	Nodes []string `json:"nodes,omitempty"`
+	// Template is the result of TemplateNodeInfo, nil if it returned an error.
+	Template *NodeTemplate `json:"template,omitempty"`
+	// Options is the result of GetOptions, nil if the node group uses the defaults.
+	Options *config.NodeGroupAutoscalingOptions `json:"options,omitempty"`
+}
+
+// NodeTemplate is a serializable form of a template NodeInfo.
+type NodeTemplate struct {
+	Node *apiv1.Node  `json:"node"`
+	Pods []*apiv1.Pod `json:"pods,omitempty"`
+}
+
+// ResourceLimits are minimum and maximum amounts of resources in the cluster.
+type ResourceLimits struct {
+	Min map[string]int64 `json:"min,omitempty"`
+	Max map[string]int64 `json:"max,omitempty"`
+}
+
+// Write writes the snapshot in JSON format.
+func Write(w io.Writer, snapshot *Snapshot) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(snapshot)
+}
+
+// Read reads a snapshot written by Write. Snapshots of unsupported versions
+// are rejected.
+func Read(r io.Reader) (*Snapshot, error) {
+	snapshot := &Snapshot{}
+	if err := json.NewDecoder(r).Decode(snapshot); err != nil {
+		return nil, fmt.Errorf("failed to decode snapshot: %v", err)
+	}
+	if snapshot.Version != CurrentVersion {
+		return nil, fmt.Errorf("unsupported snapshot version %d, expected %d", snapshot.Version, CurrentVersion)
+	}
+	return snapshot, nil
+}
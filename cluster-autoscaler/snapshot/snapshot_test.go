@@ -0,0 +1,177 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package snapshot
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	apiv1 "k8s.io/api/core/v1"
+	testprovider "k8s.io/autoscaler/cluster-autoscaler/cloudprovider/test"
+	"k8s.io/autoscaler/cluster-autoscaler/config"
+	"k8s.io/autoscaler/cluster-autoscaler/dryrun"
+	"k8s.io/autoscaler/cluster-autoscaler/estimator"
+	"k8s.io/autoscaler/cluster-autoscaler/expander"
+	kube_util "k8s.io/autoscaler/cluster-autoscaler/utils/kubernetes"
+	. "k8s.io/autoscaler/cluster-autoscaler/utils/test"
+)
+
+func captureTestSnapshot(t *testing.T) *Snapshot {
+	n1 := BuildTestNode("n1", 1000, 1000)
+	n1.Spec.ProviderID = "cloud://n1"
+	SetNodeReadyState(n1, true, time.Now())
+	p1 := BuildTestPod("p1", 600, 100)
+	p1.Spec.NodeName = "n1"
+	p2 := BuildTestPod("p2", 600, 100)
+
+	provider := testprovider.NewTestCloudProvider(nil, nil)
+	provider.AddNodeGroup("ng1", 1, 10, 1)
+	provider.AddNodeGroup("ng2", 0, 5, 0)
+	provider.AddNode("ng1", n1)
+	provider.GetNodeGroup("ng2").(*testprovider.TestNodeGroup).SetOptions(&config.NodeGroupAutoscalingOptions{
+		ScaleDownUtilizationThreshold: 0.2,
+	})
+
+	daemonSetLister, err := kube_util.NewTestDaemonSetLister(nil)
+	assert.NoError(t, err)
+	replicationControllerLister, err := kube_util.NewTestReplicationControllerLister(nil)
+	assert.NoError(t, err)
+	jobLister, err := kube_util.NewTestJobLister(nil)
+	assert.NoError(t, err)
+	replicaSetLister, err := kube_util.NewTestReplicaSetLister(nil)
+	assert.NoError(t, err)
+	statefulSetLister, err := kube_util.NewTestStatefulSetLister(nil)
+	assert.NoError(t, err)
+	listers := kube_util.NewListerRegistry(
+		kube_util.NewTestNodeLister([]*apiv1.Node{n1}),
+		kube_util.NewTestNodeLister([]*apiv1.Node{n1}),
+		kube_util.NewTestPodLister([]*apiv1.Pod{p1}),
+		kube_util.NewTestPodLister([]*apiv1.Pod{p2}),
+		kube_util.NewTestPodDisruptionBudgetLister(nil),
+		daemonSetLister, replicationControllerLister, jobLister, replicaSetLister, statefulSetLister)
+	options := config.AutoscalingOptions{
+		EstimatorName:                       estimator.BinpackingEstimatorName,
+		ExpanderName:                        expander.RandomExpanderName,
+		ScaleDownEnabled:                    true,
+		ScaleDownUtilizationThreshold:       0.5,
+		ScaleDownUnneededTime:               time.Minute,
+		MaxNodesTotal:                       10,
+		MaxCoresTotal:                       10,
+		MaxMemoryTotal:                      100000,
+		MaxNodeProvisionTime:                10 * time.Second,
+		FilterOutSchedulablePodsUsesPacking: true,
+	}
+	snapshot, err := Capture(options, listers, provider, time.Now())
+	assert.NoError(t, err)
+	return snapshot
+}
+
+func TestCaptureAndRead(t *testing.T) {
+	snapshot := captureTestSnapshot(t)
+	assert.Equal(t, CurrentVersion, snapshot.Version)
+	assert.Equal(t, 1, len(snapshot.Nodes))
+	assert.Equal(t, "p1", snapshot.ScheduledPods[0].Name)
+	assert.Equal(t, "p2", snapshot.UnschedulablePods[0].Name)
+	assert.Equal(t, []NodeGroup{
+		{Id: "ng1", MinSize: 1, MaxSize: 10, TargetSize: 1, Nodes: []string{"n1"}},
+		{Id: "ng2", MinSize: 0, MaxSize: 5, TargetSize: 0, Options: &config.NodeGroupAutoscalingOptions{ScaleDownUtilizationThreshold: 0.2}},
+	}, snapshot.NodeGroups)
+
+	var buf bytes.Buffer
+	assert.NoError(t, Write(&buf, snapshot))
+	read, err := Read(&buf)
+	assert.NoError(t, err)
+	assert.Equal(t, snapshot.Options, read.Options)
+	assert.Equal(t, snapshot.NodeGroups, read.NodeGroups)
+	assert.Equal(t, snapshot.Nodes[0].Name, read.Nodes[0].Name)
+	assert.Equal(t, snapshot.Time.Unix(), read.Time.Unix())
+
+	_, err = Read(strings.NewReader(`{"version": 1000}`))
+	assert.Error(t, err)
+}
+
+func TestReplay(t *testing.T) {
+	snapshot := captureTestSnapshot(t)
+
+	var buf bytes.Buffer
+	assert.NoError(t, Replay(snapshot, 2, time.Minute, &buf))
+
+	decoder := json.NewDecoder(&buf)
+	var report dryrun.Report
+	assert.NoError(t, decoder.Decode(&report))
+	assert.NotNil(t, report.ScaleUp)
+	assert.Equal(t, []dryrun.ScaleUpNodeGroup{{NodeGroup: "ng1", CurrentSize: 1, NewSize: 2, MaxSize: 10}}, report.ScaleUp.NodeGroups)
+	assert.Equal(t, []dryrun.CloudProviderAction{{Operation: "IncreaseSize", NodeGroup: "ng1", Delta: 1}}, report.CloudProviderActions)
+	// Loops start at the capture time.
+	assert.True(t, snapshot.Time.Equal(report.Time))
+
+	// The second loop sees the same state.
+	report = dryrun.Report{}
+	assert.NoError(t, decoder.Decode(&report))
+	assert.NotNil(t, report.ScaleUp)
+	assert.True(t, snapshot.Time.Add(time.Minute).Equal(report.Time))
+}
+
+func TestReplayUsesOptionsForProcessors(t *testing.T) {
+	captureTime := time.Date(2019, 6, 1, 12, 0, 0, 0, time.UTC)
+	var nodes []*apiv1.Node
+	for _, id := range []string{"ng1", "ng2"} {
+		node := BuildTestNode(id+"-node", 1000, 1000)
+		node.Labels["nodepool-id"] = id
+		SetNodeReadyState(node, true, captureTime.Add(-time.Hour))
+		nodes = append(nodes, node)
+	}
+	snapshot := &Snapshot{
+		Version: CurrentVersion,
+		Time:    captureTime,
+		Options: config.AutoscalingOptions{
+			EstimatorName:                       estimator.BinpackingEstimatorName,
+			ExpanderName:                        expander.RandomExpanderName,
+			MaxNodesTotal:                       10,
+			MaxCoresTotal:                       10,
+			MaxMemoryTotal:                      100000,
+			MaxNodeProvisionTime:                10 * time.Second,
+			FilterOutSchedulablePodsUsesPacking: true,
+			BalanceSimilarNodeGroups:            true,
+			BalancingIgnoredLabels:              []string{"nodepool-id"},
+		},
+		Nodes: nodes,
+		// Two of the pods fit on existing nodes.
+		UnschedulablePods: []*apiv1.Pod{
+			BuildTestPod("p1", 600, 100), BuildTestPod("p2", 600, 100), BuildTestPod("p3", 600, 100), BuildTestPod("p4", 600, 100),
+		},
+		NodeGroups: []NodeGroup{
+			{Id: "ng1", MinSize: 1, MaxSize: 10, TargetSize: 1, Nodes: []string{"ng1-node"}},
+			{Id: "ng2", MinSize: 1, MaxSize: 10, TargetSize: 1, Nodes: []string{"ng2-node"}},
+		},
+	}
+
+	var buf bytes.Buffer
+	assert.NoError(t, Replay(snapshot, 1, time.Minute, &buf))
+	var report dryrun.Report
+	assert.NoError(t, json.NewDecoder(&buf).Decode(&report))
+	// Node groups only differing in an ignored label are balanced.
+	assert.ElementsMatch(t, []dryrun.CloudProviderAction{
+		{Operation: "IncreaseSize", NodeGroup: "ng1", Delta: 1},
+		{Operation: "IncreaseSize", NodeGroup: "ng2", Delta: 1},
+	}, report.CloudProviderActions)
+}
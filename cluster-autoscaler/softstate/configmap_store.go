@@ -0,0 +1,123 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package softstate
+
+import (
+	"encoding/json"
+	"fmt"
+
+	apiv1 "k8s.io/api/core/v1"
+	kube_errors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kube_client "k8s.io/client-go/kubernetes"
+
+	"k8s.io/klog"
+)
+
+const (
+	// StateConfigMapName is the name of the ConfigMap holding the soft state.
+	StateConfigMapName = "cluster-autoscaler-soft-state"
+	// ConfigMapKey is the key in the ConfigMap under which the state is stored.
+	ConfigMapKey = "state"
+	// MaxConfigMapStateSize is the maximum size of the encoded state saved in the
+	// ConfigMap. It leaves room for the object metadata below the 1MiB size limit
+	// of ConfigMaps.
+	MaxConfigMapStateSize = 1000 * 1024
+)
+
+// ConfigMapStore keeps the state as JSON in a ConfigMap, so it is available
+// to a new leader running on a different machine.
+type ConfigMapStore struct {
+	kubeClient kube_client.Interface
+	namespace  string
+	name       string
+}
+
+// NewConfigMapStore builds a store keeping the state in the given ConfigMap.
+func NewConfigMapStore(kubeClient kube_client.Interface, namespace, name string) *ConfigMapStore {
+	return &ConfigMapStore{
+		kubeClient: kubeClient,
+		namespace:  namespace,
+		name:       name,
+	}
+}
+
+// Save overwrites the state in the ConfigMap, creating it if needed. The usage
+// tracker content grows fastest with the cluster size, so it is left out if the
+// state doesn't fit in the ConfigMap otherwise.
+func (s *ConfigMapStore) Save(state *State) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("failed to encode soft state: %v", err)
+	}
+	if len(data) > MaxConfigMapStateSize && len(state.NodeUsage) > 0 {
+		klog.Warningf("Soft state takes %d bytes, more than %d bytes allowed in ConfigMap %s/%s, saving it without node usage data",
+			len(data), MaxConfigMapStateSize, s.namespace, s.name)
+		withoutUsage := *state
+		withoutUsage.NodeUsage = nil
+		data, err = json.Marshal(&withoutUsage)
+		if err != nil {
+			return fmt.Errorf("failed to encode soft state: %v", err)
+		}
+	}
+	if len(data) > MaxConfigMapStateSize {
+		return fmt.Errorf("soft state takes %d bytes, more than %d bytes allowed in ConfigMap %s/%s",
+			len(data), MaxConfigMapStateSize, s.namespace, s.name)
+	}
+	maps := s.kubeClient.CoreV1().ConfigMaps(s.namespace)
+	configMap, err := maps.Get(s.name, metav1.GetOptions{})
+	if kube_errors.IsNotFound(err) {
+		configMap = &apiv1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: s.namespace,
+				Name:      s.name,
+			},
+			Data: map[string]string{ConfigMapKey: string(data)},
+		}
+		_, err = maps.Create(configMap)
+	} else if err == nil {
+		if configMap.Data == nil {
+			configMap.Data = make(map[string]string)
+		}
+		configMap.Data[ConfigMapKey] = string(data)
+		_, err = maps.Update(configMap)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write soft state ConfigMap %s/%s: %v", s.namespace, s.name, err)
+	}
+	return nil
+}
+
+// Load returns the state from the ConfigMap, or nil if the ConfigMap doesn't exist.
+func (s *ConfigMapStore) Load() (*State, error) {
+	configMap, err := s.kubeClient.CoreV1().ConfigMaps(s.namespace).Get(s.name, metav1.GetOptions{})
+	if kube_errors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read soft state ConfigMap %s/%s: %v", s.namespace, s.name, err)
+	}
+	data, found := configMap.Data[ConfigMapKey]
+	if !found {
+		return nil, nil
+	}
+	state := &State{}
+	if err := json.Unmarshal([]byte(data), state); err != nil {
+		return nil, fmt.Errorf("failed to decode soft state: %v", err)
+	}
+	return state, nil
+}
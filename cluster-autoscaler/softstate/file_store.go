@@ -0,0 +1,78 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package softstate
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// FileStore keeps the state as JSON in a local file. It survives restarts of
+// Cluster Autoscaler, but not a leader change unless the file is on a volume
+// shared by all replicas.
+type FileStore struct {
+	path string
+}
+
+// NewFileStore builds a store keeping the state in the given file.
+func NewFileStore(path string) *FileStore {
+	return &FileStore{path: path}
+}
+
+// Save overwrites the state in the file. The state is written to a temporary
+// file first, so a crash during Save doesn't leave a truncated file behind.
+func (s *FileStore) Save(state *State) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("failed to encode soft state: %v", err)
+	}
+	tmpFile, err := ioutil.TempFile(filepath.Dir(s.path), filepath.Base(s.path)+".tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create soft state file: %v", err)
+	}
+	defer os.Remove(tmpFile.Name())
+	_, err = tmpFile.Write(data)
+	if closeErr := tmpFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write soft state file: %v", err)
+	}
+	if err := os.Rename(tmpFile.Name(), s.path); err != nil {
+		return fmt.Errorf("failed to write soft state file: %v", err)
+	}
+	return nil
+}
+
+// Load returns the state from the file, or nil if the file doesn't exist.
+func (s *FileStore) Load() (*State, error) {
+	data, err := ioutil.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read soft state file: %v", err)
+	}
+	state := &State{}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("failed to decode soft state: %v", err)
+	}
+	return state, nil
+}
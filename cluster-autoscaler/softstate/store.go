@@ -0,0 +1,77 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package softstate persists the in-memory state of Cluster Autoscaler that
+// can't be rebuilt from the cluster, like the times since which nodes are
+// unneeded and node group backoffs. The state is checkpointed periodically and
+// restored on startup, so a restart or a leader change doesn't reset it.
+package softstate
+
+import (
+	"fmt"
+	"time"
+
+	"k8s.io/autoscaler/cluster-autoscaler/clusterstate"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator"
+	kube_client "k8s.io/client-go/kubernetes"
+)
+
+const (
+	// ConfigMapStoreName is the name of the store keeping the state in a ConfigMap.
+	ConfigMapStoreName = "configmap"
+	// FileStoreName is the name of the store keeping the state in a local file.
+	FileStoreName = "file"
+)
+
+// AvailableStores is a list of available soft state stores.
+var AvailableStores = []string{ConfigMapStoreName, FileStoreName}
+
+// State is the soft state of Cluster Autoscaler.
+type State struct {
+	// Timestamp is the time when the state was checkpointed.
+	Timestamp time.Time `json:"timestamp"`
+	// UnneededNodes maps names of unneeded nodes to the times since which they are unneeded.
+	UnneededNodes map[string]time.Time `json:"unneededNodes,omitempty"`
+	// NodeUsage is the content of the scale-down usage tracker.
+	NodeUsage map[string]simulator.UsageRecordState `json:"nodeUsage,omitempty"`
+	// ScaleDownDeletions maps node group ids to the times of node deletions counted against scale-down budgets.
+	ScaleDownDeletions map[string][]time.Time `json:"scaleDownDeletions,omitempty"`
+	// ClusterState holds scale-up requests and node group backoffs.
+	ClusterState clusterstate.SoftState `json:"clusterState"`
+}
+
+// Store saves and loads the soft state.
+type Store interface {
+	// Save overwrites the saved state.
+	Save(state *State) error
+	// Load returns the saved state, or nil if nothing was saved yet.
+	Load() (*State, error)
+}
+
+// NewStore builds a store of the given name. The ConfigMap store keeps the state
+// in the given namespace, the file store in the given file.
+func NewStore(name string, kubeClient kube_client.Interface, namespace string, path string) (Store, error) {
+	switch name {
+	case ConfigMapStoreName:
+		return NewConfigMapStore(kubeClient, namespace, StateConfigMapName), nil
+	case FileStoreName:
+		if path == "" {
+			return nil, fmt.Errorf("file soft state store requires a file path")
+		}
+		return NewFileStore(path), nil
+	}
+	return nil, fmt.Errorf("unknown soft state store %s", name)
+}
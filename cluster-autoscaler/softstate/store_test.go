@@ -0,0 +1,163 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package softstate
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/autoscaler/cluster-autoscaler/clusterstate"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator"
+	"k8s.io/autoscaler/cluster-autoscaler/utils/backoff"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func testState(timestamp time.Time) *State {
+	return &State{
+		Timestamp: timestamp,
+		UnneededNodes: map[string]time.Time{
+			"n1": timestamp.Add(-5 * time.Minute),
+		},
+		NodeUsage: map[string]simulator.UsageRecordState{
+			"n1": {Using: map[string]time.Time{"n2": timestamp}},
+			"n2": {UsedBy: map[string]time.Time{"n1": timestamp}},
+		},
+		ClusterState: clusterstate.SoftState{
+			ScaleUpRequests: map[string]clusterstate.ScaleUpRequestState{
+				"ng1": {Time: timestamp, ExpectedAddTime: timestamp.Add(15 * time.Minute), Increase: 2},
+			},
+			Backoff: map[string]backoff.Entry{
+				"ng2": {Duration: 5 * time.Minute, BackoffUntil: timestamp.Add(5 * time.Minute), LastFailedExecution: timestamp},
+			},
+		},
+	}
+}
+
+func TestConfigMapStore(t *testing.T) {
+	timestamp := time.Date(2019, 6, 3, 10, 0, 0, 0, time.UTC)
+	client := fake.NewSimpleClientset()
+	store := NewConfigMapStore(client, "kube-system", StateConfigMapName)
+
+	state, err := store.Load()
+	assert.NoError(t, err)
+	assert.Nil(t, state)
+
+	assert.NoError(t, store.Save(testState(timestamp)))
+	state, err = store.Load()
+	assert.NoError(t, err)
+	assert.Equal(t, testState(timestamp), state)
+
+	// Saving again updates the existing ConfigMap.
+	later := timestamp.Add(time.Minute)
+	assert.NoError(t, store.Save(testState(later)))
+	state, err = store.Load()
+	assert.NoError(t, err)
+	assert.Equal(t, testState(later), state)
+}
+
+func TestConfigMapStoreSizeLimit(t *testing.T) {
+	timestamp := time.Date(2019, 6, 3, 10, 0, 0, 0, time.UTC)
+	client := fake.NewSimpleClientset()
+	store := NewConfigMapStore(client, "kube-system", StateConfigMapName)
+
+	// Usage data of a large cluster doesn't fit in the ConfigMap.
+	state := testState(timestamp)
+	for i := 0; i < 2000; i++ {
+		using := make(map[string]time.Time)
+		for j := 0; j < 20; j++ {
+			using[fmt.Sprintf("node-%d", (i+j+1)%2000)] = timestamp
+		}
+		state.NodeUsage[fmt.Sprintf("node-%d", i)] = simulator.UsageRecordState{Using: using}
+	}
+	assert.NoError(t, store.Save(state))
+	configMap, err := client.CoreV1().ConfigMaps("kube-system").Get(StateConfigMapName, metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.True(t, len(configMap.Data[ConfigMapKey]) <= MaxConfigMapStateSize)
+
+	// The rest of the state is kept.
+	expected := testState(timestamp)
+	expected.NodeUsage = nil
+	state, err = store.Load()
+	assert.NoError(t, err)
+	assert.Equal(t, expected, state)
+
+	// State that doesn't fit without usage data isn't saved.
+	state = testState(timestamp.Add(time.Minute))
+	for i := 0; i < 40000; i++ {
+		state.UnneededNodes[fmt.Sprintf("node-%d", i)] = timestamp
+	}
+	assert.Error(t, store.Save(state))
+	state, err = store.Load()
+	assert.NoError(t, err)
+	assert.Equal(t, expected, state)
+}
+
+func TestConfigMapStoreInvalidData(t *testing.T) {
+	client := fake.NewSimpleClientset(&apiv1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "kube-system", Name: StateConfigMapName},
+		Data:       map[string]string{ConfigMapKey: "{"},
+	})
+	store := NewConfigMapStore(client, "kube-system", StateConfigMapName)
+	_, err := store.Load()
+	assert.Error(t, err)
+}
+
+func TestFileStore(t *testing.T) {
+	timestamp := time.Date(2019, 6, 3, 10, 0, 0, 0, time.UTC)
+	dir, err := ioutil.TempDir("", "softstate")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	store := NewFileStore(filepath.Join(dir, "state.json"))
+
+	state, err := store.Load()
+	assert.NoError(t, err)
+	assert.Nil(t, state)
+
+	assert.NoError(t, store.Save(testState(timestamp)))
+	later := timestamp.Add(time.Minute)
+	assert.NoError(t, store.Save(testState(later)))
+	state, err = store.Load()
+	assert.NoError(t, err)
+	assert.Equal(t, testState(later), state)
+
+	// Only the state file is left in the directory.
+	files, err := ioutil.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(files))
+}
+
+func TestNewStore(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	store, err := NewStore(ConfigMapStoreName, client, "kube-system", "")
+	assert.NoError(t, err)
+	assert.IsType(t, &ConfigMapStore{}, store)
+	store, err = NewStore(FileStoreName, client, "kube-system", "/tmp/state.json")
+	assert.NoError(t, err)
+	assert.IsType(t, &FileStore{}, store)
+	_, err = NewStore(FileStoreName, client, "kube-system", "")
+	assert.Error(t, err)
+	_, err = NewStore("crd", client, "kube-system", "")
+	assert.Error(t, err)
+}
@@ -29,8 +29,24 @@ type Backoff interface {
 	Backoff(nodeGroup cloudprovider.NodeGroup, nodeInfo *schedulernodeinfo.NodeInfo, errorClass cloudprovider.InstanceErrorClass, errorCode string, currentTime time.Time) time.Time
 	// IsBackedOff returns true if execution is backed off for the given node group.
 	IsBackedOff(nodeGroup cloudprovider.NodeGroup, nodeInfo *schedulernodeinfo.NodeInfo, currentTime time.Time) bool
+	// GetEntry returns the backoff data of the given node group, if there is any.
+	GetEntry(nodeGroup cloudprovider.NodeGroup, nodeInfo *schedulernodeinfo.NodeInfo) (Entry, bool)
 	// RemoveBackoff removes backoff data for the given node group.
 	RemoveBackoff(nodeGroup cloudprovider.NodeGroup, nodeInfo *schedulernodeinfo.NodeInfo)
 	// RemoveStaleBackoffData removes stale backoff data.
 	RemoveStaleBackoffData(currentTime time.Time)
+	// GetEntries returns the backoff data of all node groups, keyed by node group key.
+	GetEntries() map[string]Entry
+	// RestoreEntries adds backoff data previously returned by GetEntries. Stale entries are skipped.
+	RestoreEntries(entries map[string]Entry, currentTime time.Time)
+}
+
+// Entry is the backoff data of a single node group, kept across restarts.
+type Entry struct {
+	// Duration is the length of the last backoff.
+	Duration time.Duration `json:"duration"`
+	// BackoffUntil is the time when the node group stops being backed off.
+	BackoffUntil time.Time `json:"backoffUntil"`
+	// LastFailedExecution is the time of the last failure that caused a backoff.
+	LastFailedExecution time.Time `json:"lastFailedExecution"`
 }
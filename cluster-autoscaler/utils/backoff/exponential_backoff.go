@@ -95,6 +95,19 @@ func (b *exponentialBackoff) IsBackedOff(nodeGroup cloudprovider.NodeGroup, node
 	return found && backoffInfo.backoffUntil.After(currentTime)
 }
 
+// GetEntry returns the backoff data of the given node group, if there is any.
+func (b *exponentialBackoff) GetEntry(nodeGroup cloudprovider.NodeGroup, nodeInfo *schedulernodeinfo.NodeInfo) (Entry, bool) {
+	backoffInfo, found := b.backoffInfo[b.nodeGroupKey(nodeGroup)]
+	if !found {
+		return Entry{}, false
+	}
+	return Entry{
+		Duration:            backoffInfo.duration,
+		BackoffUntil:        backoffInfo.backoffUntil,
+		LastFailedExecution: backoffInfo.lastFailedExecution,
+	}, true
+}
+
 // RemoveBackoff removes backoff data for the given node group.
 func (b *exponentialBackoff) RemoveBackoff(nodeGroup cloudprovider.NodeGroup, nodeInfo *schedulernodeinfo.NodeInfo) {
 	delete(b.backoffInfo, b.nodeGroupKey(nodeGroup))
@@ -108,3 +121,31 @@ func (b *exponentialBackoff) RemoveStaleBackoffData(currentTime time.Time) {
 		}
 	}
 }
+
+// GetEntries returns the backoff data of all node groups, keyed by node group key.
+func (b *exponentialBackoff) GetEntries() map[string]Entry {
+	entries := make(map[string]Entry, len(b.backoffInfo))
+	for key, backoffInfo := range b.backoffInfo {
+		entries[key] = Entry{
+			Duration:            backoffInfo.duration,
+			BackoffUntil:        backoffInfo.backoffUntil,
+			LastFailedExecution: backoffInfo.lastFailedExecution,
+		}
+	}
+	return entries
+}
+
+// RestoreEntries adds backoff data previously returned by GetEntries. Entries that
+// would be removed by RemoveStaleBackoffData are skipped.
+func (b *exponentialBackoff) RestoreEntries(entries map[string]Entry, currentTime time.Time) {
+	for key, entry := range entries {
+		if entry.LastFailedExecution.Add(b.backoffResetTimeout).Before(currentTime) {
+			continue
+		}
+		b.backoffInfo[key] = exponentialBackoffInfo{
+			duration:            entry.Duration,
+			backoffUntil:        entry.BackoffUntil,
+			lastFailedExecution: entry.LastFailedExecution,
+		}
+	}
+}
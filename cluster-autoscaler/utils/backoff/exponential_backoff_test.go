@@ -69,6 +69,17 @@ func TestRemoveBackoff(t *testing.T) {
 	assert.False(t, backoff.IsBackedOff(nodeGroup1, nil, startTime))
 }
 
+func TestGetEntry(t *testing.T) {
+	backoff := NewIdBasedExponentialBackoff(1*time.Minute, 3*time.Minute, 3*time.Hour)
+	startTime := time.Now()
+	_, found := backoff.GetEntry(nodeGroup1, nil)
+	assert.False(t, found)
+	backoff.Backoff(nodeGroup1, nil, cloudprovider.OtherErrorClass, "", startTime)
+	entry, found := backoff.GetEntry(nodeGroup1, nil)
+	assert.True(t, found)
+	assert.Equal(t, Entry{Duration: time.Minute, BackoffUntil: startTime.Add(time.Minute), LastFailedExecution: startTime}, entry)
+}
+
 func TestResetStaleBackoffData(t *testing.T) {
 	backoff := NewIdBasedExponentialBackoff(1*time.Minute, 3*time.Minute, 3*time.Hour)
 	startTime := time.Now()
@@ -81,3 +92,25 @@ func TestResetStaleBackoffData(t *testing.T) {
 	backoff.RemoveStaleBackoffData(startTime.Add(5 * time.Hour))
 	assert.Equal(t, 0, len(backoff.(*exponentialBackoff).backoffInfo))
 }
+
+func TestRestoreEntries(t *testing.T) {
+	backoff := NewIdBasedExponentialBackoff(1*time.Minute, 10*time.Minute, 3*time.Hour)
+	startTime := time.Now()
+	backoff.Backoff(nodeGroup1, nil, cloudprovider.OtherErrorClass, "", startTime)
+	backoff.Backoff(nodeGroup1, nil, cloudprovider.OtherErrorClass, "", startTime.Add(2*time.Minute))
+	entries := backoff.GetEntries()
+	assert.Equal(t, 1, len(entries))
+	assert.Equal(t, 2*time.Minute, entries["id1"].Duration)
+
+	restored := NewIdBasedExponentialBackoff(1*time.Minute, 10*time.Minute, 3*time.Hour)
+	restored.RestoreEntries(entries, startTime.Add(3*time.Minute))
+	assert.True(t, restored.IsBackedOff(nodeGroup1, nil, startTime.Add(3*time.Minute)))
+	assert.False(t, restored.IsBackedOff(nodeGroup2, nil, startTime.Add(3*time.Minute)))
+	// The backoff duration keeps growing after the restore.
+	restored.Backoff(nodeGroup1, nil, cloudprovider.OtherErrorClass, "", startTime.Add(5*time.Minute))
+	assert.True(t, restored.IsBackedOff(nodeGroup1, nil, startTime.Add(8*time.Minute)))
+
+	stale := NewIdBasedExponentialBackoff(1*time.Minute, 10*time.Minute, 3*time.Hour)
+	stale.RestoreEntries(entries, startTime.Add(4*time.Hour))
+	assert.Equal(t, 0, len(stale.GetEntries()))
+}
@@ -0,0 +1,87 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubernetes
+
+import (
+	"fmt"
+
+	apiv1 "k8s.io/api/core/v1"
+	kube_errors "k8s.io/apimachinery/pkg/api/errors"
+	v1lister "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/klog"
+)
+
+// ConfigMapLoader reads configuration kept under a key of a ConfigMap. Invalid
+// configuration is reported with a warning event on the ConfigMap.
+type ConfigMapLoader struct {
+	configMapLister v1lister.ConfigMapNamespaceLister
+	logRecorder     record.EventRecorder
+	name            string
+	key             string
+	// description names the configuration in logs and events, e.g. "maintenance windows".
+	description string
+	// invalidReason is the reason of the warning event recorded for invalid configuration.
+	invalidReason string
+}
+
+// NewConfigMapLoader creates a ConfigMapLoader reading the key of the named ConfigMap.
+func NewConfigMapLoader(configMapLister v1lister.ConfigMapNamespaceLister, logRecorder record.EventRecorder,
+	name, key, description, invalidReason string) *ConfigMapLoader {
+	return &ConfigMapLoader{
+		configMapLister: configMapLister,
+		logRecorder:     logRecorder,
+		name:            name,
+		key:             key,
+		description:     description,
+		invalidReason:   invalidReason,
+	}
+}
+
+// Load gets the ConfigMap and passes the configuration to parse. If the ConfigMap
+// doesn't exist, parse isn't called and no error is returned. An error is returned
+// if the ConfigMap can't be read, doesn't contain the key or parse fails, in which
+// case the previously loaded configuration should be kept.
+func (l *ConfigMapLoader) Load(parse func(config string) error) error {
+	cm, err := l.configMapLister.Get(l.name)
+	if kube_errors.IsNotFound(err) {
+		klog.V(4).Infof("Config map %s not found, no %s configured", l.name, l.description)
+		return nil
+	}
+	if err != nil {
+		klog.Errorf("Failed to get %s config map %s: %v", l.description, l.name, err)
+		return err
+	}
+
+	config, found := cm.Data[l.key]
+	if !found {
+		err := fmt.Errorf("Wrong configmap for %s, doesn't contain %s key. Ignoring update.", l.description, l.key)
+		l.logConfigWarning(cm, err.Error())
+		return err
+	}
+	if err := parse(config); err != nil {
+		err = fmt.Errorf("Wrong configuration for %s: %v. Ignoring update.", l.description, err)
+		l.logConfigWarning(cm, err.Error())
+		return err
+	}
+	return nil
+}
+
+func (l *ConfigMapLoader) logConfigWarning(cm *apiv1.ConfigMap, msg string) {
+	l.logRecorder.Event(cm, apiv1.EventTypeWarning, l.invalidReason, msg)
+	klog.Warning(msg)
+}
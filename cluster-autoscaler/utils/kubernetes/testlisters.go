@@ -22,9 +22,13 @@ import (
 	appsv1 "k8s.io/api/apps/v1"
 	batchv1 "k8s.io/api/batch/v1"
 	apiv1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1beta1"
+	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
 	v1appslister "k8s.io/client-go/listers/apps/v1"
 	v1batchlister "k8s.io/client-go/listers/batch/v1"
 	v1lister "k8s.io/client-go/listers/core/v1"
+	v1storagelister "k8s.io/client-go/listers/storage/v1"
 	"k8s.io/client-go/tools/cache"
 )
 
@@ -43,6 +47,46 @@ func NewTestPodLister(pods []*apiv1.Pod) PodLister {
 	return TestPodLister{pods: pods}
 }
 
+// TestNodeLister is used in tests involving listers
+type TestNodeLister struct {
+	nodes []*apiv1.Node
+}
+
+// List returns all nodes in test lister.
+func (lister TestNodeLister) List() ([]*apiv1.Node, error) {
+	return lister.nodes, nil
+}
+
+// Get returns node from test lister.
+func (lister TestNodeLister) Get(name string) (*apiv1.Node, error) {
+	for _, node := range lister.nodes {
+		if node.Name == name {
+			return node, nil
+		}
+	}
+	return nil, errors.NewNotFound(apiv1.Resource("node"), name)
+}
+
+// NewTestNodeLister returns a lister that returns provided nodes
+func NewTestNodeLister(nodes []*apiv1.Node) NodeLister {
+	return TestNodeLister{nodes: nodes}
+}
+
+// TestPodDisruptionBudgetLister is used in tests involving listers
+type TestPodDisruptionBudgetLister struct {
+	pdbs []*policyv1.PodDisruptionBudget
+}
+
+// List returns all pdbs in test lister.
+func (lister TestPodDisruptionBudgetLister) List() ([]*policyv1.PodDisruptionBudget, error) {
+	return lister.pdbs, nil
+}
+
+// NewTestPodDisruptionBudgetLister returns a lister that returns provided pdbs
+func NewTestPodDisruptionBudgetLister(pdbs []*policyv1.PodDisruptionBudget) PodDisruptionBudgetLister {
+	return TestPodDisruptionBudgetLister{pdbs: pdbs}
+}
+
 // NewTestDaemonSetLister returns a lister that returns provided DaemonSets
 func NewTestDaemonSetLister(dss []*appsv1.DaemonSet) (v1appslister.DaemonSetLister, error) {
 	store := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
@@ -114,3 +158,39 @@ func NewTestConfigMapLister(cms []*apiv1.ConfigMap) (v1lister.ConfigMapLister, e
 	}
 	return v1lister.NewConfigMapLister(store), nil
 }
+
+// NewTestPersistentVolumeClaimLister returns a lister that returns provided PersistentVolumeClaims
+func NewTestPersistentVolumeClaimLister(pvcs []*apiv1.PersistentVolumeClaim) (v1lister.PersistentVolumeClaimLister, error) {
+	store := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	for _, pvc := range pvcs {
+		err := store.Add(pvc)
+		if err != nil {
+			return nil, fmt.Errorf("Error adding object to cache: %v", err)
+		}
+	}
+	return v1lister.NewPersistentVolumeClaimLister(store), nil
+}
+
+// NewTestPersistentVolumeLister returns a lister that returns provided PersistentVolumes
+func NewTestPersistentVolumeLister(pvs []*apiv1.PersistentVolume) (v1lister.PersistentVolumeLister, error) {
+	store := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	for _, pv := range pvs {
+		err := store.Add(pv)
+		if err != nil {
+			return nil, fmt.Errorf("Error adding object to cache: %v", err)
+		}
+	}
+	return v1lister.NewPersistentVolumeLister(store), nil
+}
+
+// NewTestStorageClassLister returns a lister that returns provided StorageClasses
+func NewTestStorageClassLister(classes []*storagev1.StorageClass) (v1storagelister.StorageClassLister, error) {
+	store := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	for _, class := range classes {
+		err := store.Add(class)
+		if err != nil {
+			return nil, fmt.Errorf("Error adding object to cache: %v", err)
+		}
+	}
+	return v1storagelister.NewStorageClassLister(store), nil
+}
@@ -0,0 +1,154 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package schedule implements cron schedules of capacity profiles and
+// maintenance windows.
+package schedule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule is a parsed cron expression with five fields: minute, hour, day of
+// month, month and day of week. Each field is either "*" or a comma separated
+// list of values and ranges ("1-5"), optionally with a step ("*/15", "0-30/10").
+// Days of week are numbered from 0 (Sunday) to 6, 7 is accepted as Sunday too.
+// As in cron, if both day of month and day of week are restricted, a time
+// matches when either of them matches.
+type Schedule struct {
+	minutes     uint64
+	hours       uint64
+	daysOfMonth uint64
+	months      uint64
+	daysOfWeek  uint64
+	// Whether day of month or day of week fields were "*".
+	anyDayOfMonth bool
+	anyDayOfWeek  bool
+}
+
+type fieldBounds struct {
+	name     string
+	min, max int
+}
+
+var (
+	minuteBounds     = fieldBounds{"minute", 0, 59}
+	hourBounds       = fieldBounds{"hour", 0, 23}
+	dayOfMonthBounds = fieldBounds{"day of month", 1, 31}
+	monthBounds      = fieldBounds{"month", 1, 12}
+	dayOfWeekBounds  = fieldBounds{"day of week", 0, 7}
+)
+
+// Parse parses a five field cron expression.
+func Parse(spec string) (*Schedule, error) {
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("expected 5 fields in schedule %q, got %d", spec, len(fields))
+	}
+	schedule := &Schedule{
+		anyDayOfMonth: fields[2] == "*",
+		anyDayOfWeek:  fields[4] == "*",
+	}
+	var err error
+	if schedule.minutes, err = parseField(fields[0], minuteBounds); err != nil {
+		return nil, err
+	}
+	if schedule.hours, err = parseField(fields[1], hourBounds); err != nil {
+		return nil, err
+	}
+	if schedule.daysOfMonth, err = parseField(fields[2], dayOfMonthBounds); err != nil {
+		return nil, err
+	}
+	if schedule.months, err = parseField(fields[3], monthBounds); err != nil {
+		return nil, err
+	}
+	if schedule.daysOfWeek, err = parseField(fields[4], dayOfWeekBounds); err != nil {
+		return nil, err
+	}
+	// Sunday can be given as both 0 and 7.
+	if schedule.daysOfWeek&(1<<7) != 0 {
+		schedule.daysOfWeek |= 1
+	}
+	return schedule, nil
+}
+
+// parseField returns a bitmask with bits set for all values matched by the field.
+func parseField(field string, bounds fieldBounds) (uint64, error) {
+	var result uint64
+	for _, part := range strings.Split(field, ",") {
+		rangePart, step := part, 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			var err error
+			rangePart = part[:i]
+			if step, err = strconv.Atoi(part[i+1:]); err != nil || step <= 0 {
+				return 0, fmt.Errorf("invalid step in %s field %q", bounds.name, field)
+			}
+		}
+		start, end := bounds.min, bounds.max
+		if rangePart != "*" {
+			var err error
+			values := strings.SplitN(rangePart, "-", 2)
+			if start, err = strconv.Atoi(values[0]); err != nil {
+				return 0, fmt.Errorf("invalid value in %s field %q", bounds.name, field)
+			}
+			end = start
+			if len(values) == 2 {
+				if end, err = strconv.Atoi(values[1]); err != nil {
+					return 0, fmt.Errorf("invalid value in %s field %q", bounds.name, field)
+				}
+			}
+		}
+		if start < bounds.min || end > bounds.max || start > end {
+			return 0, fmt.Errorf("%s field %q out of range %d-%d", bounds.name, field, bounds.min, bounds.max)
+		}
+		for value := start; value <= end; value += step {
+			result |= 1 << uint(value)
+		}
+	}
+	return result, nil
+}
+
+// Matches returns true if the schedule fires at the minute containing t.
+func (s *Schedule) Matches(t time.Time) bool {
+	if s.minutes&(1<<uint(t.Minute())) == 0 ||
+		s.hours&(1<<uint(t.Hour())) == 0 ||
+		s.months&(1<<uint(t.Month())) == 0 {
+		return false
+	}
+	dayOfMonth := s.daysOfMonth&(1<<uint(t.Day())) != 0
+	dayOfWeek := s.daysOfWeek&(1<<uint(t.Weekday())) != 0
+	if s.anyDayOfMonth || s.anyDayOfWeek {
+		return dayOfMonth && dayOfWeek
+	}
+	return dayOfMonth || dayOfWeek
+}
+
+// LastStart returns the latest time in (now - duration, now] at which the
+// schedule fired. The second value is false if the schedule didn't fire in
+// that period, meaning no window of the given duration started by the
+// schedule is open at now.
+func (s *Schedule) LastStart(now time.Time, duration time.Duration) (time.Time, bool) {
+	earliest := now.Add(-duration)
+	for t := now.Truncate(time.Minute); t.After(earliest); t = t.Add(-time.Minute) {
+		if s.Matches(t) {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
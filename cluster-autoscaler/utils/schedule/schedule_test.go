@@ -0,0 +1,100 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package schedule
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseErrors(t *testing.T) {
+	for _, spec := range []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"a * * * *",
+		"1-b * * * *",
+	} {
+		_, err := Parse(spec)
+		assert.Error(t, err, "schedule %q", spec)
+	}
+}
+
+func TestScheduleMatches(t *testing.T) {
+	// Monday.
+	monday := time.Date(2019, time.June, 3, 8, 30, 0, 0, time.UTC)
+	sunday := time.Date(2019, time.June, 2, 8, 30, 0, 0, time.UTC)
+
+	testCases := []struct {
+		spec    string
+		t       time.Time
+		matches bool
+	}{
+		{"* * * * *", monday, true},
+		{"30 8 * * *", monday, true},
+		{"30 8 * * *", monday.Add(time.Minute), false},
+		{"*/15 * * * *", monday, true},
+		{"*/20 * * * *", monday, false},
+		{"0-40/10 8 * * *", monday, true},
+		{"30 7,8 * * *", monday, true},
+		{"30 8 * * 1-5", monday, true},
+		{"30 8 * * 1-5", sunday, false},
+		{"30 8 * * 0", sunday, true},
+		{"30 8 * * 7", sunday, true},
+		{"30 8 * 6 *", monday, true},
+		{"30 8 * 7 *", monday, false},
+		// Either day of month or day of week has to match if both are restricted.
+		{"30 8 3 * 0", monday, true},
+		{"30 8 2 * 1", monday, true},
+		{"30 8 4 * 0", monday, false},
+		// Both have to match if one of them is "*".
+		{"30 8 4 * *", monday, false},
+		{"30 8 * * 0", monday, false},
+	}
+	for _, tc := range testCases {
+		schedule, err := Parse(tc.spec)
+		assert.NoError(t, err)
+		assert.Equal(t, tc.matches, schedule.Matches(tc.t), "schedule %q at %v", tc.spec, tc.t)
+	}
+}
+
+func TestScheduleLastStart(t *testing.T) {
+	schedule, err := Parse("0 8 * * 1-5")
+	assert.NoError(t, err)
+	start := time.Date(2019, time.June, 3, 8, 0, 0, 0, time.UTC)
+
+	_, found := schedule.LastStart(start.Add(-time.Second), 10*time.Hour)
+	assert.False(t, found)
+
+	for _, now := range []time.Time{start, start.Add(5 * time.Hour), start.Add(10*time.Hour - time.Second)} {
+		lastStart, found := schedule.LastStart(now, 10*time.Hour)
+		assert.True(t, found)
+		assert.Equal(t, start, lastStart)
+	}
+
+	_, found = schedule.LastStart(start.Add(10*time.Hour), 10*time.Hour)
+	assert.False(t, found)
+}